@@ -0,0 +1,90 @@
+// Command assets-backfill populates the assets registry from existing products.
+//
+// It rebuilds product_assets for every product (soft-deleted included) and
+// registers each referenced object that has no assets row yet, reading its
+// size and content type from storage. With -probe it also downloads each object
+// to record its SHA-256 and image dimensions. Re-running it is safe.
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"evening-gown/internal/assets"
+	"evening-gown/internal/bootstrap"
+	"evening-gown/internal/config"
+	"evening-gown/internal/database"
+	"evening-gown/internal/logging"
+	"evening-gown/internal/storage"
+)
+
+func main() {
+	probe := flag.Bool("probe", false, "download objects to record sha256 and dimensions")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("load config", "err", err)
+		os.Exit(1)
+	}
+	logger, closeLogger, err := logging.Init(cfg.Log)
+	if err != nil {
+		slog.Error("init logger", "err", err)
+		os.Exit(1)
+	}
+	defer func() { _ = closeLogger() }()
+
+	if cfg.Postgres.DSN == "" {
+		logger.Error("POSTGRES_DSN is empty (assets-backfill requires Postgres)")
+		os.Exit(1)
+	}
+
+	db, err := database.New(ctx, cfg.Postgres)
+	if err != nil {
+		logger.Error("open postgres", "err", err)
+		os.Exit(1)
+	}
+	defer func() {
+		_ = database.Close(db)
+	}()
+
+	if err := bootstrap.AutoMigrate(db); err != nil {
+		logger.Error("auto migrate", "err", err)
+		os.Exit(1)
+	}
+
+	// Without storage, rows are registered without size/content type.
+	var describe assets.DescribeFunc
+	store, err := storage.New(ctx, cfg.Minio)
+	if err != nil {
+		logger.Error("init storage", "err", err)
+		os.Exit(1)
+	}
+	if closer, ok := store.(io.Closer); ok {
+		defer func() {
+			_ = closer.Close()
+		}()
+	}
+	if store != nil {
+		describe = assets.StoreDescriber(store, *probe)
+	} else {
+		logger.Warn("storage disabled; registering assets without metadata")
+	}
+
+	res, err := assets.Backfill(ctx, db, describe)
+	if err != nil {
+		logger.Error("assets backfill", "err", err,
+			"products", res.Products, "registered", res.Registered, "missing", res.Missing)
+		os.Exit(1)
+	}
+	logger.Info("assets backfill completed",
+		"products", res.Products, "registered", res.Registered, "missing", res.Missing)
+}
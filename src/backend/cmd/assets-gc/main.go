@@ -0,0 +1,104 @@
+// Command assets-gc deletes stored objects under products/ (and their cached
+// variants) that no product references.
+//
+// It runs in dry-run mode by default and prints a JSON report of the objects
+// it would delete; pass -dry-run=false to delete them. Objects younger than
+// the grace period, and objects referenced by products or revisions that can
+// still be restored, are kept (GC_GRACE_PERIOD / GC_RESTORE_WINDOW, or the
+// flags below).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"evening-gown/internal/assets"
+	"evening-gown/internal/bootstrap"
+	"evening-gown/internal/config"
+	"evening-gown/internal/database"
+	"evening-gown/internal/logging"
+	"evening-gown/internal/storage"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("load config", "err", err)
+		os.Exit(1)
+	}
+
+	dryRun := flag.Bool("dry-run", true, "report orphaned objects without deleting them")
+	grace := flag.Duration("grace", cfg.GC.GracePeriod, "keep unreferenced objects younger than this")
+	window := flag.Duration("restore-window", cfg.GC.RestoreWindow, "keep objects of products soft-deleted within this window")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger, closeLogger, err := logging.Init(cfg.Log)
+	if err != nil {
+		slog.Error("init logger", "err", err)
+		os.Exit(1)
+	}
+	defer func() { _ = closeLogger() }()
+
+	if cfg.Postgres.DSN == "" {
+		logger.Error("POSTGRES_DSN is empty (assets-gc requires Postgres)")
+		os.Exit(1)
+	}
+
+	db, err := database.New(ctx, cfg.Postgres)
+	if err != nil {
+		logger.Error("open postgres", "err", err)
+		os.Exit(1)
+	}
+	defer func() {
+		_ = database.Close(db)
+	}()
+
+	if err := bootstrap.AutoMigrate(db); err != nil {
+		logger.Error("auto migrate", "err", err)
+		os.Exit(1)
+	}
+
+	store, err := storage.New(ctx, cfg.Minio)
+	if err != nil {
+		logger.Error("init storage", "err", err)
+		os.Exit(1)
+	}
+	if closer, ok := store.(io.Closer); ok {
+		defer func() {
+			_ = closer.Close()
+		}()
+	}
+	if store == nil {
+		logger.Error("storage is not configured (assets-gc requires object storage)")
+		os.Exit(1)
+	}
+
+	report, err := assets.CollectGarbage(ctx, db, store, assets.GCOptions{
+		GracePeriod:   *grace,
+		RestoreWindow: *window,
+		StagingTTL:    cfg.Upload.StagingTTL,
+		DryRun:        *dryRun,
+	})
+	if err != nil {
+		logger.Error("assets gc", "err", err, "scanned", report.Scanned, "deleted", report.Deleted)
+		os.Exit(1)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+
+	if report.DeleteErrors > 0 {
+		logger.Error("assets gc completed with errors", "deleted", report.Deleted, "delete_errors", report.DeleteErrors)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,82 @@
+// Command detail-migrate upgrades stored product details to the current
+// schema_version.
+//
+// It runs in dry-run mode by default: every product whose detail would be
+// migrated (or cannot be) is printed as one JSON line with the versions, the
+// migrations applied and the schema errors the result still has, followed by
+// a summary line. Pass -dry-run=false to write the migrated details back; a
+// revision is saved for products that have a revision history.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"evening-gown/internal/bootstrap"
+	"evening-gown/internal/config"
+	"evening-gown/internal/database"
+	"evening-gown/internal/detailmigrate"
+	"evening-gown/internal/logging"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", true, "report the products to migrate without writing them")
+	productID := flag.Uint("product", 0, "only migrate this product id")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("load config", "err", err)
+		os.Exit(1)
+	}
+	logger, closeLogger, err := logging.Init(cfg.Log)
+	if err != nil {
+		slog.Error("init logger", "err", err)
+		os.Exit(1)
+	}
+	defer func() { _ = closeLogger() }()
+
+	if cfg.Postgres.DSN == "" {
+		logger.Error("POSTGRES_DSN is empty (detail-migrate requires Postgres)")
+		os.Exit(1)
+	}
+
+	db, err := database.New(ctx, cfg.Postgres)
+	if err != nil {
+		logger.Error("open postgres", "err", err)
+		os.Exit(1)
+	}
+	defer func() {
+		_ = database.Close(db)
+	}()
+
+	if err := bootstrap.AutoMigrate(db); err != nil {
+		logger.Error("auto migrate", "err", err)
+		os.Exit(1)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	res, err := detailmigrate.Run(ctx, db, detailmigrate.Options{
+		DryRun:    *dryRun,
+		ProductID: uint(*productID),
+	}, func(r detailmigrate.ProductReport) {
+		_ = enc.Encode(r)
+	})
+	_ = enc.Encode(res)
+	if err != nil {
+		logger.Error("detail migrate", "err", err, "scanned", res.Scanned, "migrated", res.Migrated)
+		os.Exit(1)
+	}
+	if res.Failed > 0 {
+		logger.Error("detail migrate completed with errors", "migrated", res.Migrated, "failed", res.Failed)
+		os.Exit(1)
+	}
+}
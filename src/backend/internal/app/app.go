@@ -3,12 +3,14 @@ package app
 import (
 	"context"
 	"errors"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"evening-gown/internal/assets"
 	jwtauth "evening-gown/internal/auth"
 	"evening-gown/internal/bootstrap"
 	"evening-gown/internal/cache"
@@ -20,7 +22,10 @@ import (
 	publicHandlers "evening-gown/internal/handler/public"
 	"evening-gown/internal/logging"
 	"evening-gown/internal/middleware"
+	"evening-gown/internal/model"
 	"evening-gown/internal/router"
+	"evening-gown/internal/scheduler"
+	"evening-gown/internal/security"
 	"evening-gown/internal/storage"
 
 	"github.com/redis/go-redis/v9"
@@ -67,13 +72,13 @@ func Run() error {
 
 	// JWT service (shared by admin auth middleware).
 	var jwtSvc *jwtauth.Service
-	if cfg.JWT.Secret != "" {
+	if cfg.JWT.Enabled() {
 		jwtSvc, err = jwtauth.New(cfg.JWT)
 		if err != nil {
 			return err
 		}
 	} else {
-		logger.Info("jwt disabled: JWT_SECRET not set")
+		logger.Info("jwt disabled: neither JWT_SECRET nor JWT_PRIVATE_KEY_FILE set")
 	}
 
 	var redisClient *redis.Client
@@ -91,26 +96,43 @@ func Run() error {
 		logger.Info("redis disabled: REDIS_ADDR not set")
 	}
 
-	minioClient, err := storage.NewClient(ctx, cfg.Minio)
+	store, err := storage.New(ctx, cfg.Minio)
 	if err != nil {
 		return err
 	}
-	if minioClient == nil {
-		logger.Info("minio disabled: MINIO_ENDPOINT not set")
+	// The local backend holds an open root directory.
+	if closer, ok := store.(io.Closer); ok {
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.Warn("storage close error", "err", err)
+			}
+		}()
+	}
+	if store == nil {
+		logger.Info("storage disabled: MINIO_ENDPOINT not set")
+	} else {
+		logger.Info("storage enabled", "backend", store.Backend())
+		// Presigned uploads that are never finalized expire with the bucket;
+		// the GC job sweeps them too.
+		if expirer, ok := store.(storage.Expirer); ok {
+			if err := expirer.ExpirePrefix(ctx, model.UploadStagingPrefix, cfg.Upload.StagingTTL); err != nil {
+				logger.Warn("set staging upload expiry failed", "prefix", model.UploadStagingPrefix, "err", err)
+			}
+		}
 	}
 
 	// Legacy auth handler (dev-only token issuer / verify helper).
 	var authHandler *authHandlerPkg.Handler
-	if cfg.JWT.Secret != "" {
-		authHandler = authHandlerPkg.New(cfg.JWT)
+	if jwtSvc != nil {
+		authHandler = authHandlerPkg.NewWithService(jwtSvc)
 	}
 
-	healthHandler := health.New(db, redisClient, minioClient)
+	healthHandler := health.New(db, redisClient, store)
 	publicCache := cache.NewPublicCache(redisClient)
 
-	deps := router.Dependencies{Health: healthHandler, Auth: authHandler, EnableDevTokenIssuer: cfg.Dev.EnableDevTokenIssuer}
-	if minioClient != nil {
-		deps.Public.Assets = publicHandlers.NewAssetsHandler(db, minioClient, cfg.Minio, publicCache)
+	deps := router.Dependencies{Health: healthHandler, Auth: authHandler, EnableDevTokenIssuer: cfg.Dev.EnableDevTokenIssuer, TrustedProxies: cfg.App.TrustedProxies}
+	if store != nil {
+		deps.Public.Assets = publicHandlers.NewAssetsHandler(db, store, cfg.Upload, publicCache)
 	}
 
 	// Business APIs require Postgres.
@@ -132,17 +154,43 @@ func Run() error {
 		deps.Public.Contacts = publicHandlers.NewContactsHandlerWithRedis(db, redisClient)
 		deps.Public.Events = publicHandlers.NewEventsHandler(db)
 
-		deps.Admin.Auth = adminHandlers.NewAuthHandler(db, jwtSvc)
-		if minioClient != nil {
-			deps.Admin.Assets = adminHandlers.NewAssetsHandler(db, minioClient, cfg.Minio)
+		deps.Admin.Auth = adminHandlers.NewAuthHandlerWithLoginGuard(db, jwtSvc,
+			security.LockoutPolicy{MaxFailures: cfg.Admin.LoginMaxFailures, BaseLock: cfg.Admin.LoginLockBase, MaxLock: cfg.Admin.LoginLockMax},
+			cache.NewLoginLimiter(redisClient, cfg.Admin.LoginIPMaxFailures, cfg.Admin.LoginIPWindow),
+		)
+		if store != nil {
+			deps.Admin.Assets = adminHandlers.NewAssetsHandler(db, store)
 		}
-		deps.Admin.Uploads = adminHandlers.NewUploadsHandler(minioClient, cfg.Minio, cfg.Upload)
-		deps.Admin.Products = adminHandlers.NewProductsHandler(db, publicCache)
+		deps.Admin.Uploads = adminHandlers.NewUploadsHandler(db, store, cfg.Upload)
+		deps.Admin.Products = adminHandlers.NewProductsHandlerWithStore(db, publicCache, store)
 		deps.Admin.Updates = adminHandlers.NewUpdatesHandler(db, publicCache)
 		deps.Admin.Contacts = adminHandlers.NewContactsHandlerWithRedis(db, redisClient)
 		deps.Admin.Events = adminHandlers.NewEventsHandlerWithRedis(db, redisClient)
 		deps.Admin.Settings = adminHandlers.NewSettingsHandler(db)
+		deps.Admin.Users = adminHandlers.NewUsersHandler(db, cfg.Admin.InviteTTL)
+		deps.Admin.TwoFactor = adminHandlers.NewTwoFactorHandler(db, cfg.Admin.TOTPIssuer)
+		deps.Admin.Sessions = adminHandlers.NewSessionsHandler(db)
+		deps.Admin.AuditLogs = adminHandlers.NewAuditLogsHandler(db)
 		deps.Admin.AuthMiddleware = middleware.AdminAuth(db, jwtSvc)
+		if cfg.Admin.Require2FA {
+			deps.Admin.TwoFactorMiddleware = middleware.RequireTwoFactor()
+		}
+
+		if cfg.Scheduler.Enabled {
+			sched := scheduler.New(db, publicCache, cache.NewLocker(redisClient), cfg.Scheduler.Interval, logger)
+			go sched.Run(ctx)
+		} else {
+			logger.Info("scheduler disabled: SCHEDULER_ENABLED=false")
+		}
+
+		if cfg.GC.Enabled && store != nil {
+			gc := assets.NewGCJob(db, store, cache.NewLocker(redisClient), cfg.GC.Interval, assets.GCOptions{
+				GracePeriod:   cfg.GC.GracePeriod,
+				RestoreWindow: cfg.GC.RestoreWindow,
+				StagingTTL:    cfg.Upload.StagingTTL,
+			}, logger)
+			go gc.Run(ctx)
+		}
 	} else {
 		logger.Info("business APIs disabled: postgres not configured")
 	}
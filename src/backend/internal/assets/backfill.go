@@ -0,0 +1,160 @@
+package assets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+
+	"evening-gown/internal/imaging"
+	"evening-gown/internal/model"
+	"evening-gown/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+// ErrObjectMissing is returned by a DescribeFunc for keys that are not in the bucket.
+var ErrObjectMissing = errors.New("object missing")
+
+// DescribeFunc fills the stored metadata of an object (content type, size and,
+// when available, hash and dimensions) into a.
+type DescribeFunc func(ctx context.Context, a *model.Asset) error
+
+// BackfillResult counts what Backfill did.
+type BackfillResult struct {
+	Products   int // products whose references were rebuilt
+	Registered int // assets rows created
+	Missing    int // referenced keys not found in the bucket
+}
+
+const backfillBatchSize = 200
+
+// SyncAllProducts rebuilds the references of every product, soft-deleted ones
+// included, and returns how many products it processed.
+func SyncAllProducts(ctx context.Context, db *gorm.DB) (int, error) {
+	n := 0
+	var batch []model.Product
+	err := db.WithContext(ctx).Model(&model.Product{}).Order("id asc").
+		FindInBatches(&batch, backfillBatchSize, func(_ *gorm.DB, _ int) error {
+			return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				for _, p := range batch {
+					if err := SyncProduct(tx, p); err != nil {
+						return err
+					}
+					n++
+				}
+				return nil
+			})
+		}).Error
+	return n, err
+}
+
+// Backfill runs SyncAllProducts and then registers each referenced key that
+// has no assets row yet. describe may be nil, in which case rows are created
+// without metadata.
+func Backfill(ctx context.Context, db *gorm.DB, describe DescribeFunc) (BackfillResult, error) {
+	var res BackfillResult
+	var err error
+	if res.Products, err = SyncAllProducts(ctx, db); err != nil {
+		return res, err
+	}
+
+	// Referenced keys without a registry row, with the lowest referencing
+	// product as owner.
+	var pending []struct {
+		ObjectKey string
+		ProductID uint
+		StyleNo   string
+	}
+	if err := db.WithContext(ctx).Model(&model.ProductAsset{}).
+		Select("product_assets.object_key, MIN(product_assets.product_id) AS product_id, MIN(products.style_no) AS style_no").
+		Joins("JOIN products ON products.id = product_assets.product_id").
+		Where("NOT EXISTS (SELECT 1 FROM assets WHERE assets.object_key = product_assets.object_key)").
+		Group("product_assets.object_key").
+		Order("product_assets.object_key").
+		Scan(&pending).Error; err != nil {
+		return res, err
+	}
+
+	for _, row := range pending {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		owner := row.ProductID
+		a := model.Asset{
+			ObjectKey: row.ObjectKey,
+			ProductID: &owner,
+			StyleNo:   row.StyleNo,
+			Kind:      kindFromKey(row.ObjectKey),
+		}
+		if describe != nil {
+			err := describe(ctx, &a)
+			if errors.Is(err, ErrObjectMissing) {
+				res.Missing++
+				continue
+			}
+			if err != nil {
+				return res, err
+			}
+		}
+		if err := Register(ctx, db, &a); err != nil {
+			return res, err
+		}
+		res.Registered++
+	}
+	return res, nil
+}
+
+// kindFromKey reads {kind} from products/{styleNo}/{kind}/...
+func kindFromKey(key string) string {
+	parts := strings.Split(key, "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	switch parts[2] {
+	case "cover", "hover", "gallery":
+		return parts[2]
+	}
+	return ""
+}
+
+// StoreDescriber describes objects with Stat. With probe set it also
+// downloads each object to compute its SHA-256, image dimensions and
+// placeholder.
+func StoreDescriber(store storage.Store, probe bool) DescribeFunc {
+	return func(ctx context.Context, a *model.Asset) error {
+		meta, err := store.Stat(ctx, a.ObjectKey)
+		if err != nil {
+			if errors.Is(err, storage.ErrObjectNotFound) {
+				return ErrObjectMissing
+			}
+			return err
+		}
+		a.ContentType = meta.ContentType
+		a.Size = meta.Size
+		if !probe {
+			return nil
+		}
+
+		obj, err := store.Open(ctx, a.ObjectKey, 0, -1)
+		if err != nil {
+			return err
+		}
+		defer obj.Close()
+		data, err := io.ReadAll(obj)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		a.SHA256 = hex.EncodeToString(sum[:])
+		if img, _, err := imaging.Decode(data, 0); err == nil {
+			b := img.Bounds()
+			a.Width, a.Height = b.Dx(), b.Dy()
+			ph := imaging.PlaceholderOf(img)
+			a.Blurhash, a.DominantColor = ph.Blurhash, ph.DominantColor
+		}
+		return nil
+	}
+}
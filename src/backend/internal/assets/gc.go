@@ -0,0 +1,272 @@
+package assets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"evening-gown/internal/imaging"
+	"evening-gown/internal/model"
+	"evening-gown/internal/storage"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoReferences aborts a GC run when live products exist but none references
+// any object (product_assets not built yet; run cmd/assets-backfill).
+var ErrNoReferences = errors.New("no product asset references found")
+
+// GCOptions controls CollectGarbage.
+type GCOptions struct {
+	// GracePeriod protects recent objects, e.g. uploads whose product has not
+	// been saved yet.
+	GracePeriod time.Duration
+	// RestoreWindow is how long a soft-deleted product still counts as
+	// restorable; the objects it and its revisions reference are kept.
+	// Revisions of live products are restorable at any age and always kept.
+	RestoreWindow time.Duration
+	// StagingTTL is the age after which unfinalized presigned uploads (under
+	// model.UploadStagingPrefix) are deleted. Default: 24h.
+	StagingTTL time.Duration
+	DryRun     bool
+}
+
+// GCReport summarizes one GC run. Orphans lists every object selected for
+// deletion (deleted unless DryRun).
+type GCReport struct {
+	DryRun       bool       `json:"dryRun"`
+	StartedAt    time.Time  `json:"startedAt"`
+	Scanned      int        `json:"scanned"`
+	Referenced   int        `json:"referenced"`
+	Recent       int        `json:"recent"`
+	Scheduled    int        `json:"scheduled"`
+	Orphans      []GCObject `json:"orphans"`
+	OrphanBytes  int64      `json:"orphanBytes"`
+	Deleted      int        `json:"deleted"`
+	DeleteErrors int        `json:"deleteErrors"`
+}
+
+type GCObject struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// gcPrefixes are the storage prefixes the GC owns: uploads, their cached
+// variants and staged presigned uploads.
+var gcPrefixes = []string{"products/", imaging.VariantPrefix, model.UploadStagingPrefix}
+
+// CollectGarbage deletes objects under products/ (and their cached variants)
+// that no product references and that are older than the grace period.
+//
+// Kept: keys referenced by live products and by products soft-deleted within
+// the restore window, and by any revision of those products (RestoreRevision
+// has no age limit), plus all renditions of a kept upload and the variants
+// derived from it.
+//
+// Staged presigned uploads are never referenced; they are collected once
+// older than StagingTTL, together with their upload intents.
+//
+// The grace period runs from an object's last modification, or from when a
+// deduplicated upload last reused it (model.Asset.ReusedAt), whichever is later.
+//
+// Objects scheduled for deletion (model.AssetDeletion) are collected once due,
+// regardless of the grace period, unless something restorable still
+// references them; their schedule entries are kept until then.
+func CollectGarbage(ctx context.Context, db *gorm.DB, store storage.Store, opt GCOptions) (GCReport, error) {
+	now := time.Now().UTC()
+	report := GCReport{DryRun: opt.DryRun, StartedAt: now, Orphans: []GCObject{}}
+
+	kept, err := restorableKeys(ctx, db, now.Add(-opt.RestoreWindow))
+	if err != nil {
+		return report, err
+	}
+	if len(kept.keys) == 0 {
+		// An empty reference table next to existing products means the
+		// references were never built; collecting now would wipe the bucket.
+		var products int64
+		if err := db.WithContext(ctx).Model(&model.Product{}).Where("deleted_at IS NULL").Count(&products).Error; err != nil {
+			return report, err
+		}
+		if products > 0 {
+			return report, ErrNoReferences
+		}
+	}
+	youngest := now.Add(-opt.GracePeriod)
+	stagingTTL := opt.StagingTTL
+	if stagingTTL <= 0 {
+		stagingTTL = 24 * time.Hour
+	}
+	stagingCutoff := now.Add(-stagingTTL)
+
+	var dueKeys []string
+	if err := db.WithContext(ctx).Model(&model.AssetDeletion{}).
+		Where("due_at <= ?", now).
+		Pluck("object_key", &dueKeys).Error; err != nil {
+		return report, err
+	}
+	due := make(map[string]bool, len(dueKeys))
+	for _, k := range dueKeys {
+		due[k] = true
+	}
+
+	var reusedKeys []string
+	if err := db.WithContext(ctx).Model(&model.Asset{}).
+		Where("reused_at > ?", youngest).
+		Pluck("object_key", &reusedKeys).Error; err != nil {
+		return report, err
+	}
+	reused := make(map[string]bool, len(reusedKeys))
+	for _, k := range reusedKeys {
+		reused[k] = true
+	}
+
+	for _, prefix := range gcPrefixes {
+		err := store.List(ctx, prefix, func(obj storage.ObjectInfo) error {
+			report.Scanned++
+			cutoff := youngest
+			if strings.HasPrefix(obj.Key, model.UploadStagingPrefix) {
+				cutoff = stagingCutoff
+			} else if kept.has(obj.Key) {
+				report.Referenced++
+				return nil
+			}
+			if due[obj.Key] {
+				report.Scheduled++
+				cutoff = now
+			}
+			if obj.LastModified.After(cutoff) || (cutoff == youngest && reused[obj.Key]) {
+				report.Recent++
+				return nil
+			}
+			report.Orphans = append(report.Orphans, GCObject{Key: obj.Key, Size: obj.Size, LastModified: obj.LastModified})
+			report.OrphanBytes += obj.Size
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if opt.DryRun {
+		return report, nil
+	}
+	_ = db.WithContext(ctx).Where("created_at < ?", stagingCutoff).Delete(&model.UploadIntent{}).Error
+	for _, obj := range report.Orphans {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if err := store.Remove(ctx, obj.Key); err != nil {
+			report.DeleteErrors++
+			delete(due, obj.Key) // retry next run
+			continue
+		}
+		report.Deleted++
+		_ = db.WithContext(ctx).Where("object_key = ?", obj.Key).Delete(&model.Asset{}).Error
+	}
+
+	// Settle schedule entries whose object is gone (deleted above, or never
+	// there); entries for still referenced objects wait for a later run.
+	var settled []string
+	for key := range due {
+		if !kept.has(key) {
+			settled = append(settled, key)
+		}
+	}
+	if len(settled) > 0 {
+		_ = db.WithContext(ctx).Where("object_key IN ?", settled).Delete(&model.AssetDeletion{}).Error
+	}
+	return report, nil
+}
+
+// ScheduleDeletion schedules keys for deletion by the GC once dueAt has
+// passed. Rescheduling a key moves its due time. Call it inside the
+// transaction that stops referencing the keys.
+func ScheduleDeletion(tx *gorm.DB, keys []string, dueAt time.Time, reason string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	rows := make([]model.AssetDeletion, 0, len(keys))
+	for _, k := range keys {
+		rows = append(rows, model.AssetDeletion{ObjectKey: k, Reason: reason, DueAt: dueAt.UTC()})
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "object_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"reason", "due_at"}),
+	}).Create(&rows).Error
+}
+
+// keySet holds kept keys and the upload prefixes of kept renditions.
+type keySet struct {
+	keys     map[string]bool
+	prefixes map[string]bool
+}
+
+func (s keySet) add(key string) {
+	s.keys[key] = true
+	if prefix, _, ok := imaging.SplitRenditionKey(key); ok {
+		s.prefixes[prefix] = true
+	}
+}
+
+func (s keySet) has(key string) bool {
+	// variants/{original}/w{N}.{fmt} lives as long as its original.
+	if rest, ok := strings.CutPrefix(key, imaging.VariantPrefix); ok {
+		i := strings.LastIndex(rest, "/")
+		if i <= 0 {
+			return false
+		}
+		key = rest[:i]
+	}
+	if s.keys[key] {
+		return true
+	}
+	prefix, _, ok := imaging.SplitRenditionKey(key)
+	return ok && s.prefixes[prefix]
+}
+
+func restorableKeys(ctx context.Context, db *gorm.DB, cutoff time.Time) (keySet, error) {
+	set := keySet{keys: map[string]bool{}, prefixes: map[string]bool{}}
+
+	var keys []string
+	if err := db.WithContext(ctx).Model(&model.ProductAsset{}).
+		Distinct("product_assets.object_key").
+		Joins("JOIN products ON products.id = product_assets.product_id").
+		Where("products.deleted_at IS NULL OR products.deleted_at > ?", cutoff).
+		Pluck("product_assets.object_key", &keys).Error; err != nil {
+		return set, err
+	}
+	for _, k := range keys {
+		set.add(k)
+	}
+
+	var revs []model.ProductRevision
+	err := db.WithContext(ctx).Model(&model.ProductRevision{}).
+		Select("product_revisions.id, product_revisions.product_id, product_revisions.snapshot").
+		Joins("JOIN products ON products.id = product_revisions.product_id").
+		Where("products.deleted_at IS NULL OR products.deleted_at > ?", cutoff).
+		FindInBatches(&revs, backfillBatchSize, func(_ *gorm.DB, _ int) error {
+			for _, rev := range revs {
+				var snap model.ProductSnapshot
+				if err := json.Unmarshal(rev.Snapshot, &snap); err != nil {
+					continue
+				}
+				p := model.Product{
+					ID:            rev.ProductID,
+					CoverImageURL: snap.CoverImageURL,
+					CoverImageKey: snap.CoverImageKey,
+					HoverImageURL: snap.HoverImageURL,
+					HoverImageKey: snap.HoverImageKey,
+					DetailJSON:    snap.Detail,
+				}
+				for _, ref := range model.ProductAssetRefs(p) {
+					set.add(ref.ObjectKey)
+				}
+			}
+			return nil
+		}).Error
+	return set, err
+}
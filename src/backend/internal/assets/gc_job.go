@@ -0,0 +1,88 @@
+package assets
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"evening-gown/internal/cache"
+	"evening-gown/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+// GCLockKey guards a GC run across instances.
+const GCLockKey = "eg:assets:gc:lock"
+
+// GCJob runs CollectGarbage periodically.
+//
+// The lock is held for a whole interval after a successful run (it is not
+// released), so restarts and additional instances don't collect more often
+// than once per interval.
+type GCJob struct {
+	db       *gorm.DB
+	store    storage.Store
+	locker   *cache.Locker
+	interval time.Duration
+	opt      GCOptions
+	logger   *slog.Logger
+}
+
+func NewGCJob(db *gorm.DB, store storage.Store, locker *cache.Locker, interval time.Duration, opt GCOptions, logger *slog.Logger) *GCJob {
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &GCJob{db: db, store: store, locker: locker, interval: interval, opt: opt, logger: logger}
+}
+
+// Run ticks until ctx is canceled.
+func (j *GCJob) Run(ctx context.Context) {
+	if j == nil || j.db == nil || j.store == nil {
+		return
+	}
+	j.logger.Info("assets gc started", "interval", j.interval.String(), "dry_run", j.opt.DryRun)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		j.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *GCJob) tick(ctx context.Context) {
+	release, ok, err := j.locker.TryLock(ctx, GCLockKey, j.interval)
+	if err != nil {
+		j.logger.Warn("assets gc lock failed", "err", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	report, err := CollectGarbage(ctx, j.db, j.store, j.opt)
+	if err != nil {
+		// Let another instance (or the next tick) retry.
+		release()
+		j.logger.Error("assets gc failed", "err", err)
+		return
+	}
+	j.logger.Info("assets gc completed",
+		"dry_run", report.DryRun,
+		"scanned", report.Scanned,
+		"referenced", report.Referenced,
+		"recent", report.Recent,
+		"scheduled", report.Scheduled,
+		"orphans", len(report.Orphans),
+		"orphan_bytes", report.OrphanBytes,
+		"deleted", report.Deleted,
+		"delete_errors", report.DeleteErrors,
+	)
+}
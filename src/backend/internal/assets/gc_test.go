@@ -0,0 +1,278 @@
+package assets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"evening-gown/internal/model"
+	"evening-gown/internal/storage"
+)
+
+// testStore is a local store whose objects get explicit modification times.
+type testStore struct {
+	*storage.LocalStore
+	t   *testing.T
+	dir string
+}
+
+func newTestStore(t *testing.T) *testStore {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := storage.NewLocalStore(dir)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return &testStore{LocalStore: s, t: t, dir: dir}
+}
+
+func (s *testStore) put(key string, modified time.Time) {
+	s.t.Helper()
+	if err := s.Put(context.Background(), key, strings.NewReader("0123456789"), 10, ""); err != nil {
+		s.t.Fatalf("put %s: %v", key, err)
+	}
+	if err := os.Chtimes(filepath.Join(s.dir, filepath.FromSlash(key)), modified, modified); err != nil {
+		s.t.Fatalf("chtimes %s: %v", key, err)
+	}
+}
+
+func (s *testStore) exists(key string) bool {
+	_, err := s.Stat(context.Background(), key)
+	return err == nil
+}
+
+func TestCollectGarbage(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	old := now.Add(-60 * 24 * time.Hour)
+	recentlyDeleted := now.Add(-2 * 24 * time.Hour)
+	longDeleted := now.Add(-45 * 24 * time.Hour)
+
+	live := model.Product{
+		Slug: "a", StyleNo: "A1", Season: "ss25", Category: "gown", Availability: "in_stock",
+		CoverImageKey: "products/A1/cover/2025/01/01/u1/w2048.webp",
+		DetailJSON:    json.RawMessage(`{"gallery":[{"url":"/api/v1/assets/products/A1/gallery/2025/01/01/g1/w1280.webp?w=640"}]}`),
+	}
+	restorable := model.Product{
+		Slug: "b", StyleNo: "B1", Season: "ss25", Category: "gown", Availability: "in_stock",
+		CoverImageKey: "products/B1/cover/2025/01/01/u2/w2048.webp",
+		DeletedAt:     &recentlyDeleted,
+	}
+	expired := model.Product{
+		Slug: "c", StyleNo: "C1", Season: "ss25", Category: "gown", Availability: "in_stock",
+		CoverImageKey: "products/C1/cover/2025/01/01/u3/w2048.webp",
+		DeletedAt:     &longDeleted,
+	}
+	for _, p := range []*model.Product{&live, &restorable, &expired} {
+		if err := db.Create(p).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+		if err := SyncProduct(db, *p); err != nil {
+			t.Fatalf("SyncProduct: %v", err)
+		}
+	}
+
+	// The live product's previous covers are still reachable through its
+	// revisions, however old: RestoreRevision has no age limit.
+	for i, rev := range []struct {
+		key     string
+		created time.Time
+	}{
+		{"products/A1/cover/2024/12/01/prev/w2048.webp", now},
+		{"products/A1/cover/2024/01/01/ancient/w2048.webp", now.Add(-400 * 24 * time.Hour)},
+	} {
+		snap, _ := json.Marshal(model.ProductSnapshot{CoverImageKey: rev.key})
+		if err := db.Create(&model.ProductRevision{ProductID: live.ID, Revision: i + 1, Source: "update", Snapshot: snap, CreatedAt: rev.created}).Error; err != nil {
+			t.Fatalf("create revision: %v", err)
+		}
+	}
+	// Revisions of a product deleted before the restore window are not kept.
+	snap, _ := json.Marshal(model.ProductSnapshot{CoverImageKey: "products/C1/cover/2024/12/01/prev/w2048.webp"})
+	if err := db.Create(&model.ProductRevision{ProductID: expired.ID, Revision: 1, Source: "update", Snapshot: snap}).Error; err != nil {
+		t.Fatalf("create revision: %v", err)
+	}
+	if err := db.Create(&model.Asset{ObjectKey: "products/A1/cover/2025/01/01/orphan/w2048.webp"}).Error; err != nil {
+		t.Fatalf("create asset: %v", err)
+	}
+
+	kept := []string{
+		"products/A1/cover/2025/01/01/u1/w2048.webp",
+		"products/A1/cover/2025/01/01/u1/w320.webp", // sibling rendition
+		"products/A1/gallery/2025/01/01/g1/w1280.webp",
+		"variants/products/A1/gallery/2025/01/01/g1/w1280.webp/w640.jpeg",
+		"products/A1/cover/2024/12/01/prev/w2048.webp",
+		"products/A1/cover/2024/01/01/ancient/w2048.webp",
+		"products/B1/cover/2025/01/01/u2/w2048.webp",
+		"products/A1/cover/2025/09/01/fresh/w2048.webp", // within the grace period
+	}
+	orphans := []string{
+		"products/A1/cover/2025/01/01/orphan/w2048.webp",
+		"products/C1/cover/2025/01/01/u3/w2048.webp",
+		"products/C1/cover/2024/12/01/prev/w2048.webp",
+		"variants/products/C1/cover/2025/01/01/u3/w2048.webp/w640.webp",
+		"uploads/2025/01/01/never-finalized",
+	}
+	store := newTestStore(t)
+	for _, k := range append(append([]string{}, kept...), orphans...) {
+		store.put(k, old)
+	}
+	store.put("products/A1/cover/2025/09/01/fresh/w2048.webp", now.Add(-time.Hour))
+	store.put("uploads/2025/09/01/in-flight", now.Add(-time.Hour)) // staged, within StagingTTL
+	store.put("settings/logo.png", old)                            // outside the GC prefixes
+
+	opt := GCOptions{GracePeriod: 7 * 24 * time.Hour, RestoreWindow: 30 * 24 * time.Hour, StagingTTL: 24 * time.Hour, DryRun: true}
+	report, err := CollectGarbage(ctx, db, store, opt)
+	if err != nil {
+		t.Fatalf("CollectGarbage (dry run): %v", err)
+	}
+	if report.Scanned != len(kept)+len(orphans)+1 || report.Recent != 2 || report.Referenced != len(kept)-1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	var got []string
+	for _, o := range report.Orphans {
+		got = append(got, o.Key)
+	}
+	sort.Strings(got)
+	want := append([]string{}, orphans...)
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("orphans = %v, want %v", got, want)
+	}
+	if report.Deleted != 0 || !store.exists(orphans[0]) {
+		t.Fatalf("dry run must not delete: %+v", report)
+	}
+
+	opt.DryRun = false
+	report, err = CollectGarbage(ctx, db, store, opt)
+	if err != nil {
+		t.Fatalf("CollectGarbage: %v", err)
+	}
+	if report.Deleted != len(orphans) {
+		t.Fatalf("expected %d deletions, got %+v", len(orphans), report)
+	}
+	for _, k := range orphans {
+		if store.exists(k) {
+			t.Fatalf("orphan %s was not deleted", k)
+		}
+	}
+	for _, k := range append(kept, "settings/logo.png", "uploads/2025/09/01/in-flight") {
+		if !store.exists(k) {
+			t.Fatalf("kept object %s was deleted", k)
+		}
+	}
+	var cnt int64
+	db.Model(&model.Asset{}).Where("object_key = ?", orphans[0]).Count(&cnt)
+	if cnt != 0 {
+		t.Fatalf("expected registry row of deleted object to be removed")
+	}
+}
+
+func TestCollectGarbage_RefusesWithoutReferences(t *testing.T) {
+	db := openTestDB(t)
+	p := model.Product{Slug: "a", StyleNo: "A1", Season: "ss25", Category: "gown", Availability: "in_stock",
+		CoverImageKey: "products/A1/cover/2025/01/01/u1/w2048.webp"}
+	if err := db.Create(&p).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	store := newTestStore(t)
+	store.put(p.CoverImageKey, time.Now().Add(-365*24*time.Hour))
+
+	_, err := CollectGarbage(context.Background(), db, store, GCOptions{})
+	if !errors.Is(err, ErrNoReferences) || !store.exists(p.CoverImageKey) {
+		t.Fatalf("expected ErrNoReferences without deleting, got %v", err)
+	}
+}
+
+func TestCollectGarbage_ScheduledDeletions(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	p := model.Product{Slug: "a", StyleNo: "A2", Season: "ss25", Category: "gown", Availability: "in_stock",
+		CoverImageKey: "products/A2/cover/2025/01/01/u1/w2048.webp"}
+	if err := db.Create(&p).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	if err := SyncProduct(db, p); err != nil {
+		t.Fatalf("SyncProduct: %v", err)
+	}
+
+	store := newTestStore(t)
+	renamed := "products/A1/cover/2025/01/01/u1/w2048.webp" // fresh, but due
+	notYet := "products/A1/cover/2025/01/01/u1/w320.webp"
+	store.put(renamed, now.Add(-time.Hour))
+	store.put(notYet, now.Add(-time.Hour))
+	store.put(p.CoverImageKey, now.Add(-time.Hour))
+
+	if err := ScheduleDeletion(db, []string{renamed, p.CoverImageKey, "products/A1/gone.webp"}, now.Add(-time.Minute), "rename"); err != nil {
+		t.Fatalf("ScheduleDeletion: %v", err)
+	}
+	if err := ScheduleDeletion(db, []string{notYet}, now.Add(time.Hour), "rename"); err != nil {
+		t.Fatalf("ScheduleDeletion: %v", err)
+	}
+
+	opt := GCOptions{GracePeriod: 7 * 24 * time.Hour, RestoreWindow: 30 * 24 * time.Hour}
+	report, err := CollectGarbage(ctx, db, store, opt)
+	if err != nil {
+		t.Fatalf("CollectGarbage: %v", err)
+	}
+	if report.Scheduled != 1 || report.Deleted != 1 || store.exists(renamed) {
+		t.Fatalf("expected the due object to be deleted: %+v", report)
+	}
+	if !store.exists(notYet) || !store.exists(p.CoverImageKey) {
+		t.Fatalf("objects not due, or still referenced, were deleted")
+	}
+
+	var pending []string
+	db.Model(&model.AssetDeletion{}).Order("object_key").Pluck("object_key", &pending)
+	if strings.Join(pending, ",") != notYet+","+p.CoverImageKey {
+		t.Fatalf("pending schedule = %v", pending)
+	}
+}
+
+func TestCollectGarbage_ReusedUploadsGetGrace(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	p := model.Product{Slug: "a", StyleNo: "A3", Season: "ss25", Category: "gown", Availability: "in_stock",
+		CoverImageKey: "products/A3/cover/2025/01/01/u1/w2048.webp"}
+	if err := db.Create(&p).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	if err := SyncProduct(db, p); err != nil {
+		t.Fatalf("SyncProduct: %v", err)
+	}
+
+	// An old upload just handed out again by dedup, not saved to a product yet.
+	reusedKey := "products/A3/gallery/2025/01/01/u2/w2048.webp"
+	staleKey := "products/A3/gallery/2025/01/01/u3/w2048.webp"
+	longAgo := now.Add(-60 * 24 * time.Hour)
+	rows := []model.Asset{{ObjectKey: reusedKey, StyleNo: "A3"}, {ObjectKey: staleKey, StyleNo: "A3", ReusedAt: &longAgo}}
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatalf("create assets: %v", err)
+	}
+	if err := MarkReused(ctx, db, rows[:1]); err != nil {
+		t.Fatalf("MarkReused: %v", err)
+	}
+
+	store := newTestStore(t)
+	for _, k := range []string{p.CoverImageKey, reusedKey, staleKey} {
+		store.put(k, longAgo)
+	}
+	report, err := CollectGarbage(ctx, db, store, GCOptions{GracePeriod: 7 * 24 * time.Hour, RestoreWindow: 30 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("CollectGarbage: %v", err)
+	}
+	if report.Recent != 1 || report.Deleted != 1 || !store.exists(reusedKey) || store.exists(staleKey) {
+		t.Fatalf("expected only the recently reused upload to be kept: %+v", report)
+	}
+}
@@ -0,0 +1,155 @@
+// Package assets maintains the registry of uploaded objects (model.Asset) and
+// the product→object references (model.ProductAsset) that asset endpoints
+// authorize against.
+package assets
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"evening-gown/internal/imaging"
+	"evening-gown/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SyncProduct rewrites the references of p from its current content and
+// variants, and claims ownership of referenced, still unowned assets. Call it
+// inside the transaction that saves p (or its variants).
+func SyncProduct(tx *gorm.DB, p model.Product) error {
+	if err := tx.Where("product_id = ?", p.ID).Delete(&model.ProductAsset{}).Error; err != nil {
+		return err
+	}
+	var variants []model.ProductVariant
+	if err := tx.Select("id, image_keys").Where("product_id = ?", p.ID).Find(&variants).Error; err != nil {
+		return err
+	}
+	refs := append(model.ProductAssetRefs(p), model.VariantAssetRefs(p.ID, variants)...)
+	if len(refs) == 0 {
+		return nil
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&refs).Error; err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(refs))
+	for _, r := range refs {
+		keys = append(keys, r.ObjectKey)
+	}
+	return tx.Model(&model.Asset{}).
+		Where("object_key IN ? AND product_id IS NULL", keys).
+		Update("product_id", p.ID).Error
+}
+
+// Register inserts a, or refreshes the stored metadata when the key is
+// already registered.
+func Register(ctx context.Context, db *gorm.DB, a *model.Asset) error {
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "object_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content_type", "size", "sha256", "width", "height", "blurhash", "dominant_color"}),
+	}).Create(a).Error
+}
+
+// FindUpload returns the renditions of an earlier upload of the same file
+// (same source hash) for styleNo, narrowest first, or none. When the file was
+// uploaded more than once, the most recent upload wins.
+func FindUpload(ctx context.Context, db *gorm.DB, styleNo, sourceSHA256 string) ([]model.Asset, error) {
+	if sourceSHA256 == "" {
+		return nil, nil
+	}
+	var rows []model.Asset
+	if err := db.WithContext(ctx).
+		Where("style_no = ? AND source_sha256 = ?", styleNo, sourceSHA256).
+		Order("id desc").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	prefix, _, ok := imaging.SplitRenditionKey(rows[0].ObjectKey)
+	if !ok {
+		return nil, nil
+	}
+	out := rows[:0]
+	for _, a := range rows {
+		if p, _, ok := imaging.SplitRenditionKey(a.ObjectKey); ok && p == prefix {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Width < out[j].Width })
+	return out, nil
+}
+
+// MarkReused stamps rows handed out again by a deduplicated upload, so the
+// GC grants them a fresh grace period (see CollectGarbage).
+func MarkReused(ctx context.Context, db *gorm.DB, rows []model.Asset) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(rows))
+	for _, a := range rows {
+		ids = append(ids, a.ID)
+	}
+	return db.WithContext(ctx).Model(&model.Asset{}).
+		Where("id IN ?", ids).
+		Update("reused_at", time.Now().UTC()).Error
+}
+
+// IsPublished reports whether key is referenced by a published, not deleted product.
+func IsPublished(ctx context.Context, db *gorm.DB, key string) (bool, error) {
+	return referenced(ctx, db, key, true)
+}
+
+// IsReferenced reports whether key is referenced by any not deleted product.
+func IsReferenced(ctx context.Context, db *gorm.DB, key string) (bool, error) {
+	return referenced(ctx, db, key, false)
+}
+
+func referenced(ctx context.Context, db *gorm.DB, key string, published bool) (bool, error) {
+	q := db.WithContext(ctx).Model(&model.ProductAsset{}).
+		Joins("JOIN products ON products.id = product_assets.product_id").
+		Where("product_assets.object_key = ?", key).
+		Where("products.deleted_at IS NULL")
+	if published {
+		q = q.Where("products.published_at IS NOT NULL")
+	}
+	var cnt int64
+	if err := q.Limit(1).Count(&cnt).Error; err != nil {
+		return false, err
+	}
+	return cnt > 0, nil
+}
+
+// ImageInfo is what clients need to reserve layout space for an image and
+// show a placeholder while it loads.
+type ImageInfo struct {
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	DominantColor string `json:"dominantColor,omitempty"`
+	Blurhash      string `json:"blurhash,omitempty"`
+}
+
+// ImageInfos returns the ImageInfo of the registered keys among keys. Keys
+// that are not registered, or were registered without dimensions, are
+// omitted.
+func ImageInfos(ctx context.Context, db *gorm.DB, keys []string) (map[string]ImageInfo, error) {
+	out := map[string]ImageInfo{}
+	if len(keys) == 0 {
+		return out, nil
+	}
+	var rows []model.Asset
+	if err := db.WithContext(ctx).
+		Select("object_key, width, height, blurhash, dominant_color").
+		Where("object_key IN ? AND width > 0 AND height > 0", keys).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range rows {
+		out[a.ObjectKey] = ImageInfo{Width: a.Width, Height: a.Height, DominantColor: a.DominantColor, Blurhash: a.Blurhash}
+	}
+	return out, nil
+}
@@ -0,0 +1,109 @@
+package assets
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"evening-gown/internal/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err == nil {
+		t.Cleanup(func() { _ = sqlDB.Close() })
+	}
+	if err := db.AutoMigrate(&model.Product{}, &model.ProductRevision{}, &model.Asset{}, &model.ProductAsset{}, &model.UploadIntent{}, &model.AssetDeletion{}, &model.ProductVariant{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestBackfillAndAuthorize(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	published := model.Product{
+		Slug: "a", StyleNo: "A1", Season: "ss25", Category: "gown", Availability: "in_stock",
+		CoverImageKey: "products/A1/cover/2025/09/01/x/w2048.webp",
+		DetailJSON:    json.RawMessage(`{"gallery":[{"objectKey":"products/A1/gallery/2025/09/01/y/w2048.webp"}]}`),
+		PublishedAt:   &now,
+	}
+	draft := model.Product{
+		Slug: "b", StyleNo: "B1", Season: "ss25", Category: "gown", Availability: "in_stock",
+		CoverImageKey: "products/B1/cover/2025/09/01/z/w2048.webp",
+	}
+	deleted := model.Product{
+		Slug: "c", StyleNo: "C1", Season: "ss25", Category: "gown", Availability: "in_stock",
+		CoverImageKey: "products/C1/cover/2025/09/01/gone.webp",
+		PublishedAt:   &now,
+		DeletedAt:     &now,
+	}
+	for _, p := range []*model.Product{&published, &draft, &deleted} {
+		if err := db.Create(p).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+	}
+
+	describe := func(_ context.Context, a *model.Asset) error {
+		if a.ObjectKey == "products/A1/gallery/2025/09/01/y/w2048.webp" {
+			return ErrObjectMissing
+		}
+		a.ContentType, a.Size = "image/webp", 42
+		return nil
+	}
+	res, err := Backfill(ctx, db, describe)
+	if err != nil {
+		t.Fatalf("Backfill: %v", err)
+	}
+	if res.Products != 3 || res.Registered != 3 || res.Missing != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	var cover model.Asset
+	if err := db.Where("object_key = ?", published.CoverImageKey).First(&cover).Error; err != nil {
+		t.Fatalf("load asset: %v", err)
+	}
+	if cover.ProductID == nil || *cover.ProductID != published.ID || cover.Kind != "cover" || cover.StyleNo != "A1" || cover.Size != 42 {
+		t.Fatalf("unexpected asset: %+v", cover)
+	}
+
+	// Re-running is a no-op.
+	if res, err := Backfill(ctx, db, describe); err != nil || res.Registered != 0 {
+		t.Fatalf("second Backfill: %+v %v", res, err)
+	}
+
+	check := func(name string, fn func(context.Context, *gorm.DB, string) (bool, error), key string, want bool) {
+		t.Helper()
+		got, err := fn(ctx, db, key)
+		if err != nil {
+			t.Fatalf("%s(%q): %v", name, key, err)
+		}
+		if got != want {
+			t.Fatalf("%s(%q) = %v, want %v", name, key, got, want)
+		}
+	}
+	check("IsPublished", IsPublished, published.CoverImageKey, true)
+	check("IsPublished", IsPublished, "products/A1/gallery/2025/09/01/y/w2048.webp", true)
+	check("IsPublished", IsPublished, draft.CoverImageKey, false)
+	check("IsReferenced", IsReferenced, draft.CoverImageKey, true)
+	check("IsReferenced", IsReferenced, deleted.CoverImageKey, false)
+
+	// Saving new content drops stale references.
+	published.CoverImageKey = "products/A1/cover/2025/09/02/new/w2048.webp"
+	if err := SyncProduct(db, published); err != nil {
+		t.Fatalf("SyncProduct: %v", err)
+	}
+	check("IsPublished", IsPublished, "products/A1/cover/2025/09/01/x/w2048.webp", false)
+	check("IsPublished", IsPublished, published.CoverImageKey, true)
+}
@@ -9,6 +9,7 @@ import (
 	"evening-gown/internal/config"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 var (
@@ -19,8 +20,9 @@ var (
 )
 
 type Service struct {
-	cfg config.JWTConfig
-	key []byte
+	cfg       config.JWTConfig
+	signer    signingKey
+	verifiers []verifyKey
 }
 
 // AdminClaims extends the standard registered claims with a password update marker.
@@ -30,16 +32,101 @@ type Service struct {
 type AdminClaims struct {
 	jwt.RegisteredClaims
 	PasswordUpdatedAt int64 `json:"pwd_at,omitempty"`
+	// TokenVersion mirrors User.TokenVersion at issuance. Bumping the user's version
+	// (e.g. when an account is disabled) revokes every outstanding token at once.
+	TokenVersion int `json:"tv,omitempty"`
+	// SessionID ties access and refresh tokens to an AdminSession row ("sid").
+	// Empty on tokens issued before sessions existed.
+	SessionID string `json:"sid,omitempty"`
 	// TokenType distinguishes access vs refresh tokens.
-	// Values: "access" | "refresh". Empty means legacy access token.
+	// Values: "access" | "refresh" | "mfa". Empty means legacy access token.
 	TokenType string `json:"token_type,omitempty"`
 }
 
 func New(cfg config.JWTConfig) (*Service, error) {
-	if strings.TrimSpace(cfg.Secret) == "" {
-		return nil, ErrJWTMissingSecret
+	signer, verifiers, err := loadKeys(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{cfg: cfg, signer: signer, verifiers: verifiers}, nil
+}
+
+// JWKS returns the public verification keys (asymmetric only; HS256 secrets are never published).
+func (s *Service) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if s == nil {
+		return set
+	}
+	for _, k := range s.verifiers {
+		if jwk, ok := toJWK(k.kid, k.key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// sign signs claims with the current signing key and stamps its kid header.
+func (s *Service) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signer.method, claims)
+	token.Header["kid"] = s.signer.kid
+	return token.SignedString(s.signer.key)
+}
+
+// parse validates tokenString into claims against the verification key set.
+//
+// Tokens with a known kid are checked against that key only; tokens without a kid
+// (issued before kid headers existed) are tried against every key of their algorithm.
+func (s *Service) parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	methods := make([]string, 0, len(s.verifiers))
+	seen := map[string]bool{}
+	for _, k := range s.verifiers {
+		if alg := k.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+
+	opts := []jwt.ParserOption{
+		// Prevent alg=none and other unexpected algorithms.
+		jwt.WithValidMethods(methods),
+	}
+	if strings.TrimSpace(s.cfg.Issuer) != "" {
+		opts = append(opts, jwt.WithIssuer(s.cfg.Issuer))
+	}
+	if strings.TrimSpace(s.cfg.Audience) != "" {
+		opts = append(opts, jwt.WithAudience(s.cfg.Audience))
+	}
+
+	return jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
+		if t.Method == nil {
+			return nil, ErrJWTInvalidToken
+		}
+		alg := t.Method.Alg()
+		kid, _ := t.Header["kid"].(string)
+
+		var keys []jwt.VerificationKey
+		for _, k := range s.verifiers {
+			if k.method.Alg() != alg {
+				continue
+			}
+			if kid != "" && k.kid == kid {
+				return k.key, nil
+			}
+			keys = append(keys, k.key)
+		}
+		if kid != "" || len(keys) == 0 {
+			return nil, fmt.Errorf("unknown signing key %q (%s)", kid, alg)
+		}
+		return jwt.VerificationKeySet{Keys: keys}, nil
+	}, opts...)
+}
+
+// RefreshTTL returns the configured refresh token lifetime (0 means the default).
+func (s *Service) RefreshTTL() time.Duration {
+	if s == nil {
+		return 0
 	}
-	return &Service{cfg: cfg, key: []byte(cfg.Secret)}, nil
+	return s.cfg.RefreshExpiresIn
 }
 
 func (s *Service) IssueToken(subject string) (tokenString string, expiresAt time.Time, err error) {
@@ -65,16 +152,16 @@ func (s *Service) IssueToken(subject string) (tokenString string, expiresAt time
 		claims.Audience = jwt.ClaimStrings{s.cfg.Audience}
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString(s.key)
+	ss, err := s.sign(claims)
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("sign token: %w", err)
 	}
 	return ss, expiresAt, nil
 }
 
-// IssueAdminToken issues a HS256 JWT for admin usage with an additional password marker.
-func (s *Service) IssueAdminToken(subject string, passwordUpdatedAtUnix int64) (tokenString string, expiresAt time.Time, err error) {
+// IssueAdminToken issues a JWT for admin usage with password and token-version markers.
+// Each token gets a random jti; sessionID links it to its AdminSession.
+func (s *Service) IssueAdminToken(subject string, passwordUpdatedAtUnix int64, tokenVersion int, sessionID string) (tokenString string, expiresAt time.Time, err error) {
 	if s == nil {
 		return "", time.Time{}, ErrJWTDisabled
 	}
@@ -93,25 +180,28 @@ func (s *Service) IssueAdminToken(subject string, passwordUpdatedAtUnix int64) (
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now.Add(-30 * time.Second)),
+			ID:        uuid.NewString(),
 		},
 		PasswordUpdatedAt: passwordUpdatedAtUnix,
-		TokenType:        "access",
+		TokenVersion:      tokenVersion,
+		SessionID:         sessionID,
+		TokenType:         "access",
 	}
 	if strings.TrimSpace(s.cfg.Audience) != "" {
 		claims.Audience = jwt.ClaimStrings{s.cfg.Audience}
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString(s.key)
+	ss, err := s.sign(claims)
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("sign token: %w", err)
 	}
 	return ss, expiresAt, nil
 }
 
-// IssueAdminRefreshToken issues a HS256 refresh token for admin usage.
+// IssueAdminRefreshToken issues a refresh token for admin usage.
 // It is meant to be exchanged for short-lived access tokens via a refresh endpoint.
-func (s *Service) IssueAdminRefreshToken(subject string, passwordUpdatedAtUnix int64) (tokenString string, expiresAt time.Time, err error) {
+// jti identifies the token within its session's refresh family (see model.AdminSession).
+func (s *Service) IssueAdminRefreshToken(subject string, passwordUpdatedAtUnix int64, tokenVersion int, sessionID, jti string) (tokenString string, expiresAt time.Time, err error) {
 	if s == nil {
 		return "", time.Time{}, ErrJWTDisabled
 	}
@@ -135,16 +225,18 @@ func (s *Service) IssueAdminRefreshToken(subject string, passwordUpdatedAtUnix i
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now.Add(-30 * time.Second)),
+			ID:        jti,
 		},
 		PasswordUpdatedAt: passwordUpdatedAtUnix,
-		TokenType:        "refresh",
+		TokenVersion:      tokenVersion,
+		SessionID:         sessionID,
+		TokenType:         "refresh",
 	}
 	if strings.TrimSpace(s.cfg.Audience) != "" {
 		claims.Audience = jwt.ClaimStrings{s.cfg.Audience}
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString(s.key)
+	ss, err := s.sign(claims)
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("sign token: %w", err)
 	}
@@ -160,23 +252,7 @@ func (s *Service) ParseToken(tokenString string) (*jwt.RegisteredClaims, error)
 		return nil, ErrJWTMissingToken
 	}
 
-	opts := []jwt.ParserOption{
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
-	}
-	if strings.TrimSpace(s.cfg.Issuer) != "" {
-		opts = append(opts, jwt.WithIssuer(s.cfg.Issuer))
-	}
-	if strings.TrimSpace(s.cfg.Audience) != "" {
-		opts = append(opts, jwt.WithAudience(s.cfg.Audience))
-	}
-
-	parsed, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(t *jwt.Token) (any, error) {
-		// Prevent alg=none and other unexpected algorithms.
-		if t.Method == nil || t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return s.key, nil
-	}, opts...)
+	parsed, err := s.parse(tokenString, &jwt.RegisteredClaims{})
 	if err != nil {
 		return nil, fmt.Errorf("parse token: %w", err)
 	}
@@ -202,22 +278,7 @@ func (s *Service) ParseAdminToken(tokenString string) (*AdminClaims, error) {
 		return nil, ErrJWTMissingToken
 	}
 
-	opts := []jwt.ParserOption{
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
-	}
-	if strings.TrimSpace(s.cfg.Issuer) != "" {
-		opts = append(opts, jwt.WithIssuer(s.cfg.Issuer))
-	}
-	if strings.TrimSpace(s.cfg.Audience) != "" {
-		opts = append(opts, jwt.WithAudience(s.cfg.Audience))
-	}
-
-	parsed, err := jwt.ParseWithClaims(tokenString, &AdminClaims{}, func(t *jwt.Token) (any, error) {
-		if t.Method == nil || t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return s.key, nil
-	}, opts...)
+	parsed, err := s.parse(tokenString, &AdminClaims{})
 	if err != nil {
 		return nil, fmt.Errorf("parse token: %w", err)
 	}
@@ -230,8 +291,8 @@ func (s *Service) ParseAdminToken(tokenString string) (*AdminClaims, error) {
 		return nil, ErrJWTInvalidToken
 	}
 
-	// Do not allow refresh tokens to pass as access tokens.
-	if strings.EqualFold(strings.TrimSpace(claims.TokenType), "refresh") {
+	// Do not allow refresh/mfa tokens to pass as access tokens.
+	if tt := strings.TrimSpace(claims.TokenType); tt != "" && !strings.EqualFold(tt, "access") {
 		return nil, ErrJWTInvalidToken
 	}
 
@@ -248,22 +309,76 @@ func (s *Service) ParseAdminRefreshToken(tokenString string) (*AdminClaims, erro
 		return nil, ErrJWTMissingToken
 	}
 
-	opts := []jwt.ParserOption{
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+	parsed, err := s.parse(tokenString, &AdminClaims{})
+	if err != nil {
+		return nil, fmt.Errorf("parse token: %w", err)
 	}
-	if strings.TrimSpace(s.cfg.Issuer) != "" {
-		opts = append(opts, jwt.WithIssuer(s.cfg.Issuer))
+	if parsed == nil || !parsed.Valid {
+		return nil, ErrJWTInvalidToken
+	}
+
+	claims, ok := parsed.Claims.(*AdminClaims)
+	if !ok || claims == nil {
+		return nil, ErrJWTInvalidToken
+	}
+	if !strings.EqualFold(strings.TrimSpace(claims.TokenType), "refresh") {
+		return nil, ErrJWTInvalidToken
+	}
+
+	return claims, nil
+}
+
+// MFATokenTTL bounds how long a user has to enter the second factor after a password login.
+const MFATokenTTL = 5 * time.Minute
+
+// IssueAdminMFAToken issues a short-lived token proving the password step of a 2FA login.
+// It can only be exchanged for real tokens via the 2FA verify endpoint.
+func (s *Service) IssueAdminMFAToken(subject string, passwordUpdatedAtUnix int64, tokenVersion int) (tokenString string, expiresAt time.Time, err error) {
+	if s == nil {
+		return "", time.Time{}, ErrJWTDisabled
+	}
+	if strings.TrimSpace(subject) == "" {
+		return "", time.Time{}, fmt.Errorf("subject is empty")
+	}
+
+	now := time.Now()
+	expiresAt = now.Add(MFATokenTTL)
+
+	claims := AdminClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.cfg.Issuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now.Add(-30 * time.Second)),
+		},
+		PasswordUpdatedAt: passwordUpdatedAtUnix,
+		TokenVersion:      tokenVersion,
+		TokenType:         "mfa",
 	}
 	if strings.TrimSpace(s.cfg.Audience) != "" {
-		opts = append(opts, jwt.WithAudience(s.cfg.Audience))
+		claims.Audience = jwt.ClaimStrings{s.cfg.Audience}
 	}
 
-	parsed, err := jwt.ParseWithClaims(tokenString, &AdminClaims{}, func(t *jwt.Token) (any, error) {
-		if t.Method == nil || t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return s.key, nil
-	}, opts...)
+	ss, err := s.sign(claims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("sign token: %w", err)
+	}
+	return ss, expiresAt, nil
+}
+
+// ParseAdminMFAToken validates a 2FA challenge token and returns its claims.
+func (s *Service) ParseAdminMFAToken(tokenString string) (*AdminClaims, error) {
+	if s == nil {
+		return nil, ErrJWTDisabled
+	}
+	tokenString = strings.TrimSpace(tokenString)
+	if tokenString == "" {
+		return nil, ErrJWTMissingToken
+	}
+
+	parsed, err := s.parse(tokenString, &AdminClaims{})
 	if err != nil {
 		return nil, fmt.Errorf("parse token: %w", err)
 	}
@@ -275,7 +390,7 @@ func (s *Service) ParseAdminRefreshToken(tokenString string) (*AdminClaims, erro
 	if !ok || claims == nil {
 		return nil, ErrJWTInvalidToken
 	}
-	if !strings.EqualFold(strings.TrimSpace(claims.TokenType), "refresh") {
+	if !strings.EqualFold(strings.TrimSpace(claims.TokenType), "mfa") {
 		return nil, ErrJWTInvalidToken
 	}
 
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"evening-gown/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writePEM(t *testing.T, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write pem: %v", err)
+	}
+	return path
+}
+
+func writePrivateKey(t *testing.T, name string, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal private key: %v", err)
+	}
+	return writePEM(t, name, "PRIVATE KEY", der)
+}
+
+func writePublicKey(t *testing.T, name string, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	return writePEM(t, name, "PUBLIC KEY", der)
+}
+
+func kidOf(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &AdminClaims{})
+	if err != nil {
+		t.Fatalf("parse unverified: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestService_HS256_SecretRotation(t *testing.T) {
+	oldSvc, err := New(config.JWTConfig{Secret: "old-secret", Issuer: "eg", ExpiresIn: time.Minute})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	oldToken, _, err := oldSvc.IssueAdminToken("1", 0, 0, "")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if kid := kidOf(t, oldToken); kid == "" {
+		t.Fatalf("expected kid header")
+	}
+
+	// Without the previous secret the old token is rejected.
+	svc, err := New(config.JWTConfig{Secret: "new-secret", Issuer: "eg", ExpiresIn: time.Minute})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if _, err := svc.ParseAdminToken(oldToken); err == nil {
+		t.Fatalf("expected old token to be rejected")
+	}
+
+	svc, err = New(config.JWTConfig{Secret: "new-secret", Issuer: "eg", ExpiresIn: time.Minute, PreviousSecrets: []string{"old-secret"}})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if _, err := svc.ParseAdminToken(oldToken); err != nil {
+		t.Fatalf("expected old token accepted during rotation: %v", err)
+	}
+	if got := len(svc.JWKS().Keys); got != 0 {
+		t.Fatalf("expected no published keys for HS256, got %d", got)
+	}
+
+	// Tokens issued before kid headers existed still verify.
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, AdminClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "eg",
+			Subject:   "1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	})
+	legacyToken, err := legacy.SignedString([]byte("old-secret"))
+	if err != nil {
+		t.Fatalf("sign legacy: %v", err)
+	}
+	if _, err := svc.ParseAdminToken(legacyToken); err != nil {
+		t.Fatalf("expected legacy token accepted: %v", err)
+	}
+}
+
+func TestService_RS256_KeyRotationAndJWKS(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa: %v", err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa: %v", err)
+	}
+
+	oldSvc, err := New(config.JWTConfig{Algorithm: AlgRS256, PrivateKeyFile: writePrivateKey(t, "old.pem", oldKey), ExpiresIn: time.Minute})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	oldToken, _, err := oldSvc.IssueAdminToken("1", 0, 0, "s1")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+
+	svc, err := New(config.JWTConfig{
+		Algorithm:      AlgRS256,
+		PrivateKeyFile: writePrivateKey(t, "new.pem", newKey),
+		VerifyKeyFiles: []string{writePublicKey(t, "old.pub.pem", &oldKey.PublicKey)},
+		ExpiresIn:      time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	newToken, _, err := svc.IssueAdminToken("1", 0, 0, "s2")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if kidOf(t, newToken) == kidOf(t, oldToken) {
+		t.Fatalf("expected distinct kids per key")
+	}
+
+	for name, tok := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := svc.ParseAdminToken(tok); err != nil {
+			t.Fatalf("%s token rejected: %v", name, err)
+		}
+	}
+	// The old service does not know the new key.
+	if _, err := oldSvc.ParseAdminToken(newToken); err == nil {
+		t.Fatalf("expected new token to be rejected by old key set")
+	}
+
+	set := svc.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected 2 published keys, got %d", len(set.Keys))
+	}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || k.Alg != AlgRS256 || k.N == "" || k.E == "" || k.Kid == "" {
+			t.Fatalf("unexpected jwk: %+v", k)
+		}
+	}
+
+	// HS256 tokens must not be accepted by an RS256-only key set (alg confusion).
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, AdminClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+	})
+	forgedToken, err := forged.SignedString(x509.MarshalPKCS1PublicKey(&newKey.PublicKey))
+	if err != nil {
+		t.Fatalf("sign forged: %v", err)
+	}
+	if _, err := svc.ParseAdminToken(forgedToken); err == nil {
+		t.Fatalf("expected HS256 token to be rejected")
+	}
+}
+
+func TestService_EdDSA(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519: %v", err)
+	}
+	svc, err := New(config.JWTConfig{Algorithm: AlgEdDSA, PrivateKeyFile: writePrivateKey(t, "ed.pem", priv), KeyID: "ed-2026", ExpiresIn: time.Minute})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	tok, _, err := svc.IssueAdminToken("1", 0, 0, "")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if got := kidOf(t, tok); got != "ed-2026" {
+		t.Fatalf("expected configured kid, got %q", got)
+	}
+	if _, err := svc.ParseAdminToken(tok); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	set := svc.JWKS()
+	if len(set.Keys) != 1 || set.Keys[0].Kty != "OKP" || set.Keys[0].Crv != "Ed25519" || set.Keys[0].Kid != "ed-2026" {
+		t.Fatalf("unexpected jwks: %+v", set)
+	}
+
+	// A mismatched algorithm is a configuration error.
+	if _, err := New(config.JWTConfig{Algorithm: AlgRS256, PrivateKeyFile: writePrivateKey(t, "ed2.pem", priv)}); err == nil {
+		t.Fatalf("expected algorithm/key mismatch error")
+	}
+
+	// Without an algorithm it follows the key; with HS256 the key is an error.
+	inferred, err := New(config.JWTConfig{PrivateKeyFile: writePrivateKey(t, "ed3.pem", priv), ExpiresIn: time.Minute})
+	if err != nil {
+		t.Fatalf("new without algorithm: %v", err)
+	}
+	if set := inferred.JWKS(); len(set.Keys) != 1 || set.Keys[0].Alg != AlgEdDSA {
+		t.Fatalf("expected inferred EdDSA, got %+v", set)
+	}
+	if _, err := New(config.JWTConfig{Algorithm: AlgHS256, Secret: "s", PrivateKeyFile: writePrivateKey(t, "ed4.pem", priv)}); !errors.Is(err, ErrJWTPrivateKeyWithHS256) {
+		t.Fatalf("expected ErrJWTPrivateKeyWithHS256, got %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"evening-gown/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms (JWT_ALGORITHM).
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	ErrJWTMissingPrivateKey = errors.New("missing jwt private key")
+	// ErrJWTPrivateKeyWithHS256 reports a JWT_PRIVATE_KEY_FILE that HS256 would
+	// silently ignore.
+	ErrJWTPrivateKeyWithHS256 = errors.New("JWT_PRIVATE_KEY_FILE is set but JWT_ALGORITHM is HS256; set it to RS256 or EdDSA, or leave it empty")
+)
+
+// signingKey is the key new tokens are signed with.
+type signingKey struct {
+	kid    string
+	method jwt.SigningMethod
+	key    any // []byte | *rsa.PrivateKey | ed25519.PrivateKey
+}
+
+// verifyKey is a key accepted when validating tokens.
+type verifyKey struct {
+	kid    string
+	method jwt.SigningMethod
+	key    any // []byte | *rsa.PublicKey | ed25519.PublicKey
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// loadKeys builds the signing key and the verification key set from config.
+//
+// HS256 signs with JWT_SECRET; JWT_PREVIOUS_SECRETS stay valid for verification.
+// RS256/EdDSA sign with the PEM in JWT_PRIVATE_KEY_FILE; the PEM public keys in
+// JWT_VERIFY_KEY_FILES stay valid for verification during a rotation window.
+// Without JWT_ALGORITHM the algorithm follows the private key when one is set,
+// else it is HS256.
+func loadKeys(cfg config.JWTConfig) (signingKey, []verifyKey, error) {
+	var (
+		signer    signingKey
+		verifiers []verifyKey
+	)
+
+	alg := strings.TrimSpace(cfg.Algorithm)
+	hasKeyFile := strings.TrimSpace(cfg.PrivateKeyFile) != ""
+	switch {
+	case strings.EqualFold(alg, AlgHS256) && hasKeyFile:
+		return signer, nil, ErrJWTPrivateKeyWithHS256
+
+	case alg == "" && !hasKeyFile || strings.EqualFold(alg, AlgHS256):
+		if strings.TrimSpace(cfg.Secret) == "" {
+			return signer, nil, ErrJWTMissingSecret
+		}
+		secret := []byte(cfg.Secret)
+		signer = signingKey{kid: keyIDOr(cfg.KeyID, hmacKeyID(secret)), method: jwt.SigningMethodHS256, key: secret}
+		verifiers = append(verifiers, verifyKey{kid: signer.kid, method: signer.method, key: secret})
+
+	case alg == "" || strings.EqualFold(alg, AlgRS256) || strings.EqualFold(alg, AlgEdDSA):
+		if !hasKeyFile {
+			return signer, nil, ErrJWTMissingPrivateKey
+		}
+		priv, err := readPrivateKey(cfg.PrivateKeyFile)
+		if err != nil {
+			return signer, nil, err
+		}
+		method, pub, err := methodForKey(priv)
+		if err != nil {
+			return signer, nil, err
+		}
+		if alg != "" && !strings.EqualFold(method.Alg(), alg) {
+			return signer, nil, fmt.Errorf("jwt private key is %s, JWT_ALGORITHM is %s", method.Alg(), alg)
+		}
+		kid, err := thumbprint(pub)
+		if err != nil {
+			return signer, nil, err
+		}
+		signer = signingKey{kid: keyIDOr(cfg.KeyID, kid), method: method, key: priv}
+		verifiers = append(verifiers, verifyKey{kid: signer.kid, method: method, key: pub})
+
+	default:
+		return signer, nil, fmt.Errorf("unsupported JWT_ALGORITHM %q", alg)
+	}
+
+	for _, secret := range cfg.PreviousSecrets {
+		if strings.TrimSpace(secret) == "" {
+			continue
+		}
+		b := []byte(secret)
+		verifiers = append(verifiers, verifyKey{kid: hmacKeyID(b), method: jwt.SigningMethodHS256, key: b})
+	}
+	for _, path := range cfg.VerifyKeyFiles {
+		if strings.TrimSpace(path) == "" {
+			continue
+		}
+		pub, err := readPublicKey(path)
+		if err != nil {
+			return signer, nil, err
+		}
+		method, err := methodForPublicKey(pub)
+		if err != nil {
+			return signer, nil, err
+		}
+		kid, err := thumbprint(pub)
+		if err != nil {
+			return signer, nil, err
+		}
+		verifiers = append(verifiers, verifyKey{kid: kid, method: method, key: pub})
+	}
+
+	return signer, verifiers, nil
+}
+
+func keyIDOr(configured, fallback string) string {
+	if k := strings.TrimSpace(configured); k != "" {
+		return k
+	}
+	return fallback
+}
+
+// hmacKeyID derives a stable, non-reversible kid for a shared secret.
+func hmacKeyID(secret []byte) string {
+	sum := sha256.Sum256(secret)
+	return "hs-" + hex.EncodeToString(sum[:8])
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := k.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("jwt private key %s: unsupported key type %T", path, k)
+		}
+		return signer, nil
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("jwt private key %s: expected PKCS#8 or PKCS#1 PEM", path)
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwt verify key %s: %w", path, err)
+		}
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		k, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwt verify key %s: %w", path, err)
+		}
+		return k, nil
+	default:
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwt verify key %s: %w", path, err)
+		}
+		return k, nil
+	}
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	raw, err := os.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return nil, fmt.Errorf("read jwt key: %w", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("jwt key %s: no PEM block found", path)
+	}
+	return block, nil
+}
+
+func methodForKey(priv crypto.Signer) (jwt.SigningMethod, crypto.PublicKey, error) {
+	pub := priv.Public()
+	method, err := methodForPublicKey(pub)
+	return method, pub, err
+}
+
+func methodForPublicKey(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("jwt rsa key too small: %d bits", k.N.BitLen())
+		}
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported jwt key type %T", pub)
+	}
+}
+
+// toJWK converts a public key into its JWK representation.
+func toJWK(kid string, pub crypto.PublicKey) (JWK, bool) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA", Kid: kid, Use: "sig", Alg: AlgRS256,
+			N: b64(k.N.Bytes()),
+			E: b64(big.NewInt(int64(k.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Kid: kid, Use: "sig", Alg: AlgEdDSA, Crv: "Ed25519", X: b64(k)}, true
+	default:
+		return JWK{}, false
+	}
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint, used as the default kid.
+func thumbprint(pub crypto.PublicKey) (string, error) {
+	jwk, ok := toJWK("", pub)
+	if !ok {
+		return "", fmt.Errorf("unsupported jwt key type %T", pub)
+	}
+	// Required members only, in lexicographic order.
+	var members any
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return b64(sum[:]), nil
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
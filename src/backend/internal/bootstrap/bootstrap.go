@@ -1,11 +1,13 @@
 package bootstrap
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"evening-gown/internal/assets"
 	"evening-gown/internal/model"
 	"evening-gown/internal/security"
 
@@ -29,11 +31,22 @@ func AutoMigrate(db *gorm.DB) error {
 
 	if err := db.AutoMigrate(
 		&model.User{},
+		&model.UserOneTimeToken{},
+		&model.UserRecoveryCode{},
+		&model.AdminSession{},
 		&model.Product{},
+		&model.ProductRevision{},
+		&model.ProductVariant{},
+		&model.TemplateApplyJob{},
 		&model.AppSetting{},
 		&model.UpdatePost{},
 		&model.ContactLead{},
 		&model.Event{},
+		&model.AuditLog{},
+		&model.Asset{},
+		&model.ProductAsset{},
+		&model.UploadIntent{},
+		&model.AssetDeletion{},
 	); err != nil {
 		return err
 	}
@@ -43,6 +56,14 @@ func AutoMigrate(db *gorm.DB) error {
 		return err
 	}
 
+	if err := ensureProductSearchIndex(db); err != nil {
+		return err
+	}
+
+	if err := ensureProductAssetRefs(db); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -74,6 +95,62 @@ END $$;`
 	return db.Exec(q).Error
 }
 
+// ensureProductAssetRefs builds product_assets once, when the table is still
+// empty but products exist (first start after upgrading), so asset
+// authorization keeps working before `cmd/assets-backfill` has been run.
+func ensureProductAssetRefs(db *gorm.DB) error {
+	if db == nil {
+		return nil
+	}
+	var refs, products int64
+	if err := db.Model(&model.ProductAsset{}).Count(&refs).Error; err != nil {
+		return err
+	}
+	if refs > 0 {
+		return nil
+	}
+	if err := db.Model(&model.Product{}).Count(&products).Error; err != nil {
+		return err
+	}
+	if products == 0 {
+		return nil
+	}
+	_, err := assets.SyncAllProducts(context.Background(), db)
+	return err
+}
+
+// ensureProductSearchIndex creates the GIN index backing public product search.
+func ensureProductSearchIndex(db *gorm.DB) error {
+	if db == nil {
+		return nil
+	}
+	// SQLite (tests) has no tsvector; the search handler falls back to LIKE there.
+	if db.Dialector == nil || db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	q := "CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (" + model.ProductSearchVectorSQL + ")"
+	if err := db.Exec(q).Error; err != nil {
+		return err
+	}
+
+	// Substring matches (style number fragments, zh text) need pg_trgm. Creating
+	// the extension needs privileges the app role may not have; without it the
+	// search still works, those branches just scan.
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		return nil
+	}
+	for name, expr := range map[string]string{
+		"idx_products_search_style_trgm": model.ProductSearchStyleSQL,
+		"idx_products_search_text_trgm":  model.ProductSearchTextSQL,
+	} {
+		q := "CREATE INDEX IF NOT EXISTS " + name + " ON products USING GIN ((" + expr + ") gin_trgm_ops)"
+		if err := db.Exec(q).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func ensureProductDetailTemplateSetting(db *gorm.DB) error {
 	if db == nil {
 		return ErrPostgresRequired
@@ -88,17 +165,18 @@ func ensureProductDetailTemplateSetting(db *gorm.DB) error {
 	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&set).Error
 }
 
-// EnsureSingleAdmin creates the first super admin user if no admin exists.
+// EnsureSingleAdmin creates the first owner user if no owner exists.
 //
-// It enforces a "single super admin" policy on creation, but does not delete
-// extra rows if they already exist.
+// Further backoffice users (editor/sales/viewer or additional owners) are managed
+// by owners at runtime; this only bootstraps the initial account. Legacy "admin"
+// rows count as owners.
 func EnsureSingleAdmin(db *gorm.DB, email, password string) error {
 	if db == nil {
 		return ErrPostgresRequired
 	}
 
 	var adminCount int64
-	if err := db.Model(&model.User{}).Where("role = ? AND deleted_at IS NULL", "admin").Count(&adminCount).Error; err != nil {
+	if err := db.Model(&model.User{}).Where("role IN ? AND deleted_at IS NULL", []string{model.RoleOwner, model.RoleAdmin}).Count(&adminCount).Error; err != nil {
 		return fmt.Errorf("count admin users: %w", err)
 	}
 	if adminCount > 0 {
@@ -121,7 +199,7 @@ func EnsureSingleAdmin(db *gorm.DB, email, password string) error {
 	user := model.User{
 		Email:          email,
 		PasswordHash:   hash,
-		Role:           "admin",
+		Role:           model.RoleOwner,
 		Status:         "active",
 		PasswordUpdatedAt: &now,
 	}
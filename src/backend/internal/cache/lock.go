@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Locker hands out short-lived exclusive leases in Redis so that periodic jobs
+// run on a single instance at a time.
+//
+// With Redis disabled every TryLock succeeds: the project then runs as a single
+// instance and there is nobody to coordinate with.
+type Locker struct {
+	rdb *redis.Client
+}
+
+func NewLocker(rdb *redis.Client) *Locker {
+	return &Locker{rdb: rdb}
+}
+
+// releaseScript deletes the lock only if it is still held by the caller's token,
+// so a lease that expired and was taken over is never released by the old owner.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// TryLock acquires key for ttl. It returns ok=false when another holder has it.
+// The returned release func is safe to call once the work is done; the lease
+// also expires on its own after ttl.
+func (l *Locker) TryLock(ctx context.Context, key string, ttl time.Duration) (release func(), ok bool, err error) {
+	if l == nil || l.rdb == nil {
+		return func() {}, true, nil
+	}
+
+	token := uuid.NewString()
+	ok, err = l.rdb.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !ok {
+		return func() {}, false, err
+	}
+	return func() {
+		// Use a fresh context: the caller's may already be canceled on shutdown.
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = releaseScript.Run(releaseCtx, l.rdb, []string{key}, token).Err()
+	}, true, nil
+}
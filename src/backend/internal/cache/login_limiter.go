@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LoginLimiter counts failed admin login attempts per client IP in Redis.
+//
+// It complements the per-account lockout: an attacker spraying many accounts from
+// one address is throttled even though no single account crosses its threshold.
+// Like PublicCache it is best-effort; with Redis disabled/unavailable every
+// attempt is allowed.
+type LoginLimiter struct {
+	rdb         *redis.Client
+	maxAttempts int
+	window      time.Duration
+}
+
+func NewLoginLimiter(rdb *redis.Client, maxAttempts int, window time.Duration) *LoginLimiter {
+	return &LoginLimiter{rdb: rdb, maxAttempts: maxAttempts, window: window}
+}
+
+func (l *LoginLimiter) enabled() bool {
+	return l != nil && l.rdb != nil && l.maxAttempts > 0 && l.window > 0
+}
+
+// Blocked reports whether ip has used up its failed attempts for the current window.
+// retryAfter is the remaining window when blocked.
+func (l *LoginLimiter) Blocked(ctx context.Context, ip string) (blocked bool, retryAfter time.Duration) {
+	if !l.enabled() {
+		return false, 0
+	}
+	key := loginAttemptsKey(ip)
+	n, err := l.rdb.Get(ctx, key).Int()
+	if err != nil || n < l.maxAttempts {
+		return false, 0
+	}
+	ttl, err := l.rdb.TTL(ctx, key).Result()
+	if err != nil || ttl <= 0 {
+		ttl = l.window
+	}
+	return true, ttl
+}
+
+// recordFailureScript counts a failure and starts the window in one step, so
+// the counter can never be left without a TTL. A key that lost it anyway (e.g.
+// written by an older version) gets one on the next failure.
+var recordFailureScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if redis.call("TTL", KEYS[1]) < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// RecordFailure counts a failed attempt from ip. The window starts at the first failure.
+func (l *LoginLimiter) RecordFailure(ctx context.Context, ip string) {
+	if !l.enabled() {
+		return
+	}
+	_ = recordFailureScript.Run(ctx, l.rdb, []string{loginAttemptsKey(ip)}, l.window.Milliseconds()).Err()
+}
+
+func loginAttemptsKey(ip string) string {
+	return fmt.Sprintf("eg:admin:login:fail:ip=%s", escapeKeyPart(strings.ToLower(ip)))
+}
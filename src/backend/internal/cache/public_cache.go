@@ -126,6 +126,16 @@ func (c *PublicCache) ProductsListKey(ver int64, season, category, availability,
 	return fmt.Sprintf("eg:public:products:list:v%d:season=%s:category=%s:availability=%s:is_new=%s:limit=%d:offset=%d", ver, escapeKeyPart(season), escapeKeyPart(category), escapeKeyPart(availability), isNew, limit, offset)
 }
 
+func (c *PublicCache) ProductsSearchKey(ver int64, terms []string, season, category, availability, isNew string, limit, offset int) string {
+	isNew = strings.TrimSpace(isNew)
+	if isNew != "true" && isNew != "false" {
+		isNew = ""
+	}
+
+	// Terms are already normalized (lower-case, deduplicated) by the handler.
+	return fmt.Sprintf("eg:public:products:search:v%d:q=%s:season=%s:category=%s:availability=%s:is_new=%s:limit=%d:offset=%d", ver, escapeKeyPart(strings.Join(terms, "+")), escapeKeyPart(season), escapeKeyPart(category), escapeKeyPart(availability), isNew, limit, offset)
+}
+
 func (c *PublicCache) ProductDetailKey(ver int64, id uint) string {
 	return fmt.Sprintf("eg:public:products:get:v%d:id=%d", ver, id)
 }
@@ -15,15 +15,40 @@ import (
 
 // Config aggregates application configuration.
 type Config struct {
-	App      AppConfig
-	Postgres PostgresConfig
-	Redis    RedisConfig
-	Minio    MinioConfig
-	Upload   UploadConfig
-	JWT      JWTConfig
-	Admin    AdminConfig
-	Dev      DevConfig
-	Log      LogConfig
+	App       AppConfig
+	Postgres  PostgresConfig
+	Redis     RedisConfig
+	Minio     MinioConfig
+	Upload    UploadConfig
+	JWT       JWTConfig
+	Admin     AdminConfig
+	Dev       DevConfig
+	Log       LogConfig
+	Scheduler SchedulerConfig
+	GC        GCConfig
+}
+
+// SchedulerConfig controls the in-process publish/unpublish scheduler.
+type SchedulerConfig struct {
+	// Enabled runs the scheduler loop in this process. Default: true.
+	// Multiple instances are safe: a Redis lock lets only one apply changes per tick.
+	Enabled bool
+	// Interval between runs. Default: 30s.
+	Interval time.Duration
+}
+
+// GCConfig controls the in-process orphaned-object GC (see internal/assets).
+type GCConfig struct {
+	// Enabled runs GC periodically in this process. Default: false; run
+	// cmd/assets-gc in dry-run mode first.
+	Enabled bool
+	// Interval between runs. Default: 24h.
+	Interval time.Duration
+	// GracePeriod keeps unreferenced objects younger than this. Default: 168h (7 days).
+	GracePeriod time.Duration
+	// RestoreWindow keeps objects of products (and their revisions)
+	// soft-deleted within this window. Default: 720h (30 days).
+	RestoreWindow time.Duration
 }
 
 // LogConfig controls application logging.
@@ -51,7 +76,7 @@ type LogConfig struct {
 	Compress    bool
 }
 
-// AdminConfig controls bootstrap and login for the single super admin.
+// AdminConfig controls bootstrap of the first owner account.
 //
 // Notes:
 // - ADMIN_PASSWORD is only used for bootstrapping when no admin exists yet.
@@ -59,6 +84,22 @@ type LogConfig struct {
 type AdminConfig struct {
 	Email    string
 	Password string
+	// InviteTTL is how long invite/reset links for backoffice users stay valid.
+	InviteTTL time.Duration
+
+	// Login lockout: after LoginMaxFailures consecutive failures an account is locked
+	// for LoginLockBase, doubling per further failure up to LoginLockMax.
+	LoginMaxFailures int
+	LoginLockBase    time.Duration
+	LoginLockMax     time.Duration
+	// Per-IP limiter (Redis): at most LoginIPMaxFailures failed logins per LoginIPWindow.
+	LoginIPMaxFailures int
+	LoginIPWindow      time.Duration
+
+	// Require2FA blocks backoffice APIs (except enrollment) for users without TOTP.
+	Require2FA bool
+	// TOTPIssuer is the account issuer shown in authenticator apps.
+	TOTPIssuer string
 }
 
 // DevConfig contains development-only toggles.
@@ -72,6 +113,11 @@ type DevConfig struct {
 type AppConfig struct {
 	Host string
 	Port string
+	// TrustedProxies lists the reverse proxies (IPs or CIDRs) whose
+	// X-Forwarded-For / X-Real-IP headers are believed when resolving the
+	// client IP (login rate limits, sessions, audit). Empty: no proxy is
+	// trusted and the peer address is used.
+	TrustedProxies []string
 }
 
 // Addr returns host:port with sensible defaults if unset.
@@ -98,8 +144,14 @@ type RedisConfig struct {
 	DialTimeout time.Duration
 }
 
-// MinioConfig defines MinIO/S3 compatible client options.
+// MinioConfig defines object storage options: a MinIO/S3 compatible bucket,
+// or a local directory for small deployments and development.
 type MinioConfig struct {
+	// Backend selects the storage implementation: minio (default) or local.
+	Backend string
+	// LocalDir is the root directory of the local backend. Default: data/objects.
+	LocalDir string
+
 	Endpoint  string
 	AccessKey string
 	SecretKey string
@@ -109,14 +161,36 @@ type MinioConfig struct {
 	// PublicBaseURL is an optional base URL for building public object URLs.
 	// Example: https://cdn.example.com or https://minio.example.com
 	// When set, public object URL becomes: {PublicBaseURL}/{Bucket}/{ObjectKey}
+	// Presigned upload URLs are signed for this host too (browsers cannot
+	// reach Endpoint); without it presigned uploads are disabled.
 	PublicBaseURL string
 }
 
 // UploadConfig defines request limits for file uploads.
 type UploadConfig struct {
 	// MaxImageUploadBytes limits the uploaded image file size.
-	// Default: 1MB.
+	// Default: 20MB (camera JPEG/HEIC originals).
 	MaxImageUploadBytes int64
+	// MaxConcurrentDecodes bounds how many uploads are decoded/resized at once,
+	// and separately how many public ?w=/fmt= variants are generated at once
+	// (a large image can take hundreds of MB while decoded). Default: 2.
+	MaxConcurrentDecodes int
+
+	// ImageWidths are the WebP rendition widths generated per uploaded image.
+	// Default: 320,640,1280,2048.
+	ImageWidths []int
+	// WebPQuality is the lossy WebP quality (1-100). Default: 82.
+	WebPQuality int
+	// MaxImagePixels rejects images whose width*height exceeds it. Default: 50M.
+	MaxImagePixels int
+
+	// MaxDirectUploadBytes limits presigned (direct-to-storage) uploads. Default: 50MB.
+	MaxDirectUploadBytes int64
+	// PresignExpires is how long a presigned upload URL stays valid. Default: 15m.
+	PresignExpires time.Duration
+	// StagingTTL is how long an uploaded but unfinalized object is kept before
+	// it expires (and can no longer be finalized). Default: 24h.
+	StagingTTL time.Duration
 }
 
 // JWTConfig defines JSON Web Token signing and validation settings.
@@ -128,6 +202,24 @@ type JWTConfig struct {
 	ExpiresIn time.Duration
 	// RefreshExpiresIn is the refresh token lifetime.
 	RefreshExpiresIn time.Duration
+
+	// Algorithm is the signing algorithm: HS256 (uses Secret), RS256 or EdDSA.
+	// Empty: inferred from PrivateKeyFile when set, else HS256.
+	Algorithm string
+	// PrivateKeyFile is the PEM signing key for RS256/EdDSA.
+	PrivateKeyFile string
+	// KeyID overrides the "kid" header of issued tokens.
+	// Default: RFC 7638 thumbprint (RS256/EdDSA) or a hash prefix of the secret (HS256).
+	KeyID string
+	// VerifyKeyFiles are extra PEM public keys still accepted (key rotation window).
+	VerifyKeyFiles []string
+	// PreviousSecrets are retired HS256 secrets still accepted (secret rotation window).
+	PreviousSecrets []string
+}
+
+// Enabled reports whether a signing key is configured.
+func (c JWTConfig) Enabled() bool {
+	return strings.TrimSpace(c.Secret) != "" || strings.TrimSpace(c.PrivateKeyFile) != ""
 }
 
 // Load reads environment variables (optionally from .env) and returns a Config.
@@ -141,6 +233,8 @@ func Load() (Config, error) {
 		App: AppConfig{
 			Host: getEnv("APP_HOST", "0.0.0.0"),
 			Port: getEnv("APP_PORT", "8080"),
+
+			TrustedProxies: getListEnv("TRUSTED_PROXIES"),
 		},
 		Postgres: PostgresConfig{
 			DSN:             getEnv("POSTGRES_DSN", ""),
@@ -156,6 +250,8 @@ func Load() (Config, error) {
 			DialTimeout: getDurationEnv("REDIS_DIAL_TIMEOUT", 5*time.Second),
 		},
 		Minio: MinioConfig{
+			Backend:   getEnv("STORAGE_BACKEND", "minio"),
+			LocalDir:  getEnv("STORAGE_LOCAL_DIR", "data/objects"),
 			Endpoint:  getEnv("MINIO_ENDPOINT", ""),
 			AccessKey: getEnv("MINIO_ACCESS_KEY", ""),
 			SecretKey: getEnv("MINIO_SECRET_KEY", ""),
@@ -164,8 +260,26 @@ func Load() (Config, error) {
 			UseSSL:    getBoolEnv("MINIO_USE_SSL", false),
 			PublicBaseURL: getEnv("MINIO_PUBLIC_BASE_URL", ""),
 		},
+		Scheduler: SchedulerConfig{
+			Enabled:  getBoolEnv("SCHEDULER_ENABLED", true),
+			Interval: getDurationEnv("SCHEDULER_INTERVAL", 30*time.Second),
+		},
+		GC: GCConfig{
+			Enabled:       getBoolEnv("GC_ENABLED", false),
+			Interval:      getDurationEnv("GC_INTERVAL", 24*time.Hour),
+			GracePeriod:   getDurationEnv("GC_GRACE_PERIOD", 7*24*time.Hour),
+			RestoreWindow: getDurationEnv("GC_RESTORE_WINDOW", 30*24*time.Hour),
+		},
 		Upload: UploadConfig{
-			MaxImageUploadBytes: getInt64Env("MAX_IMAGE_UPLOAD_BYTES", 1048576),
+			MaxImageUploadBytes:  getInt64Env("MAX_IMAGE_UPLOAD_BYTES", 20<<20),
+			MaxConcurrentDecodes: getIntEnv("IMAGE_MAX_CONCURRENT_DECODES", 2),
+			ImageWidths:          getIntListEnv("IMAGE_RENDITION_WIDTHS", []int{320, 640, 1280, 2048}),
+			WebPQuality:          getIntEnv("IMAGE_WEBP_QUALITY", 82),
+			MaxImagePixels:       getIntEnv("IMAGE_MAX_PIXELS", 50_000_000),
+
+			MaxDirectUploadBytes: getInt64Env("MAX_DIRECT_UPLOAD_BYTES", 50<<20),
+			PresignExpires:       getDurationEnv("UPLOAD_PRESIGN_EXPIRES", 15*time.Minute),
+			StagingTTL:           getDurationEnv("UPLOAD_STAGING_TTL", 24*time.Hour),
 		},
 		JWT: JWTConfig{
 			Secret:    getEnv("JWT_SECRET", ""),
@@ -174,10 +288,26 @@ func Load() (Config, error) {
 			// Default to a short-lived access token; use refresh tokens for long sessions.
 			ExpiresIn:        getDurationEnv("JWT_EXPIRES_IN", 15*time.Minute),
 			RefreshExpiresIn: getDurationEnv("JWT_REFRESH_EXPIRES_IN", 30*24*time.Hour),
+
+			Algorithm:       getEnv("JWT_ALGORITHM", ""),
+			PrivateKeyFile:  getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			KeyID:           getEnv("JWT_KEY_ID", ""),
+			VerifyKeyFiles:  getListEnv("JWT_VERIFY_KEY_FILES"),
+			PreviousSecrets: getListEnv("JWT_PREVIOUS_SECRETS"),
 		},
 		Admin: AdminConfig{
 			Email:    getEnv("ADMIN_EMAIL", ""),
 			Password: getEnv("ADMIN_PASSWORD", ""),
+			InviteTTL: getDurationEnv("ADMIN_INVITE_TTL", 72*time.Hour),
+
+			LoginMaxFailures:   getIntEnv("ADMIN_LOGIN_MAX_FAILURES", 5),
+			LoginLockBase:      getDurationEnv("ADMIN_LOGIN_LOCK_BASE", time.Minute),
+			LoginLockMax:       getDurationEnv("ADMIN_LOGIN_LOCK_MAX", time.Hour),
+			LoginIPMaxFailures: getIntEnv("ADMIN_LOGIN_IP_MAX_FAILURES", 20),
+			LoginIPWindow:      getDurationEnv("ADMIN_LOGIN_IP_WINDOW", 15*time.Minute),
+
+			Require2FA: getBoolEnv("ADMIN_REQUIRE_2FA", false),
+			TOTPIssuer: getEnv("ADMIN_TOTP_ISSUER", "evening-gown"),
 		},
 		Dev: DevConfig{
 			EnableDevTokenIssuer: getBoolEnv("ENABLE_DEV_TOKEN_ISSUER", false),
@@ -196,6 +326,14 @@ func Load() (Config, error) {
 		},
 	}
 
+	for _, p := range cfg.App.TrustedProxies {
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				return Config{}, fmt.Errorf("TRUSTED_PROXIES: invalid IP or CIDR %q", p)
+			}
+		}
+	}
+
 	return cfg, nil
 }
 
@@ -206,6 +344,39 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+// getListEnv splits a comma-separated variable, dropping empty items.
+func getListEnv(key string) []string {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return nil
+	}
+	var out []string
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// getIntListEnv parses a comma-separated list of integers.
+func getIntListEnv(key string, fallback []int) []int {
+	items := getListEnv(key)
+	if len(items) == 0 {
+		return fallback
+	}
+	out := make([]int, 0, len(items))
+	for _, item := range items {
+		value, err := strconv.Atoi(item)
+		if err != nil {
+			log.Printf("config: %s expects comma-separated integers, got %q: %v (using %v)", key, item, err, fallback)
+			return fallback
+		}
+		out = append(out, value)
+	}
+	return out
+}
+
 func getIntEnv(key string, fallback int) int {
 	raw, ok := os.LookupEnv(key)
 	if !ok || raw == "" {
@@ -0,0 +1,128 @@
+// Package detailmigrate upgrades the stored product details to the current
+// schema_version (see model.MigrateProductDetail) and writes them back.
+//
+// Readers already upgrade details in memory (model.UpgradeProductDetail);
+// running this once after a schema bump makes the stored rows, and therefore
+// search and exports, match what readers see.
+package detailmigrate
+
+import (
+	"context"
+
+	"evening-gown/internal/jsonschema"
+	"evening-gown/internal/model"
+	"evening-gown/internal/revisions"
+
+	"gorm.io/gorm"
+)
+
+const batchSize = 200
+
+// Options controls Run.
+type Options struct {
+	// DryRun reports what would be migrated without writing anything.
+	DryRun bool
+	// ProductID limits the run to one product (0: all products).
+	ProductID uint
+}
+
+// ProductReport describes one product whose detail is (or would be)
+// migrated, or could not be.
+type ProductReport struct {
+	ProductID uint     `json:"productId"`
+	StyleNo   string   `json:"styleNo"`
+	From      int      `json:"from"`
+	To        int      `json:"to"`
+	Applied   []string `json:"applied,omitempty"`
+	// Revision is the revision saved with the write-back; 0 when the product
+	// has no revision history (or on a dry run).
+	Revision int `json:"revision,omitempty"`
+	// Warnings lists what the migrated document still breaks in the current
+	// schema; it is written back all the same and the editor reports the
+	// same errors on the next save.
+	Warnings []jsonschema.Error `json:"warnings,omitempty"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// Result counts what Run did.
+type Result struct {
+	DryRun   bool `json:"dryRun"`
+	Scanned  int  `json:"scanned"`
+	Migrated int  `json:"migrated"` // would be migrated, on a dry run
+	Failed   int  `json:"failed"`
+}
+
+// Run migrates every product, soft-deleted ones included, whose detail is
+// older than model.ProductDetailSchemaVersion. Each product is written in its
+// own transaction, with a revision snapshot when the product has revisions.
+// report, when not nil, is called for every product that needed migrating.
+// A product that fails is reported and counted, and does not stop the run.
+func Run(ctx context.Context, db *gorm.DB, opts Options, report func(ProductReport)) (Result, error) {
+	res := Result{DryRun: opts.DryRun}
+	q := db.WithContext(ctx).Model(&model.Product{}).Select("id").Order("id asc")
+	if opts.ProductID != 0 {
+		q = q.Where("id = ?", opts.ProductID)
+	}
+
+	var batch []model.Product
+	err := q.FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+		for _, row := range batch {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			res.Scanned++
+			r, changed, err := migrateProduct(ctx, db, row.ID, opts.DryRun)
+			if err != nil {
+				r.Error = err.Error()
+				res.Failed++
+			} else if changed {
+				res.Migrated++
+			}
+			if (changed || err != nil) && report != nil {
+				report(r)
+			}
+		}
+		return nil
+	}).Error
+	return res, err
+}
+
+// migrateProduct migrates the detail of one product. The product is
+// (re)loaded inside the transaction so a concurrent save is never
+// overwritten with a migration of its previous content.
+func migrateProduct(ctx context.Context, db *gorm.DB, id uint, dryRun bool) (ProductReport, bool, error) {
+	r := ProductReport{ProductID: id}
+	changed := false
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var p model.Product
+		if err := tx.First(&p, id).Error; err != nil {
+			return err
+		}
+		r.StyleNo = p.StyleNo
+
+		out, mig, err := model.MigrateProductDetail(p.DetailJSON)
+		r.From, r.To, r.Applied = mig.From, mig.To, mig.Applied
+		if err != nil || !mig.Changed() {
+			return err
+		}
+		changed = true
+		r.Warnings = model.ValidateProductDetail(out)
+		if dryRun {
+			return nil
+		}
+
+		// UpdateColumn: a migration is not an edit, updated_at stays.
+		if err := tx.Model(&model.Product{}).Where("id = ?", p.ID).UpdateColumn("detail_json", out).Error; err != nil {
+			return err
+		}
+		p.DetailJSON = out
+
+		rev, err := revisions.Save(tx, nil, p, revisions.Options{Source: model.RevisionSourceMigration, SkipUntracked: true})
+		if err != nil {
+			return err
+		}
+		r.Revision = rev.Revision
+		return nil
+	})
+	return r, changed, err
+}
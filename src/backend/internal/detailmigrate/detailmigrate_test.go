@@ -0,0 +1,115 @@
+package detailmigrate
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"evening-gown/internal/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err == nil {
+		t.Cleanup(func() { _ = sqlDB.Close() })
+	}
+	if err := db.AutoMigrate(&model.Product{}, &model.ProductRevision{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestRun(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	deletedAt := time.Now().UTC()
+
+	products := []model.Product{
+		{Slug: "a", StyleNo: "A1", DetailJSON: json.RawMessage(`{"specs":[{"k":"Fabric","v":"Silk"}]}`)},
+		{Slug: "b", StyleNo: "B1", DetailJSON: json.RawMessage(`{"title_i18n":{"en":"B"}}`), DeletedAt: &deletedAt},
+		{Slug: "c", StyleNo: "C1", DetailJSON: model.DefaultProductDetailTemplate()},
+		{Slug: "d", StyleNo: "D1", DetailJSON: json.RawMessage(`{"schema_version":9}`)},
+		{Slug: "e", StyleNo: "E1"},
+	}
+	for i := range products {
+		products[i].Season, products[i].Category, products[i].Availability = "ss25", "gown", "in_stock"
+		if err := db.Create(&products[i]).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+	}
+	a, b := products[0], products[1]
+	if err := db.Create(&model.ProductRevision{ProductID: a.ID, Revision: 1, Source: model.RevisionSourceCreate, Snapshot: json.RawMessage(`{}`)}).Error; err != nil {
+		t.Fatalf("create revision: %v", err)
+	}
+
+	collect := func(opts Options) (Result, map[string]ProductReport) {
+		t.Helper()
+		reports := map[string]ProductReport{}
+		res, err := Run(ctx, db, opts, func(r ProductReport) { reports[r.StyleNo] = r })
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+		return res, reports
+	}
+
+	// Dry run: reports, writes nothing.
+	res, reports := collect(Options{DryRun: true})
+	if res.Scanned != 5 || res.Migrated != 2 || res.Failed != 1 || len(reports) != 3 {
+		t.Fatalf("unexpected dry run: %+v %+v", res, reports)
+	}
+	if r := reports["A1"]; r.From != 1 || r.To != 2 || len(r.Applied) != 1 || r.Revision != 0 || len(r.Warnings) != 0 {
+		t.Fatalf("unexpected report: %+v", r)
+	}
+	if reports["D1"].Error == "" {
+		t.Fatalf("expected an error for a newer schema_version: %+v", reports["D1"])
+	}
+	var stored model.Product
+	db.First(&stored, a.ID)
+	if string(stored.DetailJSON) != string(a.DetailJSON) {
+		t.Fatalf("dry run wrote the detail: %s", stored.DetailJSON)
+	}
+
+	// Single product.
+	if res, _ := collect(Options{DryRun: true, ProductID: b.ID}); res.Scanned != 1 || res.Migrated != 1 {
+		t.Fatalf("unexpected filtered run: %+v", res)
+	}
+
+	res, reports = collect(Options{})
+	if res.Migrated != 2 || res.Failed != 1 {
+		t.Fatalf("unexpected run: %+v", res)
+	}
+	if reports["A1"].Revision != 2 || reports["B1"].Revision != 0 {
+		t.Fatalf("expected a revision for the product with history only: %+v", reports)
+	}
+	db.First(&stored, a.ID)
+	out, _, _ := model.MigrateProductDetail(a.DetailJSON)
+	if string(stored.DetailJSON) != string(out) || !stored.UpdatedAt.Equal(a.UpdatedAt) {
+		t.Fatalf("unexpected stored product: %s (updated %v, was %v)", stored.DetailJSON, stored.UpdatedAt, a.UpdatedAt)
+	}
+	var rev model.ProductRevision
+	db.Where("product_id = ? AND revision = 2", a.ID).First(&rev)
+	var snap model.ProductSnapshot
+	_ = json.Unmarshal(rev.Snapshot, &snap)
+	if rev.Source != model.RevisionSourceMigration || string(snap.Detail) != string(out) {
+		t.Fatalf("unexpected revision: %+v", rev)
+	}
+	var cnt int64
+	db.Model(&model.ProductRevision{}).Where("product_id = ?", b.ID).Count(&cnt)
+	if cnt != 0 {
+		t.Fatalf("expected no revision for a product without history")
+	}
+
+	// Idempotent.
+	if res, _ := collect(Options{}); res.Migrated != 0 || res.Failed != 1 {
+		t.Fatalf("unexpected second run: %+v", res)
+	}
+}
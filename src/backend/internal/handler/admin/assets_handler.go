@@ -1,29 +1,29 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
 	"path"
 	"strings"
 
-	"evening-gown/internal/config"
+	"evening-gown/internal/assets"
 	"evening-gown/internal/model"
+	"evening-gown/internal/storage"
 
 	"github.com/gin-gonic/gin"
-	"github.com/minio/minio-go/v7"
 	"gorm.io/gorm"
 )
 
 type AssetsHandler struct {
-	db         *gorm.DB
-	minioClient *minio.Client
-	minioCfg    config.MinioConfig
+	db    *gorm.DB
+	store storage.Store
 }
 
-func NewAssetsHandler(db *gorm.DB, minioClient *minio.Client, minioCfg config.MinioConfig) *AssetsHandler {
-	return &AssetsHandler{db: db, minioClient: minioClient, minioCfg: minioCfg}
+func NewAssetsHandler(db *gorm.DB, store storage.Store) *AssetsHandler {
+	return &AssetsHandler{db: db, store: store}
 }
 
-// Get streams an object from MinIO through the application for admin usage.
+// Get streams an object from storage through the application for admin usage.
 //
 // Route: GET /api/v1/admin/assets/*key
 //
@@ -35,12 +35,8 @@ func (h *AssetsHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
 		return
 	}
-	if h.minioClient == nil || strings.TrimSpace(h.minioCfg.Endpoint) == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "minio disabled"})
-		return
-	}
-	if strings.TrimSpace(h.minioCfg.Bucket) == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "minio bucket not configured"})
+	if h.store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "storage disabled"})
 		return
 	}
 
@@ -80,35 +76,13 @@ func (h *AssetsHandler) Get(c *gin.Context) {
 		return
 	}
 
-	ctx := c.Request.Context()
-
-	stat, err := h.minioClient.StatObject(ctx, h.minioCfg.Bucket, cleanKey, minio.StatObjectOptions{})
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-		return
-	}
-
-	obj, err := h.minioClient.GetObject(ctx, h.minioCfg.Bucket, cleanKey, minio.GetObjectOptions{})
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-		return
-	}
-	defer obj.Close()
-
-	contentType := strings.TrimSpace(stat.ContentType)
-	if contentType == "" {
-		contentType = "application/octet-stream"
-	}
-
 	headers := map[string]string{
 		// Avoid long-term caching for draft assets.
 		"Cache-Control": "private, max-age=60",
 	}
-	if strings.TrimSpace(stat.ETag) != "" {
-		c.Header("ETag", stat.ETag)
+	if err := storage.ServeObject(c.Writer, c.Request, h.store, cleanKey, headers); errors.Is(err, storage.ErrObjectNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 	}
-
-	c.DataFromReader(http.StatusOK, stat.Size, contentType, obj, headers)
 }
 
 func (h *AssetsHandler) isKnownProductAsset(c *gin.Context, objectKey string) (bool, error) {
@@ -130,8 +104,7 @@ func (h *AssetsHandler) isKnownProductAsset(c *gin.Context, objectKey string) (b
 		return false, nil
 	}
 
-	styleNo, err := model.NormalizeStyleNo(strings.TrimSpace(parts[1]))
-	if err != nil {
+	if _, err := model.NormalizeStyleNo(strings.TrimSpace(parts[1])); err != nil {
 		return false, nil
 	}
 
@@ -140,16 +113,5 @@ func (h *AssetsHandler) isKnownProductAsset(c *gin.Context, objectKey string) (b
 		return false, nil
 	}
 
-	var cnt int64
-	like := "%" + objectKey + "%"
-	err = h.db.WithContext(c.Request.Context()).Model(&model.Product{}).
-		Where("style_no = ?", styleNo).
-		Where("deleted_at IS NULL").
-		Where("(cover_image_key = ? OR hover_image_key = ? OR CAST(detail_json AS TEXT) LIKE ?)", objectKey, objectKey, like).
-		Limit(1).
-		Count(&cnt).Error
-	if err != nil {
-		return false, err
-	}
-	return cnt > 0, nil
+	return assets.IsReferenced(c.Request.Context(), h.db, objectKey)
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"evening-gown/internal/assets"
 	"evening-gown/internal/model"
 
 	"github.com/gin-gonic/gin"
@@ -22,7 +23,7 @@ func openTestDB(t *testing.T) *gorm.DB {
 	if err == nil {
 		t.Cleanup(func() { _ = sqlDB.Close() })
 	}
-	if err := db.AutoMigrate(&model.Product{}); err != nil {
+	if err := db.AutoMigrate(&model.Product{}, &model.ProductRevision{}, &model.Asset{}, &model.ProductAsset{}, &model.UploadIntent{}, &model.AssetDeletion{}, &model.ProductVariant{}, &model.AuditLog{}); err != nil {
 		t.Fatalf("migrate: %v", err)
 	}
 	return db
@@ -51,6 +52,9 @@ func TestAdminAssets_isKnownProductAsset(t *testing.T) {
 	if err := db.Create(&p).Error; err != nil {
 		t.Fatalf("create product: %v", err)
 	}
+	if err := assets.SyncProduct(db, p); err != nil {
+		t.Fatalf("sync product assets: %v", err)
+	}
 
 	c, _ := gin.CreateTestContext(httptest.NewRecorder())
 	c.Request = httptest.NewRequest("GET", "/api/v1/admin/assets/"+key, nil)
@@ -72,6 +76,15 @@ func TestAdminAssets_isKnownProductAsset(t *testing.T) {
 		t.Fatalf("expected ok=false")
 	}
 
+	// A key prefix is not a reference (the old LIKE scan matched it).
+	ok, err = h.isKnownProductAsset(c, "products/1001/cover/2025/12/24/ab")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if ok {
+		t.Fatalf("expected ok=false for key prefix")
+	}
+
 	// Not referenced in DB
 	ok, err = h.isKnownProductAsset(c, "products/1001/cover/2025/12/24/other.webp")
 	if err != nil {
@@ -0,0 +1,177 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"evening-gown/internal/logging"
+	"evening-gown/internal/model"
+
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recordAudit appends an audit record for a mutation.
+//
+// db is the mutation's transaction: the record commits or rolls back with the
+// change, so a change is never applied without its record. The error aborts
+// the transaction. before/after are the entity states (nil for
+// creation/deletion) and are reduced to a diff of changed fields.
+func recordAudit(c *gin.Context, db *gorm.DB, action, entityType string, entityID any, before, after any) error {
+	diff, err := model.AuditDiff(before, after)
+	if err != nil {
+		return fmt.Errorf("audit diff %s: %w", action, err)
+	}
+
+	entry := model.AuditLog{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   fmt.Sprint(entityID),
+		Diff:       diff,
+		RequestID:  strings.TrimSpace(requestid.Get(c)),
+		IP:         c.ClientIP(),
+	}
+	if actor, ok := adminFromContext(c); ok {
+		entry.ActorID = actor.ID
+		entry.ActorEmail = actor.Email
+	}
+
+	if err := db.Create(&entry).Error; err != nil {
+		return fmt.Errorf("audit write %s: %w", action, err)
+	}
+	return nil
+}
+
+// reloadAudited reloads an entity inside the mutation's transaction tx and
+// records the audit entry from before to the new state, which it returns. It
+// returns gorm.ErrRecordNotFound when the entity is gone or soft-deleted.
+func reloadAudited[T any](c *gin.Context, tx *gorm.DB, action, entityType string, id uint, before T) (T, error) {
+	var after T
+	if err := tx.Where("deleted_at IS NULL").First(&after, id).Error; err != nil {
+		return after, err
+	}
+	return after, recordAudit(c, tx, action, entityType, id, before, after)
+}
+
+// updateAudited applies update to the entity before (a soft-deletable model,
+// selected by id) and records the audit entry in one transaction, and returns
+// its new state. It responds itself when it returns false.
+func updateAudited[T any](c *gin.Context, db *gorm.DB, entityType, action string, id uint, before T, update func(tx *gorm.DB) *gorm.DB) (T, bool) {
+	var after T
+	err := db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		res := update(tx.Model(new(T)).Where("id = ? AND deleted_at IS NULL", id))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		var err error
+		after, err = reloadAudited(c, tx, action, entityType, id, before)
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return after, false
+	}
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin audited update failed", err, "action", action, "entity_id", id)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return after, false
+	}
+	return after, true
+}
+
+type AuditLogsHandler struct {
+	db *gorm.DB
+}
+
+func NewAuditLogsHandler(db *gorm.DB) *AuditLogsHandler {
+	return &AuditLogsHandler{db: db}
+}
+
+// List returns audit records, newest first.
+// Route: GET /api/v1/admin/audit-logs
+//
+// Filters: actor_id, action, entity_type, entity_id, from/to (RFC3339).
+// Pagination is keyset-based: pass next_cursor from the previous page as ?cursor=.
+func (h *AuditLogsHandler) List(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	q := h.db.WithContext(c.Request.Context()).Model(&model.AuditLog{})
+
+	if v := strings.TrimSpace(c.Query("actor_id")); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid actor_id"})
+			return
+		}
+		q = q.Where("actor_id = ?", uint(id))
+	}
+	if v := strings.TrimSpace(c.Query("action")); v != "" {
+		q = q.Where("action = ?", v)
+	}
+	if v := strings.TrimSpace(c.Query("entity_type")); v != "" {
+		q = q.Where("entity_type = ?", v)
+	}
+	if v := strings.TrimSpace(c.Query("entity_id")); v != "" {
+		q = q.Where("entity_id = ?", v)
+	}
+	if from := strings.TrimSpace(c.Query("from")); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+		q = q.Where("created_at >= ?", t)
+	}
+	if to := strings.TrimSpace(c.Query("to")); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return
+		}
+		q = q.Where("created_at <= ?", t)
+	}
+	if v := strings.TrimSpace(c.Query("cursor")); v != "" {
+		cursor, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || cursor == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		q = q.Where("id < ?", uint(cursor))
+	}
+
+	limit := parseIntQuery(c, "limit", 50)
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	// Fetch one extra row to know whether another page exists.
+	var items []model.AuditLog
+	if err := q.Order("id desc").Limit(limit + 1).Find(&items).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin audit logs query list failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	var next *string
+	if len(items) > limit {
+		items = items[:limit]
+		s := strconv.FormatUint(uint64(items[len(items)-1].ID), 10)
+		next = &s
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items, "next_cursor": next})
+}
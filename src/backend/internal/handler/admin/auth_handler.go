@@ -7,22 +7,32 @@ import (
 	"time"
 
 	"evening-gown/internal/auth"
+	"evening-gown/internal/cache"
 	"evening-gown/internal/logging"
 	"evening-gown/internal/middleware"
 	"evening-gown/internal/model"
 	"evening-gown/internal/security"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type AuthHandler struct {
 	db     *gorm.DB
 	jwtSvc *auth.Service
+
+	lockout security.LockoutPolicy
+	limiter *cache.LoginLimiter
 }
 
 func NewAuthHandler(db *gorm.DB, jwtSvc *auth.Service) *AuthHandler {
-	return &AuthHandler{db: db, jwtSvc: jwtSvc}
+	return NewAuthHandlerWithLoginGuard(db, jwtSvc, security.DefaultLockoutPolicy(), nil)
+}
+
+// NewAuthHandlerWithLoginGuard configures account lockout and the optional per-IP limiter.
+func NewAuthHandlerWithLoginGuard(db *gorm.DB, jwtSvc *auth.Service, lockout security.LockoutPolicy, limiter *cache.LoginLimiter) *AuthHandler {
+	return &AuthHandler{db: db, jwtSvc: jwtSvc, lockout: lockout, limiter: limiter}
 }
 
 type loginRequest struct {
@@ -62,48 +72,138 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	var user model.User
-	if err := h.db.WithContext(c.Request.Context()).Where("email = ? AND deleted_at IS NULL", email).First(&user).Error; err != nil {
-		// Avoid leaking which part failed.
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"code":    "invalid_credentials",
-			"message": "invalid credentials",
-			"error":   "invalid credentials",
+	ctx := c.Request.Context()
+	ip := c.ClientIP()
+	if blocked, retryAfter := h.limiter.Blocked(ctx, ip); blocked {
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second)/time.Second)))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"code":    "too_many_requests",
+			"message": "too many login attempts",
+			"error":   "too many login attempts",
 		})
 		return
 	}
-	if user.Role != "admin" || user.Status != "active" {
+
+	// All failures below share the same response so callers cannot tell an unknown
+	// email, a wrong password or a locked account apart.
+	invalidCredentials := func() {
+		h.limiter.RecordFailure(ctx, ip)
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code":    "invalid_credentials",
 			"message": "invalid credentials",
 			"error":   "invalid credentials",
 		})
+	}
+
+	var user model.User
+	if err := h.db.WithContext(ctx).Where("email = ? AND deleted_at IS NULL", email).First(&user).Error; err != nil {
+		invalidCredentials()
+		return
+	}
+
+	now := time.Now().UTC()
+	if !model.IsKnownRole(user.Role) || !loginStatusAllowed(user, now) {
+		invalidCredentials()
 		return
 	}
 	if !security.CheckPassword(user.PasswordHash, password) {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"code":    "invalid_credentials",
-			"message": "invalid credentials",
-			"error":   "invalid credentials",
+		if err := h.recordFailedLogin(c, user.ID, now); err != nil {
+			logging.ErrorWithStack(logging.FromGin(c), "admin record failed login failed", err, "user_id", user.ID)
+		}
+		invalidCredentials()
+		return
+	}
+
+	pwdAt := int64(0)
+	if user.PasswordUpdatedAt != nil {
+		pwdAt = user.PasswordUpdatedAt.UTC().Unix()
+	}
+
+	// With 2FA enabled the password step only yields a short-lived challenge token.
+	// Failure counters are kept until the second factor succeeds, so knowing the
+	// password does not reset the lockout for code guessing.
+	if user.TOTPEnabled {
+		mfaToken, mfaExp, err := h.jwtSvc.IssueAdminMFAToken(strconv.FormatUint(uint64(user.ID), 10), pwdAt, user.TokenVersion)
+		if err != nil {
+			logging.ErrorWithStack(logging.FromGin(c), "admin issue mfa token failed", err, "user_id", user.ID)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    "issue_token_failed",
+				"message": "issue token failed",
+				"error":   "issue token failed",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":   true,
+			"mfa_token":      mfaToken,
+			"mfa_expires_at": mfaExp.UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
-	now := time.Now().UTC()
-	_ = h.db.WithContext(c.Request.Context()).Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]any{
-		"last_login_at":       now,
-		"failed_login_count":  0,
-		"locked_until":        nil,
-		"refresh_token_issued_at": now,
-		"updated_at":          now,
-	}).Error
+	h.completeLogin(c, user, now)
+}
+
+// completeLogin clears failure state, opens a new session and responds with its tokens.
+func (h *AuthHandler) completeLogin(c *gin.Context, user model.User, now time.Time) {
+	updates := map[string]any{
+		"last_login_at":      now,
+		"failed_login_count": 0,
+		"locked_until":       nil,
+		"updated_at":         now,
+	}
+	if user.Status == "locked" {
+		// The lock has expired (checked by the caller); a successful login clears it.
+		updates["status"] = "active"
+	}
+	_ = h.db.WithContext(c.Request.Context()).Model(&model.User{}).Where("id = ?", user.ID).Updates(updates).Error
+
+	session, err := h.openSession(c, user.ID, now)
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin create session failed", err, "user_id", user.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    "issue_token_failed",
+			"message": "issue token failed",
+			"error":   "issue token failed",
+		})
+		return
+	}
+
+	h.respondWithSessionTokens(c, user, session)
+}
+
+// openSession registers a new device session with a fresh refresh-token family.
+func (h *AuthHandler) openSession(c *gin.Context, userID uint, now time.Time) (model.AdminSession, error) {
+	session := model.AdminSession{
+		UserID:     userID,
+		SessionID:  uuid.NewString(),
+		RefreshJTI: uuid.NewString(),
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(h.refreshTTL()),
+	}
+	err := h.db.WithContext(c.Request.Context()).Create(&session).Error
+	return session, err
+}
 
+func (h *AuthHandler) refreshTTL() time.Duration {
+	if h.jwtSvc != nil {
+		if ttl := h.jwtSvc.RefreshTTL(); ttl > 0 {
+			return ttl
+		}
+	}
+	return 30 * 24 * time.Hour
+}
+
+// respondWithSessionTokens issues an access token and the session's current refresh token.
+func (h *AuthHandler) respondWithSessionTokens(c *gin.Context, user model.User, session model.AdminSession) {
 	pwdAt := int64(0)
 	if user.PasswordUpdatedAt != nil {
 		pwdAt = user.PasswordUpdatedAt.UTC().Unix()
 	}
 
-	accessToken, accessExp, err := h.jwtSvc.IssueAdminToken(strconv.FormatUint(uint64(user.ID), 10), pwdAt)
+	accessToken, accessExp, err := h.jwtSvc.IssueAdminToken(strconv.FormatUint(uint64(user.ID), 10), pwdAt, user.TokenVersion, session.SessionID)
 	if err != nil {
 		logging.ErrorWithStack(logging.FromGin(c), "admin issue token failed", err, "user_id", user.ID)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -114,7 +214,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	refreshToken, refreshExp, err := h.jwtSvc.IssueAdminRefreshToken(strconv.FormatUint(uint64(user.ID), 10), pwdAt)
+	refreshToken, refreshExp, err := h.jwtSvc.IssueAdminRefreshToken(strconv.FormatUint(uint64(user.ID), 10), pwdAt, user.TokenVersion, session.SessionID, session.RefreshJTI)
 	if err != nil {
 		logging.ErrorWithStack(logging.FromGin(c), "admin issue refresh token failed", err, "user_id", user.ID)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -130,6 +230,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		"expires_at":         accessExp.UTC().Format(time.RFC3339),
 		"refresh_token":      refreshToken,
 		"refresh_expires_at": refreshExp.UTC().Format(time.RFC3339),
+		"session_id":         session.ID,
 	})
 }
 
@@ -190,7 +291,8 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		})
 		return
 	}
-	if user.Role != "admin" || user.Status != "active" {
+	// A login lock only blocks password guessing; existing sessions keep working.
+	if !model.IsKnownRole(user.Role) || (user.Status != "active" && user.Status != "locked") {
 		c.JSON(http.StatusForbidden, gin.H{
 			"code":    "forbidden",
 			"message": "forbidden",
@@ -230,40 +332,88 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		}
 	}
 
-	// Refresh token rotation guard: reject refresh tokens older than the latest issued marker.
-	if user.RefreshTokenIssuedAt != nil {
-		if claims.IssuedAt == nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    "unauthorized",
-				"message": "unauthorized",
-				"error":   "unauthorized",
-			})
-			return
+	// Revoked via token version bump (user disabled/deleted).
+	if claims.TokenVersion != user.TokenVersion {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    "unauthorized",
+			"message": "unauthorized",
+			"error":   "unauthorized",
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	unauthorized := func() {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    "unauthorized",
+			"message": "unauthorized",
+			"error":   "unauthorized",
+		})
+	}
+
+	// Refresh tokens issued before sessions existed carry no sid. Keep honoring them
+	// (rotation guard on the user marker) and move them into a session.
+	if strings.TrimSpace(claims.SessionID) == "" {
+		if user.RefreshTokenIssuedAt != nil {
+			if claims.IssuedAt == nil || claims.IssuedAt.Time.UTC().Unix() < user.RefreshTokenIssuedAt.UTC().Unix() {
+				unauthorized()
+				return
+			}
 		}
-		if claims.IssuedAt.Time.UTC().Unix() < user.RefreshTokenIssuedAt.UTC().Unix() {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    "unauthorized",
-				"message": "unauthorized",
-				"error":   "unauthorized",
+		session, err := h.openSession(c, user.ID, now)
+		if err != nil {
+			logging.ErrorWithStack(logging.FromGin(c), "admin create session failed", err, "user_id", user.ID)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    "issue_token_failed",
+				"message": "issue token failed",
+				"error":   "issue token failed",
 			})
 			return
 		}
+		// Retire the legacy token so it cannot be exchanged again.
+		_ = h.db.WithContext(c.Request.Context()).Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]any{
+			"refresh_token_issued_at": now.Add(time.Second),
+			"updated_at":              now,
+		}).Error
+		h.respondWithSessionTokens(c, user, session)
+		return
 	}
 
-	now := time.Now().UTC()
-	_ = h.db.WithContext(c.Request.Context()).Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]any{
-		"refresh_token_issued_at": now,
-		"updated_at":             now,
-	}).Error
+	var session model.AdminSession
+	if err := h.db.WithContext(c.Request.Context()).
+		Where("session_id = ? AND user_id = ?", claims.SessionID, user.ID).
+		First(&session).Error; err != nil {
+		unauthorized()
+		return
+	}
+	if session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
+		unauthorized()
+		return
+	}
 
-	pwdAt := int64(0)
-	if user.PasswordUpdatedAt != nil {
-		pwdAt = user.PasswordUpdatedAt.UTC().Unix()
+	// Reuse detection: only the latest refresh token of the family may be exchanged.
+	// An older one showing up means it was copied, so the whole session is revoked.
+	if claims.ID != session.RefreshJTI {
+		if err := revokeSessions(h.db.WithContext(c.Request.Context()).Where("id = ?", session.ID), "refresh_reuse", now); err != nil {
+			logging.ErrorWithStack(logging.FromGin(c), "admin revoke session failed", err, "session_id", session.ID)
+		}
+		logging.FromGin(c).Warn("admin refresh token reuse detected; session revoked", "user_id", user.ID, "session_id", session.ID)
+		unauthorized()
+		return
 	}
 
-	accessToken, accessExp, err := h.jwtSvc.IssueAdminToken(strconv.FormatUint(uint64(user.ID), 10), pwdAt)
-	if err != nil {
-		logging.ErrorWithStack(logging.FromGin(c), "admin issue token failed", err, "user_id", user.ID)
+	nextJTI := uuid.NewString()
+	res := h.db.WithContext(c.Request.Context()).Model(&model.AdminSession{}).
+		Where("id = ? AND refresh_jti = ? AND revoked_at IS NULL", session.ID, session.RefreshJTI).
+		Updates(map[string]any{
+			"refresh_jti":  nextJTI,
+			"last_used_at": now,
+			"ip":           c.ClientIP(),
+			"user_agent":   c.Request.UserAgent(),
+			"expires_at":   now.Add(h.refreshTTL()),
+		})
+	if res.Error != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin rotate session failed", res.Error, "session_id", session.ID)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    "issue_token_failed",
 			"message": "issue token failed",
@@ -271,24 +421,49 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		})
 		return
 	}
+	if res.RowsAffected == 0 {
+		// A concurrent refresh won the rotation.
+		unauthorized()
+		return
+	}
+	session.RefreshJTI = nextJTI
 
-	refreshToken, refreshExp, err := h.jwtSvc.IssueAdminRefreshToken(strconv.FormatUint(uint64(user.ID), 10), pwdAt)
-	if err != nil {
-		logging.ErrorWithStack(logging.FromGin(c), "admin issue refresh token failed", err, "user_id", user.ID)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    "issue_token_failed",
-			"message": "issue token failed",
-			"error":   "issue token failed",
+	h.respondWithSessionTokens(c, user, session)
+}
+
+// Logout revokes the session of the presented access token.
+// Route: POST /api/v1/admin/auth/logout
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    "service_unavailable",
+			"message": "service unavailable",
+			"error":   "service unavailable",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token":              accessToken,
-		"expires_at":         accessExp.UTC().Format(time.RFC3339),
-		"refresh_token":      refreshToken,
-		"refresh_expires_at": refreshExp.UTC().Format(time.RFC3339),
-	})
+	user, ok := adminFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    "unauthorized",
+			"message": "unauthorized",
+			"error":   "unauthorized",
+		})
+		return
+	}
+
+	// Legacy tokens without a session simply expire.
+	if sid := c.GetString(middleware.ContextSessionIDKey); sid != "" {
+		q := h.db.WithContext(c.Request.Context()).Where("session_id = ? AND user_id = ?", sid, user.ID)
+		if err := revokeSessions(q, "logout", time.Now().UTC()); err != nil {
+			logging.ErrorWithStack(logging.FromGin(c), "admin logout failed", err, "user_id", user.ID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed"})
+			return
+		}
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
 func (h *AuthHandler) Me(c *gin.Context) {
@@ -312,9 +487,11 @@ func (h *AuthHandler) Me(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"id":    user.ID,
-		"email": user.Email,
-		"role":  user.Role,
+		"id":          user.ID,
+		"email":       user.Email,
+		"role":        user.Role,
+		"permissions": model.RolePermissions(user.Role),
+		"totpEnabled": user.TOTPEnabled,
 	})
 }
 
@@ -401,8 +578,57 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		})
 		return
 	}
+	// The new pwd_at already rejects every old token; also close the sessions so
+	// they disappear from the device list.
+	_ = revokeSessions(h.db.WithContext(c.Request.Context()).Where("user_id = ?", user.ID), "password_changed", time.Now().UTC())
 
 	c.JSON(http.StatusOK, gin.H{
 		"ok": true,
 	})
 }
+
+// loginStatusAllowed reports whether user may attempt a password login at now.
+// A "locked" user becomes eligible again once LockedUntil has passed; a lock without
+// an expiry stays in place until an owner unlocks the account.
+func loginStatusAllowed(user model.User, now time.Time) bool {
+	if user.LockedUntil != nil && now.Before(user.LockedUntil.UTC()) {
+		return false
+	}
+	switch user.Status {
+	case "active":
+		return true
+	case "locked":
+		return user.LockedUntil != nil
+	default:
+		return false
+	}
+}
+
+// recordFailedLogin increments the failure counter and locks the account once the
+// lockout policy says so. Each failure after the threshold doubles the lock.
+func (h *AuthHandler) recordFailedLogin(c *gin.Context, userID uint, now time.Time) error {
+	return h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", userID).
+			Update("failed_login_count", gorm.Expr("failed_login_count + 1")).Error; err != nil {
+			return err
+		}
+
+		var failures int
+		if err := tx.Model(&model.User{}).Where("id = ?", userID).Select("failed_login_count").Scan(&failures).Error; err != nil {
+			return err
+		}
+
+		lock := h.lockout.LockDuration(failures)
+		if lock <= 0 {
+			return nil
+		}
+		until := now.Add(lock)
+		return tx.Model(&model.User{}).
+			Where("id = ? AND status IN ?", userID, []string{"active", "locked"}).
+			Updates(map[string]any{
+				"status":       "locked",
+				"locked_until": &until,
+				"updated_at":   now,
+			}).Error
+	})
+}
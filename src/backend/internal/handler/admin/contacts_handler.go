@@ -2,6 +2,7 @@ package admin
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -179,8 +180,18 @@ func (h *ContactsHandler) Update(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.WithContext(ctx).Model(&model.ContactLead{}).Where("id = ?", uint(id)).Update("status", st).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var lead model.ContactLead
+	if err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.ContactLead{}).Where("id = ?", uint(id)).Update("status", st).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&lead, uint(id)).Error; err != nil {
+			return err
+		}
+		return recordAudit(c, tx, "contact.update", model.AuditEntityContact, before.ID, before, lead)
+	}); err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin contacts update failed", err, "contact_id", before.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
 
@@ -200,11 +211,6 @@ func (h *ContactsHandler) Update(c *gin.Context) {
 		}
 	}
 
-	var lead model.ContactLead
-	if err := h.db.WithContext(ctx).First(&lead, uint(id)).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-		return
-	}
 	c.JSON(http.StatusOK, lead)
 }
 
@@ -227,13 +233,24 @@ func (h *ContactsHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	res := h.db.WithContext(ctx).Delete(&model.ContactLead{}, uint(id))
-	if res.Error != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": res.Error.Error()})
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&model.ContactLead{}, uint(id))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// Leads hold personal data; the audit log keeps a redacted summary.
+		return recordAudit(c, tx, "contact.delete", model.AuditEntityContact, before.ID, before.AuditSummary(), nil)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-	if res.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin contacts delete failed", err, "contact_id", before.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
 
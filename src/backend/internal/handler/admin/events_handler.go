@@ -3,6 +3,7 @@ package admin
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -345,15 +346,31 @@ func (h *EventsHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	res := h.db.WithContext(c.Request.Context()).Delete(&model.Event{}, uint(id))
-	if res.Error != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": res.Error.Error()})
+	var before model.Event
+	if err := h.db.WithContext(c.Request.Context()).First(&before, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-	if res.RowsAffected == 0 {
+
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&model.Event{}, uint(id))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return recordAudit(c, tx, "event.delete", model.AuditEntityEvent, before.ID, before, nil)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin events delete failed", err, "event_id", before.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
 
 	c.Status(http.StatusNoContent)
 }
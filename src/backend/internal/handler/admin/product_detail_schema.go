@@ -0,0 +1,42 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"evening-gown/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProductDetailSchema returns the JSON Schema product details and the
+// detail template are validated against, so the editor can check documents
+// before saving them.
+// Route: GET /api/v1/admin/settings/product-detail-schema
+func (h *SettingsHandler) GetProductDetailSchema(c *gin.Context) {
+	c.Data(http.StatusOK, "application/schema+json", model.ProductDetailSchema())
+}
+
+// prepareDetail upgrades a submitted detail document to the current
+// schema_version (see model.MigrateProductDetail), so older clients keep
+// working and only current documents get stored, and validates the result
+// with checkDetailSchema.
+func prepareDetail(c *gin.Context, detail json.RawMessage, msg string) (json.RawMessage, bool) {
+	if migrated, _, err := model.MigrateProductDetail(detail); err == nil {
+		// Otherwise validation reports what is wrong with the document.
+		detail = migrated
+	}
+	return detail, checkDetailSchema(c, detail, msg)
+}
+
+// checkDetailSchema validates a detail document (see
+// model.ValidateProductDetail). When it is invalid, it responds 400 with msg
+// and the list of {path, message} errors, and returns false.
+func checkDetailSchema(c *gin.Context, detail json.RawMessage, msg string) bool {
+	errs := model.ValidateProductDetail(detail)
+	if len(errs) == 0 {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": msg, "errors": errs})
+	return false
+}
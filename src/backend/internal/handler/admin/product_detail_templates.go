@@ -0,0 +1,179 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"evening-gown/internal/logging"
+	"evening-gown/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Per-category detail templates. A category (gown, couture, bridal, ...) can
+// have its own template, e.g. with train length and veil spec rows and a
+// different section layout; products of a category without one keep using
+// the global template (see GetProductDetailTemplate).
+
+type productDetailTemplateItem struct {
+	Key       string    `json:"key"`
+	Category  string    `json:"category"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ListProductDetailTemplates lists the categories that have their own
+// template.
+// Route: GET /api/v1/admin/settings/product-detail-templates
+func (h *SettingsHandler) ListProductDetailTemplates(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	var settings []model.AppSetting
+	if err := h.db.WithContext(c.Request.Context()).
+		Select("key", "updated_at").
+		// "_" is a LIKE wildcard; ProductDetailTemplateCategory checks the prefix.
+		Where("key LIKE ?", model.ProductDetailTemplateKeyPrefix+"%").
+		Find(&settings).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin detail templates query failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	items := make([]productDetailTemplateItem, 0, len(settings))
+	for _, s := range settings {
+		category, ok := model.ProductDetailTemplateCategory(s.Key)
+		if !ok {
+			continue
+		}
+		items = append(items, productDetailTemplateItem{Key: s.Key, Category: category, UpdatedAt: s.UpdatedAt})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Category < items[j].Category })
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// GetCategoryProductDetailTemplate returns the template products of a
+// category get. When the category has none, it returns the global template
+// with inherited set, so the editor can start from it.
+// Route: GET /api/v1/admin/settings/product-detail-templates/:category
+func (h *SettingsHandler) GetCategoryProductDetailTemplate(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	category, ok := templateCategoryParam(c)
+	if !ok {
+		return
+	}
+
+	s, found, err := findProductDetailTemplate(h.db.WithContext(c.Request.Context()), category)
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin detail template query failed", err, "category", category)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	resp := productDetailTemplateResponse{
+		Key:       model.ProductDetailTemplateKey(category),
+		Category:  category,
+		Value:     model.DefaultProductDetailTemplate(),
+		Inherited: !found || s.Key != model.ProductDetailTemplateKey(category),
+	}
+	if found {
+		resp.Value = detailTemplateValue(s.ValueJSON)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// PutCategoryProductDetailTemplate creates or replaces the template of a
+// category. It is validated like the global template.
+// Route: PUT /api/v1/admin/settings/product-detail-templates/:category
+func (h *SettingsHandler) PutCategoryProductDetailTemplate(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	category, ok := templateCategoryParam(c)
+	if !ok {
+		return
+	}
+
+	set, ok := h.saveProductDetailTemplate(c, model.ProductDetailTemplateKey(category))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, productDetailTemplateResponse{Key: set.Key, Category: category, Value: set.ValueJSON})
+}
+
+// DeleteCategoryProductDetailTemplate removes the template of a category, so
+// its products fall back to the global template again.
+// Route: DELETE /api/v1/admin/settings/product-detail-templates/:category
+func (h *SettingsHandler) DeleteCategoryProductDetailTemplate(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	category, ok := templateCategoryParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var before model.AppSetting
+	if err := h.db.WithContext(ctx).
+		Where("key = ?", model.ProductDetailTemplateKey(category)).
+		First(&before).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		logging.ErrorWithStack(logging.FromGin(c), "admin detail template query failed", err, "category", category)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&before).Error; err != nil {
+			return err
+		}
+		return recordAudit(c, tx, "setting.delete", model.AuditEntitySetting, before.Key, before.ValueJSON, nil)
+	}); err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin detail template delete failed", err, "category", category)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func templateCategoryParam(c *gin.Context) (string, bool) {
+	category, ok := model.NormalizeProductCategory(c.Param("category"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category"})
+		return "", false
+	}
+	return category, true
+}
+
+// findProductDetailTemplate loads the stored template that applies to a
+// category: its own one, else the global one. found is false when neither is
+// stored and callers use model.DefaultProductDetailTemplate.
+func findProductDetailTemplate(db *gorm.DB, category string) (s model.AppSetting, found bool, err error) {
+	key := model.ProductDetailTemplateKey(category)
+	var settings []model.AppSetting
+	if err := db.Where("key IN ?", []string{key, model.SettingKeyProductDetailTemplate}).
+		Find(&settings).Error; err != nil {
+		return model.AppSetting{}, false, err
+	}
+	for _, want := range []string{key, model.SettingKeyProductDetailTemplate} {
+		for _, s := range settings {
+			if s.Key == want && len(s.ValueJSON) > 0 {
+				return s, true, nil
+			}
+		}
+	}
+	return model.AppSetting{}, false, nil
+}
@@ -0,0 +1,288 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"evening-gown/internal/assets"
+	"evening-gown/internal/logging"
+	"evening-gown/internal/model"
+	"evening-gown/internal/revisions"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// saveProductRevision snapshots after as the next revision of the product,
+// authored by the signed-in admin (see revisions.Save).
+func saveProductRevision(tx *gorm.DB, c *gin.Context, before *model.Product, after model.Product, source string, restoredFrom *int) (model.ProductRevision, error) {
+	author, _ := adminFromContext(c)
+	return revisions.Save(tx, before, after, revisions.Options{Source: source, RestoredFrom: restoredFrom, Author: author})
+}
+
+// ListRevisions lists a product's revisions, newest first (snapshots omitted).
+// Route: GET /api/v1/admin/products/:id/revisions
+func (h *ProductsHandler) ListRevisions(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	p, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+
+	limit := parseIntQuery(c, "limit", 50)
+	offset := parseIntQuery(c, "offset", 0)
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	q := h.db.WithContext(c.Request.Context()).Model(&model.ProductRevision{}).
+		Where("product_id = ?", p.ID)
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin product revisions query count failed", err, "product_id", p.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	var items []model.ProductRevision
+	if err := q.Omit("snapshot").Order("revision desc").Limit(limit).Offset(offset).Find(&items).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin product revisions query list failed", err, "product_id", p.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"total": total, "items": items})
+}
+
+// GetRevision returns one revision including its snapshot.
+// Route: GET /api/v1/admin/products/:id/revisions/:rev
+func (h *ProductsHandler) GetRevision(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	p, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+	rev, ok := h.loadRevision(c, p.ID, c.Param("rev"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, rev)
+}
+
+type revisionFieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// DiffRevisions compares two revisions of a product.
+// Route: GET /api/v1/admin/products/:id/revisions/diff?from=1&to=3
+//
+// "to" defaults to the latest revision and "from" to the one before "to".
+// Top-level fields are listed in "fields"; DetailJSON changes are listed in
+// "detail" as JSON Pointer paths (see model.JSONDiff).
+func (h *ProductsHandler) DiffRevisions(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	p, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+
+	toParam := strings.TrimSpace(c.Query("to"))
+	if toParam == "" {
+		var latest int
+		if err := h.db.WithContext(c.Request.Context()).Model(&model.ProductRevision{}).
+			Where("product_id = ?", p.ID).
+			Select("COALESCE(MAX(revision), 0)").
+			Scan(&latest).Error; err != nil {
+			logging.ErrorWithStack(logging.FromGin(c), "admin product revisions latest query failed", err, "product_id", p.ID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			return
+		}
+		toParam = strconv.Itoa(latest)
+	}
+	to, ok := h.loadRevision(c, p.ID, toParam)
+	if !ok {
+		return
+	}
+	fromParam := strings.TrimSpace(c.Query("from"))
+	if fromParam == "" {
+		fromParam = strconv.Itoa(to.Revision - 1)
+	}
+	from, ok := h.loadRevision(c, p.ID, fromParam)
+	if !ok {
+		return
+	}
+
+	var fromFields, toFields map[string]json.RawMessage
+	if err := json.Unmarshal(from.Snapshot, &fromFields); err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin product revision snapshot decode failed", err, "revision_id", from.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid snapshot"})
+		return
+	}
+	if err := json.Unmarshal(to.Snapshot, &toFields); err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin product revision snapshot decode failed", err, "revision_id", to.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid snapshot"})
+		return
+	}
+
+	fields := []revisionFieldChange{}
+	keys := make([]string, 0, len(toFields))
+	for k := range toFields {
+		if k != "detail" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if string(fromFields[k]) != string(toFields[k]) {
+			fields = append(fields, revisionFieldChange{Field: k, From: fromFields[k], To: toFields[k]})
+		}
+	}
+
+	detail, err := model.JSONDiff(fromFields["detail"], toFields["detail"])
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin product revision detail diff failed", err, "product_id", p.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "diff failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"productId": p.ID,
+		"from":      from.Revision,
+		"to":        to.Revision,
+		"fields":    fields,
+		"detail":    detail,
+	})
+}
+
+// RestoreRevision copies a revision's content back onto the product and records
+// the result as a new revision. Publishing state, slug and styleNo are left
+// unchanged (see model.ProductSnapshot.Columns).
+// Route: POST /api/v1/admin/products/:id/revisions/:rev/restore
+func (h *ProductsHandler) RestoreRevision(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	before, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+	rev, ok := h.loadRevision(c, before.ID, c.Param("rev"))
+	if !ok {
+		return
+	}
+
+	var snap model.ProductSnapshot
+	if err := json.Unmarshal(rev.Snapshot, &snap); err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin product revision snapshot decode failed", err, "revision_id", rev.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid snapshot"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var after model.Product
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Product{}).
+			Where("id = ?", before.ID).
+			Where("deleted_at IS NULL").
+			Updates(snap.Columns()).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("deleted_at IS NULL").First(&after, before.ID).Error; err != nil {
+			return err
+		}
+		if err := assets.SyncProduct(tx, after); err != nil {
+			return err
+		}
+		restoredFrom := rev.Revision
+		if _, err := saveProductRevision(tx, c, &before, after, model.RevisionSourceRestore, &restoredFrom); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, "product.restore", model.AuditEntityProduct, before.ID, before, after)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Deleted since it was loaded.
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin product revision restore failed", err, "product_id", before.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "restore failed"})
+		return
+	}
+
+	if after.PublishedAt != nil && h.cache != nil {
+		_, _ = h.cache.BumpProductsVersion(ctx)
+	}
+
+	c.JSON(http.StatusOK, after)
+}
+
+// loadProduct loads the (not deleted) product from the :id path param.
+// It writes the error response and returns false on failure.
+func (h *ProductsHandler) loadProduct(c *gin.Context) (model.Product, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return model.Product{}, false
+	}
+
+	var p model.Product
+	if err := h.db.WithContext(c.Request.Context()).
+		Where("deleted_at IS NULL").
+		First(&p, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return model.Product{}, false
+	}
+	return p, true
+}
+
+func (h *ProductsHandler) loadRevision(c *gin.Context, productID uint, raw string) (model.ProductRevision, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || n <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return model.ProductRevision{}, false
+	}
+
+	var rev model.ProductRevision
+	err = h.db.WithContext(c.Request.Context()).
+		Where("product_id = ? AND revision = ?", productID, n).
+		First(&rev).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "revision not found"})
+		return model.ProductRevision{}, false
+	}
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin product revision query failed", err, "product_id", productID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return model.ProductRevision{}, false
+	}
+	return rev, true
+}
@@ -0,0 +1,215 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"evening-gown/internal/logging"
+	"evening-gown/internal/model"
+	"evening-gown/internal/templateapply"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// templateApplyStaleAfter is how long a running job may go without progress
+// before it is considered interrupted (e.g. by a restart). Jobs report after
+// every batch, which takes well under a second.
+const templateApplyStaleAfter = 5 * time.Minute
+
+var errTemplateApplyRunning = errors.New("an apply template job is already running")
+
+type applyTemplateRequest struct {
+	// Category limits the job to one category; empty for all products.
+	Category string `json:"category"`
+}
+
+// PreviewApplyTemplate lists the products whose detail re-applying the
+// detail templates would change, with the changes as JSON Pointer paths (see
+// model.JSONDiff). Nothing is written.
+// Route: POST /api/v1/admin/products/apply-template/preview
+func (h *ProductsHandler) PreviewApplyTemplate(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	opts, ok := bindApplyTemplate(c)
+	if !ok {
+		return
+	}
+
+	items, res, err := templateapply.Preview(c.Request.Context(), h.db, opts)
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin apply template preview failed", err, "category", opts.Category)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"total": res.Total, "changed": res.Changed, "failed": res.Failed, "items": items})
+}
+
+// ApplyTemplate starts a job that merges the detail templates into existing
+// products, in batches of one transaction each, and responds 202 with the
+// job. Its progress is polled with GetApplyTemplateJob. Only one job runs at
+// a time.
+// Route: POST /api/v1/admin/products/apply-template
+func (h *ProductsHandler) ApplyTemplate(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	opts, ok := bindApplyTemplate(c)
+	if !ok {
+		return
+	}
+	opts.Author, _ = adminFromContext(c)
+
+	ctx := c.Request.Context()
+	if err := expireTemplateApplyJobs(h.db.WithContext(ctx)); err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin apply template jobs query failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	total, err := templateapply.Count(ctx, h.db, opts)
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin apply template count failed", err, "category", opts.Category)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	job := model.TemplateApplyJob{
+		Category:    opts.Category,
+		Status:      model.TemplateApplyJobRunning,
+		Total:       total,
+		AuthorID:    opts.Author.ID,
+		AuthorEmail: opts.Author.Email,
+	}
+	var running model.TemplateApplyJob
+	if err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The partial unique index on running jobs makes a concurrent start
+		// insert nothing.
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&job)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			if err := tx.Where("status = ?", model.TemplateApplyJobRunning).First(&running).Error; err != nil {
+				return err
+			}
+			return errTemplateApplyRunning
+		}
+		return recordAudit(c, tx, "product.apply_template", model.AuditEntityTemplateApplyJob, job.ID, nil, job)
+	}); err != nil {
+		if errors.Is(err, errTemplateApplyRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "job": running})
+			return
+		}
+		logging.ErrorWithStack(logging.FromGin(c), "admin apply template job create failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
+		return
+	}
+
+	// The job outlives the request.
+	go h.runApplyTemplate(context.WithoutCancel(ctx), logging.FromGin(c), job, opts)
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetApplyTemplateJob returns an apply template job and its progress.
+// Route: GET /api/v1/admin/products/apply-template/jobs/:jobId
+func (h *ProductsHandler) GetApplyTemplateJob(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("jobId"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var job model.TemplateApplyJob
+	if err := h.db.WithContext(c.Request.Context()).First(&job, uint(id)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		logging.ErrorWithStack(logging.FromGin(c), "admin apply template job query failed", err, "job_id", id)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// runApplyTemplate runs job, saving its progress after every batch. The
+// public products cache is bumped once, when the job ends, before the job is
+// marked finished.
+func (h *ProductsHandler) runApplyTemplate(ctx context.Context, logger *slog.Logger, job model.TemplateApplyJob, opts templateapply.Options) {
+	db := h.db.WithContext(ctx)
+	res, err := templateapply.Apply(ctx, h.db, opts, func(p templateapply.Progress) error {
+		return db.Model(&job).Updates(progressColumns(p)).Error
+	})
+
+	if res.Changed > 0 && h.cache != nil {
+		_, _ = h.cache.BumpProductsVersion(ctx)
+	}
+	updates := progressColumns(res)
+	updates["status"] = model.TemplateApplyJobSucceeded
+	updates["finished_at"] = time.Now().UTC()
+	if err != nil {
+		logging.ErrorWithStack(logger, "admin apply template job failed", err, "job_id", job.ID)
+		updates["status"] = model.TemplateApplyJobFailed
+		updates["error"] = err.Error()
+	}
+	if err := db.Model(&job).Updates(updates).Error; err != nil {
+		logging.ErrorWithStack(logger, "admin apply template job update failed", err, "job_id", job.ID)
+	}
+}
+
+func progressColumns(p templateapply.Progress) map[string]any {
+	return map[string]any{
+		"total":     p.Total,
+		"processed": p.Processed,
+		"changed":   p.Changed,
+		"failed":    p.Failed,
+	}
+}
+
+// expireTemplateApplyJobs marks running jobs that stopped reporting progress
+// as failed, so an interrupted job does not block new ones forever. It runs
+// before a new job is started.
+func expireTemplateApplyJobs(db *gorm.DB) error {
+	now := time.Now().UTC()
+	return db.Model(&model.TemplateApplyJob{}).
+		Where("status = ?", model.TemplateApplyJobRunning).
+		Where("updated_at < ?", now.Add(-templateApplyStaleAfter)).
+		Updates(map[string]any{
+			"status":      model.TemplateApplyJobFailed,
+			"error":       "interrupted",
+			"finished_at": now,
+		}).Error
+}
+
+// bindApplyTemplate reads the optional request body. It responds itself when
+// it returns false.
+func bindApplyTemplate(c *gin.Context) (templateapply.Options, bool) {
+	var req applyTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return templateapply.Options{}, false
+	}
+	var opts templateapply.Options
+	if req.Category != "" {
+		category, ok := model.NormalizeProductCategory(req.Category)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category"})
+			return templateapply.Options{}, false
+		}
+		opts.Category = category
+	}
+	return opts, true
+}
@@ -3,14 +3,17 @@ package admin
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"evening-gown/internal/assets"
 	"evening-gown/internal/cache"
 	"evening-gown/internal/logging"
 	"evening-gown/internal/model"
+	"evening-gown/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -19,12 +22,18 @@ import (
 type ProductsHandler struct {
 	db    *gorm.DB
 	cache *cache.PublicCache
+	store storage.Store
 }
 
 func NewProductsHandler(db *gorm.DB, publicCache *cache.PublicCache) *ProductsHandler {
 	return &ProductsHandler{db: db, cache: publicCache}
 }
 
+// NewProductsHandlerWithStore also enables RenameStyle, which moves objects.
+func NewProductsHandlerWithStore(db *gorm.DB, publicCache *cache.PublicCache, store storage.Store) *ProductsHandler {
+	return &ProductsHandler{db: db, cache: publicCache, store: store}
+}
+
 
 type productCreateRequest struct {
 	Slug         string `json:"slug"`
@@ -115,6 +124,9 @@ func (h *ProductsHandler) List(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
 		return
 	}
+	for i := range items {
+		model.UpgradeProductDetail(&items[i])
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"total": total,
@@ -156,9 +168,14 @@ func (h *ProductsHandler) Create(c *gin.Context) {
 		newRank = *req.NewRank
 	}
 
+	detail, ok := prepareDetail(c, req.Detail, "invalid detail")
+	if !ok {
+		return
+	}
+
 	ctx := c.Request.Context()
-	tpl := h.loadProductDetailTemplate(ctx)
-	mergedDetail, err := model.MergeProductDetailWithTemplate(tpl, req.Detail)
+	tpl := h.loadProductDetailTemplate(ctx, req.Category)
+	mergedDetail, err := model.MergeProductDetailWithTemplate(tpl, detail)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid detail"})
 		return
@@ -180,7 +197,18 @@ func (h *ProductsHandler) Create(c *gin.Context) {
 		DetailJSON:    mergedDetail,
 	}
 
-	if err := h.db.WithContext(ctx).Create(&p).Error; err != nil {
+	if err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&p).Error; err != nil {
+			return err
+		}
+		if err := assets.SyncProduct(tx, p); err != nil {
+			return err
+		}
+		if _, err := saveProductRevision(tx, c, nil, p, model.RevisionSourceCreate, nil); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, "product.create", model.AuditEntityProduct, p.ID, nil, p)
+	}); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -207,6 +235,7 @@ func (h *ProductsHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
+	model.UpgradeProductDetail(&p)
 
 	c.JSON(http.StatusOK, p)
 }
@@ -254,7 +283,12 @@ func (h *ProductsHandler) Update(c *gin.Context) {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid styleNo"})
 				return
 			}
-			updates["style_no"] = norm
+			// Image keys live under products/{styleNo}/; only RenameStyle
+			// moves them along.
+			if norm != before.StyleNo {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "styleNo cannot be changed here; use rename-style"})
+				return
+			}
 		}
 	}
 	if req.Season != nil {
@@ -290,13 +324,32 @@ func (h *ProductsHandler) Update(c *gin.Context) {
 	if req.HoverImageKey != nil {
 		updates["hover_image_key"] = strings.TrimSpace(*req.HoverImageKey)
 	}
-	if req.Detail != nil {
-		tpl := h.loadProductDetailTemplate(ctx)
-		merged, err := model.MergeProductDetailWithTemplate(tpl, *req.Detail)
+	category := before.Category
+	if s, ok := updates["category"].(string); ok {
+		category = s
+	}
+	// Moving a product to a category with another template merges that
+	// template into the stored detail, like an edit of the detail would.
+	templateChanged := model.ProductDetailTemplateKey(category) != model.ProductDetailTemplateKey(before.Category)
+	if req.Detail != nil || templateChanged {
+		detail := model.UpgradeDetail(before.DetailJSON)
+		if req.Detail != nil {
+			var ok bool
+			if detail, ok = prepareDetail(c, *req.Detail, "invalid detail"); !ok {
+				return
+			}
+		}
+		tpl := h.loadProductDetailTemplate(ctx, category)
+		merged, err := model.MergeProductDetailWithTemplate(tpl, detail)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid detail"})
 			return
 		}
+		if merged, err = h.withVariantOptionGroups(ctx, before.ID, merged); err != nil {
+			logging.ErrorWithStack(logging.FromGin(c), "admin product variants query failed", err, "product_id", before.ID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			return
+		}
 		updates["detail_json"] = merged
 	}
 
@@ -305,10 +358,26 @@ func (h *ProductsHandler) Update(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.WithContext(ctx).Model(&model.Product{}).
-		Where("id = ?", uint(id)).
-		Where("deleted_at IS NULL").
-		Updates(updates).Error; err != nil {
+	// Every save is snapshotted as a revision (see product_revisions_handler.go).
+	var after model.Product
+	if err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Product{}).
+			Where("id = ?", uint(id)).
+			Where("deleted_at IS NULL").
+			Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("deleted_at IS NULL").First(&after, uint(id)).Error; err != nil {
+			return err
+		}
+		if err := assets.SyncProduct(tx, after); err != nil {
+			return err
+		}
+		if _, err := saveProductRevision(tx, c, &before, after, model.RevisionSourceUpdate, nil); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, "product.update", model.AuditEntityProduct, before.ID, before, after)
+	}); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -317,27 +386,24 @@ func (h *ProductsHandler) Update(c *gin.Context) {
 		_, _ = h.cache.BumpProductsVersion(ctx)
 	}
 
-	h.Get(c)
+	c.JSON(http.StatusOK, after)
 }
 
-func (h *ProductsHandler) loadProductDetailTemplate(ctx context.Context) json.RawMessage {
+// loadProductDetailTemplate returns the detail template for products of a
+// category: the category's own template, else the global one, else the
+// built-in default.
+func (h *ProductsHandler) loadProductDetailTemplate(ctx context.Context, category string) json.RawMessage {
 	// Default fallback
 	fallback := model.DefaultProductDetailTemplate()
 	if h == nil || h.db == nil {
 		return fallback
 	}
 
-	var s model.AppSetting
-	err := h.db.WithContext(ctx).
-		Where("key = ?", model.SettingKeyProductDetailTemplate).
-		First(&s).Error
-	if err != nil {
-		return fallback
-	}
-	if len(s.ValueJSON) == 0 {
+	s, found, err := findProductDetailTemplate(h.db.WithContext(ctx), category)
+	if err != nil || !found {
 		return fallback
 	}
-	return s.ValueJSON
+	return model.UpgradeDetail(s.ValueJSON)
 }
 
 func (h *ProductsHandler) Publish(c *gin.Context) {
@@ -352,25 +418,29 @@ func (h *ProductsHandler) Publish(c *gin.Context) {
 		return
 	}
 
-	now := time.Now().UTC()
 	ctx := c.Request.Context()
-	res := h.db.WithContext(ctx).Model(&model.Product{}).
+	var before model.Product
+	if err := h.db.WithContext(ctx).
 		Where("id = ?", uint(id)).
 		Where("deleted_at IS NULL").
-		Update("published_at", &now)
-	if res.Error != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": res.Error.Error()})
+		First(&before).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-	if res.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+
+	now := time.Now().UTC()
+	after, ok := updateAudited(c, h.db, model.AuditEntityProduct, "product.publish", before.ID, before, func(tx *gorm.DB) *gorm.DB {
+		// A manual publish supersedes a pending scheduled one.
+		return tx.Updates(map[string]any{"published_at": &now, "publish_at": nil})
+	})
+	if !ok {
 		return
 	}
 	if h.cache != nil {
 		_, _ = h.cache.BumpProductsVersion(ctx)
 	}
 
-	h.Get(c)
+	c.JSON(http.StatusOK, after)
 }
 
 func (h *ProductsHandler) Unpublish(c *gin.Context) {
@@ -386,23 +456,26 @@ func (h *ProductsHandler) Unpublish(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	res := h.db.WithContext(ctx).Model(&model.Product{}).
+	var before model.Product
+	if err := h.db.WithContext(ctx).
 		Where("id = ?", uint(id)).
 		Where("deleted_at IS NULL").
-		Update("published_at", nil)
-	if res.Error != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": res.Error.Error()})
+		First(&before).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-	if res.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+
+	after, ok := updateAudited(c, h.db, model.AuditEntityProduct, "product.unpublish", before.ID, before, func(tx *gorm.DB) *gorm.DB {
+		return tx.Updates(map[string]any{"published_at": nil, "unpublish_at": nil})
+	})
+	if !ok {
 		return
 	}
 	if h.cache != nil {
 		_, _ = h.cache.BumpProductsVersion(ctx)
 	}
 
-	h.Get(c)
+	c.JSON(http.StatusOK, after)
 }
 
 func parseIntQuery(c *gin.Context, key string, fallback int) int {
@@ -441,16 +514,26 @@ func (h *ProductsHandler) Delete(c *gin.Context) {
 	wasPublished := before.PublishedAt != nil
 
 	now := time.Now().UTC()
-	res := h.db.WithContext(ctx).Model(&model.Product{}).
-		Where("id = ?", uint(id)).
-		Where("deleted_at IS NULL").
-		Update("deleted_at", &now)
-	if res.Error != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": res.Error.Error()})
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.Product{}).
+			Where("id = ?", uint(id)).
+			Where("deleted_at IS NULL").
+			Update("deleted_at", &now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return recordAudit(c, tx, "product.delete", model.AuditEntityProduct, before.ID, before, nil)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-	if res.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin products delete failed", err, "product_id", before.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
 	if wasPublished && h.cache != nil {
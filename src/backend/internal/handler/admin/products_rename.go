@@ -0,0 +1,293 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"evening-gown/internal/assets"
+	"evening-gown/internal/imaging"
+	"evening-gown/internal/logging"
+	"evening-gown/internal/model"
+	"evening-gown/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// renameDeleteDelay is how long the objects under the old styleNo prefix stay
+// around after a rename, so pages and CDN responses cached with the old URLs
+// keep working. The assets GC deletes them afterwards (later, if a restorable
+// revision still references them).
+const renameDeleteDelay = 7 * 24 * time.Hour
+
+var errStyleNoTaken = errors.New("styleNo already exists")
+
+type renameStyleRequest struct {
+	StyleNo string `json:"styleNo" binding:"required"`
+}
+
+// RenameStyle changes a product's styleNo and moves its images along.
+//
+// Route: POST /api/v1/admin/products/:id/rename-style
+//
+// Every object the product (or one of its variants) references under
+// products/{OLD}/, with all the renditions of the same upload, is copied to
+// products/{NEW}/. Then, in one transaction, style_no, the cover/hover keys
+// and URLs, every key inside the detail JSON and the variant codes and images
+// are rewritten, the new objects are registered and the old ones are
+// scheduled for deletion. The public cache is invalidated.
+func (h *ProductsHandler) RenameStyle(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	before, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+
+	var req renameStyleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	styleNo, err := model.NormalizeStyleNo(strings.TrimSpace(req.StyleNo))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid styleNo"})
+		return
+	}
+	if styleNo == before.StyleNo {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "styleNo unchanged"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	taken, err := h.styleNoTaken(c, styleNo)
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin product rename lookup failed", err, "style_no", styleNo)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": errStyleNoTaken.Error()})
+		return
+	}
+
+	variants, err := loadVariants(h.db.WithContext(ctx), before.ID)
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin product variants query failed", err, "product_id", before.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	oldPrefix := "products/" + before.StyleNo + "/"
+	newPrefix := "products/" + styleNo + "/"
+	moves, err := h.renameMoves(c, before, variants, oldPrefix, newPrefix)
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin product rename list failed", err, "product_id", before.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "rename failed"})
+		return
+	}
+	if len(moves) > 0 && h.store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "storage disabled"})
+		return
+	}
+
+	// Copy first: once the transaction commits, the new keys must resolve.
+	var copied []string
+	cleanup := func() {
+		for _, key := range copied {
+			_ = h.store.Remove(ctx, key)
+		}
+	}
+	oldKeys := make([]string, 0, len(moves))
+	for _, from := range sortedKeys(moves) {
+		to := moves[from]
+		if err := h.store.Copy(ctx, from, to); err != nil {
+			if errors.Is(err, storage.ErrObjectNotFound) {
+				// Already broken; the key is rewritten all the same.
+				continue
+			}
+			cleanup()
+			logging.ErrorWithStack(logging.FromGin(c), "admin product rename copy failed", err, "from", from, "to", to)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "copy failed"})
+			return
+		}
+		copied = append(copied, to)
+		oldKeys = append(oldKeys, from)
+	}
+
+	after := before
+	after.StyleNo = styleNo
+	if _, err := model.RewriteAssetKeys(&after, func(key string) (string, bool) {
+		to, ok := moves[key]
+		return to, ok
+	}); err != nil {
+		cleanup()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid detail"})
+		return
+	}
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.Product{}).
+			Where("id = ? AND style_no = ?", before.ID, before.StyleNo).
+			Where("deleted_at IS NULL").
+			Updates(map[string]any{
+				"style_no":        after.StyleNo,
+				"cover_image_key": after.CoverImageKey,
+				"cover_image_url": after.CoverImageURL,
+				"hover_image_key": after.HoverImageKey,
+				"hover_image_url": after.HoverImageURL,
+				"detail_json":     after.DetailJSON,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errStyleNoTaken // renamed concurrently
+		}
+		if err := tx.Where("deleted_at IS NULL").First(&after, before.ID).Error; err != nil {
+			return err
+		}
+		if err := renameVariants(tx, variants, styleNo, moves); err != nil {
+			return err
+		}
+		if err := registerMovedAssets(tx, moves, after); err != nil {
+			return err
+		}
+		if err := assets.SyncProduct(tx, after); err != nil {
+			return err
+		}
+		if err := assets.ScheduleDeletion(tx, oldKeys, time.Now().Add(renameDeleteDelay), "rename"); err != nil {
+			return err
+		}
+		if _, err := saveProductRevision(tx, c, &before, after, model.RevisionSourceRename, nil); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, "product.rename_style", model.AuditEntityProduct, before.ID, before, after)
+	})
+	if err != nil {
+		cleanup()
+		if errors.Is(err, errStyleNoTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if taken, _ := h.styleNoTaken(c, styleNo); taken {
+			c.JSON(http.StatusConflict, gin.H{"error": errStyleNoTaken.Error()})
+			return
+		}
+		logging.ErrorWithStack(logging.FromGin(c), "admin product rename failed", err, "product_id", before.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "rename failed"})
+		return
+	}
+
+	if h.cache != nil {
+		_, _ = h.cache.BumpProductsVersion(ctx)
+	}
+
+	c.JSON(http.StatusOK, after)
+}
+
+// styleNoTaken reports whether any product, soft-deleted ones included (the
+// column is unique), already uses styleNo.
+func (h *ProductsHandler) styleNoTaken(c *gin.Context, styleNo string) (bool, error) {
+	var cnt int64
+	err := h.db.WithContext(c.Request.Context()).Model(&model.Product{}).
+		Where("style_no = ?", styleNo).
+		Count(&cnt).Error
+	return cnt > 0, err
+}
+
+// renameMoves maps every key p and its variants reference under oldPrefix,
+// plus the other renditions of the same uploads found in storage, to its key
+// under newPrefix.
+func (h *ProductsHandler) renameMoves(c *gin.Context, p model.Product, variants []model.ProductVariant, oldPrefix, newPrefix string) (map[string]string, error) {
+	moves := map[string]string{}
+	add := func(key string) {
+		if rest, ok := strings.CutPrefix(key, oldPrefix); ok {
+			moves[key] = newPrefix + rest
+		}
+	}
+
+	uploads := map[string]bool{}
+	for _, ref := range append(model.ProductAssetRefs(p), model.VariantAssetRefs(p.ID, variants)...) {
+		add(ref.ObjectKey)
+		if prefix, _, ok := imaging.SplitRenditionKey(ref.ObjectKey); ok && strings.HasPrefix(prefix, oldPrefix) {
+			uploads[prefix] = true
+		}
+	}
+	if h.store == nil {
+		return moves, nil
+	}
+	for _, prefix := range sortedKeys(uploads) {
+		if err := h.store.List(c.Request.Context(), prefix+"/", func(obj storage.ObjectInfo) error {
+			add(obj.Key)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return moves, nil
+}
+
+// renameVariants re-derives the variant codes from styleNo and moves their
+// image keys.
+func renameVariants(tx *gorm.DB, variants []model.ProductVariant, styleNo string, moves map[string]string) error {
+	for _, v := range variants {
+		keys := v.DecodeImageKeys()
+		for i, k := range keys {
+			if to, ok := moves[k]; ok {
+				keys[i] = to
+			}
+		}
+		imageKeys, err := json.Marshal(keys)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&model.ProductVariant{}).Where("id = ?", v.ID).Updates(map[string]any{
+			"code":       model.VariantCode(styleNo, v.DecodeOptions()),
+			"image_keys": json.RawMessage(imageKeys),
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerMovedAssets registers the copies of registered objects under their
+// new keys, owned by p.
+func registerMovedAssets(tx *gorm.DB, moves map[string]string, p model.Product) error {
+	if len(moves) == 0 {
+		return nil
+	}
+	var rows []model.Asset
+	if err := tx.Where("object_key IN ?", sortedKeys(moves)).Find(&rows).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	for i := range rows {
+		rows[i].ID = 0
+		rows[i].ObjectKey = moves[rows[i].ObjectKey]
+		rows[i].StyleNo = p.StyleNo
+		rows[i].ProductID = &p.ID
+		rows[i].CreatedAt = time.Time{}
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,150 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"evening-gown/internal/assets"
+	"evening-gown/internal/cache"
+	"evening-gown/internal/middleware"
+	"evening-gown/internal/model"
+	"evening-gown/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProducts_RenameStyle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openTestDB(t)
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	for _, key := range []string{
+		"products/A1/cover/2025/01/01/u1/w320.webp",
+		"products/A1/cover/2025/01/01/u1/w2048.webp",
+		"products/A1/gallery/2025/01/01/g1/w1280.webp",
+	} {
+		if err := store.Put(ctx, key, strings.NewReader("img"), 3, "image/webp"); err != nil {
+			t.Fatalf("put %s: %v", key, err)
+		}
+		if err := db.Create(&model.Asset{ObjectKey: key, StyleNo: "A1", Kind: "cover"}).Error; err != nil {
+			t.Fatalf("create asset: %v", err)
+		}
+	}
+	p := model.Product{
+		Slug: "a", StyleNo: "A1", Season: "ss25", Category: "gown", Availability: "in_stock",
+		CoverImageKey: "products/A1/cover/2025/01/01/u1/w2048.webp",
+		CoverImageURL: "/api/v1/assets/products/A1/cover/2025/01/01/u1/w2048.webp",
+		HoverImageURL: "https://example.com/hover.jpg",
+		DetailJSON:    json.RawMessage(`{"gallery":[{"url":"/api/v1/assets/products/A1/gallery/2025/01/01/g1/w1280.webp?w=640"}]}`),
+	}
+	other := model.Product{Slug: "b", StyleNo: "B1", Season: "ss25", Category: "gown", Availability: "in_stock"}
+	for _, row := range []*model.Product{&p, &other} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+		if err := assets.SyncProduct(db, *row); err != nil {
+			t.Fatalf("SyncProduct: %v", err)
+		}
+	}
+
+	h := NewProductsHandlerWithStore(db, cache.NewPublicCache(nil), store)
+	rename := func(styleNo string) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(gin.H{"styleNo": styleNo})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/admin/products/"+strconv.Itoa(int(p.ID))+"/rename-style", bytes.NewReader(raw))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(int(p.ID))}}
+		c.Set(middleware.ContextUserKey, model.User{ID: 7})
+		h.RenameStyle(c)
+		return w
+	}
+
+	if w := rename("B1"); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a taken styleNo, got %d", w.Code)
+	}
+	if w := rename("A1"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unchanged styleNo, got %d", w.Code)
+	}
+
+	w := rename("c2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("rename: %d %s", w.Code, w.Body.String())
+	}
+	var after model.Product
+	if err := db.First(&after, p.ID).Error; err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if after.StyleNo != "C2" ||
+		after.CoverImageKey != "products/C2/cover/2025/01/01/u1/w2048.webp" ||
+		after.CoverImageURL != "/api/v1/assets/products/C2/cover/2025/01/01/u1/w2048.webp" ||
+		after.HoverImageURL != "https://example.com/hover.jpg" ||
+		!strings.Contains(string(after.DetailJSON), "/api/v1/assets/products/C2/gallery/2025/01/01/g1/w1280.webp?w=640") {
+		t.Fatalf("unexpected product after rename: %+v detail=%s", after, after.DetailJSON)
+	}
+
+	// All renditions moved, the unreferenced w320 sibling included.
+	for _, key := range []string{
+		"products/C2/cover/2025/01/01/u1/w320.webp",
+		"products/C2/cover/2025/01/01/u1/w2048.webp",
+		"products/C2/gallery/2025/01/01/g1/w1280.webp",
+	} {
+		if _, err := store.Stat(ctx, key); err != nil {
+			t.Fatalf("%s not copied: %v", key, err)
+		}
+		var a model.Asset
+		if err := db.Where("object_key = ?", key).First(&a).Error; err != nil || a.StyleNo != "C2" || a.ProductID == nil || *a.ProductID != p.ID {
+			t.Fatalf("%s not registered: %+v %v", key, a, err)
+		}
+		if ok, _ := assets.IsReferenced(ctx, db, key); !ok && !strings.HasSuffix(key, "w320.webp") {
+			t.Fatalf("%s not referenced", key)
+		}
+	}
+
+	// The old objects stay until the GC collects them.
+	var scheduled []string
+	db.Model(&model.AssetDeletion{}).Order("object_key").Pluck("object_key", &scheduled)
+	if len(scheduled) != 3 || !strings.HasPrefix(scheduled[0], "products/A1/") {
+		t.Fatalf("unexpected scheduled deletions: %v", scheduled)
+	}
+	if _, err := store.Stat(ctx, "products/A1/cover/2025/01/01/u1/w2048.webp"); err != nil {
+		t.Fatalf("old object removed too early: %v", err)
+	}
+
+	var rev model.ProductRevision
+	if err := db.Where("product_id = ?", p.ID).Order("revision desc").First(&rev).Error; err != nil || rev.Source != model.RevisionSourceRename {
+		t.Fatalf("expected a rename revision, got %+v %v", rev, err)
+	}
+
+	// Restoring the pre-rename revision brings the content back, not the
+	// styleNo: renames only happen through RenameStyle.
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/admin/products/"+strconv.Itoa(int(p.ID))+"/revisions/1/restore", nil)
+	c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(int(p.ID))}, {Key: "rev", Value: "1"}}
+	c.Set(middleware.ContextUserKey, model.User{ID: 7})
+	h.RestoreRevision(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("restore: %d %s", w.Code, w.Body.String())
+	}
+	if err := db.First(&after, p.ID).Error; err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if after.StyleNo != "C2" || after.Slug != "a" || after.CoverImageKey != "products/A1/cover/2025/01/01/u1/w2048.webp" {
+		t.Fatalf("unexpected product after restore: %+v", after)
+	}
+	if ok, _ := assets.IsReferenced(ctx, db, after.CoverImageKey); !ok {
+		t.Fatalf("restored key %s not referenced", after.CoverImageKey)
+	}
+}
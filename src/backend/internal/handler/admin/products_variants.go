@@ -0,0 +1,353 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"evening-gown/internal/assets"
+	"evening-gown/internal/logging"
+	"evening-gown/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errVariantExists    = errors.New("a variant with these options already exists")
+	errVariantCodeTaken = errors.New("variant code already used by another product")
+	errProductRenamed   = errors.New("product styleNo changed, retry")
+)
+
+type variantRequest struct {
+	Options      []model.VariantOption `json:"options"`
+	Availability *string               `json:"availability"`
+	MOQ          *int                  `json:"moq"`
+	LeadTimeDays *int                  `json:"leadTimeDays"`
+	ImageKeys    []string              `json:"imageKeys"`
+	Position     *int                  `json:"position"`
+}
+
+// ListVariants lists a product's variants in display order.
+// Route: GET /api/v1/admin/products/:id/variants
+func (h *ProductsHandler) ListVariants(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	p, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+	variants, err := loadVariants(h.db.WithContext(c.Request.Context()), p.ID)
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin product variants query failed", err, "product_id", p.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": variants})
+}
+
+// CreateVariant adds a variant to a product.
+// Route: POST /api/v1/admin/products/:id/variants
+//
+// Body: {options:[{group,value,label_i18n}], availability, moq, leadTimeDays,
+// imageKeys, position}. The code is derived from the styleNo and the option
+// values; option_groups in the detail are regenerated from all variants.
+func (h *ProductsHandler) CreateVariant(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	before, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+	var req variantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	v := model.ProductVariant{ProductID: before.ID, Availability: "in_stock"}
+	if req.Options == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "options required"})
+		return
+	}
+	if msg := applyVariantRequest(&v, req, before.StyleNo); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	h.saveVariants(c, before, "product.variant.create", http.StatusCreated, func(tx *gorm.DB) (any, error) {
+		if err := variantOptionsFree(tx, v); err != nil {
+			return nil, err
+		}
+		if err := tx.Create(&v).Error; err != nil {
+			return nil, err
+		}
+		return v, nil
+	})
+}
+
+// UpdateVariant changes a variant; omitted fields are kept.
+// Route: PATCH /api/v1/admin/products/:id/variants/:variantId
+func (h *ProductsHandler) UpdateVariant(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	before, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+	v, ok := h.loadVariant(c, before.ID)
+	if !ok {
+		return
+	}
+	var req variantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := applyVariantRequest(&v, req, before.StyleNo); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	h.saveVariants(c, before, "product.variant.update", http.StatusOK, func(tx *gorm.DB) (any, error) {
+		if err := variantOptionsFree(tx, v); err != nil {
+			return nil, err
+		}
+		if err := tx.Save(&v).Error; err != nil {
+			return nil, err
+		}
+		return v, nil
+	})
+}
+
+// DeleteVariant removes a variant.
+// Route: DELETE /api/v1/admin/products/:id/variants/:variantId
+func (h *ProductsHandler) DeleteVariant(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	before, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+	v, ok := h.loadVariant(c, before.ID)
+	if !ok {
+		return
+	}
+
+	h.saveVariants(c, before, "product.variant.delete", http.StatusNoContent, func(tx *gorm.DB) (any, error) {
+		return nil, tx.Delete(&model.ProductVariant{}, v.ID).Error
+	})
+}
+
+// saveVariants runs mutate and then, in the same transaction, regenerates the
+// product's option_groups from its variants, rebuilds its asset references
+// and saves a revision. It responds with status and what mutate returned.
+//
+// The product is locked and re-read first, so a detail saved concurrently is
+// not overwritten with the copy loaded before the transaction. Once the last
+// variant is gone, option_groups are left as they are (editable by hand again).
+func (h *ProductsHandler) saveVariants(c *gin.Context, loaded model.Product, action string, status int, mutate func(tx *gorm.DB) (any, error)) {
+	ctx := c.Request.Context()
+	var (
+		result any
+		before model.Product
+		after  model.Product
+	)
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+			Where("deleted_at IS NULL").
+			First(&before, loaded.ID).Error; err != nil {
+			return err
+		}
+		if before.StyleNo != loaded.StyleNo {
+			// Codes and image keys were derived from the old styleNo.
+			return errProductRenamed
+		}
+		var err error
+		if result, err = mutate(tx); err != nil {
+			return err
+		}
+		variants, err := loadVariants(tx, before.ID)
+		if err != nil {
+			return err
+		}
+		if len(variants) > 0 {
+			detail, err := model.ApplyVariantOptionGroups(model.UpgradeDetail(before.DetailJSON), variants)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&model.Product{}).
+				Where("id = ?", before.ID).
+				Update("detail_json", detail).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.First(&after, before.ID).Error; err != nil {
+			return err
+		}
+		if err := assets.SyncProduct(tx, after); err != nil {
+			return err
+		}
+		if _, err := saveProductRevision(tx, c, &before, after, model.RevisionSourceUpdate, nil); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, action, model.AuditEntityProduct, before.ID, before, after)
+	})
+	if errors.Is(err, errVariantExists) || errors.Is(err, errVariantCodeTaken) || errors.Is(err, errProductRenamed) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin product variant save failed", err, "product_id", loaded.ID, "action", action)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save failed"})
+		return
+	}
+
+	if after.PublishedAt != nil && h.cache != nil {
+		_, _ = h.cache.BumpProductsVersion(ctx)
+	}
+
+	if result == nil {
+		c.Status(status)
+		return
+	}
+	c.JSON(status, result)
+}
+
+// applyVariantRequest validates req and applies it to v. It returns an error
+// message for the client, or "".
+func applyVariantRequest(v *model.ProductVariant, req variantRequest, styleNo string) string {
+	if req.Options != nil {
+		opts, err := model.NormalizeVariantOptions(req.Options)
+		if err != nil {
+			return "invalid options"
+		}
+		raw, _ := json.Marshal(opts)
+		v.Options = raw
+		v.OptionsKey = model.VariantOptionsKey(opts)
+		v.Code = model.VariantCode(styleNo, opts)
+	}
+	if req.Availability != nil {
+		a := strings.TrimSpace(*req.Availability)
+		if !slices.Contains(model.VariantAvailabilities, a) {
+			return "invalid availability"
+		}
+		v.Availability = a
+	}
+	if req.MOQ != nil {
+		if *req.MOQ < 0 {
+			return "invalid moq"
+		}
+		v.MOQ = *req.MOQ
+	}
+	if req.LeadTimeDays != nil {
+		if *req.LeadTimeDays < 0 {
+			return "invalid leadTimeDays"
+		}
+		v.LeadTimeDays = *req.LeadTimeDays
+	}
+	if req.ImageKeys != nil {
+		keys := make([]string, 0, len(req.ImageKeys))
+		for _, k := range req.ImageKeys {
+			key := model.AssetKeyFromString(k)
+			if key == "" || !strings.HasPrefix(key, "products/"+styleNo+"/") {
+				return "invalid imageKeys"
+			}
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+		raw, _ := json.Marshal(keys)
+		v.ImageKeys = raw
+	}
+	if req.Position != nil {
+		v.Position = *req.Position
+	}
+	if len(v.ImageKeys) == 0 {
+		v.ImageKeys = json.RawMessage(`[]`)
+	}
+	return ""
+}
+
+// variantOptionsFree fails with errVariantExists when another variant of the
+// product has the same options, and with errVariantCodeTaken when the code
+// (unique across products, as a SKU) is already used elsewhere: styleNo
+// "X-IVORY" with size M and styleNo "X" with IVORY and M both give X-IVORY-M.
+func variantOptionsFree(tx *gorm.DB, v model.ProductVariant) error {
+	var cnt int64
+	if err := tx.Model(&model.ProductVariant{}).
+		Where("product_id = ? AND options_key = ? AND id <> ?", v.ProductID, v.OptionsKey, v.ID).
+		Count(&cnt).Error; err != nil {
+		return err
+	}
+	if cnt > 0 {
+		return errVariantExists
+	}
+	if err := tx.Model(&model.ProductVariant{}).
+		Where("code = ? AND id <> ?", v.Code, v.ID).
+		Count(&cnt).Error; err != nil {
+		return err
+	}
+	if cnt > 0 {
+		return errVariantCodeTaken
+	}
+	return nil
+}
+
+func loadVariants(db *gorm.DB, productID uint) ([]model.ProductVariant, error) {
+	variants := []model.ProductVariant{}
+	if err := db.Where("product_id = ?", productID).Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	model.SortVariants(variants)
+	return variants, nil
+}
+
+// loadVariant loads the :variantId variant of productID. It writes the error
+// response and returns false on failure.
+func (h *ProductsHandler) loadVariant(c *gin.Context, productID uint) (model.ProductVariant, bool) {
+	id, err := strconv.ParseUint(c.Param("variantId"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant id"})
+		return model.ProductVariant{}, false
+	}
+	var v model.ProductVariant
+	if err := h.db.WithContext(c.Request.Context()).
+		Where("product_id = ?", productID).
+		First(&v, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return model.ProductVariant{}, false
+	}
+	return v, true
+}
+
+// withVariantOptionGroups regenerates option_groups of detail when the
+// product has variants, so edits of the detail cannot drift from them.
+func (h *ProductsHandler) withVariantOptionGroups(ctx context.Context, productID uint, detail json.RawMessage) (json.RawMessage, error) {
+	variants, err := loadVariants(h.db.WithContext(ctx), productID)
+	if err != nil || len(variants) == 0 {
+		return detail, err
+	}
+	return model.ApplyVariantOptionGroups(detail, variants)
+}
@@ -0,0 +1,184 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"evening-gown/internal/assets"
+	"evening-gown/internal/cache"
+	"evening-gown/internal/middleware"
+	"evening-gown/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func callVariants(h gin.HandlerFunc, method string, productID, variantID uint, body any) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		raw, _ := json.Marshal(body)
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/api/v1/admin/products/variants", reader)
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(int(productID))}, {Key: "variantId", Value: strconv.Itoa(int(variantID))}}
+	c.Set(middleware.ContextUserKey, model.User{ID: 7})
+	h(c)
+	c.Writer.WriteHeaderNow()
+	return w
+}
+
+func TestProducts_Variants(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openTestDB(t)
+
+	p := model.Product{Slug: "a", StyleNo: "SS25-DR-01", Season: "ss25", Category: "gown", Availability: "in_stock",
+		DetailJSON: json.RawMessage(`{"option_groups":[{"key":"color","name_i18n":{"zh":"颜色","en":"Color"},"options":[{"key":"free-form"}]}]}`)}
+	if err := db.Create(&p).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	h := NewProductsHandler(db, cache.NewPublicCache(nil))
+
+	image := "products/SS25-DR-01/gallery/2025/01/01/v1/w1280.webp"
+	w := callVariants(h.CreateVariant, http.MethodPost, p.ID, 0, gin.H{
+		"options":      []gin.H{{"group": "color", "value": "Ivory", "label_i18n": gin.H{"zh": "象牙白", "en": "Ivory"}}, {"group": "size", "value": "M"}},
+		"availability": "preorder",
+		"moq":          10,
+		"leadTimeDays": 45,
+		"imageKeys":    []string{"/api/v1/assets/" + image + "?w=640"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	var created model.ProductVariant
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Code != "SS25-DR-01-IVORY-M" || created.Availability != "preorder" || created.MOQ != 10 || created.LeadTimeDays != 45 ||
+		created.DecodeImageKeys()[0] != image {
+		t.Fatalf("unexpected variant: %s", w.Body.String())
+	}
+
+	// Same combination, other order: conflict. Foreign images and bad values: rejected.
+	if w := callVariants(h.CreateVariant, http.MethodPost, p.ID, 0, gin.H{
+		"options": []gin.H{{"group": "size", "value": "m"}, {"group": "color", "value": "ivory"}},
+	}); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d %s", w.Code, w.Body.String())
+	}
+	for _, body := range []gin.H{
+		{"options": []gin.H{{"group": "color", "value": "noir"}}, "imageKeys": []string{"products/OTHER/gallery/x.webp"}},
+		{"options": []gin.H{{"group": "color", "value": "noir"}}, "availability": "sold"},
+		{"options": []gin.H{{"group": "color", "value": "noir"}}, "moq": -1},
+		{"availability": "in_stock"},
+	} {
+		if w := callVariants(h.CreateVariant, http.MethodPost, p.ID, 0, body); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %v, got %d", body, w.Code)
+		}
+	}
+
+	w = callVariants(h.CreateVariant, http.MethodPost, p.ID, 0, gin.H{
+		"options": []gin.H{{"group": "color", "value": "noir"}, {"group": "size", "value": "s"}},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create second: %d %s", w.Code, w.Body.String())
+	}
+	var second model.ProductVariant
+	_ = json.Unmarshal(w.Body.Bytes(), &second)
+
+	// Codes are SKUs, unique across products: another product whose styleNo
+	// ends like these options gets a 409, not a unique index error.
+	other := model.Product{Slug: "b", StyleNo: "SS25-DR-01-NOIR", Season: "ss25", Category: "gown", Availability: "in_stock"}
+	if err := db.Create(&other).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	if w := callVariants(h.CreateVariant, http.MethodPost, other.ID, 0, gin.H{
+		"options": []gin.H{{"group": "size", "value": "s"}},
+	}); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a code used by another product, got %d %s", w.Code, w.Body.String())
+	}
+
+	// option_groups are generated from the variants; free-form options are gone.
+	var after model.Product
+	db.First(&after, p.ID)
+	detail := string(after.DetailJSON)
+	if strings.Contains(detail, "free-form") || !strings.Contains(detail, `"key":"ivory"`) || !strings.Contains(detail, `"key":"noir"`) || !strings.Contains(detail, `"key":"size"`) {
+		t.Fatalf("unexpected option_groups: %s", detail)
+	}
+	if ok, _ := assets.IsReferenced(t.Context(), db, image); !ok {
+		t.Fatalf("variant image must be referenced by the product")
+	}
+
+	w = callVariants(h.UpdateVariant, http.MethodPatch, p.ID, created.ID, gin.H{"availability": "archived", "position": 5})
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+	if w := callVariants(h.UpdateVariant, http.MethodPatch, p.ID, created.ID, gin.H{
+		"options": []gin.H{{"group": "color", "value": "noir"}, {"group": "size", "value": "s"}},
+	}); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 when updating into an existing combination, got %d", w.Code)
+	}
+
+	w = callVariants(h.ListVariants, http.MethodGet, p.ID, 0, nil)
+	var list struct {
+		Items []model.ProductVariant `json:"items"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Items) != 2 || list.Items[0].ID != second.ID || list.Items[1].Availability != "archived" {
+		t.Fatalf("unexpected list: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := callVariants(h.DeleteVariant, http.MethodDelete, p.ID, created.ID, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d %s", w.Code, w.Body.String())
+	}
+	if w := callVariants(h.DeleteVariant, http.MethodDelete, p.ID, created.ID, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 on second delete, got %d", w.Code)
+	}
+	db.First(&after, p.ID)
+	if strings.Contains(string(after.DetailJSON), "ivory") {
+		t.Fatalf("deleted variant still in option_groups: %s", after.DetailJSON)
+	}
+	if ok, _ := assets.IsReferenced(t.Context(), db, image); ok {
+		t.Fatalf("image of the deleted variant is still referenced")
+	}
+
+	// Without variants, option_groups are no longer generated: deleting the
+	// last variant keeps them for editing by hand.
+	if w := callVariants(h.DeleteVariant, http.MethodDelete, p.ID, second.ID, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete last: %d %s", w.Code, w.Body.String())
+	}
+	db.First(&after, p.ID)
+	if !strings.Contains(string(after.DetailJSON), `"key":"noir"`) {
+		t.Fatalf("deleting the last variant wiped option_groups: %s", after.DetailJSON)
+	}
+}
+
+func TestProducts_Variants_AuditFailureRollsBack(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openTestDB(t)
+
+	p := model.Product{Slug: "a", StyleNo: "SS25-DR-01", Season: "ss25", Category: "gown", Availability: "in_stock", DetailJSON: json.RawMessage(`{}`)}
+	if err := db.Create(&p).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	if err := db.Migrator().DropTable(&model.AuditLog{}); err != nil {
+		t.Fatalf("drop audit logs: %v", err)
+	}
+	h := NewProductsHandler(db, cache.NewPublicCache(nil))
+
+	w := callVariants(h.CreateVariant, http.MethodPost, p.ID, 0, gin.H{"options": []gin.H{{"group": "size", "value": "M"}}})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected the failed audit write to fail the request, got %d %s", w.Code, w.Body.String())
+	}
+	var variants, revisions int64
+	db.Model(&model.ProductVariant{}).Count(&variants)
+	db.Model(&model.ProductRevision{}).Count(&revisions)
+	if variants != 0 || revisions != 0 {
+		t.Fatalf("expected the change to be rolled back, got %d variants and %d revisions", variants, revisions)
+	}
+}
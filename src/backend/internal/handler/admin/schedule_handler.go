@@ -0,0 +1,246 @@
+package admin
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"evening-gown/internal/logging"
+	"evening-gown/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// scheduleRequest replaces both schedule fields; null clears one.
+// Times are RFC3339 with an offset, so "midnight in the buyer's timezone" is
+// expressed directly (e.g. 2025-09-01T00:00:00+08:00).
+type scheduleRequest struct {
+	PublishAt   *time.Time `json:"publishAt"`
+	UnpublishAt *time.Time `json:"unpublishAt"`
+}
+
+func (r scheduleRequest) validate() (map[string]any, string) {
+	if r.PublishAt != nil && !r.PublishAt.After(time.Now()) {
+		return nil, "publishAt must be in the future"
+	}
+	if r.PublishAt != nil && r.UnpublishAt != nil && !r.UnpublishAt.After(*r.PublishAt) {
+		return nil, "unpublishAt must be after publishAt"
+	}
+	updates := map[string]any{"publish_at": nil, "unpublish_at": nil}
+	if r.PublishAt != nil {
+		t := r.PublishAt.UTC()
+		updates["publish_at"] = &t
+	}
+	if r.UnpublishAt != nil {
+		t := r.UnpublishAt.UTC()
+		updates["unpublish_at"] = &t
+	}
+	return updates, ""
+}
+
+// scheduledChange is one upcoming transition applied by internal/scheduler.
+type scheduledChange struct {
+	Action string    `json:"action"` // publish|unpublish
+	At     time.Time `json:"at"`
+	ID     uint      `json:"id"`
+	Item   any       `json:"item"`
+}
+
+// parseScheduledQuery reads ?until= (RFC3339) and ?limit= for the scheduled lists.
+func parseScheduledQuery(c *gin.Context) (until *time.Time, limit int, ok bool) {
+	if v := strings.TrimSpace(c.Query("until")); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid until"})
+			return nil, 0, false
+		}
+		until = &t
+	}
+	limit = parseIntQuery(c, "limit", 50)
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	return until, limit, true
+}
+
+// scheduledQuery selects rows with column set (and not after until), soonest first.
+func scheduledQuery(q *gorm.DB, column string, until *time.Time, limit int) *gorm.DB {
+	q = q.Where("deleted_at IS NULL").Where(column + " IS NOT NULL")
+	if until != nil {
+		q = q.Where(column+" <= ?", *until)
+	}
+	return q.Order(column + " asc, id asc").Limit(limit)
+}
+
+// mergeScheduled sorts changes by time and keeps the first limit.
+func mergeScheduled(items []scheduledChange, limit int) []scheduledChange {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].At.Equal(items[j].At) {
+			return items[i].ID < items[j].ID
+		}
+		return items[i].At.Before(items[j].At)
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+// Schedule sets or clears a product's publish_at/unpublish_at.
+// Route: PUT /api/v1/admin/products/:id/schedule
+func (h *ProductsHandler) Schedule(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	before, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+
+	var req scheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updates, msg := req.validate()
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	after, ok := updateAudited(c, h.db, model.AuditEntityProduct, "product.schedule", before.ID, before, func(tx *gorm.DB) *gorm.DB {
+		return tx.Updates(updates)
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, after)
+}
+
+// Scheduled lists upcoming scheduled product transitions, soonest first.
+// Route: GET /api/v1/admin/products/scheduled?until=&limit=
+func (h *ProductsHandler) Scheduled(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	until, limit, ok := parseScheduledQuery(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var publishing, unpublishing []model.Product
+	if err := scheduledQuery(h.db.WithContext(ctx), "publish_at", until, limit).Find(&publishing).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin products scheduled query failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if err := scheduledQuery(h.db.WithContext(ctx), "unpublish_at", until, limit).Find(&unpublishing).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin products scheduled query failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	items := make([]scheduledChange, 0, len(publishing)+len(unpublishing))
+	for _, p := range publishing {
+		items = append(items, scheduledChange{Action: "publish", At: *p.PublishAt, ID: p.ID, Item: p})
+	}
+	for _, p := range unpublishing {
+		items = append(items, scheduledChange{Action: "unpublish", At: *p.UnpublishAt, ID: p.ID, Item: p})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": mergeScheduled(items, limit)})
+}
+
+// Schedule sets or clears an update's publish_at/unpublish_at.
+// Route: PUT /api/v1/admin/updates/:id/schedule
+func (h *UpdatesHandler) Schedule(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var before model.UpdatePost
+	if err := h.db.WithContext(ctx).
+		Where("id = ?", uint(id)).
+		Where("deleted_at IS NULL").
+		First(&before).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	var req scheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updates, msg := req.validate()
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	after, ok := updateAudited(c, h.db, model.AuditEntityUpdate, "update.schedule", before.ID, before, func(tx *gorm.DB) *gorm.DB {
+		return tx.Updates(updates)
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, after)
+}
+
+// Scheduled lists upcoming scheduled update transitions, soonest first.
+// Route: GET /api/v1/admin/updates/scheduled?until=&limit=
+func (h *UpdatesHandler) Scheduled(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	until, limit, ok := parseScheduledQuery(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var publishing, unpublishing []model.UpdatePost
+	if err := scheduledQuery(h.db.WithContext(ctx), "publish_at", until, limit).Find(&publishing).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin updates scheduled query failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if err := scheduledQuery(h.db.WithContext(ctx), "unpublish_at", until, limit).Find(&unpublishing).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin updates scheduled query failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	items := make([]scheduledChange, 0, len(publishing)+len(unpublishing))
+	for _, p := range publishing {
+		items = append(items, scheduledChange{Action: "publish", At: *p.PublishAt, ID: p.ID, Item: p})
+	}
+	for _, p := range unpublishing {
+		items = append(items, scheduledChange{Action: "unpublish", At: *p.UnpublishAt, ID: p.ID, Item: p})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": mergeScheduled(items, limit)})
+}
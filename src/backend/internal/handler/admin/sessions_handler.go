@@ -0,0 +1,103 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"evening-gown/internal/logging"
+	"evening-gown/internal/middleware"
+	"evening-gown/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SessionsHandler lists and revokes the signed-in user's device sessions.
+type SessionsHandler struct {
+	db *gorm.DB
+}
+
+func NewSessionsHandler(db *gorm.DB) *SessionsHandler {
+	return &SessionsHandler{db: db}
+}
+
+type sessionItem struct {
+	model.AdminSession
+	Current bool `json:"current"`
+}
+
+// List returns the current user's active sessions (newest activity first).
+// Route: GET /api/v1/admin/sessions
+func (h *SessionsHandler) List(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	user, ok := adminFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "unauthorized", "message": "unauthorized", "error": "unauthorized"})
+		return
+	}
+
+	var rows []model.AdminSession
+	if err := h.db.WithContext(c.Request.Context()).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now().UTC()).
+		Order("last_used_at desc, id desc").
+		Find(&rows).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin sessions query failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	current := c.GetString(middleware.ContextSessionIDKey)
+	items := make([]sessionItem, 0, len(rows))
+	for _, r := range rows {
+		items = append(items, sessionItem{AdminSession: r, Current: current != "" && r.SessionID == current})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"total": len(items), "items": items})
+}
+
+// Delete revokes one of the current user's sessions (remote logout).
+// Route: DELETE /api/v1/admin/sessions/:id
+func (h *SessionsHandler) Delete(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	user, ok := adminFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "unauthorized", "message": "unauthorized", "error": "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var session model.AdminSession
+	if err := h.db.WithContext(c.Request.Context()).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", uint(id), user.ID).
+		First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	if err := revokeSessions(h.db.WithContext(c.Request.Context()).Where("id = ?", session.ID), "revoked", time.Now().UTC()); err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin revoke session failed", err, "session_id", session.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "revoke failed"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// revokeSessions marks the still-active sessions matched by q as revoked.
+func revokeSessions(q *gorm.DB, reason string, now time.Time) error {
+	return q.Model(&model.AdminSession{}).
+		Where("revoked_at IS NULL").
+		Updates(map[string]any{"revoked_at": &now, "revoke_reason": reason}).Error
+}
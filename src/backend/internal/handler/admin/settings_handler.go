@@ -21,6 +21,10 @@ func NewSettingsHandler(db *gorm.DB) *SettingsHandler {
 type productDetailTemplateResponse struct {
 	Key   string          `json:"key"`
 	Value json.RawMessage `json:"value"`
+
+	// Set by the per-category endpoints (see product_detail_templates.go).
+	Category  string `json:"category,omitempty"`
+	Inherited bool   `json:"inherited,omitempty"`
 }
 
 // GetProductDetailTemplate returns the current product detail template.
@@ -44,21 +48,24 @@ func (h *SettingsHandler) GetProductDetailTemplate(c *gin.Context) {
 		return
 	}
 
-	val := s.ValueJSON
+	c.JSON(http.StatusOK, productDetailTemplateResponse{Key: s.Key, Value: detailTemplateValue(s.ValueJSON)})
+}
+
+// detailTemplateValue returns a stored template upgraded to the current
+// schema_version, or the default template when it is not a JSON object.
+func detailTemplateValue(val json.RawMessage) json.RawMessage {
 	if len(val) == 0 {
-		val = model.DefaultProductDetailTemplate()
+		return model.DefaultProductDetailTemplate()
 	}
 	// Ensure stored value is valid JSON object; otherwise fallback.
 	var anyV any
 	if err := json.Unmarshal(val, &anyV); err != nil {
-		val = model.DefaultProductDetailTemplate()
-	} else {
-		if _, ok := anyV.(map[string]any); !ok {
-			val = model.DefaultProductDetailTemplate()
-		}
+		return model.DefaultProductDetailTemplate()
 	}
-
-	c.JSON(http.StatusOK, productDetailTemplateResponse{Key: s.Key, Value: val})
+	if _, ok := anyV.(map[string]any); !ok {
+		return model.DefaultProductDetailTemplate()
+	}
+	return model.UpgradeDetail(val)
 }
 
 type putProductDetailTemplateRequest struct {
@@ -73,28 +80,55 @@ func (h *SettingsHandler) PutProductDetailTemplate(c *gin.Context) {
 		return
 	}
 
+	set, ok := h.saveProductDetailTemplate(c, model.SettingKeyProductDetailTemplate)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, productDetailTemplateResponse{Key: set.Key, Value: set.ValueJSON})
+}
+
+// saveProductDetailTemplate validates the template in the request body and
+// stores it under key. It responds itself when it returns false.
+func (h *SettingsHandler) saveProductDetailTemplate(c *gin.Context, key string) (model.AppSetting, bool) {
 	var req putProductDetailTemplateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return model.AppSetting{}, false
 	}
 
 	// Validate: must be a JSON object.
 	var anyV any
 	if err := json.Unmarshal(req.Value, &anyV); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid value"})
-		return
+		return model.AppSetting{}, false
 	}
 	if _, ok := anyV.(map[string]any); !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "value must be a JSON object"})
-		return
+		return model.AppSetting{}, false
+	}
+	value, ok := prepareDetail(c, req.Value, "invalid value")
+	if !ok {
+		return model.AppSetting{}, false
 	}
+	req.Value = value
+
+	set := model.AppSetting{Key: key, ValueJSON: req.Value}
+	if err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var before model.AppSetting
+		_ = tx.Where("key = ?", key).First(&before).Error
 
-	set := model.AppSetting{Key: model.SettingKeyProductDetailTemplate, ValueJSON: req.Value}
-	if err := h.db.WithContext(c.Request.Context()).Save(&set).Error; err != nil {
+		if err := tx.Save(&set).Error; err != nil {
+			return err
+		}
+		// Diff the template itself so changed top-level keys show up individually.
+		var prev json.RawMessage
+		if len(before.ValueJSON) > 0 {
+			prev = before.ValueJSON
+		}
+		return recordAudit(c, tx, "setting.update", model.AuditEntitySetting, set.Key, prev, set.ValueJSON)
+	}); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return model.AppSetting{}, false
 	}
-
-	c.JSON(http.StatusOK, productDetailTemplateResponse{Key: set.Key, Value: set.ValueJSON})
+	return set, true
 }
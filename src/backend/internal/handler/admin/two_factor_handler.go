@@ -0,0 +1,426 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"evening-gown/internal/logging"
+	"evening-gown/internal/model"
+	"evening-gown/internal/security"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+// TwoFactorHandler manages TOTP enrollment for the signed-in backoffice user.
+type TwoFactorHandler struct {
+	db     *gorm.DB
+	issuer string
+}
+
+func NewTwoFactorHandler(db *gorm.DB, issuer string) *TwoFactorHandler {
+	issuer = strings.TrimSpace(issuer)
+	if issuer == "" {
+		issuer = "evening-gown"
+	}
+	return &TwoFactorHandler{db: db, issuer: issuer}
+}
+
+type twoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type twoFactorVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type twoFactorDisableRequest struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// VerifyTwoFactor completes a 2FA login: it exchanges the challenge token from Login
+// plus a TOTP (or recovery) code for access and refresh tokens.
+//
+// Route: POST /api/v1/admin/auth/2fa/verify (unprotected; authenticates via mfa_token)
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	if h == nil || h.db == nil || h.jwtSvc == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    "service_unavailable",
+			"message": "service unavailable",
+			"error":   "service unavailable",
+		})
+		return
+	}
+
+	var req twoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	ip := c.ClientIP()
+	if blocked, retryAfter := h.limiter.Blocked(ctx, ip); blocked {
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second)/time.Second)))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"code":    "too_many_requests",
+			"message": "too many login attempts",
+			"error":   "too many login attempts",
+		})
+		return
+	}
+
+	unauthorized := func() {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    "unauthorized",
+			"message": "unauthorized",
+			"error":   "unauthorized",
+		})
+	}
+
+	claims, err := h.jwtSvc.ParseAdminMFAToken(req.MFAToken)
+	if err != nil {
+		unauthorized()
+		return
+	}
+	uid, err := strconv.ParseUint(strings.TrimSpace(claims.Subject), 10, 64)
+	if err != nil || uid == 0 {
+		unauthorized()
+		return
+	}
+
+	var user model.User
+	if err := h.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", uint(uid)).First(&user).Error; err != nil {
+		unauthorized()
+		return
+	}
+
+	// The challenge must still match the account (password not changed, tokens not revoked).
+	dbPwdAt := int64(0)
+	if user.PasswordUpdatedAt != nil {
+		dbPwdAt = user.PasswordUpdatedAt.UTC().Unix()
+	}
+	now := time.Now().UTC()
+	if claims.PasswordUpdatedAt != dbPwdAt || claims.TokenVersion != user.TokenVersion ||
+		!user.TOTPEnabled || !model.IsKnownRole(user.Role) || !loginStatusAllowed(user, now) {
+		unauthorized()
+		return
+	}
+
+	ok, err := verifySecondFactor(h.db.WithContext(ctx), user, req.Code, req.RecoveryCode, now)
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin verify 2fa failed", err, "user_id", user.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "verify failed"})
+		return
+	}
+	if !ok {
+		if err := h.recordFailedLogin(c, user.ID, now); err != nil {
+			logging.ErrorWithStack(logging.FromGin(c), "admin record failed login failed", err, "user_id", user.ID)
+		}
+		h.limiter.RecordFailure(ctx, ip)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    "invalid_mfa_code",
+			"message": "invalid code",
+			"error":   "invalid code",
+		})
+		return
+	}
+
+	h.completeLogin(c, user, now)
+}
+
+// Status reports whether 2FA is enabled for the current user.
+// Route: GET /api/v1/admin/me/2fa
+func (h *TwoFactorHandler) Status(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	user, ok := adminFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "unauthorized", "message": "unauthorized", "error": "unauthorized"})
+		return
+	}
+
+	var remaining int64
+	if err := h.db.WithContext(c.Request.Context()).Model(&model.UserRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Count(&remaining).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin 2fa status query failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                user.TOTPEnabled,
+		"recoveryCodesRemaining": remaining,
+	})
+}
+
+// Setup starts enrollment by generating a new pending secret.
+//
+// The response contains the otpauth:// provisioning URI for rendering a QR code.
+// 2FA is not active until Confirm succeeds with a code from the authenticator.
+// Route: POST /api/v1/admin/me/2fa/setup
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	user, ok := adminFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "unauthorized", "message": "unauthorized", "error": "unauthorized"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "2fa already enabled"})
+		return
+	}
+
+	secret, err := security.NewTOTPSecret()
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin 2fa secret generation failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "setup failed"})
+		return
+	}
+	if err := h.db.WithContext(c.Request.Context()).Model(&model.User{}).
+		Where("id = ? AND totp_enabled = ?", user.ID, false).
+		Updates(map[string]any{"totp_secret": secret, "updated_at": time.Now().UTC()}).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin 2fa setup save failed", err, "user_id", user.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "setup failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_url": security.TOTPProvisioningURI(h.issuer, user.Email, secret),
+	})
+}
+
+// Confirm activates 2FA after verifying a code for the pending secret and returns
+// freshly generated recovery codes (shown only once).
+// Route: POST /api/v1/admin/me/2fa/confirm
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	user, ok := adminFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "unauthorized", "message": "unauthorized", "error": "unauthorized"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "2fa already enabled"})
+		return
+	}
+	if strings.TrimSpace(user.TOTPSecret) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "2fa setup has not been started"})
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	step, valid := security.VerifyTOTP(user.TOTPSecret, req.Code, time.Now().UTC())
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+
+	var codes []string
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]any{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+			"updated_at":     time.Now().UTC(),
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin 2fa confirm failed", err, "user_id", user.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "confirm failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "recoveryCodes": codes})
+}
+
+// RegenerateRecoveryCodes invalidates all recovery codes and issues new ones.
+// Requires a current TOTP code.
+// Route: POST /api/v1/admin/me/2fa/recovery-codes
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	user, ok := adminFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "unauthorized", "message": "unauthorized", "error": "unauthorized"})
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "2fa is not enabled"})
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var codes []string
+	invalid := false
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		ok, err := verifySecondFactor(tx, user, req.Code, "", time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			invalid = true
+			return nil
+		}
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin 2fa recovery codes failed", err, "user_id", user.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "regenerate failed"})
+		return
+	}
+	if invalid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
+// Disable turns 2FA off for the current user. Requires the password and a TOTP or
+// recovery code.
+// Route: POST /api/v1/admin/me/2fa/disable
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	user, ok := adminFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "unauthorized", "message": "unauthorized", "error": "unauthorized"})
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "2fa is not enabled"})
+		return
+	}
+
+	var req twoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !security.CheckPassword(user.PasswordHash, req.Password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password is incorrect"})
+		return
+	}
+
+	invalid := false
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		ok, err := verifySecondFactor(tx, user, req.Code, req.RecoveryCode, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			invalid = true
+			return nil
+		}
+		return clearTwoFactor(tx, user.ID)
+	})
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin 2fa disable failed", err, "user_id", user.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "disable failed"})
+		return
+	}
+	if invalid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": false})
+}
+
+// verifySecondFactor checks a TOTP code (rejecting replays) or consumes a recovery code.
+func verifySecondFactor(db *gorm.DB, user model.User, code, recoveryCode string, now time.Time) (bool, error) {
+	if code = strings.TrimSpace(code); code != "" {
+		step, ok := security.VerifyTOTP(user.TOTPSecret, code, now)
+		if !ok || step <= user.TOTPLastStep {
+			return false, nil
+		}
+		// Guarded update so two concurrent requests cannot both spend the same step.
+		res := db.Model(&model.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if res.Error != nil {
+			return false, res.Error
+		}
+		return res.RowsAffected == 1, nil
+	}
+
+	if recoveryCode = strings.TrimSpace(recoveryCode); recoveryCode != "" {
+		res := db.Model(&model.UserRecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, security.HashRecoveryCode(recoveryCode)).
+			Update("used_at", &now)
+		if res.Error != nil {
+			return false, res.Error
+		}
+		return res.RowsAffected == 1, nil
+	}
+
+	return false, nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&model.UserRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes, err := security.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]model.UserRecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		rows = append(rows, model.UserRecoveryCode{UserID: userID, CodeHash: security.HashRecoveryCode(code)})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func clearTwoFactor(tx *gorm.DB, userID uint) error {
+	if err := tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]any{
+		"totp_secret":    "",
+		"totp_enabled":   false,
+		"totp_last_step": 0,
+		"updated_at":     time.Now().UTC(),
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userID).Delete(&model.UserRecoveryCode{}).Error
+}
@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -132,8 +133,14 @@ func (h *UpdatesHandler) Create(c *gin.Context) {
 		post.PublishedAt = &now
 	}
 
-	if err := h.db.WithContext(c.Request.Context()).Create(&post).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&post).Error; err != nil {
+			return err
+		}
+		return recordAudit(c, tx, "update.create", model.AuditEntityUpdate, post.ID, nil, post)
+	}); err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin updates create failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
 		return
 	}
 	if h.cache != nil && isPublicCompanyUpdate(post) {
@@ -246,25 +253,17 @@ func (h *UpdatesHandler) Update(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.WithContext(ctx).Model(&model.UpdatePost{}).
-		Where("id = ?", uint(id)).
-		Where("deleted_at IS NULL").
-		Updates(updates).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	after, ok := updateAudited(c, h.db, model.AuditEntityUpdate, "update.update", before.ID, before, func(tx *gorm.DB) *gorm.DB {
+		return tx.Updates(updates)
+	})
+	if !ok {
 		return
 	}
-
-	var after model.UpdatePost
-	if err := h.db.WithContext(ctx).
-		Where("id = ?", uint(id)).
-		Where("deleted_at IS NULL").
-		First(&after).Error; err == nil {
-		if h.cache != nil && (isPublicCompanyUpdate(before) || isPublicCompanyUpdate(after)) {
-			_, _ = h.cache.BumpUpdatesVersion(ctx)
-		}
+	if h.cache != nil && (isPublicCompanyUpdate(before) || isPublicCompanyUpdate(after)) {
+		_, _ = h.cache.BumpUpdatesVersion(ctx)
 	}
 
-	h.Get(c)
+	c.JSON(http.StatusOK, after)
 }
 
 func (h *UpdatesHandler) Publish(c *gin.Context) {
@@ -290,26 +289,21 @@ func (h *UpdatesHandler) Publish(c *gin.Context) {
 	}
 
 	now := time.Now().UTC()
-	res := h.db.WithContext(ctx).Model(&model.UpdatePost{}).
-		Where("id = ?", uint(id)).
-		Where("deleted_at IS NULL").
-		Updates(map[string]any{
+	after, ok := updateAudited(c, h.db, model.AuditEntityUpdate, "update.publish", before.ID, before, func(tx *gorm.DB) *gorm.DB {
+		return tx.Updates(map[string]any{
 			"status":       "published",
 			"published_at": &now,
+			"publish_at":   nil,
 		})
-	if res.Error != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": res.Error.Error()})
-		return
-	}
-	if res.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	})
+	if !ok {
 		return
 	}
 	if h.cache != nil && strings.TrimSpace(before.Type) == "company" {
 		_, _ = h.cache.BumpUpdatesVersion(ctx)
 	}
 
-	h.Get(c)
+	c.JSON(http.StatusOK, after)
 }
 
 func (h *UpdatesHandler) Unpublish(c *gin.Context) {
@@ -334,26 +328,21 @@ func (h *UpdatesHandler) Unpublish(c *gin.Context) {
 		return
 	}
 
-	res := h.db.WithContext(ctx).Model(&model.UpdatePost{}).
-		Where("id = ?", uint(id)).
-		Where("deleted_at IS NULL").
-		Updates(map[string]any{
+	after, ok := updateAudited(c, h.db, model.AuditEntityUpdate, "update.unpublish", before.ID, before, func(tx *gorm.DB) *gorm.DB {
+		return tx.Updates(map[string]any{
 			"status":       "draft",
 			"published_at": nil,
+			"unpublish_at": nil,
 		})
-	if res.Error != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": res.Error.Error()})
-		return
-	}
-	if res.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	})
+	if !ok {
 		return
 	}
 	if h.cache != nil && isPublicCompanyUpdate(before) {
 		_, _ = h.cache.BumpUpdatesVersion(ctx)
 	}
 
-	h.Get(c)
+	c.JSON(http.StatusOK, after)
 }
 
 func (h *UpdatesHandler) Delete(c *gin.Context) {
@@ -379,16 +368,26 @@ func (h *UpdatesHandler) Delete(c *gin.Context) {
 	}
 
 	now := time.Now().UTC()
-	res := h.db.WithContext(ctx).Model(&model.UpdatePost{}).
-		Where("id = ?", uint(id)).
-		Where("deleted_at IS NULL").
-		Update("deleted_at", &now)
-	if res.Error != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": res.Error.Error()})
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.UpdatePost{}).
+			Where("id = ?", uint(id)).
+			Where("deleted_at IS NULL").
+			Update("deleted_at", &now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return recordAudit(c, tx, "update.delete", model.AuditEntityUpdate, before.ID, before, nil)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-	if res.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin updates delete failed", err, "update_id", before.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
 	if h.cache != nil && isPublicCompanyUpdate(before) {
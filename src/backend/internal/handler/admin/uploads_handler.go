@@ -2,52 +2,108 @@ package admin
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	"evening-gown/internal/assets"
 	"evening-gown/internal/config"
+	"evening-gown/internal/imaging"
+	"evening-gown/internal/logging"
 	"evening-gown/internal/model"
 	"evening-gown/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
 )
 
 type UploadsHandler struct {
-	minioClient *minio.Client
-	minioCfg    config.MinioConfig
-	maxBytes    int64
+	db       *gorm.DB
+	store    storage.Store
+	maxBytes int64
+	imaging  imaging.Options
+	// decodeSlots bounds concurrent imaging.Process calls.
+	decodeSlots chan struct{}
+
+	// Presigned uploads (see uploads_presign.go).
+	maxDirectBytes int64
+	presignExpires time.Duration
+	stagingTTL     time.Duration
 }
 
-func NewUploadsHandler(minioClient *minio.Client, minioCfg config.MinioConfig, uploadCfg config.UploadConfig) *UploadsHandler {
+func NewUploadsHandler(db *gorm.DB, store storage.Store, uploadCfg config.UploadConfig) *UploadsHandler {
 	maxBytes := uploadCfg.MaxImageUploadBytes
 	if maxBytes <= 0 {
-		maxBytes = 1048576
+		maxBytes = 20 << 20
+	}
+	decodes := uploadCfg.MaxConcurrentDecodes
+	if decodes <= 0 {
+		decodes = 2
+	}
+	maxDirectBytes := uploadCfg.MaxDirectUploadBytes
+	if maxDirectBytes <= 0 {
+		maxDirectBytes = 50 << 20
+	}
+	presignExpires := uploadCfg.PresignExpires
+	if presignExpires <= 0 {
+		presignExpires = 15 * time.Minute
+	}
+	stagingTTL := uploadCfg.StagingTTL
+	if stagingTTL < presignExpires {
+		stagingTTL = max(presignExpires, 24*time.Hour)
+	}
+	return &UploadsHandler{
+		db:          db,
+		store:       store,
+		maxBytes:    maxBytes,
+		decodeSlots: make(chan struct{}, decodes),
+		imaging: imaging.Options{
+			Widths:    uploadCfg.ImageWidths,
+			Quality:   float32(uploadCfg.WebPQuality),
+			MaxPixels: uploadCfg.MaxImagePixels,
+		},
+		maxDirectBytes: maxDirectBytes,
+		presignExpires: presignExpires,
+		stagingTTL:     stagingTTL,
 	}
-	return &UploadsHandler{minioClient: minioClient, minioCfg: minioCfg, maxBytes: maxBytes}
 }
 
-// UploadImage accepts an already-compressed webp image and uploads it to MinIO.
+type uploadRendition struct {
+	URL       string `json:"url"`
+	ObjectKey string `json:"objectKey"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Size      int64  `json:"size"`
+}
+
+// UploadImage accepts a JPEG/PNG/WebP/HEIC image, re-encodes it into WebP
+// renditions (EXIF/GPS stripped, auto-oriented) and writes them to storage.
 //
 // Form fields:
-// - file: image/webp
+// - file: image/jpeg|image/png|image/webp|image/heic
 // - kind: cover|hover|gallery
 // - styleNo: int
+//
+// Renditions share one prefix:
+// products/{styleNo}/{kind}/{yyyy}/{mm}/{dd}/{uuid}/w{width}.webp
+// url/objectKey point at the widest rendition; "renditions" lists all of them.
+//
+// A file already uploaded for the same styleNo (same SHA-256) is not stored
+// again: the earlier renditions are returned with "deduplicated": true.
 func (h *UploadsHandler) UploadImage(c *gin.Context) {
 	if h == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
 		return
 	}
-	if h.minioClient == nil || strings.TrimSpace(h.minioCfg.Endpoint) == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "minio disabled"})
-		return
-	}
-	if strings.TrimSpace(h.minioCfg.Bucket) == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "minio bucket not configured"})
+	if h.store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "storage disabled"})
 		return
 	}
 
@@ -92,25 +148,59 @@ func (h *UploadsHandler) UploadImage(c *gin.Context) {
 	}
 	defer f.Close()
 
-	buf := make([]byte, 512)
-	n, _ := f.Read(buf)
-	headCT := strings.TrimSpace(fh.Header.Get("Content-Type"))
-	detectCT := ""
-	if n > 0 {
-		detectCT = http.DetectContentType(buf[:n])
+	// Hash while reading: identical files are deduplicated per styleNo.
+	hasher := sha256.New()
+	data, err := io.ReadAll(io.TeeReader(io.LimitReader(f, h.maxBytes+1), hasher))
+	if err != nil || int64(len(data)) > h.maxBytes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unable to read file"})
+		return
 	}
-	if headCT != "image/webp" && detectCT != "image/webp" {
+	sourceSum := hex.EncodeToString(hasher.Sum(nil))
+
+	// The client-declared Content-Type is ignored; the bytes decide.
+	if imaging.DetectFormat(data) == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      "only image/webp is accepted",
-			"contentType": headCT,
+			"error":       "only jpeg, png, webp or heic images are accepted",
+			"contentType": strings.TrimSpace(fh.Header.Get("Content-Type")),
 		})
 		return
 	}
 
+	status, resp, _ := h.storeImage(c, data, sourceSum, styleNo, kind)
+	c.JSON(status, resp)
+}
+
+// storeImage runs the imaging pipeline on data, writes the renditions and
+// registers them. It returns the response to send, and an error when the
+// upload failed for a reason other than the image being rejected (storage
+// trouble, or the request giving up while waiting for a decode slot).
+//
+// sourceSum is the hex SHA-256 of data. When the same file was already
+// uploaded for styleNo, its renditions are returned instead (whatever kind
+// they were uploaded as) and nothing is written.
+func (h *UploadsHandler) storeImage(c *gin.Context, data []byte, sourceSum, styleNo, kind string) (int, gin.H, error) {
+	if resp, ok := h.reuseUpload(c, data, sourceSum, styleNo); ok {
+		return http.StatusOK, resp, nil
+	}
+
+	select {
+	case h.decodeSlots <- struct{}{}:
+	case <-c.Request.Context().Done():
+		return http.StatusServiceUnavailable, gin.H{"error": "service unavailable"}, c.Request.Context().Err()
+	}
+	res, err := imaging.Process(data, h.imaging)
+	<-h.decodeSlots
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		return http.StatusRequestEntityTooLarge, gin.H{"error": "image dimensions too large"}, nil
+	}
+	if err != nil {
+		return http.StatusBadRequest, gin.H{"error": "unable to decode image", "format": res.Format}, nil
+	}
+
 	ctx := c.Request.Context()
 	now := time.Now().UTC()
-	objectKey := fmt.Sprintf(
-		"products/%s/%s/%04d/%02d/%02d/%s.webp",
+	prefix := fmt.Sprintf(
+		"products/%s/%s/%04d/%02d/%02d/%s",
 		styleNo,
 		kind,
 		now.Year(),
@@ -119,18 +209,124 @@ func (h *UploadsHandler) UploadImage(c *gin.Context) {
 		uuid.NewString(),
 	)
 
-	r := io.MultiReader(bytes.NewReader(buf[:n]), f)
-	if err := storage.PutObject(ctx, h.minioClient, h.minioCfg, objectKey, r, fh.Size, "image/webp"); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	// Each rendition is registered in the assets table (best effort: access is
+	// authorized through product references, not through this registry).
+	owner := h.ownerProductID(ctx, styleNo)
+	uploader, _ := adminFromContext(c)
+
+	renditions := make([]uploadRendition, 0, len(res.Renditions))
+	for _, r := range res.Renditions {
+		objectKey := imaging.RenditionKey(prefix, r.Width)
+		size := int64(len(r.Data))
+		if err := h.store.Put(ctx, objectKey, bytes.NewReader(r.Data), size, "image/webp"); err != nil {
+			// Don't leave a partial rendition set behind.
+			for _, done := range renditions {
+				_ = h.store.Remove(ctx, done.ObjectKey)
+			}
+			return http.StatusBadRequest, gin.H{"error": err.Error()}, err
+		}
+		if h.db != nil {
+			sum := sha256.Sum256(r.Data)
+			if err := assets.Register(ctx, h.db, &model.Asset{
+				ObjectKey:     objectKey,
+				ProductID:     owner,
+				StyleNo:       styleNo,
+				Kind:          kind,
+				ContentType:   "image/webp",
+				Size:          size,
+				SHA256:        hex.EncodeToString(sum[:]),
+				SourceSHA256:  sourceSum,
+				Width:         r.Width,
+				Height:        r.Height,
+				Blurhash:      res.Placeholder.Blurhash,
+				DominantColor: res.Placeholder.DominantColor,
+				UploadedBy:    uploader.ID,
+			}); err != nil {
+				logging.FromGin(c).Warn("asset register failed", "key", objectKey, "err", err)
+			}
+		}
+		renditions = append(renditions, uploadRendition{
+			URL:       "/api/v1/assets/" + objectKey,
+			ObjectKey: objectKey,
+			Width:     r.Width,
+			Height:    r.Height,
+			Size:      size,
+		})
 	}
 
-	assetURL := "/api/v1/assets/" + strings.TrimPrefix(objectKey, "/")
+	return http.StatusOK, uploadResponse(renditions, res.Placeholder, res.Format, false), nil
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"url":         assetURL,
-		"objectKey":   objectKey,
-		"contentType": "image/webp",
-		"size":        fh.Size,
-	})
+// reuseUpload builds the response for an earlier upload of the same file, if
+// one is registered for styleNo and still stored.
+func (h *UploadsHandler) reuseUpload(c *gin.Context, data []byte, sourceSum, styleNo string) (gin.H, bool) {
+	if h.db == nil || sourceSum == "" {
+		return nil, false
+	}
+	ctx := c.Request.Context()
+	rows, err := assets.FindUpload(ctx, h.db, styleNo, sourceSum)
+	if err != nil {
+		logging.FromGin(c).Warn("upload dedup lookup failed", "styleNo", styleNo, "err", err)
+		return nil, false
+	}
+	if len(rows) == 0 {
+		return nil, false
+	}
+	// The registry can outlive objects removed out of band.
+	if _, err := h.store.Stat(ctx, rows[len(rows)-1].ObjectKey); err != nil {
+		return nil, false
+	}
+	// The objects may be older than the GC grace period; restart it, or
+	// upload afresh when that fails.
+	if err := assets.MarkReused(ctx, h.db, rows); err != nil {
+		logging.FromGin(c).Warn("upload dedup mark reused failed", "styleNo", styleNo, "err", err)
+		return nil, false
+	}
+
+	renditions := make([]uploadRendition, 0, len(rows))
+	for _, a := range rows {
+		renditions = append(renditions, uploadRendition{
+			URL:       "/api/v1/assets/" + a.ObjectKey,
+			ObjectKey: a.ObjectKey,
+			Width:     a.Width,
+			Height:    a.Height,
+			Size:      a.Size,
+		})
+	}
+	placeholder := imaging.Placeholder{Blurhash: rows[0].Blurhash, DominantColor: rows[0].DominantColor}
+	return uploadResponse(renditions, placeholder, imaging.DetectFormat(data), true), true
+}
+
+// uploadResponse is the body of a successful upload; url/objectKey point at
+// the widest rendition.
+func uploadResponse(renditions []uploadRendition, placeholder imaging.Placeholder, sourceFormat string, deduplicated bool) gin.H {
+	primary := renditions[len(renditions)-1]
+	return gin.H{
+		"url":           primary.URL,
+		"objectKey":     primary.ObjectKey,
+		"contentType":   "image/webp",
+		"size":          primary.Size,
+		"width":         primary.Width,
+		"height":        primary.Height,
+		"blurhash":      placeholder.Blurhash,
+		"dominantColor": placeholder.DominantColor,
+		"sourceFormat":  sourceFormat,
+		"renditions":    renditions,
+		"deduplicated":  deduplicated,
+	}
+}
+
+// ownerProductID returns the id of the (not deleted) product with styleNo, if any.
+func (h *UploadsHandler) ownerProductID(ctx context.Context, styleNo string) *uint {
+	if h.db == nil {
+		return nil
+	}
+	var p model.Product
+	if err := h.db.WithContext(ctx).Select("id").
+		Where("style_no = ?", styleNo).
+		Where("deleted_at IS NULL").
+		First(&p).Error; err != nil {
+		return nil
+	}
+	return &p.ID
 }
@@ -0,0 +1,171 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"evening-gown/internal/config"
+	"evening-gown/internal/middleware"
+	"evening-gown/internal/model"
+	"evening-gown/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	xwebp "golang.org/x/image/webp"
+)
+
+type uploadResult struct {
+	ObjectKey     string `json:"objectKey"`
+	Blurhash      string `json:"blurhash"`
+	DominantColor string `json:"dominantColor"`
+	Deduplicated  bool   `json:"deduplicated"`
+	SourceFormat  string `json:"sourceFormat"`
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	Renditions    []struct {
+		ObjectKey string `json:"objectKey"`
+	} `json:"renditions"`
+}
+
+func uploadImage(t *testing.T, h *UploadsHandler, styleNo, kind string, data []byte) uploadResult {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("styleNo", styleNo)
+	_ = mw.WriteField("kind", kind)
+	fw, _ := mw.CreateFormFile("file", "photo.png")
+	_, _ = fw.Write(data)
+	_ = mw.Close()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/admin/uploads/images", &body)
+	c.Request.Header.Set("Content-Type", mw.FormDataContentType())
+	c.Set(middleware.ContextUserKey, model.User{ID: 7})
+	h.UploadImage(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload: %d %s", w.Code, w.Body.String())
+	}
+	var res uploadResult
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return res
+}
+
+func TestUploadImage_Deduplicates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openTestDB(t)
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	defer store.Close()
+	h := NewUploadsHandler(db, store, config.UploadConfig{ImageWidths: []int{16, 32}})
+
+	photo := testPNG(t, 40, 20)
+	first := uploadImage(t, h, "1001", "cover", photo)
+	if first.Deduplicated || len(first.Renditions) != 2 || first.Blurhash == "" || first.DominantColor != "#ffffff" {
+		t.Fatalf("unexpected first upload: %+v", first)
+	}
+
+	// Same file for the same style, even as another kind: reused.
+	again := uploadImage(t, h, "1001", "gallery", photo)
+	if !again.Deduplicated || again.ObjectKey != first.ObjectKey || len(again.Renditions) != 2 || again.Blurhash != first.Blurhash {
+		t.Fatalf("expected dedup to %s, got %+v", first.ObjectKey, again)
+	}
+	var rows int64
+	db.Model(&model.Asset{}).Count(&rows)
+	if rows != 2 {
+		t.Fatalf("dedup must not register new objects, have %d rows", rows)
+	}
+	var reused int64
+	db.Model(&model.Asset{}).Where("reused_at IS NOT NULL").Count(&reused)
+	if reused != 2 {
+		t.Fatalf("dedup must restart the GC grace period of both renditions, marked %d", reused)
+	}
+
+	// Other styles and other files get their own objects.
+	if other := uploadImage(t, h, "1002", "cover", photo); other.Deduplicated {
+		t.Fatalf("dedup must be scoped to styleNo: %+v", other)
+	}
+	if other := uploadImage(t, h, "1001", "cover", testPNG(t, 41, 20)); other.Deduplicated {
+		t.Fatalf("different file was deduplicated: %+v", other)
+	}
+
+	// Objects removed out of band are uploaded again.
+	for _, r := range first.Renditions {
+		_ = store.Remove(t.Context(), r.ObjectKey)
+	}
+	fresh := uploadImage(t, h, "1001", "cover", photo)
+	if fresh.Deduplicated || fresh.ObjectKey == first.ObjectKey {
+		t.Fatalf("expected a fresh upload after the original was removed: %+v", fresh)
+	}
+	if res := uploadImage(t, h, "1001", "hover", photo); !res.Deduplicated || res.ObjectKey != fresh.ObjectKey {
+		t.Fatalf("expected dedup to the fresh upload, got %+v", res)
+	}
+}
+
+// exifRotatedJPEG encodes a w×h JPEG carrying an EXIF orientation tag, as
+// phone cameras write it for portrait shots.
+func exifRotatedJPEG(t *testing.T, w, h int, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+	var tiff bytes.Buffer
+	tiff.WriteString("MM")
+	_ = binary.Write(&tiff, binary.BigEndian, uint16(42))
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(8))
+	_ = binary.Write(&tiff, binary.BigEndian, uint16(1))
+	_ = binary.Write(&tiff, binary.BigEndian, []uint16{0x0112, 3})
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(1))
+	_ = binary.Write(&tiff, binary.BigEndian, []uint16{orientation, 0})
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(0))
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+
+	jpg := buf.Bytes()
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, seg...)
+	out = append(out, payload...)
+	return append(out, jpg[2:]...)
+}
+
+func TestUploadImage_AutoOrientsEXIFRotatedJPEG(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openTestDB(t)
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	defer store.Close()
+	h := NewUploadsHandler(db, store, config.UploadConfig{ImageWidths: []int{16}})
+
+	// Stored landscape, displayed portrait (orientation 6 = rotate 90° CW).
+	res := uploadImage(t, h, "1001", "cover", exifRotatedJPEG(t, 40, 20, 6))
+	if res.SourceFormat != "jpeg" || res.Width != 16 || res.Height != 32 {
+		t.Fatalf("expected an upright 16x32 rendition of a jpeg, got %+v", res)
+	}
+
+	rc, err := store.Open(t.Context(), res.ObjectKey, 0, -1)
+	if err != nil {
+		t.Fatalf("open rendition: %v", err)
+	}
+	defer rc.Close()
+	cfg, err := xwebp.DecodeConfig(rc)
+	if err != nil {
+		t.Fatalf("decode rendition: %v", err)
+	}
+	if cfg.Width != 16 || cfg.Height != 32 {
+		t.Fatalf("stored rendition is %dx%d, want 16x32", cfg.Width, cfg.Height)
+	}
+}
@@ -0,0 +1,258 @@
+package admin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"evening-gown/internal/imaging"
+	"evening-gown/internal/logging"
+	"evening-gown/internal/model"
+	"evening-gown/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type presignUploadRequest struct {
+	Kind    string `json:"kind"`
+	StyleNo string `json:"styleNo"`
+	// Size is optional; when given, oversized files are rejected up front.
+	Size int64 `json:"size"`
+}
+
+// PresignUpload reserves a staging key and returns a presigned PUT URL for it,
+// so large files go straight to storage instead of through this process.
+//
+// Route: POST /api/v1/admin/uploads/presign
+//
+// The client PUTs the file body to uploadUrl before expiresAt, then calls
+// FinalizeUpload with objectKey. Staged objects live under uploads/ and expire
+// after UPLOAD_STAGING_TTL when not finalized.
+func (h *UploadsHandler) PresignUpload(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	if h.store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "storage disabled"})
+		return
+	}
+
+	var req presignUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	kind := strings.TrimSpace(req.Kind)
+	if kind != "cover" && kind != "hover" && kind != "gallery" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid kind"})
+		return
+	}
+	styleNo, err := model.NormalizeStyleNo(strings.TrimSpace(req.StyleNo))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid styleNo"})
+		return
+	}
+	if req.Size < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid size"})
+		return
+	}
+	if req.Size > h.maxDirectBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large", "maxBytes": h.maxDirectBytes})
+		return
+	}
+
+	now := time.Now().UTC()
+	objectKey := fmt.Sprintf("%s%04d/%02d/%02d/%s", model.UploadStagingPrefix, now.Year(), now.Month(), now.Day(), uuid.NewString())
+
+	ctx := c.Request.Context()
+	uploadURL, err := h.store.PresignPut(ctx, objectKey, h.presignExpires)
+	if errors.Is(err, storage.ErrPresignUnsupported) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "presigned uploads not supported by storage backend"})
+		return
+	}
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "presign upload failed", err, "key", objectKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "presign failed"})
+		return
+	}
+
+	admin, _ := adminFromContext(c)
+	intent := model.UploadIntent{
+		ObjectKey:   objectKey,
+		StyleNo:     styleNo,
+		Kind:        kind,
+		ExpiresAt:   now.Add(h.presignExpires),
+		CreatedByID: admin.ID,
+	}
+	if err := h.db.WithContext(ctx).Create(&intent).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "create upload intent failed", err, "key", objectKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "presign failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"objectKey": objectKey,
+		"uploadUrl": uploadURL,
+		"method":    http.MethodPut,
+		"expiresAt": intent.ExpiresAt,
+		"maxBytes":  h.maxDirectBytes,
+	})
+}
+
+type finalizeUploadRequest struct {
+	ObjectKey string `json:"objectKey"`
+}
+
+// FinalizeUpload turns a presigned upload into product renditions.
+//
+// Route: POST /api/v1/admin/uploads/finalize
+//
+// It stats the staged object, enforces MAX_DIRECT_UPLOAD_BYTES, sniffs the
+// content (the Content-Type the client sent is ignored), then runs the same
+// pipeline and registration as UploadImage and responds with the same body.
+// The staged object is deleted once processed. Each upload finalizes once,
+// only by the admin who presigned it.
+func (h *UploadsHandler) FinalizeUpload(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+	if h.store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "storage disabled"})
+		return
+	}
+
+	var req finalizeUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	objectKey := strings.TrimSpace(req.ObjectKey)
+	if !strings.HasPrefix(objectKey, model.UploadStagingPrefix) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid objectKey"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	admin, _ := adminFromContext(c)
+	var intent model.UploadIntent
+	if err := h.db.WithContext(ctx).Where("object_key = ?", objectKey).First(&intent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		logging.ErrorWithStack(logging.FromGin(c), "load upload intent failed", err, "key", objectKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if intent.CreatedByID != admin.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if intent.FinalizedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "upload already finalized"})
+		return
+	}
+	if time.Since(intent.CreatedAt) > h.stagingTTL {
+		c.JSON(http.StatusGone, gin.H{"error": "upload expired"})
+		return
+	}
+
+	meta, err := h.store.Stat(ctx, objectKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "upload not found"})
+			return
+		}
+		logging.ErrorWithStack(logging.FromGin(c), "stat staged upload failed", err, "key", objectKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "finalize failed"})
+		return
+	}
+	if meta.Size <= 0 || meta.Size > h.maxDirectBytes {
+		_ = h.store.Remove(ctx, objectKey)
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large", "maxBytes": h.maxDirectBytes})
+		return
+	}
+
+	// Claim the intent so concurrent finalize calls don't process it twice.
+	now := time.Now().UTC()
+	claim := h.db.WithContext(ctx).Model(&model.UploadIntent{}).
+		Where("id = ? AND finalized_at IS NULL", intent.ID).
+		Update("finalized_at", now)
+	if claim.Error != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "claim upload intent failed", claim.Error, "key", objectKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "finalize failed"})
+		return
+	}
+	if claim.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "upload already finalized"})
+		return
+	}
+	release := func() {
+		_ = h.db.WithContext(ctx).Model(&model.UploadIntent{}).Where("id = ?", intent.ID).Update("finalized_at", nil).Error
+	}
+
+	data, sourceSum, err := h.readStaged(c, objectKey)
+	if err != nil {
+		release()
+		logging.ErrorWithStack(logging.FromGin(c), "read staged upload failed", err, "key", objectKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "finalize failed"})
+		return
+	}
+	if imaging.DetectFormat(data) == "" {
+		// Rejected content: the client may PUT another file while the URL
+		// is still valid and finalize again.
+		_ = h.store.Remove(ctx, objectKey)
+		release()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":       "only jpeg, png, webp or heic images are accepted",
+			"contentType": meta.ContentType,
+		})
+		return
+	}
+
+	status, resp, storeErr := h.storeImage(c, data, sourceSum, intent.StyleNo, intent.Kind)
+	if storeErr != nil {
+		// Storage trouble: keep the staged object so the client can retry.
+		release()
+		c.JSON(status, resp)
+		return
+	}
+	if status != http.StatusOK {
+		// Undecodable or oversized images won't get better on retry; drop
+		// them, but leave the upload open for another file.
+		_ = h.store.Remove(ctx, objectKey)
+		release()
+		c.JSON(status, resp)
+		return
+	}
+
+	if err := h.store.Remove(ctx, objectKey); err != nil {
+		// Harmless: the object expires with the staging prefix.
+		logging.FromGin(c).Warn("remove staged upload failed", "key", objectKey, "err", err)
+	}
+	c.JSON(status, resp)
+}
+
+// readStaged reads a staged upload and its hex SHA-256.
+func (h *UploadsHandler) readStaged(c *gin.Context, objectKey string) ([]byte, string, error) {
+	body, err := h.store.Open(c.Request.Context(), objectKey, 0, h.maxDirectBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	defer body.Close()
+	hasher := sha256.New()
+	data, err := io.ReadAll(io.TeeReader(body, hasher))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,149 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"evening-gown/internal/config"
+	"evening-gown/internal/middleware"
+	"evening-gown/internal/model"
+	"evening-gown/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.Set(0, 0, color.NRGBA{R: 0x80, A: 0xff})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func callUploads(h gin.HandlerFunc, adminID uint, body any) *httptest.ResponseRecorder {
+	raw, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/admin/uploads", bytes.NewReader(raw))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set(middleware.ContextUserKey, model.User{ID: adminID})
+	h(c)
+	return w
+}
+
+func TestUploads_PresignAndFinalize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openTestDB(t)
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	defer store.Close()
+	h := NewUploadsHandler(db, store, config.UploadConfig{ImageWidths: []int{16, 32}})
+	ctx := context.Background()
+
+	// The local backend cannot presign.
+	w := callUploads(h.PresignUpload, 7, gin.H{"kind": "cover", "styleNo": "1001"})
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %d %s", w.Code, w.Body.String())
+	}
+	w = callUploads(h.PresignUpload, 7, gin.H{"kind": "cover", "styleNo": "1001", "size": int64(60 << 20)})
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", w.Code)
+	}
+
+	stage := func(key string, data []byte) {
+		t.Helper()
+		intent := model.UploadIntent{ObjectKey: key, StyleNo: "1001", Kind: "gallery", ExpiresAt: time.Now().Add(time.Minute), CreatedByID: 7}
+		if err := db.Create(&intent).Error; err != nil {
+			t.Fatalf("create intent: %v", err)
+		}
+		if err := store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "application/octet-stream"); err != nil {
+			t.Fatalf("stage: %v", err)
+		}
+	}
+
+	key := "uploads/2025/09/01/good"
+	stage(key, testPNG(t, 40, 20))
+
+	if w := callUploads(h.FinalizeUpload, 8, gin.H{"objectKey": key}); w.Code != http.StatusNotFound {
+		t.Fatalf("another admin must not finalize: %d", w.Code)
+	}
+	w = callUploads(h.FinalizeUpload, 7, gin.H{"objectKey": key})
+	if w.Code != http.StatusOK {
+		t.Fatalf("finalize: %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		ObjectKey    string `json:"objectKey"`
+		Width        int    `json:"width"`
+		SourceFormat string `json:"sourceFormat"`
+		Renditions   []struct {
+			ObjectKey string `json:"objectKey"`
+		} `json:"renditions"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if !strings.HasPrefix(resp.ObjectKey, "products/1001/gallery/") || resp.Width != 32 || resp.SourceFormat != "png" || len(resp.Renditions) != 2 {
+		t.Fatalf("unexpected response: %s", w.Body.String())
+	}
+	for _, r := range resp.Renditions {
+		if _, err := store.Stat(ctx, r.ObjectKey); err != nil {
+			t.Fatalf("rendition %s not stored: %v", r.ObjectKey, err)
+		}
+	}
+	if _, err := store.Stat(ctx, key); err == nil {
+		t.Fatalf("staged object should be removed")
+	}
+	var registered int64
+	db.Model(&model.Asset{}).Where("uploaded_by = ? AND style_no = ?", 7, "1001").Count(&registered)
+	if registered != 2 {
+		t.Fatalf("expected 2 registered renditions, got %d", registered)
+	}
+	if w := callUploads(h.FinalizeUpload, 7, gin.H{"objectKey": key}); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 on second finalize, got %d", w.Code)
+	}
+
+	// Sniffing rejects non-images whatever the client claimed.
+	bad := "uploads/2025/09/01/bad"
+	stage(bad, []byte("<html>not an image</html>"))
+	if w := callUploads(h.FinalizeUpload, 7, gin.H{"objectKey": bad}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for non-image, got %d", w.Code)
+	}
+	if _, err := store.Stat(ctx, bad); err == nil {
+		t.Fatalf("rejected staged object should be removed")
+	}
+	// The upload stays open: a valid file PUT to the same key finalizes.
+	png := testPNG(t, 40, 20)
+	if err := store.Put(ctx, bad, bytes.NewReader(png), int64(len(png)), "image/png"); err != nil {
+		t.Fatalf("restage: %v", err)
+	}
+	if w := callUploads(h.FinalizeUpload, 7, gin.H{"objectKey": bad}); w.Code != http.StatusOK {
+		t.Fatalf("expected finalize after a rejected file to succeed, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Not uploaded yet, or not a staging key.
+	missing := "uploads/2025/09/01/missing"
+	if err := db.Create(&model.UploadIntent{ObjectKey: missing, StyleNo: "1001", Kind: "cover", ExpiresAt: time.Now(), CreatedByID: 7}).Error; err != nil {
+		t.Fatalf("create intent: %v", err)
+	}
+	if w := callUploads(h.FinalizeUpload, 7, gin.H{"objectKey": missing}); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing upload, got %d", w.Code)
+	}
+	if w := callUploads(h.FinalizeUpload, 7, gin.H{"objectKey": resp.ObjectKey}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for non-staging key, got %d", w.Code)
+	}
+}
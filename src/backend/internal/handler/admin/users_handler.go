@@ -0,0 +1,678 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"evening-gown/internal/logging"
+	"evening-gown/internal/middleware"
+	"evening-gown/internal/model"
+	"evening-gown/internal/security"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	errLastOwner   = errors.New("cannot remove the last active owner")
+	errEmailExists = errors.New("email already exists")
+)
+
+type UsersHandler struct {
+	db        *gorm.DB
+	inviteTTL time.Duration
+}
+
+func NewUsersHandler(db *gorm.DB, inviteTTL time.Duration) *UsersHandler {
+	if inviteTTL <= 0 {
+		inviteTTL = 72 * time.Hour
+	}
+	return &UsersHandler{db: db, inviteTTL: inviteTTL}
+}
+
+type userInviteRequest struct {
+	Email string `json:"email" binding:"required"`
+	Role  string `json:"role" binding:"required"`
+}
+
+type userUpdateRequest struct {
+	Role *string `json:"role"`
+}
+
+type acceptTokenRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type oneTimeTokenResponse struct {
+	Token     string `json:"token"`
+	Purpose   string `json:"purpose"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+// List returns backoffice users.
+//
+// Query params:
+// - status: invited|active|disabled|locked
+// - role: owner|admin|editor|sales|viewer
+// - include_deleted=true: include soft-deleted users
+func (h *UsersHandler) List(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	q := h.db.WithContext(c.Request.Context()).Model(&model.User{})
+	if !strings.EqualFold(strings.TrimSpace(c.Query("include_deleted")), "true") {
+		q = q.Where("deleted_at IS NULL")
+	}
+	if st := strings.TrimSpace(c.Query("status")); st != "" {
+		q = q.Where("status = ?", st)
+	}
+	if role := strings.TrimSpace(c.Query("role")); role != "" {
+		q = q.Where("role = ?", role)
+	}
+
+	limit := parseIntQuery(c, "limit", 50)
+	offset := parseIntQuery(c, "offset", 0)
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin users query count failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	var items []model.User
+	if err := q.Order("id asc").Limit(limit).Offset(offset).Find(&items).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin users query list failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"total": total, "items": items})
+}
+
+// Create invites a new backoffice user.
+//
+// The user is created with status "invited" and no usable password. The response
+// carries a one-time token that the invitee exchanges for their own password via
+// POST /api/v1/admin/auth/accept-token.
+func (h *UsersHandler) Create(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	actor, ok := adminFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "unauthorized", "message": "unauthorized", "error": "unauthorized"})
+		return
+	}
+
+	var req userInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email == "" || !strings.Contains(email, "@") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email"})
+		return
+	}
+	role := strings.TrimSpace(req.Role)
+	if !model.IsAssignableRole(role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	user := model.User{
+		Email:        email,
+		PasswordHash: "",
+		Role:         role,
+		Status:       "invited",
+	}
+	var before *model.User
+	var tok oneTimeTokenResponse
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// email is unique across soft-deleted users too.
+		var existing []model.User
+		if err := tx.Where("email = ?", email).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		switch {
+		case len(existing) == 0:
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		case existing[0].DeletedAt != nil:
+			// Re-inviting a deleted user restores the account as a new invite,
+			// without its old password, sessions or second factor.
+			before = &existing[0]
+			if err := restoreInvitedUser(tx, before.ID, role); err != nil {
+				return err
+			}
+			if err := tx.First(&user, before.ID).Error; err != nil {
+				return err
+			}
+		default:
+			return errEmailExists
+		}
+		var err error
+		if tok, err = h.issueToken(tx, user.ID, model.UserTokenPurposeInvite, actor.ID); err != nil {
+			return err
+		}
+		if before != nil {
+			return recordAudit(c, tx, "user.invite", model.AuditEntityUser, user.ID, before, user)
+		}
+		return recordAudit(c, tx, "user.invite", model.AuditEntityUser, user.ID, nil, user)
+	})
+	if errors.Is(err, errEmailExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": errEmailExists.Error()})
+		return
+	}
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin users invite failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"user": user, "invite": tok})
+}
+
+func (h *UsersHandler) Get(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var user model.User
+	if err := h.db.WithContext(c.Request.Context()).
+		Where("deleted_at IS NULL").
+		First(&user, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// Update changes a user's role.
+func (h *UsersHandler) Update(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	target, ok := h.loadTarget(c)
+	if !ok {
+		return
+	}
+
+	var req userUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Role == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no updates"})
+		return
+	}
+	role := strings.TrimSpace(*req.Role)
+	if !model.IsAssignableRole(role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var after model.User
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) (err error) {
+		if model.IsOwnerRole(target.Role) && !model.IsOwnerRole(role) {
+			if err := ensureAnotherActiveOwner(tx, target.ID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&model.User{}).Where("id = ? AND deleted_at IS NULL", target.ID).Update("role", role).Error; err != nil {
+			return err
+		}
+		after, err = reloadAudited(c, tx, "user.update", model.AuditEntityUser, target.ID, target)
+		return err
+	})
+	if errors.Is(err, errLastOwner) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin users update failed", err, "user_id", target.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, after)
+}
+
+// Disable blocks a user and revokes all of their access and refresh tokens.
+func (h *UsersHandler) Disable(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	target, ok := h.loadTarget(c)
+	if !ok {
+		return
+	}
+	if actor, _ := adminFromContext(c); actor.ID == target.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot disable yourself"})
+		return
+	}
+
+	now := time.Now().UTC()
+	var after model.User
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) (err error) {
+		if model.IsOwnerRole(target.Role) {
+			if err := ensureAnotherActiveOwner(tx, target.ID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&model.User{}).Where("id = ? AND deleted_at IS NULL", target.ID).Updates(map[string]any{
+			"status":        "disabled",
+			"token_version": gorm.Expr("token_version + 1"),
+			"updated_at":    now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := revokeSessions(tx.Where("user_id = ?", target.ID), "user_disabled", now); err != nil {
+			return err
+		}
+		after, err = reloadAudited(c, tx, "user.disable", model.AuditEntityUser, target.ID, target)
+		return err
+	})
+	if errors.Is(err, errLastOwner) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin users disable failed", err, "user_id", target.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, after)
+}
+
+// Enable re-activates a disabled or locked user. Tokens revoked by Disable stay revoked.
+func (h *UsersHandler) Enable(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	target, ok := h.loadTarget(c)
+	if !ok {
+		return
+	}
+	if target.Status == "invited" {
+		c.JSON(http.StatusConflict, gin.H{"error": "user has not accepted the invite yet"})
+		return
+	}
+
+	after, ok := updateAudited(c, h.db, model.AuditEntityUser, "user.enable", target.ID, target, func(tx *gorm.DB) *gorm.DB {
+		return tx.Updates(map[string]any{
+			"status":             "active",
+			"failed_login_count": 0,
+			"locked_until":       nil,
+			"updated_at":         time.Now().UTC(),
+		})
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, after)
+}
+
+// Unlock clears a login lockout (failed attempts and lock expiry).
+func (h *UsersHandler) Unlock(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	target, ok := h.loadTarget(c)
+	if !ok {
+		return
+	}
+
+	updates := map[string]any{
+		"failed_login_count": 0,
+		"locked_until":       nil,
+		"updated_at":         time.Now().UTC(),
+	}
+	if target.Status == "locked" {
+		updates["status"] = "active"
+	}
+	after, ok := updateAudited(c, h.db, model.AuditEntityUser, "user.unlock", target.ID, target, func(tx *gorm.DB) *gorm.DB {
+		return tx.Updates(updates)
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, after)
+}
+
+// ResetTwoFactor removes a user's TOTP enrollment and recovery codes, e.g. after a
+// lost device. The user can enroll again on next sign-in.
+func (h *UsersHandler) ResetTwoFactor(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	target, ok := h.loadTarget(c)
+	if !ok {
+		return
+	}
+
+	var after model.User
+	if err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) (err error) {
+		if err := clearTwoFactor(tx, target.ID); err != nil {
+			return err
+		}
+		after, err = reloadAudited(c, tx, "user.reset_2fa", model.AuditEntityUser, target.ID, target)
+		return err
+	}); err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin users reset 2fa failed", err, "user_id", target.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reset failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, after)
+}
+
+// ResetPassword issues a one-time password reset token for a user.
+// The current password keeps working until the token is redeemed.
+func (h *UsersHandler) ResetPassword(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	target, ok := h.loadTarget(c)
+	if !ok {
+		return
+	}
+	actor, _ := adminFromContext(c)
+
+	purpose := model.UserTokenPurposeReset
+	if target.Status == "invited" {
+		// Re-send the invite instead.
+		purpose = model.UserTokenPurposeInvite
+	}
+
+	var tok oneTimeTokenResponse
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if tok, err = h.issueToken(tx, target.ID, purpose, actor.ID); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, "user.reset_password", model.AuditEntityUser, target.ID, nil, gin.H{"purpose": purpose})
+	})
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin users issue token failed", err, "user_id", target.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "issue token failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tok)
+}
+
+// Delete soft-deletes a user and revokes all of their tokens.
+func (h *UsersHandler) Delete(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	target, ok := h.loadTarget(c)
+	if !ok {
+		return
+	}
+	if actor, _ := adminFromContext(c); actor.ID == target.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot delete yourself"})
+		return
+	}
+
+	now := time.Now().UTC()
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if model.IsOwnerRole(target.Role) {
+			if err := ensureAnotherActiveOwner(tx, target.ID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&model.User{}).Where("id = ? AND deleted_at IS NULL", target.ID).Updates(map[string]any{
+			"deleted_at":    &now,
+			"token_version": gorm.Expr("token_version + 1"),
+			"updated_at":    now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := revokeSessions(tx.Where("user_id = ?", target.ID), "user_disabled", now); err != nil {
+			return err
+		}
+		// Pending invite/reset links must not resurrect a deleted account.
+		if err := tx.Where("user_id = ? AND used_at IS NULL", target.ID).Delete(&model.UserOneTimeToken{}).Error; err != nil {
+			return err
+		}
+		return recordAudit(c, tx, "user.delete", model.AuditEntityUser, target.ID, target, nil)
+	})
+	if errors.Is(err, errLastOwner) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin users delete failed", err, "user_id", target.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AcceptToken redeems an invite or reset token and sets the user's password.
+//
+// Route: POST /api/v1/admin/auth/accept-token (unprotected; authenticates via the token)
+func (h *UsersHandler) AcceptToken(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	var req acceptTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hash, err := security.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokenHash := security.HashToken(req.Token)
+	now := time.Now().UTC()
+
+	invalidToken := errors.New("invalid token")
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var tok model.UserOneTimeToken
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).First(&tok).Error; err != nil {
+			return invalidToken
+		}
+
+		var user model.User
+		if err := tx.Where("id = ? AND deleted_at IS NULL", tok.UserID).First(&user).Error; err != nil {
+			return invalidToken
+		}
+
+		// Mark used first (guarded by used_at IS NULL) so concurrent redeems cannot both win.
+		res := tx.Model(&model.UserOneTimeToken{}).Where("id = ? AND used_at IS NULL", tok.ID).Update("used_at", &now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return invalidToken
+		}
+
+		// JWT iat is second-precision. Keep the marker strictly increasing (see ChangePassword).
+		pwdAt := now.Truncate(time.Second)
+		if user.PasswordUpdatedAt != nil {
+			prev := user.PasswordUpdatedAt.UTC().Truncate(time.Second)
+			if !pwdAt.After(prev) {
+				pwdAt = prev.Add(time.Second)
+			}
+		}
+		updates := map[string]any{
+			"password_hash":       hash,
+			"password_updated_at": &pwdAt,
+			"failed_login_count":  0,
+			"locked_until":        nil,
+			"updated_at":          now,
+		}
+		if tok.Purpose == model.UserTokenPurposeInvite && user.Status == "invited" {
+			updates["status"] = "active"
+		}
+		if user.Status == "locked" {
+			updates["status"] = "active"
+		}
+		if err := tx.Model(&model.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		// Whoever held the old password may still hold a session.
+		return revokeSessions(tx.Where("user_id = ?", user.ID), "password_reset", now)
+	})
+	if errors.Is(err, invalidToken) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "invalid_token",
+			"message": "invalid or expired token",
+			"error":   "invalid or expired token",
+		})
+		return
+	}
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "admin accept token failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "accept token failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (h *UsersHandler) loadTarget(c *gin.Context) (model.User, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return model.User{}, false
+	}
+
+	var user model.User
+	if err := h.db.WithContext(c.Request.Context()).
+		Where("id = ? AND deleted_at IS NULL", uint(id)).
+		First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return model.User{}, false
+	}
+	return user, true
+}
+
+// issueToken replaces any pending token of the same purpose with a fresh one.
+func (h *UsersHandler) issueToken(tx *gorm.DB, userID uint, purpose string, createdBy uint) (oneTimeTokenResponse, error) {
+	if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).Delete(&model.UserOneTimeToken{}).Error; err != nil {
+		return oneTimeTokenResponse{}, err
+	}
+
+	plain, hash, err := security.NewOneTimeToken()
+	if err != nil {
+		return oneTimeTokenResponse{}, err
+	}
+	expiresAt := time.Now().UTC().Add(h.inviteTTL)
+	row := model.UserOneTimeToken{
+		UserID:      userID,
+		Purpose:     purpose,
+		TokenHash:   hash,
+		ExpiresAt:   expiresAt,
+		CreatedByID: createdBy,
+	}
+	if err := tx.Create(&row).Error; err != nil {
+		return oneTimeTokenResponse{}, err
+	}
+	return oneTimeTokenResponse{Token: plain, Purpose: purpose, ExpiresAt: expiresAt.Format(time.RFC3339)}, nil
+}
+
+// restoreInvitedUser turns a soft-deleted user back into a pending invite with
+// the given role. Tokens issued before the deletion stay revoked.
+func restoreInvitedUser(tx *gorm.DB, id uint, role string) error {
+	if err := tx.Model(&model.User{}).Where("id = ? AND deleted_at IS NOT NULL", id).Updates(map[string]any{
+		"password_hash":           "",
+		"role":                    role,
+		"status":                  "invited",
+		"failed_login_count":      0,
+		"locked_until":            nil,
+		"last_login_at":           nil,
+		"password_updated_at":     nil,
+		"refresh_token_issued_at": nil,
+		"token_version":           gorm.Expr("token_version + 1"),
+		"deleted_at":              nil,
+		"updated_at":              time.Now().UTC(),
+	}).Error; err != nil {
+		return err
+	}
+	return clearTwoFactor(tx, id)
+}
+
+// ensureAnotherActiveOwner guards against locking everyone out of owner-only features.
+func ensureAnotherActiveOwner(tx *gorm.DB, excludeID uint) error {
+	var cnt int64
+	if err := tx.Model(&model.User{}).
+		Where("role IN ?", []string{model.RoleOwner, model.RoleAdmin}).
+		Where("status = ? AND deleted_at IS NULL AND id <> ?", "active", excludeID).
+		Count(&cnt).Error; err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return errLastOwner
+	}
+	return nil
+}
+
+// adminFromContext returns the authenticated backoffice user set by middleware.AdminAuth.
+func adminFromContext(c *gin.Context) (model.User, bool) {
+	v, ok := c.Get(middleware.ContextUserKey)
+	if !ok {
+		return model.User{}, false
+	}
+	user, ok := v.(model.User)
+	return user, ok
+}
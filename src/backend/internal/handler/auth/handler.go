@@ -23,11 +23,16 @@ func New(cfg config.JWTConfig) *Handler {
 	return &Handler{svc: svc}
 }
 
+// NewWithService reuses an already configured JWT service (shared key set).
+func NewWithService(svc *auth.Service) *Handler {
+	return &Handler{svc: svc}
+}
+
 type issueTokenRequest struct {
 	Subject string `json:"sub" binding:"required"`
 }
 
-// IssueToken issues a HS256 JWT.
+// IssueToken issues a JWT signed with the configured key.
 func (h *Handler) IssueToken(c *gin.Context) {
 	if h == nil || h.svc == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "jwt disabled"})
@@ -80,6 +85,20 @@ func (h *Handler) VerifyToken(c *gin.Context) {
 	})
 }
 
+// JWKS publishes the public verification keys.
+// Route: GET /.well-known/jwks.json
+//
+// Only asymmetric keys (RS256/EdDSA) are listed; with HS256 the set is empty.
+func (h *Handler) JWKS(c *gin.Context) {
+	if h == nil || h.svc == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "jwt disabled"})
+		return
+	}
+	// Short cache so a newly added rotation key propagates quickly.
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.svc.JWKS())
+}
+
 func tokenFromRequest(c *gin.Context) string {
 	if c == nil {
 		return ""
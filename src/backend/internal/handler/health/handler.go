@@ -5,8 +5,9 @@ import (
 	"net/http"
 	"time"
 
+	"evening-gown/internal/storage"
+
 	"github.com/gin-gonic/gin"
-	"github.com/minio/minio-go/v7"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
@@ -15,10 +16,10 @@ import (
 type Handler struct {
 	DB          *gorm.DB
 	Cache       *redis.Client
-	ObjectStore *minio.Client
+	ObjectStore storage.Store
 }
 
-func New(db *gorm.DB, cache *redis.Client, objectStore *minio.Client) *Handler {
+func New(db *gorm.DB, cache *redis.Client, objectStore storage.Store) *Handler {
 	return &Handler{DB: db, Cache: cache, ObjectStore: objectStore}
 }
 
@@ -27,7 +28,8 @@ func (h *Handler) Ping(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "pong"})
 }
 
-// Health reports dependency health (PostgreSQL and Redis).
+// Health reports dependency health (PostgreSQL, Redis and object storage).
+// The storage check is reported under its backend name (minio or local).
 func (h *Handler) Health(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
 	defer cancel()
@@ -62,11 +64,12 @@ func (h *Handler) Health(c *gin.Context) {
 	}
 
 	if h.ObjectStore != nil {
-		if _, err := h.ObjectStore.ListBuckets(ctx); err != nil {
+		name := h.ObjectStore.Backend()
+		if err := h.ObjectStore.Ping(ctx); err != nil {
 			status = http.StatusServiceUnavailable
-			checks["minio"] = "error: " + err.Error()
+			checks[name] = "error: " + err.Error()
 		} else {
-			checks["minio"] = "ok"
+			checks[name] = "ok"
 		}
 	} else {
 		checks["minio"] = "disabled"
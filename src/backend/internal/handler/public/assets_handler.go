@@ -1,51 +1,86 @@
 package public
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"net/http"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
+	"evening-gown/internal/assets"
 	"evening-gown/internal/cache"
 	"evening-gown/internal/config"
-	"evening-gown/internal/model"
+	"evening-gown/internal/imaging"
+	"evening-gown/internal/logging"
+	"evening-gown/internal/storage"
 
 	"github.com/gin-gonic/gin"
-	"github.com/minio/minio-go/v7"
+	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
 )
 
 type AssetsHandler struct {
-	db         *gorm.DB
-	minioClient *minio.Client
-	minioCfg    config.MinioConfig
-	cache       *cache.PublicCache
+	db    *gorm.DB
+	store storage.Store
+	cache *cache.PublicCache
+
+	// Variant (?w=&fmt=) settings, shared with the upload pipeline.
+	widths    []int
+	quality   float32
+	maxPixels int
+	variants  singleflight.Group
+	// decodeSlots bounds concurrent variant generation; requests that find
+	// no free slot get a 503 instead of queueing more decodes.
+	decodeSlots chan struct{}
 }
 
-func NewAssetsHandler(db *gorm.DB, minioClient *minio.Client, minioCfg config.MinioConfig, publicCache *cache.PublicCache) *AssetsHandler {
-	return &AssetsHandler{db: db, minioClient: minioClient, minioCfg: minioCfg, cache: publicCache}
+func NewAssetsHandler(db *gorm.DB, store storage.Store, uploadCfg config.UploadConfig, publicCache *cache.PublicCache) *AssetsHandler {
+	widths := uploadCfg.ImageWidths
+	if len(widths) == 0 {
+		widths = imaging.DefaultWidths
+	}
+	decodes := uploadCfg.MaxConcurrentDecodes
+	if decodes <= 0 {
+		decodes = 2
+	}
+	return &AssetsHandler{
+		db:        db,
+		store:     store,
+		cache:     publicCache,
+		widths:    widths,
+		quality:   float32(uploadCfg.WebPQuality),
+		maxPixels: uploadCfg.MaxImagePixels,
+
+		decodeSlots: make(chan struct{}, decodes),
+	}
 }
 
 const publicAssetAllowTTL = 15 * time.Minute
 
-// Get streams an object from MinIO through the application.
+// Get streams an object from storage through the application.
 //
 // Route: GET /api/v1/assets/*key
 //
+// Query:
+// - w: one of the configured rendition widths (IMAGE_RENDITION_WIDTHS)
+// - fmt: webp|jpeg (defaults to webp when w is set)
+//
 // Notes:
 // - Intended for public website consumption (published products).
 // - Keeps MinIO buckets private; browsers never talk to MinIO directly.
+// - Variants are authorized through the original key, then served from the
+//   upload renditions or generated once and cached under variants/ in storage.
 func (h *AssetsHandler) Get(c *gin.Context) {
 	if h == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
 		return
 	}
-	if h.minioClient == nil || strings.TrimSpace(h.minioCfg.Endpoint) == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "minio disabled"})
-		return
-	}
-	if strings.TrimSpace(h.minioCfg.Bucket) == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "minio bucket not configured"})
+	if h.store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "storage disabled"})
 		return
 	}
 
@@ -77,6 +112,11 @@ func (h *AssetsHandler) Get(c *gin.Context) {
 		return
 	}
 
+	variant, ok := h.parseVariant(c)
+	if !ok {
+		return
+	}
+
 	// Prevent unauthorized reads of draft/backoffice-managed images.
 	// Only allow assets that are referenced by a published product.
 	// NOTE: Admin backoffice can fetch draft assets via /api/v1/admin/assets/*key.
@@ -113,65 +153,49 @@ func (h *AssetsHandler) Get(c *gin.Context) {
 
 allowed:
 
-	ctx := c.Request.Context()
-
-	stat, err := h.minioClient.StatObject(ctx, h.minioCfg.Bucket, cleanKey, minio.StatObjectOptions{})
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-		return
-	}
-
-	obj, err := h.minioClient.GetObject(ctx, h.minioCfg.Bucket, cleanKey, minio.GetObjectOptions{})
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-		return
-	}
-	defer obj.Close()
-
-	contentType := strings.TrimSpace(stat.ContentType)
-	if contentType == "" {
-		contentType = "application/octet-stream"
-	}
-
 	// Cache aggressively: object keys are content-addressed-ish (include uuid/date),
-	// so updates generate new keys and won't break caches.
+	// so updates generate new keys and won't break caches. Variant keys derive
+	// from the original key and the whitelisted parameters, so the same holds.
 	headers := map[string]string{
 		"Cache-Control": "public, max-age=31536000, immutable",
 	}
-	if strings.TrimSpace(stat.ETag) != "" {
-		c.Header("ETag", stat.ETag)
+
+	if !variant.isOriginal() {
+		servedKey, data, err := h.resolveVariant(c, cleanKey, variant)
+		if err != nil {
+			if errors.Is(err, errVariantBusy) {
+				c.Header("Retry-After", strconv.Itoa(variantRetryAfterSeconds))
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+				return
+			}
+			if errors.Is(err, imaging.ErrUnsupportedFormat) || errors.Is(err, storage.ErrObjectNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			logging.ErrorWithStack(logging.FromGin(c), "asset variant failed", err, "key", cleanKey)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "variant failed"})
+			return
+		}
+		if servedKey == "" {
+			// Cache write-back failed: serve the generated bytes directly.
+			meta := storage.ObjectMeta{Size: int64(len(data)), ContentType: imaging.OutputFormats[variant.Format]}
+			_ = storage.ServeContent(c.Writer, c.Request, meta, headers, func(_ context.Context, offset, length int64) (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(data[offset : offset+length])), nil
+			})
+			return
+		}
+		cleanKey = servedKey
 	}
 
-	c.DataFromReader(http.StatusOK, stat.Size, contentType, obj, headers)
+	if err := storage.ServeObject(c.Writer, c.Request, h.store, cleanKey, headers); errors.Is(err, storage.ErrObjectNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	}
 }
 
 func (h *AssetsHandler) isPublishedProductAsset(c *gin.Context, objectKey string) (bool, error) {
-	// products/{styleNo}/...
-	parts := strings.Split(objectKey, "/")
-	if len(parts) < 2 {
-		return false, nil
-	}
-	styleNo, err := model.NormalizeStyleNo(strings.TrimSpace(parts[1]))
-	if err != nil {
-		return false, nil
-	}
-
 	objectKey = strings.TrimSpace(strings.TrimPrefix(objectKey, "/"))
 	if objectKey == "" {
 		return false, nil
 	}
-
-	var cnt int64
-	like := "%" + objectKey + "%"
-	err = h.db.WithContext(c.Request.Context()).Model(&model.Product{}).
-		Where("style_no = ?", styleNo).
-		Where("published_at IS NOT NULL").
-		Where("deleted_at IS NULL").
-		Where("(cover_image_key = ? OR hover_image_key = ? OR CAST(detail_json AS TEXT) LIKE ?)", objectKey, objectKey, like).
-		Limit(1).
-		Count(&cnt).Error
-	if err != nil {
-		return false, err
-	}
-	return cnt > 0, nil
+	return assets.IsPublished(c.Request.Context(), h.db, objectKey)
 }
@@ -0,0 +1,154 @@
+package public
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"evening-gown/internal/imaging"
+	"evening-gown/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxVariantSourceBytes bounds how much of an original is read to build a variant.
+const maxVariantSourceBytes = 64 << 20
+
+// variantRetryAfterSeconds is the Retry-After sent when every decode slot is
+// busy; a variant takes well under a second to generate.
+const variantRetryAfterSeconds = 1
+
+// errVariantBusy is returned when a cold variant finds no free decode slot.
+var errVariantBusy = errors.New("all variant decode slots are busy")
+
+// assetVariant is a ?w=&fmt= request; the zero value means "the object as stored".
+type assetVariant struct {
+	Width  int
+	Format string
+}
+
+func (v assetVariant) isOriginal() bool { return v.Width == 0 && v.Format == "" }
+
+// parseVariant reads ?w= and ?fmt=. Only configured rendition widths and the
+// formats imaging can encode are accepted, so the set of cached variants per
+// object stays small. It writes a 400 and returns false otherwise.
+func (h *AssetsHandler) parseVariant(c *gin.Context) (assetVariant, bool) {
+	var v assetVariant
+	if raw := strings.TrimSpace(c.Query("w")); raw != "" {
+		w, err := strconv.Atoi(raw)
+		if err != nil || !slices.Contains(h.widths, w) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported width", "widths": h.widths})
+			return v, false
+		}
+		v.Width = w
+	}
+	if raw := strings.ToLower(strings.TrimSpace(c.Query("fmt"))); raw != "" {
+		if raw == "jpg" {
+			raw = imaging.FormatJPEG
+		}
+		if _, ok := imaging.OutputFormats[raw]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported fmt"})
+			return v, false
+		}
+		v.Format = raw
+	}
+	if v.Width > 0 && v.Format == "" {
+		v.Format = imaging.FormatWebP
+	}
+	return v, true
+}
+
+// resolveVariant returns the object key holding variant v of key, generating
+// and caching it in storage on first use.
+//
+// Lookup order:
+//  1. the upload rendition of the requested width (products/.../{uuid}/w{N}.webp);
+//  2. a previously cached variant under imaging.VariantKey;
+//  3. a freshly generated variant, written back to (2).
+//
+// When the write-back fails the generated bytes are still returned in data
+// (with key empty) so the request succeeds.
+func (h *AssetsHandler) resolveVariant(c *gin.Context, key string, v assetVariant) (servedKey string, data []byte, err error) {
+	ctx := c.Request.Context()
+
+	if prefix, _, ok := imaging.SplitRenditionKey(key); ok && v.Format == imaging.FormatWebP && v.Width > 0 {
+		sibling := imaging.RenditionKey(prefix, v.Width)
+		if sibling == key {
+			return key, nil, nil
+		}
+		if _, err := h.store.Stat(ctx, sibling); err == nil {
+			return sibling, nil, nil
+		}
+	}
+
+	variantKey := imaging.VariantKey(key, v.Width, v.Format)
+	if _, err := h.store.Stat(ctx, variantKey); err == nil {
+		return variantKey, nil, nil
+	}
+
+	// Concurrent requests for the same cold variant share one encode.
+	res, err, _ := h.variants.Do(variantKey, func() (any, error) {
+		return h.generateVariant(c, key, variantKey, v)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	out := res.(generatedVariant)
+	if out.stored {
+		return variantKey, nil, nil
+	}
+	return "", out.data, nil
+}
+
+type generatedVariant struct {
+	data   []byte
+	stored bool
+}
+
+func (h *AssetsHandler) generateVariant(c *gin.Context, key, variantKey string, v assetVariant) (generatedVariant, error) {
+	// Other requests may be waiting on this result; don't let the first
+	// client disconnecting cancel it for everyone.
+	ctx := context.WithoutCancel(c.Request.Context())
+
+	// Reading and decoding a large original takes hundreds of MB; don't
+	// queue up more of them than the slots allow.
+	select {
+	case h.decodeSlots <- struct{}{}:
+		defer func() { <-h.decodeSlots }()
+	default:
+		return generatedVariant{}, errVariantBusy
+	}
+
+	obj, err := h.store.Open(ctx, key, 0, -1)
+	if err != nil {
+		return generatedVariant{}, err
+	}
+	defer obj.Close()
+	src, err := io.ReadAll(io.LimitReader(obj, maxVariantSourceBytes+1))
+	if err != nil {
+		return generatedVariant{}, err
+	}
+	if len(src) > maxVariantSourceBytes {
+		return generatedVariant{}, errors.New("source object too large")
+	}
+
+	img, _, err := imaging.Decode(src, h.maxPixels)
+	if err != nil {
+		return generatedVariant{}, err
+	}
+	r, err := imaging.Encode(img, v.Width, v.Format, h.quality)
+	if err != nil {
+		return generatedVariant{}, err
+	}
+
+	if err := h.store.Put(ctx, variantKey, bytes.NewReader(r.Data), int64(len(r.Data)), imaging.OutputFormats[v.Format]); err != nil {
+		logging.FromGin(c).Warn("asset variant cache write failed", "key", variantKey, "err", err)
+		return generatedVariant{data: r.Data}, nil
+	}
+	return generatedVariant{stored: true}, nil
+}
@@ -0,0 +1,221 @@
+package public
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"evening-gown/internal/assets"
+	"evening-gown/internal/config"
+	"evening-gown/internal/imaging"
+	"evening-gown/internal/model"
+	"evening-gown/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	xwebp "golang.org/x/image/webp"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	publishedOriginal  = "products/A1/cover/2025/01/01/u1/photo.png"
+	publishedRendition = "products/A1/hover/2025/01/01/u2/w64.webp"
+	draftOriginal      = "products/B1/cover/2025/01/01/u3/photo.png"
+)
+
+type variantsFixture struct {
+	router  *gin.Engine
+	store   *storage.LocalStore
+	handler *AssetsHandler
+}
+
+func newVariantsFixture(t *testing.T) variantsFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		t.Cleanup(func() { _ = sqlDB.Close() })
+	}
+	if err := db.AutoMigrate(&model.Product{}, &model.Asset{}, &model.ProductAsset{}, &model.ProductVariant{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	photo := testPNG(t, 80, 40)
+	putObject(t, store, publishedOriginal, photo, "image/png")
+	putObject(t, store, draftOriginal, photo, "image/png")
+	putObject(t, store, publishedRendition, []byte("rendition w64"), "image/webp")
+	putObject(t, store, imaging.RenditionKey("products/A1/hover/2025/01/01/u2", 32), []byte("rendition w32"), "image/webp")
+
+	now := time.Now().UTC()
+	products := []model.Product{
+		{StyleNo: "A1", Slug: "a1", Season: "ss25", Category: "gown", Availability: "in_stock", CoverImageKey: publishedOriginal, HoverImageKey: publishedRendition, PublishedAt: &now},
+		{StyleNo: "B1", Slug: "b1", Season: "ss25", Category: "gown", Availability: "in_stock", CoverImageKey: draftOriginal},
+	}
+	for _, p := range products {
+		if err := db.Create(&p).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+		if err := assets.SyncProduct(db, p); err != nil {
+			t.Fatalf("sync assets: %v", err)
+		}
+	}
+
+	h := NewAssetsHandler(db, store, config.UploadConfig{ImageWidths: []int{32, 64}, WebPQuality: 80}, nil)
+	r := gin.New()
+	r.GET("/api/v1/assets/*key", h.Get)
+	return variantsFixture{router: r, store: store, handler: h}
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func putObject(t *testing.T, store storage.Store, key string, data []byte, contentType string) {
+	t.Helper()
+	if err := store.Put(t.Context(), key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		t.Fatalf("put %s: %v", key, err)
+	}
+}
+
+func (f variantsFixture) get(target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func (f variantsFixture) exists(t *testing.T, key string) bool {
+	t.Helper()
+	_, err := f.store.Stat(t.Context(), key)
+	return err == nil
+}
+
+func TestAssetsGet_GeneratesAndCachesVariants(t *testing.T) {
+	f := newVariantsFixture(t)
+
+	w := f.get("/api/v1/assets/" + publishedOriginal + "?w=32")
+	if w.Code != http.StatusOK {
+		t.Fatalf("w=32: %d %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/webp" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=31536000, immutable" {
+		t.Fatalf("Cache-Control = %q", cc)
+	}
+	cfg, err := xwebp.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+	if err != nil || cfg.Width != 32 || cfg.Height != 16 {
+		t.Fatalf("expected a 32x16 webp, got %+v (%v)", cfg, err)
+	}
+	webpKey := imaging.VariantKey(publishedOriginal, 32, imaging.FormatWebP)
+	if !f.exists(t, webpKey) {
+		t.Fatalf("variant was not written back to %s", webpKey)
+	}
+
+	// fmt=jpg is an alias of jpeg and is cached under its own key.
+	w = f.get("/api/v1/assets/" + publishedOriginal + "?w=64&fmt=jpg")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("fmt=jpg: %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if img, err := jpeg.DecodeConfig(bytes.NewReader(w.Body.Bytes())); err != nil || img.Width != 64 {
+		t.Fatalf("expected a 64px jpeg, got %+v (%v)", img, err)
+	}
+	if !f.exists(t, imaging.VariantKey(publishedOriginal, 64, imaging.FormatJPEG)) {
+		t.Fatal("jpeg variant was not written back")
+	}
+
+	// A cached variant is served as stored.
+	putObject(t, f.store, webpKey, []byte("cached"), "image/webp")
+	if w := f.get("/api/v1/assets/" + publishedOriginal + "?w=32"); w.Code != http.StatusOK || w.Body.String() != "cached" {
+		t.Fatalf("expected the cached variant, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestAssetsGet_ServesUploadRenditions(t *testing.T) {
+	f := newVariantsFixture(t)
+
+	w := f.get("/api/v1/assets/" + publishedRendition + "?w=32")
+	if w.Code != http.StatusOK || w.Body.String() != "rendition w32" {
+		t.Fatalf("expected the w32 sibling rendition, got %d %q", w.Code, w.Body.String())
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=31536000, immutable" {
+		t.Fatalf("Cache-Control = %q", cc)
+	}
+	if w := f.get("/api/v1/assets/" + publishedRendition + "?w=64"); w.Body.String() != "rendition w64" {
+		t.Fatalf("expected the rendition itself, got %d %q", w.Code, w.Body.String())
+	}
+	if f.exists(t, imaging.VariantKey(publishedRendition, 32, imaging.FormatWebP)) {
+		t.Fatal("an existing rendition must not be cached again under variants/")
+	}
+}
+
+func TestAssetsGet_RejectsUnsupportedVariants(t *testing.T) {
+	f := newVariantsFixture(t)
+
+	for _, q := range []string{"?w=33", "?w=abc", "?w=0", "?fmt=gif", "?w=32&fmt=png"} {
+		if w := f.get("/api/v1/assets/" + publishedOriginal + q); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d %s", q, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAssetsGet_VariantsRequirePublishedProduct(t *testing.T) {
+	f := newVariantsFixture(t)
+
+	for _, target := range []string{
+		"/api/v1/assets/" + draftOriginal + "?w=32",
+		"/api/v1/assets/products/C1/cover/2025/01/01/u9/photo.png?w=32",
+	} {
+		if w := f.get(target); w.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", target, w.Code)
+		}
+	}
+	if f.exists(t, imaging.VariantKey(draftOriginal, 32, imaging.FormatWebP)) {
+		t.Fatal("a variant of a draft asset was generated")
+	}
+}
+
+func TestAssetsGet_VariantsShedLoadWhenDecodeSlotsAreBusy(t *testing.T) {
+	f := newVariantsFixture(t)
+
+	for range cap(f.handler.decodeSlots) {
+		f.handler.decodeSlots <- struct{}{}
+	}
+	w := f.get("/api/v1/assets/" + publishedOriginal + "?w=32")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 503 with Retry-After, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if f.exists(t, imaging.VariantKey(publishedOriginal, 32, imaging.FormatWebP)) {
+		t.Fatal("a variant was generated without a decode slot")
+	}
+	// Cached variants and renditions don't need a slot.
+	if w := f.get("/api/v1/assets/" + publishedRendition + "?w=32"); w.Code != http.StatusOK {
+		t.Fatalf("expected the rendition while busy, got %d", w.Code)
+	}
+
+	for range cap(f.handler.decodeSlots) {
+		<-f.handler.decodeSlots
+	}
+	if w := f.get("/api/v1/assets/" + publishedOriginal + "?w=32"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 once a slot is free, got %d %s", w.Code, w.Body.String())
+	}
+}
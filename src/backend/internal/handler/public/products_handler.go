@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"evening-gown/internal/assets"
 	"evening-gown/internal/cache"
 	"evening-gown/internal/logging"
 	"evening-gown/internal/model"
@@ -40,6 +41,10 @@ type productListItem struct {
 	HoverImage   string `json:"hoverImage"`
 	IsNew        bool   `json:"isNew"`
 
+	// Dimensions and placeholder of the images, when registered.
+	CoverImageMeta *assets.ImageInfo `json:"coverImageMeta,omitempty"`
+	HoverImageMeta *assets.ImageInfo `json:"hoverImageMeta,omitempty"`
+
 	PriceMode string `json:"priceMode"`
 	PriceText string `json:"priceText"`
 }
@@ -117,20 +122,10 @@ func (h *ProductsHandler) List(c *gin.Context) {
 		return
 	}
 
+	infos := h.imageInfos(c, listImageKeys(products))
 	items := make([]productListItem, 0, len(products))
 	for _, p := range products {
-		items = append(items, productListItem{
-			ID:           p.ID,
-			StyleNo:      p.StyleNo,
-			Season:       p.Season,
-			Category:     p.Category,
-			Availability: p.Availability,
-			CoverImage:   pickPublicImageURL(p.CoverImageKey, p.CoverImageURL),
-			HoverImage:   pickPublicImageURL(p.HoverImageKey, p.HoverImageURL),
-			IsNew:        p.IsNew,
-			PriceMode:    "negotiable",
-			PriceText:    "面议",
-		})
+		items = append(items, toProductListItem(p, infos))
 	}
 
 	resp := gin.H{"total": total, "items": items}
@@ -186,6 +181,14 @@ func (h *ProductsHandler) Get(c *gin.Context) {
 		return
 	}
 
+	model.UpgradeProductDetail(&p)
+	coverKey := productImageKey(p.CoverImageKey, p.CoverImageURL)
+	hoverKey := productImageKey(p.HoverImageKey, p.HoverImageURL)
+	detail := jsonOrNull(p.DetailJSON)
+	gallery, galleryKeys := galleryEntries(detail)
+	infos := h.imageInfos(c, append([]string{coverKey, hoverKey}, galleryKeys...))
+	annotateGallery(gallery, galleryKeys, infos)
+
 	resp := gin.H{
 		"id":           p.ID,
 		"slug":         p.Slug,
@@ -198,7 +201,14 @@ func (h *ProductsHandler) Get(c *gin.Context) {
 		"isNew":        p.IsNew,
 		"priceMode":    "negotiable",
 		"priceText":    "面议",
-		"detail":       jsonOrNull(p.DetailJSON),
+		"detail":       detail,
+		"variants":     h.productVariants(c, p.ID),
+	}
+	if meta := imageMeta(infos, coverKey); meta != nil {
+		resp["coverImageMeta"] = meta
+	}
+	if meta := imageMeta(infos, hoverKey); meta != nil {
+		resp["hoverImageMeta"] = meta
 	}
 
 	if h.cache != nil && cacheKey != "" {
@@ -212,6 +222,24 @@ func (h *ProductsHandler) Get(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+func toProductListItem(p model.Product, infos map[string]assets.ImageInfo) productListItem {
+	return productListItem{
+		ID:           p.ID,
+		StyleNo:      p.StyleNo,
+		Season:       p.Season,
+		Category:     p.Category,
+		Availability: p.Availability,
+		CoverImage:   pickPublicImageURL(p.CoverImageKey, p.CoverImageURL),
+		HoverImage:   pickPublicImageURL(p.HoverImageKey, p.HoverImageURL),
+		IsNew:        p.IsNew,
+		PriceMode:    "negotiable",
+		PriceText:    "面议",
+
+		CoverImageMeta: imageMeta(infos, productImageKey(p.CoverImageKey, p.CoverImageURL)),
+		HoverImageMeta: imageMeta(infos, productImageKey(p.HoverImageKey, p.HoverImageURL)),
+	}
+}
+
 func pickPublicImageURL(objectKey string, legacyURL string) string {
 	key := strings.TrimSpace(strings.TrimPrefix(objectKey, "/"))
 	if key != "" {
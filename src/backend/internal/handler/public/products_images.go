@@ -0,0 +1,112 @@
+package public
+
+import (
+	"evening-gown/internal/assets"
+	"evening-gown/internal/logging"
+	"evening-gown/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// productImageKey is the object key behind a product image: the stored key,
+// or the one embedded in a legacy URL.
+func productImageKey(objectKey, legacyURL string) string {
+	if key := model.AssetKeyFromString(objectKey); key != "" {
+		return key
+	}
+	return model.AssetKeyFromString(legacyURL)
+}
+
+// imageInfos looks up the dimensions and placeholders of keys. It is best
+// effort: on failure the responses simply go without them.
+func (h *ProductsHandler) imageInfos(c *gin.Context, keys []string) map[string]assets.ImageInfo {
+	nonEmpty := keys[:0:0]
+	for _, k := range keys {
+		if k != "" {
+			nonEmpty = append(nonEmpty, k)
+		}
+	}
+	infos, err := assets.ImageInfos(c.Request.Context(), h.db, nonEmpty)
+	if err != nil {
+		logging.FromGin(c).Warn("public product image info lookup failed", "err", err)
+		return nil
+	}
+	return infos
+}
+
+// listImageKeys collects the cover and hover keys of products.
+func listImageKeys(products []model.Product) []string {
+	keys := make([]string, 0, 2*len(products))
+	for _, p := range products {
+		if k := productImageKey(p.CoverImageKey, p.CoverImageURL); k != "" {
+			keys = append(keys, k)
+		}
+		if k := productImageKey(p.HoverImageKey, p.HoverImageURL); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func imageMeta(infos map[string]assets.ImageInfo, key string) *assets.ImageInfo {
+	info, ok := infos[key]
+	if !ok {
+		return nil
+	}
+	return &info
+}
+
+// galleryEntries returns the object entries of detail.gallery with the key
+// each one shows ("objectKey", else the key inside "url" or "src").
+func galleryEntries(detail any) ([]map[string]any, []string) {
+	d, ok := detail.(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+	items, ok := d["gallery"].([]any)
+	if !ok {
+		return nil, nil
+	}
+	var (
+		entries []map[string]any
+		keys    []string
+	)
+	for _, item := range items {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		var key string
+		for _, field := range []string{"objectKey", "url", "src"} {
+			if s, ok := entry[field].(string); ok {
+				if key = model.AssetKeyFromString(s); key != "" {
+					break
+				}
+			}
+		}
+		if key != "" {
+			entries = append(entries, entry)
+			keys = append(keys, key)
+		}
+	}
+	return entries, keys
+}
+
+// annotateGallery adds width, height, dominantColor and blurhash to the
+// gallery entries of a decoded detail whose image is registered.
+func annotateGallery(entries []map[string]any, keys []string, infos map[string]assets.ImageInfo) {
+	for i, entry := range entries {
+		info, ok := infos[keys[i]]
+		if !ok {
+			continue
+		}
+		entry["width"] = info.Width
+		entry["height"] = info.Height
+		if info.DominantColor != "" {
+			entry["dominantColor"] = info.DominantColor
+		}
+		if info.Blurhash != "" {
+			entry["blurhash"] = info.Blurhash
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package public
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"evening-gown/internal/cache"
+	"evening-gown/internal/logging"
+	"evening-gown/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	publicProductsSearchTTL = 2 * time.Minute
+
+	// productSearchMaxCandidates bounds the in-memory ranking used by the non-Postgres fallback.
+	productSearchMaxCandidates = 1000
+)
+
+// productSearchFacetColumns are the product columns we return facet counts for.
+var productSearchFacetColumns = []string{"season", "category", "availability"}
+
+type productSearchItem struct {
+	productListItem
+	Score float64 `json:"score"`
+}
+
+type productSearchFacetBucket struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type productSearchRow struct {
+	model.Product
+	Score float64 `gorm:"column:score"`
+}
+
+type productSearchFilters struct {
+	Season       string
+	Category     string
+	Availability string
+	IsNew        string
+}
+
+// Search runs a full-text search over published products.
+//
+// Route: GET /api/v1/products/search
+//
+// Query params:
+// - q: free text (style number fragments, localized titles/descriptions, spec values)
+// - season, category, availability, is_new: optional filters (same as List)
+// - limit/offset: pagination
+//
+// Facet counts are "disjunctive": each facet ignores its own filter so the storefront
+// can show alternatives (e.g. other seasons) for the current query.
+func (h *ProductsHandler) Search(c *gin.Context) {
+	if h == nil || h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service unavailable"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	terms := model.ProductSearchTerms(c.Query("q"))
+	if len(terms) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	filters := productSearchFilters{
+		Season:       strings.TrimSpace(c.Query("season")),
+		Category:     strings.TrimSpace(c.Query("category")),
+		Availability: strings.TrimSpace(c.Query("availability")),
+		IsNew:        strings.TrimSpace(c.Query("is_new")),
+	}
+
+	limit := parseIntQuery(c, "limit", 50)
+	offset := parseIntQuery(c, "offset", 0)
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var cacheKey string
+	if h.cache != nil {
+		ver := h.cache.ProductsVersion(ctx)
+		cacheKey = h.cache.ProductsSearchKey(ver, terms, filters.Season, filters.Category, filters.Availability, filters.IsNew, limit, offset)
+		if b, hit, _ := h.cache.GetJSONBytes(ctx, cacheKey); hit {
+			c.Data(http.StatusOK, "application/json; charset=utf-8", b)
+			return
+		}
+	}
+
+	var total int64
+	if err := h.searchQuery(c, terms, filters, "").Count(&total).Error; err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "public products search count failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	// The fallback only ranks the newest productSearchMaxCandidates matches, so
+	// report what can actually be paged through.
+	truncated := false
+	if !isPostgres(h.db) && total > productSearchMaxCandidates {
+		total, truncated = productSearchMaxCandidates, true
+	}
+
+	var (
+		rows []productSearchRow
+		err  error
+	)
+	if isPostgres(h.db) {
+		rows, err = h.searchRankedPostgres(c, terms, filters, limit, offset)
+	} else {
+		rows, err = h.searchRankedFallback(c, terms, filters, limit, offset)
+	}
+	if err != nil {
+		logging.ErrorWithStack(logging.FromGin(c), "public products search list failed", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	products := make([]model.Product, 0, len(rows))
+	for _, r := range rows {
+		products = append(products, r.Product)
+	}
+	infos := h.imageInfos(c, listImageKeys(products))
+	items := make([]productSearchItem, 0, len(rows))
+	for _, r := range rows {
+		items = append(items, productSearchItem{productListItem: toProductListItem(r.Product, infos), Score: r.Score})
+	}
+
+	facets := make(map[string][]productSearchFacetBucket, len(productSearchFacetColumns))
+	for _, col := range productSearchFacetColumns {
+		buckets := []productSearchFacetBucket{}
+		if err := h.searchQuery(c, terms, filters, col).
+			Select(col + " AS value, COUNT(*) AS count").
+			Group(col).
+			Order("COUNT(*) DESC, " + col + " ASC").
+			Scan(&buckets).Error; err != nil {
+			logging.ErrorWithStack(logging.FromGin(c), "public products search facets failed", err, "facet", col)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			return
+		}
+		facets[col] = buckets
+	}
+
+	resp := gin.H{
+		"q":      strings.Join(terms, " "),
+		"total":  total,
+		"items":  items,
+		"facets": facets,
+	}
+	if truncated {
+		resp["truncated"] = true
+	}
+	if h.cache != nil && cacheKey != "" {
+		b, err := json.Marshal(resp)
+		if err == nil {
+			ttl := cache.TTLWithKeyJitter(publicProductsSearchTTL, cacheKey, 0.2)
+			h.cache.SetJSONBytes(ctx, cacheKey, b, ttl)
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// searchQuery builds the filtered match query. skipFacet names a facet column whose
+// filter is left out (used for disjunctive facet counts); pass "" to apply all filters.
+func (h *ProductsHandler) searchQuery(c *gin.Context, terms []string, f productSearchFilters, skipFacet string) *gorm.DB {
+	q := h.db.WithContext(c.Request.Context()).Model(&model.Product{}).
+		Where("published_at IS NOT NULL").
+		Where("deleted_at IS NULL")
+
+	if f.Season != "" && skipFacet != "season" {
+		q = q.Where("season = ?", f.Season)
+	}
+	if f.Category != "" && skipFacet != "category" {
+		q = q.Where("category = ?", f.Category)
+	}
+	if f.Availability != "" && skipFacet != "availability" {
+		q = q.Where("availability = ?", f.Availability)
+	}
+	if f.IsNew == "true" {
+		q = q.Where("is_new = true")
+	} else if f.IsNew == "false" {
+		q = q.Where("is_new = false")
+	}
+
+	// Every term must match somewhere (AND semantics). Terms only contain letters,
+	// digits and '-', so they need no LIKE escaping.
+	postgres := isPostgres(h.db)
+	for _, t := range terms {
+		like := "%" + t + "%"
+		if !postgres {
+			q = q.Where("(LOWER(style_no) LIKE ? OR "+productSearchTextSQLite+")", like, like)
+			continue
+		}
+		// Each branch is backed by an index (the tsvector GIN index and, where
+		// pg_trgm is available, trigram indexes), so the OR stays a bitmap scan.
+		// Only Han terms need the detail text: the tsvector covers the rest.
+		if model.ProductSearchNeedsText(t) {
+			q = q.Where("("+model.ProductSearchVectorSQL+" @@ to_tsquery('simple', ?) OR "+model.ProductSearchTextSQL+" LIKE ?)",
+				model.ProductSearchTSQuery(t), like)
+			continue
+		}
+		q = q.Where("("+model.ProductSearchVectorSQL+" @@ to_tsquery('simple', ?) OR "+model.ProductSearchStyleSQL+" LIKE ?)",
+			model.ProductSearchTSQuery(t), like)
+	}
+	return q
+}
+
+// productSearchTextSQLite matches a LIKE pattern against the string values of the
+// searchable detail fields (the same ones as the Postgres tsvector), never the keys.
+const productSearchTextSQLite = `EXISTS (SELECT 1 FROM (` +
+	`SELECT type, value FROM json_tree(CAST(products.detail_json AS TEXT), '$.title_i18n') UNION ALL ` +
+	`SELECT type, value FROM json_tree(CAST(products.detail_json AS TEXT), '$.description_i18n') UNION ALL ` +
+	`SELECT type, value FROM json_tree(CAST(products.detail_json AS TEXT), '$.specs')` +
+	`) AS t WHERE t.type = 'text' AND LOWER(t.value) LIKE ?)`
+
+func (h *ProductsHandler) searchRankedPostgres(c *gin.Context, terms []string, f productSearchFilters, limit, offset int) ([]productSearchRow, error) {
+	anyTerm := make([]string, 0, len(terms))
+	for _, t := range terms {
+		anyTerm = append(anyTerm, "("+model.ProductSearchTSQuery(t)+")")
+	}
+
+	// ts_rank orders text relevance; the CASE boosts exact/prefix style number hits,
+	// which buyers use most when they already know the piece.
+	score := "ts_rank(" + model.ProductSearchVectorSQL + ", to_tsquery('simple', ?)) + " +
+		"CASE WHEN LOWER(style_no) = ? THEN 1 WHEN LOWER(style_no) LIKE ? THEN 0.5 ELSE 0 END AS score"
+
+	var rows []productSearchRow
+	err := h.searchQuery(c, terms, f, "").
+		Select("id, style_no, season, category, availability, cover_image_url, cover_image_key, hover_image_url, hover_image_key, is_new, new_rank, "+score,
+			strings.Join(anyTerm, " | "), strings.Join(terms, " "), terms[0]+"%").
+		Order("score desc, is_new desc, new_rank desc, id desc").
+		Limit(limit).Offset(offset).
+		Find(&rows).Error
+	return rows, err
+}
+
+// searchRankedFallback ranks matches in Go for databases without full-text search (SQLite in tests).
+func (h *ProductsHandler) searchRankedFallback(c *gin.Context, terms []string, f productSearchFilters, limit, offset int) ([]productSearchRow, error) {
+	var products []model.Product
+	if err := h.searchQuery(c, terms, f, "").
+		Select("id, style_no, season, category, availability, cover_image_url, cover_image_key, hover_image_url, hover_image_key, is_new, new_rank, detail_json").
+		Order("id desc").
+		Limit(productSearchMaxCandidates).
+		Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	rows := make([]productSearchRow, 0, len(products))
+	for _, p := range products {
+		rows = append(rows, productSearchRow{Product: p, Score: scoreProductSearch(p, terms)})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.IsNew != b.IsNew {
+			return a.IsNew
+		}
+		if a.NewRank != b.NewRank {
+			return a.NewRank > b.NewRank
+		}
+		return a.ID > b.ID
+	})
+
+	if offset >= len(rows) {
+		return []productSearchRow{}, nil
+	}
+	end := offset + limit
+	if end > len(rows) {
+		end = len(rows)
+	}
+	return rows[offset:end], nil
+}
+
+// scoreProductSearch approximates the Postgres weights (A: style/title, B: description, C: specs).
+func scoreProductSearch(p model.Product, terms []string) float64 {
+	var detail map[string]any
+	_ = json.Unmarshal(p.DetailJSON, &detail)
+
+	style := strings.ToLower(p.StyleNo)
+	title := strings.ToLower(collectSearchStrings(detail["title_i18n"]))
+	desc := strings.ToLower(collectSearchStrings(detail["description_i18n"]))
+	specs := strings.ToLower(collectSearchStrings(detail["specs"]))
+
+	score := 0.0
+	for _, t := range terms {
+		switch {
+		case style == t:
+			score += 1
+		case strings.HasPrefix(style, t):
+			score += 0.5
+		case strings.Contains(style, t):
+			score += 0.25
+		}
+		if strings.Contains(title, t) {
+			score += 0.1
+		}
+		if strings.Contains(desc, t) {
+			score += 0.04
+		}
+		if strings.Contains(specs, t) {
+			score += 0.02
+		}
+	}
+	return score
+}
+
+func collectSearchStrings(v any) string {
+	var b strings.Builder
+	var walk func(any)
+	walk = func(v any) {
+		switch x := v.(type) {
+		case string:
+			b.WriteString(x)
+			b.WriteByte(' ')
+		case []any:
+			for _, it := range x {
+				walk(it)
+			}
+		case map[string]any:
+			for _, it := range x {
+				walk(it)
+			}
+		}
+	}
+	walk(v)
+	return b.String()
+}
+
+func isPostgres(db *gorm.DB) bool {
+	return db != nil && db.Dialector != nil && db.Dialector.Name() == "postgres"
+}
@@ -0,0 +1,52 @@
+package public
+
+import (
+	"evening-gown/internal/logging"
+	"evening-gown/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// productVariant is the public view of a model.ProductVariant.
+type productVariant struct {
+	Code         string                `json:"code"`
+	Options      []model.VariantOption `json:"options"`
+	Availability string                `json:"availability"`
+	MOQ          int                   `json:"moq"`
+	LeadTimeDays int                   `json:"leadTimeDays"`
+	Images       []string              `json:"images"`
+}
+
+// productVariants loads the variants of a product in display order. It is
+// best effort: on failure the detail goes without them.
+func (h *ProductsHandler) productVariants(c *gin.Context, productID uint) []productVariant {
+	var rows []model.ProductVariant
+	if err := h.db.WithContext(c.Request.Context()).
+		Where("product_id = ?", productID).
+		Find(&rows).Error; err != nil {
+		logging.FromGin(c).Warn("public product variants lookup failed", "product_id", productID, "err", err)
+		return []productVariant{}
+	}
+	model.SortVariants(rows)
+
+	out := make([]productVariant, 0, len(rows))
+	for _, v := range rows {
+		opts := v.DecodeOptions()
+		if opts == nil {
+			opts = []model.VariantOption{}
+		}
+		images := []string{}
+		for _, k := range v.DecodeImageKeys() {
+			images = append(images, pickPublicImageURL(k, ""))
+		}
+		out = append(out, productVariant{
+			Code:         v.Code,
+			Options:      opts,
+			Availability: v.Availability,
+			MOQ:          v.MOQ,
+			LeadTimeDays: v.LeadTimeDays,
+			Images:       images,
+		})
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// jpegOrientation returns the EXIF orientation (1..8) of a JPEG, or 1 when the
+// file carries none. Only IFD0 tag 0x0112 is read; everything else in the
+// EXIF block is ignored and dropped on re-encode anyway.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	p := 2
+	for p+4 <= len(data) {
+		if data[p] != 0xFF {
+			return 1
+		}
+		marker := data[p+1]
+		// Padding bytes between markers.
+		if marker == 0xFF {
+			p++
+			continue
+		}
+		// Start of scan / end of image: no metadata after this point.
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		segLen := int(binary.BigEndian.Uint16(data[p+2 : p+4]))
+		if segLen < 2 || p+2+segLen > len(data) {
+			return 1
+		}
+		seg := data[p+4 : p+2+segLen]
+		if marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return tiffOrientation(seg[6:])
+		}
+		p += 2 + segLen
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	if order.Uint16(tiff[2:4]) != 42 {
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < entries; i++ {
+		off := ifd + 2 + i*12
+		if off+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[off:off+2]) != 0x0112 {
+			continue
+		}
+		// SHORT, count 1: the value sits in the first two bytes of the value field.
+		v := int(order.Uint16(tiff[off+8 : off+10]))
+		if v < 1 || v > 8 {
+			return 1
+		}
+		return v
+	}
+	return 1
+}
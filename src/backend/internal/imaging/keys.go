@@ -0,0 +1,41 @@
+package imaging
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// VariantPrefix holds lazily generated variants. It is outside "products/" so
+// cached variants are never reachable by their own key through the public
+// assets endpoint; they are only served for the original they derive from.
+const VariantPrefix = "variants/"
+
+var renditionKeyRe = regexp.MustCompile(`^(.+)/w([0-9]+)\.webp$`)
+
+// RenditionKey is the object key of an upload rendition:
+// {prefix}/w{width}.webp, where prefix is unique per upload.
+func RenditionKey(prefix string, width int) string {
+	return fmt.Sprintf("%s/w%d.webp", prefix, width)
+}
+
+// SplitRenditionKey returns the upload prefix and width of a rendition key.
+// ok is false for keys outside the upload rendition scheme (e.g. legacy
+// single-file uploads).
+func SplitRenditionKey(key string) (prefix string, width int, ok bool) {
+	m := renditionKeyRe.FindStringSubmatch(key)
+	if m == nil {
+		return "", 0, false
+	}
+	width, err := strconv.Atoi(m[2])
+	if err != nil || width <= 0 {
+		return "", 0, false
+	}
+	return m[1], width, true
+}
+
+// VariantKey is where a generated variant of key is cached. width 0 means
+// the original width.
+func VariantKey(key string, width int, format string) string {
+	return fmt.Sprintf("%s%s/w%d.%s", VariantPrefix, key, width, format)
+}
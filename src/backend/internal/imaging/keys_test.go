@@ -0,0 +1,23 @@
+package imaging
+
+import "testing"
+
+func TestRenditionKeys(t *testing.T) {
+	prefix := "products/1001/cover/2025/09/01/0b7c"
+	key := RenditionKey(prefix, 640)
+	if key != prefix+"/w640.webp" {
+		t.Fatalf("RenditionKey = %q", key)
+	}
+
+	gotPrefix, w, ok := SplitRenditionKey(key)
+	if !ok || gotPrefix != prefix || w != 640 {
+		t.Fatalf("SplitRenditionKey(%q) = %q, %d, %v", key, gotPrefix, w, ok)
+	}
+	if _, _, ok := SplitRenditionKey("products/1001/cover/2025/09/01/0b7c.webp"); ok {
+		t.Fatalf("legacy single-file key must not parse as a rendition")
+	}
+
+	if got, want := VariantKey(key, 320, FormatJPEG), "variants/"+key+"/w320.jpeg"; got != want {
+		t.Fatalf("VariantKey = %q, want %q", got, want)
+	}
+}
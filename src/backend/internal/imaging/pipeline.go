@@ -0,0 +1,334 @@
+// Package imaging turns uploaded photos into the WebP renditions served to the
+// website.
+//
+// Every input is fully decoded and re-encoded, which drops EXIF/GPS and any
+// other embedded metadata. JPEG orientation is applied to the pixels first so
+// the renditions display upright without it.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"sort"
+
+	"github.com/chai2010/webp"
+	"github.com/gen2brain/heic"
+	xdraw "golang.org/x/image/draw"
+	xwebp "golang.org/x/image/webp"
+)
+
+// Supported input formats, as returned by DetectFormat.
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+	FormatHEIC = "heic"
+)
+
+const (
+	DefaultQuality   float32 = 82
+	DefaultMaxPixels         = 50_000_000
+)
+
+// OutputFormats maps the formats Encode can write to their content types.
+var OutputFormats = map[string]string{
+	FormatWebP: "image/webp",
+	FormatJPEG: "image/jpeg",
+}
+
+// DefaultWidths are the rendition widths generated when none are configured.
+var DefaultWidths = []int{320, 640, 1280, 2048}
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooManyPixels     = errors.New("image dimensions too large")
+)
+
+// Options controls Process. Zero values fall back to the defaults above.
+type Options struct {
+	Widths  []int
+	Quality float32
+	// MaxPixels rejects images whose width*height exceeds it before decoding,
+	// so a small file cannot expand into gigabytes of pixels.
+	MaxPixels int
+}
+
+// Rendition is one encoded image (WebP unless produced by Encode).
+type Rendition struct {
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Result is the outcome of Process. Width/Height are of the upright source.
+type Result struct {
+	Format      string
+	Width       int
+	Height      int
+	Placeholder Placeholder
+	Renditions  []Rendition
+}
+
+// DetectFormat sniffs the input format from its leading bytes.
+// It returns "" for anything not supported.
+func DetectFormat(data []byte) string {
+	switch {
+	case len(data) >= 3 && data[0] == 0xFF && data[1] == 0xD8 && data[2] == 0xFF:
+		return FormatJPEG
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return FormatWebP
+	case isHEIF(data):
+		return FormatHEIC
+	}
+	return ""
+}
+
+// isHEIF checks the ISO-BMFF ftyp box for a HEIF/HEIC brand.
+func isHEIF(data []byte) bool {
+	if len(data) < 12 || string(data[4:8]) != "ftyp" {
+		return false
+	}
+	switch string(data[8:12]) {
+	case "heic", "heix", "heim", "heis", "hevc", "hevx", "hevm", "hevs", "mif1", "msf1":
+		return true
+	}
+	return false
+}
+
+// Decode decodes data and returns it upright as *image.NRGBA.
+func Decode(data []byte, maxPixels int) (*image.NRGBA, string, error) {
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+
+	format := DetectFormat(data)
+	var (
+		cfg image.Config
+		err error
+	)
+	switch format {
+	case FormatJPEG:
+		cfg, err = jpeg.DecodeConfig(bytes.NewReader(data))
+	case FormatPNG:
+		cfg, err = png.DecodeConfig(bytes.NewReader(data))
+	case FormatWebP:
+		cfg, err = xwebp.DecodeConfig(bytes.NewReader(data))
+	case FormatHEIC:
+		cfg, err = heic.DecodeConfig(bytes.NewReader(data))
+	default:
+		return nil, "", ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, format, fmt.Errorf("decode %s header: %w", format, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, format, fmt.Errorf("decode %s header: empty image", format)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, format, ErrTooManyPixels
+	}
+
+	var src image.Image
+	switch format {
+	case FormatJPEG:
+		src, err = jpeg.Decode(bytes.NewReader(data))
+	case FormatPNG:
+		src, err = png.Decode(bytes.NewReader(data))
+	case FormatWebP:
+		src, err = xwebp.Decode(bytes.NewReader(data))
+	case FormatHEIC:
+		// libheif already applies the irot/imir transforms of the container.
+		src, err = heic.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, format, fmt.Errorf("decode %s: %w", format, err)
+	}
+
+	img := toNRGBA(src)
+	if format == FormatJPEG {
+		img = orient(img, jpegOrientation(data))
+	}
+	return img, format, nil
+}
+
+// Process decodes data and encodes one WebP rendition per configured width,
+// narrowest first. Widths wider than the source are replaced by a single
+// rendition at the source width; images are never upscaled.
+func Process(data []byte, opt Options) (Result, error) {
+	img, format, err := Decode(data, opt.MaxPixels)
+	if err != nil {
+		return Result{Format: format}, err
+	}
+
+	b := img.Bounds()
+	res := Result{Format: format, Width: b.Dx(), Height: b.Dy(), Placeholder: PlaceholderOf(img)}
+	for _, w := range RenditionWidths(opt.Widths, b.Dx()) {
+		r, err := Resize(img, w, opt.Quality)
+		if err != nil {
+			return res, err
+		}
+		res.Renditions = append(res.Renditions, r)
+	}
+	return res, nil
+}
+
+// RenditionWidths returns the sorted, de-duplicated widths to generate for a
+// source srcWidth pixels wide.
+func RenditionWidths(widths []int, srcWidth int) []int {
+	if len(widths) == 0 {
+		widths = DefaultWidths
+	}
+	seen := map[int]bool{}
+	out := make([]int, 0, len(widths))
+	for _, w := range widths {
+		if w <= 0 {
+			continue
+		}
+		if w > srcWidth {
+			w = srcWidth
+		}
+		if !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, srcWidth)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Resize scales img to width (keeping the aspect ratio) and encodes it as WebP.
+func Resize(img *image.NRGBA, width int, quality float32) (Rendition, error) {
+	return Encode(img, width, FormatWebP, quality)
+}
+
+// Encode scales img to width (keeping the aspect ratio; 0 or anything wider
+// than img keeps the original width) and encodes it as format, which must be
+// one of OutputFormats.
+func Encode(img *image.NRGBA, width int, format string, quality float32) (Rendition, error) {
+	if quality <= 0 || quality > 100 {
+		quality = DefaultQuality
+	}
+	b := img.Bounds()
+	if width <= 0 || width > b.Dx() {
+		width = b.Dx()
+	}
+	height := (b.Dy()*width + b.Dx()/2) / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := img
+	if width != b.Dx() {
+		dst = image.NewNRGBA(image.Rect(0, 0, width, height))
+		xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, xdraw.Src, nil)
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	switch format {
+	case FormatWebP:
+		// libwebp expects straight (non-premultiplied) RGBA, which is exactly
+		// the NRGBA pixel layout; the *image.RGBA type is only a carrier here.
+		data, err = webp.EncodeRGBA(&image.RGBA{Pix: dst.Pix, Stride: dst.Stride, Rect: dst.Rect}, quality)
+	case FormatJPEG:
+		var buf bytes.Buffer
+		err = jpeg.Encode(&buf, flatten(dst), &jpeg.Options{Quality: int(quality)})
+		data = buf.Bytes()
+	default:
+		return Rendition{}, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return Rendition{}, fmt.Errorf("encode %s: %w", format, err)
+	}
+	return Rendition{Width: width, Height: height, Data: data}, nil
+}
+
+// flatten composites img onto white, since JPEG has no alpha channel.
+func flatten(img *image.NRGBA) image.Image {
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Over)
+	return out
+}
+
+func toNRGBA(src image.Image) *image.NRGBA {
+	if n, ok := src.(*image.NRGBA); ok && n.Rect.Min == (image.Point{}) {
+		return n
+	}
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
+
+// orient applies an EXIF orientation (1..8) to img.
+func orient(img *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirror horizontal
+				sx, sy = w-1-x, y
+			case 3: // rotate 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirror vertical
+				sx, sy = x, h-1-y
+			case 5: // transpose
+				sx, sy = y, x
+			case 6: // rotate 90 CW
+				sx, sy = y, h-1-x
+			case 7: // transverse
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotate 90 CCW
+				sx, sy = w-1-y, x
+			}
+			si := sy*img.Stride + sx*4
+			di := y*dst.Stride + x*4
+			copy(dst.Pix[di:di+4], img.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// Dimensions returns the upright width and height of an encoded image without
+// decoding its pixels.
+func Dimensions(data []byte) (width, height int, err error) {
+	var cfg image.Config
+	switch DetectFormat(data) {
+	case FormatJPEG:
+		cfg, err = jpeg.DecodeConfig(bytes.NewReader(data))
+		if err == nil && jpegOrientation(data) >= 5 {
+			cfg.Width, cfg.Height = cfg.Height, cfg.Width
+		}
+	case FormatPNG:
+		cfg, err = png.DecodeConfig(bytes.NewReader(data))
+	case FormatWebP:
+		cfg, err = xwebp.DecodeConfig(bytes.NewReader(data))
+	case FormatHEIC:
+		cfg, err = heic.DecodeConfig(bytes.NewReader(data))
+	default:
+		return 0, 0, ErrUnsupportedFormat
+	}
+	return cfg.Width, cfg.Height, err
+}
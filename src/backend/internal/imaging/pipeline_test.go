@@ -0,0 +1,171 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"testing"
+
+	xwebp "golang.org/x/image/webp"
+)
+
+// testImage is w x h, white with a red top-left quadrant.
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{255, 255, 255, 255}
+			if x < w/2 && y < h/2 {
+				c = color.NRGBA{255, 0, 0, 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// withOrientation inserts an APP1 EXIF segment (big-endian TIFF, IFD0 with a
+// single Orientation entry) right after the JPEG SOI marker.
+func withOrientation(t *testing.T, jpg []byte, orientation uint16) []byte {
+	t.Helper()
+	var tiff bytes.Buffer
+	tiff.WriteString("MM")
+	_ = binary.Write(&tiff, binary.BigEndian, uint16(42))
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(8))
+	_ = binary.Write(&tiff, binary.BigEndian, uint16(1))
+	_ = binary.Write(&tiff, binary.BigEndian, []uint16{0x0112, 3})
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(1))
+	_ = binary.Write(&tiff, binary.BigEndian, []uint16{orientation, 0})
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(0))
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	seg = append(seg, payload...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, seg...)
+	return append(out, jpg[2:]...)
+}
+
+func TestDetectFormat(t *testing.T) {
+	var pngBuf, jpgBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, testImage(4, 4)); err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(&jpgBuf, testImage(4, 4), nil); err != nil {
+		t.Fatal(err)
+	}
+	heif := append([]byte{0, 0, 0, 24}, []byte("ftypheic")...)
+
+	cases := map[string][]byte{
+		FormatPNG:  pngBuf.Bytes(),
+		FormatJPEG: jpgBuf.Bytes(),
+		FormatWebP: []byte("RIFF\x00\x00\x00\x00WEBPVP8 "),
+		FormatHEIC: heif,
+		"":         []byte("GIF89a......"),
+	}
+	for want, data := range cases {
+		if got := DetectFormat(data); got != want {
+			t.Errorf("DetectFormat(%q...) = %q, want %q", data[:4], got, want)
+		}
+	}
+}
+
+func TestRenditionWidths(t *testing.T) {
+	if got, want := RenditionWidths([]int{1280, 320, 640, 2048}, 1000), []int{320, 640, 1000}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := RenditionWidths(nil, 4000), DefaultWidths; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := RenditionWidths([]int{0, -1}, 50), []int{50}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestProcess_PNGRenditions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(800, 400)); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Process(buf.Bytes(), Options{Widths: []int{320, 640, 1280}})
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if res.Format != FormatPNG || res.Width != 800 || res.Height != 400 {
+		t.Fatalf("unexpected result header: %+v", res)
+	}
+
+	want := [][2]int{{320, 160}, {640, 320}, {800, 400}}
+	if len(res.Renditions) != len(want) {
+		t.Fatalf("expected %d renditions, got %d", len(want), len(res.Renditions))
+	}
+	for i, r := range res.Renditions {
+		if r.Width != want[i][0] || r.Height != want[i][1] {
+			t.Fatalf("rendition %d: got %dx%d, want %dx%d", i, r.Width, r.Height, want[i][0], want[i][1])
+		}
+		cfg, err := xwebp.DecodeConfig(bytes.NewReader(r.Data))
+		if err != nil {
+			t.Fatalf("rendition %d is not webp: %v", i, err)
+		}
+		if cfg.Width != r.Width || cfg.Height != r.Height {
+			t.Fatalf("rendition %d: encoded %dx%d, reported %dx%d", i, cfg.Width, cfg.Height, r.Width, r.Height)
+		}
+	}
+}
+
+func TestProcess_JPEGAutoOrientAndStripsEXIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(200, 100), &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	// Orientation 6: the camera was rotated; display needs a 90° CW turn.
+	data := withOrientation(t, buf.Bytes(), 6)
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("jpegOrientation = %d, want 6", got)
+	}
+
+	res, err := Process(data, Options{Widths: []int{2048}})
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if res.Width != 100 || res.Height != 200 {
+		t.Fatalf("expected upright 100x200, got %dx%d", res.Width, res.Height)
+	}
+	r := res.Renditions[0]
+	if bytes.Contains(r.Data, []byte("Exif")) {
+		t.Fatalf("rendition still carries EXIF")
+	}
+
+	img, err := xwebp.Decode(bytes.NewReader(r.Data))
+	if err != nil {
+		t.Fatalf("decode rendition: %v", err)
+	}
+	// The red quadrant was top-left; after a 90° CW turn it is top-right.
+	if r, g, _, _ := img.At(90, 10).RGBA(); r>>8 < 200 || g>>8 > 80 {
+		t.Fatalf("expected red at top-right, got %v", img.At(90, 10))
+	}
+	if _, g, _, _ := img.At(10, 10).RGBA(); g>>8 < 200 {
+		t.Fatalf("expected white at top-left, got %v", img.At(10, 10))
+	}
+}
+
+func TestProcess_Rejects(t *testing.T) {
+	if _, err := Process([]byte("not an image"), Options{}); err != ErrUnsupportedFormat {
+		t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(100, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Process(buf.Bytes(), Options{MaxPixels: 100 * 99}); err != ErrTooManyPixels {
+		t.Fatalf("expected ErrTooManyPixels, got %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// placeholderSize bounds the longer side of the thumbnail placeholders are
+// computed from; neither needs more detail than that.
+const placeholderSize = 32
+
+// Placeholder describes an image well enough to stand in for it while it
+// loads: a BlurHash (https://blurha.sh) and its dominant color as #rrggbb.
+type Placeholder struct {
+	Blurhash      string
+	DominantColor string
+}
+
+// PlaceholderOf computes the placeholder of img. Transparent pixels are
+// treated as white, the background of the website.
+func PlaceholderOf(img *image.NRGBA) Placeholder {
+	b := img.Bounds()
+	if b.Dx() <= 0 || b.Dy() <= 0 {
+		return Placeholder{}
+	}
+	w, h := placeholderSize, placeholderSize
+	if b.Dx() >= b.Dy() {
+		h = max(1, (b.Dy()*placeholderSize+b.Dx()/2)/b.Dx())
+	} else {
+		w = max(1, (b.Dx()*placeholderSize+b.Dy()/2)/b.Dy())
+	}
+	thumb := img
+	if w < b.Dx() || h < b.Dy() {
+		thumb = image.NewNRGBA(image.Rect(0, 0, w, h))
+		xdraw.ApproxBiLinear.Scale(thumb, thumb.Bounds(), img, b, xdraw.Src, nil)
+	}
+
+	// Portrait images get more vertical components, landscape ones more
+	// horizontal ones.
+	xc, yc := 4, 3
+	if h > w {
+		xc, yc = 3, 4
+	}
+	return Placeholder{
+		Blurhash:      Blurhash(thumb, xc, yc),
+		DominantColor: DominantColor(thumb),
+	}
+}
+
+// rgbOnWhite returns the pixel at (x, y) composited onto white.
+func rgbOnWhite(img *image.NRGBA, x, y int) (r, g, b int) {
+	i := img.PixOffset(x, y)
+	p := img.Pix[i : i+4 : i+4]
+	a := int(p[3])
+	blend := func(c uint8) int { return (int(c)*a + 255*(255-a) + 127) / 255 }
+	return blend(p[0]), blend(p[1]), blend(p[2])
+}
+
+// DominantColor returns the most common color of img as #rrggbb: pixels are
+// bucketed by their 4 high bits per channel and the fullest bucket is
+// averaged. Mostly transparent pixels are ignored; "" when nothing is left.
+func DominantColor(img *image.NRGBA) string {
+	type bucket struct{ n, r, g, b int }
+	var buckets [4096]bucket
+	best := -1
+	rect := img.Bounds()
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			if img.Pix[img.PixOffset(x, y)+3] < 128 {
+				continue
+			}
+			r, g, b := rgbOnWhite(img, x, y)
+			k := (r>>4)<<8 | (g>>4)<<4 | b>>4
+			bk := &buckets[k]
+			bk.n++
+			bk.r += r
+			bk.g += g
+			bk.b += b
+			if best < 0 || bk.n > buckets[best].n {
+				best = k
+			}
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	bk := buckets[best]
+	return fmt.Sprintf("#%02x%02x%02x", bk.r/bk.n, bk.g/bk.n, bk.b/bk.n)
+}
+
+// Blurhash encodes img with xComponents × yComponents (1..9 each) DCT
+// components. Callers should pass a small image: the cost grows with the
+// pixel count times the component count.
+func Blurhash(img *image.NRGBA, xComponents, yComponents int) string {
+	xComponents = min(max(xComponents, 1), 9)
+	yComponents = min(max(yComponents, 1), 9)
+	rect := img.Bounds()
+	w, h := rect.Dx(), rect.Dy()
+	if w <= 0 || h <= 0 {
+		return ""
+	}
+
+	// Linear RGB of every pixel, computed once.
+	linear := make([][3]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b := rgbOnWhite(img, rect.Min.X+x, rect.Min.Y+y)
+			linear[y*w+x] = [3]float64{srgbToLinear(r), srgbToLinear(g), srgbToLinear(b)}
+		}
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			norm := 2.0
+			if i == 0 && j == 0 {
+				norm = 1
+			}
+			var f [3]float64
+			for y := 0; y < h; y++ {
+				cy := math.Cos(math.Pi * float64(j) * float64(y) / float64(h))
+				for x := 0; x < w; x++ {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(w)) * cy
+					px := linear[y*w+x]
+					f[0] += basis * px[0]
+					f[1] += basis * px[1]
+					f[2] += basis * px[2]
+				}
+			}
+			scale := norm / float64(w*h)
+			factors = append(factors, [3]float64{f[0] * scale, f[1] * scale, f[2] * scale})
+		}
+	}
+
+	var sb strings.Builder
+	writeBase83(&sb, (xComponents-1)+(yComponents-1)*9, 1)
+
+	dc, ac := factors[0], factors[1:]
+	maxValue := 1.0
+	if len(ac) > 0 {
+		actualMax := 0.0
+		for _, f := range ac {
+			actualMax = max(actualMax, math.Abs(f[0]), math.Abs(f[1]), math.Abs(f[2]))
+		}
+		quantisedMax := int(math.Max(0, math.Min(82, math.Floor(actualMax*166-0.5))))
+		maxValue = float64(quantisedMax+1) / 166
+		writeBase83(&sb, quantisedMax, 1)
+	} else {
+		writeBase83(&sb, 0, 1)
+	}
+
+	writeBase83(&sb, linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4)
+	for _, f := range ac {
+		q := func(v float64) int {
+			return int(math.Max(0, math.Min(18, math.Floor(signPow(v/maxValue, 0.5)*9+9.5))))
+		}
+		writeBase83(&sb, q(f[0])*19*19+q(f[1])*19+q(f[2]), 2)
+	}
+	return sb.String()
+}
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+func writeBase83(sb *strings.Builder, value, length int) {
+	for i := 1; i <= length; i++ {
+		digit := value
+		for k := 0; k < length-i; k++ {
+			digit /= 83
+		}
+		sb.WriteByte(base83Chars[digit%83])
+	}
+}
+
+func srgbToLinear(v int) float64 {
+	c := float64(v) / 255
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) int {
+	v = math.Max(0, math.Min(1, v))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
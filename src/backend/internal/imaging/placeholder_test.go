@@ -0,0 +1,39 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestPlaceholderOf(t *testing.T) {
+	red := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	for i := 0; i < len(red.Pix); i += 4 {
+		copy(red.Pix[i:i+4], []byte{255, 0, 0, 255})
+	}
+	p := PlaceholderOf(red)
+	// 4x3 components ("L"), then the DC value 0xff0000 in base83.
+	if len(p.Blurhash) != 28 || !strings.HasPrefix(p.Blurhash, "L") || p.Blurhash[2:6] != "TI:j" {
+		t.Fatalf("unexpected blurhash %q", p.Blurhash)
+	}
+	if p.DominantColor != "#ff0000" {
+		t.Fatalf("dominant color = %q", p.DominantColor)
+	}
+
+	// Portrait images use 3x4 components; the white majority dominates.
+	p = PlaceholderOf(testImage(60, 120))
+	if len(p.Blurhash) != 28 || p.Blurhash[0] != 'T' || p.DominantColor != "#ffffff" {
+		t.Fatalf("unexpected placeholder %+v", p)
+	}
+
+	// Transparent pixels are ignored by the dominant color.
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	img.SetNRGBA(0, 0, color.NRGBA{0, 0, 255, 255})
+	if got := DominantColor(img); got != "#0000ff" {
+		t.Fatalf("dominant color = %q", got)
+	}
+	if got := DominantColor(image.NewNRGBA(image.Rect(0, 0, 2, 2))); got != "" {
+		t.Fatalf("fully transparent image: %q", got)
+	}
+}
@@ -0,0 +1,504 @@
+// Package jsonschema validates JSON documents against JSON Schema (draft
+// 2020-12) documents restricted to the keywords the backend's own schemas
+// use:
+//
+//   - $ref (local "#/$defs/..." references only) and $defs
+//   - type (a name or a list of names), enum, const
+//   - properties, required, additionalProperties, propertyNames
+//   - items, minItems, maxItems
+//   - minLength, maxLength, pattern, minimum, maximum
+//
+// Annotations ($schema, $id, title, description, examples) are accepted and
+// ignored. Any other keyword makes Compile fail, so a schema can never rely on
+// a constraint that is silently not enforced.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is one validation failure. Path is the JSON Pointer (RFC 6901) of the
+// offending value in the document; "" is the document itself.
+type Error struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Schema is a compiled schema. It is safe for concurrent use.
+type Schema struct {
+	root *node
+}
+
+var knownKeywords = map[string]bool{
+	"$schema": true, "$id": true, "title": true, "description": true, "examples": true,
+	"$ref": true, "$defs": true,
+	"type": true, "enum": true, "const": true,
+	"properties": true, "required": true, "additionalProperties": true, "propertyNames": true,
+	"items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true, "minimum": true, "maximum": true,
+}
+
+var knownTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true,
+}
+
+// node is one (sub)schema. A boolean schema is represented by always/never.
+type node struct {
+	always, never bool
+
+	ref  string
+	defs map[string]*node
+
+	types    []string
+	enum     []any
+	constant any
+	hasConst bool
+
+	properties           map[string]*node
+	required             []string
+	additionalProperties *node
+	propertyNames        *node
+
+	items              *node
+	minItems, maxItems *int
+
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minimum, maximum     *float64
+}
+
+// Compile parses a schema document.
+func Compile(raw []byte) (*Schema, error) {
+	root, err := parseNode(raw, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := root.resolveRefs(root, ""); err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+func parseNode(raw json.RawMessage, at string) (*node, error) {
+	raw = bytes.TrimSpace(raw)
+	switch string(raw) {
+	case "true":
+		return &node{always: true}, nil
+	case "false":
+		return &node{never: true}, nil
+	}
+
+	var kw map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &kw); err != nil {
+		return nil, &compileError{at: at, msg: "schema must be an object or a boolean"}
+	}
+	for k := range kw {
+		if !knownKeywords[k] {
+			return nil, &compileError{at: at, msg: fmt.Sprintf("unsupported keyword %q", k)}
+		}
+	}
+
+	n := &node{}
+	sub := func(k string, v json.RawMessage) (*node, error) {
+		return parseNode(v, at+"/"+k)
+	}
+	children := func(k string, v json.RawMessage) (map[string]*node, error) {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(v, &m); err != nil {
+			return nil, err
+		}
+		out := make(map[string]*node, len(m))
+		for name, s := range m {
+			c, err := parseNode(s, at+"/"+k+"/"+escapePointer(name))
+			if err != nil {
+				return nil, err
+			}
+			out[name] = c
+		}
+		return out, nil
+	}
+
+	var err error
+	for k, v := range kw {
+		switch k {
+		case "$ref":
+			err = json.Unmarshal(v, &n.ref)
+		case "$defs":
+			n.defs, err = children(k, v)
+		case "type":
+			var one string
+			if json.Unmarshal(v, &one) == nil {
+				n.types = []string{one}
+			} else {
+				err = json.Unmarshal(v, &n.types)
+			}
+			for _, t := range n.types {
+				if !knownTypes[t] {
+					err = fmt.Errorf("unknown type %q", t)
+				}
+			}
+		case "enum":
+			var vals []json.RawMessage
+			if err = json.Unmarshal(v, &vals); err == nil {
+				for _, val := range vals {
+					d, derr := decode(val)
+					if derr != nil {
+						err = derr
+						break
+					}
+					n.enum = append(n.enum, d)
+				}
+			}
+		case "const":
+			n.constant, err = decode(v)
+			n.hasConst = true
+		case "properties":
+			n.properties, err = children(k, v)
+		case "required":
+			err = json.Unmarshal(v, &n.required)
+		case "additionalProperties":
+			n.additionalProperties, err = sub(k, v)
+		case "propertyNames":
+			n.propertyNames, err = sub(k, v)
+		case "items":
+			n.items, err = sub(k, v)
+		case "minItems":
+			err = json.Unmarshal(v, &n.minItems)
+		case "maxItems":
+			err = json.Unmarshal(v, &n.maxItems)
+		case "minLength":
+			err = json.Unmarshal(v, &n.minLength)
+		case "maxLength":
+			err = json.Unmarshal(v, &n.maxLength)
+		case "pattern":
+			var p string
+			if err = json.Unmarshal(v, &p); err == nil {
+				n.pattern, err = regexp.Compile(p)
+			}
+		case "minimum":
+			err = json.Unmarshal(v, &n.minimum)
+		case "maximum":
+			err = json.Unmarshal(v, &n.maximum)
+		}
+		if err != nil {
+			if _, ok := err.(*compileError); ok {
+				return nil, err // from a subschema
+			}
+			return nil, &compileError{at: at + "/" + k, msg: err.Error()}
+		}
+	}
+	return n, nil
+}
+
+// compileError reports a problem with the schema at #at.
+type compileError struct {
+	at, msg string
+}
+
+func (e *compileError) Error() string {
+	return "jsonschema: #" + e.at + ": " + e.msg
+}
+
+// resolveRefs checks that every $ref points into root's $defs.
+func (n *node) resolveRefs(root *node, at string) error {
+	if n == nil {
+		return nil
+	}
+	if n.ref != "" {
+		if _, err := root.lookup(n.ref); err != nil {
+			return &compileError{at: at, msg: err.Error()}
+		}
+	}
+	for name, c := range n.defs {
+		if err := c.resolveRefs(root, at+"/$defs/"+name); err != nil {
+			return err
+		}
+	}
+	for name, c := range n.properties {
+		if err := c.resolveRefs(root, at+"/properties/"+name); err != nil {
+			return err
+		}
+	}
+	for k, c := range map[string]*node{"additionalProperties": n.additionalProperties, "propertyNames": n.propertyNames, "items": n.items} {
+		if err := c.resolveRefs(root, at+"/"+k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *node) lookup(ref string) (*node, error) {
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+	def, ok := n.defs[unescapePointer(name)]
+	if !ok {
+		return nil, fmt.Errorf("unresolved $ref %q", ref)
+	}
+	return def, nil
+}
+
+// ValidateJSON decodes raw and validates it. The error is only set when raw
+// is not valid JSON.
+func (s *Schema) ValidateJSON(raw []byte) ([]Error, error) {
+	doc, err := decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	return s.Validate(doc), nil
+}
+
+// Validate validates a document decoded with encoding/json (numbers as
+// float64 or json.Number). Errors come in document order (object keys
+// sorted), so the result is deterministic.
+func (s *Schema) Validate(doc any) []Error {
+	v := validator{root: s.root}
+	v.validate(s.root, doc, "")
+	return v.errs
+}
+
+type validator struct {
+	root *node
+	errs []Error
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	v.errs = append(v.errs, Error{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate(n *node, doc any, path string) {
+	if n.always {
+		return
+	}
+	if n.never {
+		v.fail(path, "not allowed")
+		return
+	}
+	if n.ref != "" {
+		def, _ := v.root.lookup(n.ref) // checked by Compile
+		v.validate(def, doc, path)
+	}
+
+	if len(n.types) > 0 && !hasType(doc, n.types) {
+		v.fail(path, "expected %s, got %s", strings.Join(n.types, " or "), typeOf(doc))
+		return
+	}
+	if n.hasConst && !equal(doc, n.constant) {
+		v.fail(path, "must be %s", display(n.constant))
+	}
+	if len(n.enum) > 0 {
+		found := false
+		for _, e := range n.enum {
+			if equal(doc, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			opts := make([]string, len(n.enum))
+			for i, e := range n.enum {
+				opts[i] = display(e)
+			}
+			v.fail(path, "must be one of %s", strings.Join(opts, ", "))
+		}
+	}
+
+	switch d := doc.(type) {
+	case map[string]any:
+		v.validateObject(n, d, path)
+	case []any:
+		if n.minItems != nil && len(d) < *n.minItems {
+			v.fail(path, "must have at least %d items", *n.minItems)
+		}
+		if n.maxItems != nil && len(d) > *n.maxItems {
+			v.fail(path, "must have at most %d items", *n.maxItems)
+		}
+		if n.items != nil {
+			for i, it := range d {
+				v.validate(n.items, it, path+"/"+strconv.Itoa(i))
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(d)
+		if n.minLength != nil && length < *n.minLength {
+			if *n.minLength == 1 {
+				v.fail(path, "must not be empty")
+			} else {
+				v.fail(path, "must be at least %d characters", *n.minLength)
+			}
+		}
+		if n.maxLength != nil && length > *n.maxLength {
+			v.fail(path, "must be at most %d characters", *n.maxLength)
+		}
+		if n.pattern != nil && !n.pattern.MatchString(d) {
+			v.fail(path, "must match %s", n.pattern.String())
+		}
+	default:
+		if f, ok := number(doc); ok {
+			if n.minimum != nil && f < *n.minimum {
+				v.fail(path, "must be >= %s", strconv.FormatFloat(*n.minimum, 'f', -1, 64))
+			}
+			if n.maximum != nil && f > *n.maximum {
+				v.fail(path, "must be <= %s", strconv.FormatFloat(*n.maximum, 'f', -1, 64))
+			}
+		}
+	}
+}
+
+func (v *validator) validateObject(n *node, obj map[string]any, path string) {
+	for _, name := range n.required {
+		if _, ok := obj[name]; !ok {
+			v.fail(path+"/"+escapePointer(name), "is required")
+		}
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		at := path + "/" + escapePointer(k)
+		if n.propertyNames != nil {
+			before := len(v.errs)
+			v.validate(n.propertyNames, k, at)
+			if len(v.errs) > before {
+				// Report the key once, not what is wrong with it as a string.
+				v.errs = append(v.errs[:before], Error{Path: at, Message: "invalid key"})
+				continue
+			}
+		}
+		if p, ok := n.properties[k]; ok {
+			v.validate(p, obj[k], at)
+			continue
+		}
+		if n.additionalProperties == nil {
+			continue
+		}
+		if n.additionalProperties.never {
+			v.fail(at, "unknown field")
+			continue
+		}
+		v.validate(n.additionalProperties, obj[k], at)
+	}
+}
+
+func decode(raw []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	if _, ok := number(v); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func hasType(v any, types []string) bool {
+	actual := typeOf(v)
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		if t == "integer" && actual == "number" {
+			if f, _ := number(v); f == math.Trunc(f) && !math.IsInf(f, 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func equal(a, b any) bool {
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		return ok && fa == fb
+	}
+	switch x := a.(type) {
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, xv := range x {
+			yv, ok := y[k]
+			if !ok || !equal(xv, yv) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func display(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func unescapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+}
@@ -0,0 +1,108 @@
+package jsonschema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompile_RejectsUnsupportedSchemas(t *testing.T) {
+	for name, raw := range map[string]string{
+		"unknown keyword": `{"type":"object","oneOf":[]}`,
+		"nested keyword":  `{"properties":{"a":{"format":"email"}}}`,
+		"unknown type":    `{"type":"text"}`,
+		"unresolved ref":  `{"items":{"$ref":"#/$defs/missing"}}`,
+		"remote ref":      `{"$ref":"https://example.com/schema.json"}`,
+		"bad pattern":     `{"pattern":"("}`,
+		"not a schema":    `[]`,
+	} {
+		if _, err := Compile([]byte(raw)); err == nil {
+			t.Errorf("%s: expected a compile error", name)
+		}
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	s, err := Compile([]byte(`{
+		"type": "object",
+		"required": ["version", "items"],
+		"properties": {
+			"version": {"const": 2},
+			"count": {"type": "integer", "minimum": 0},
+			"items": {"type": "array", "maxItems": 2, "items": {"$ref": "#/$defs/item"}},
+			"labels": {
+				"type": "object",
+				"propertyNames": {"pattern": "^[a-z]{2}$"},
+				"additionalProperties": {"type": "string"}
+			}
+		},
+		"$defs": {
+			"item": {
+				"type": ["object", "string"],
+				"required": ["kind"],
+				"properties": {
+					"kind": {"enum": ["a", "b"]},
+					"name": {"type": "string", "minLength": 1}
+				},
+				"additionalProperties": false
+			}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	valid := `{"version": 2.0, "count": 3, "items": ["x", {"kind": "a", "name": "n"}], "labels": {"zh": "y"}, "extra": true}`
+	if errs, err := s.ValidateJSON([]byte(valid)); err != nil || len(errs) != 0 {
+		t.Fatalf("expected valid, got %v %v", errs, err)
+	}
+
+	errs, err := s.ValidateJSON([]byte(`{
+		"version": 3,
+		"count": 1.5,
+		"items": [{"kind": "c", "nmae": "", "name": ""}, {}, 7],
+		"labels": {"zh-hans": "y", "en": 1, "a/b": "z"}
+	}`))
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	got := map[string]string{}
+	for _, e := range errs {
+		got[e.Path] = e.Message
+	}
+	want := map[string]string{
+		"/version":        "must be 2",
+		"/count":          "expected integer, got number",
+		"/items":          "must have at most 2 items",
+		"/items/0/kind":   `must be one of "a", "b"`,
+		"/items/0/name":   "must not be empty",
+		"/items/0/nmae":   "unknown field",
+		"/items/1/kind":   "is required",
+		"/items/2":        "expected object or string, got number",
+		"/labels/zh-hans": "invalid key",
+		"/labels/en":      "expected string, got number",
+		"/labels/a~1b":    "invalid key",
+	}
+	for path, msg := range want {
+		if got[path] != msg {
+			t.Errorf("%s: expected %q, got %q", path, msg, got[path])
+		}
+	}
+	if len(errs) != len(want) {
+		t.Errorf("expected %d errors, got %v", len(want), errs)
+	}
+
+	// Document order: the array items come in index order.
+	var order []string
+	for _, e := range errs {
+		if strings.HasPrefix(e.Path, "/items/") {
+			order = append(order, e.Path[:len("/items/0")])
+		}
+	}
+	if strings.Join(order, ",") != "/items/0,/items/0,/items/0,/items/1,/items/2" {
+		t.Errorf("unexpected error order: %v", order)
+	}
+
+	if _, err := s.ValidateJSON([]byte(`{"version": 2} {}`)); err == nil {
+		t.Fatalf("expected trailing data to be rejected")
+	}
+}
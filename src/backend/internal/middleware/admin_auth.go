@@ -15,6 +15,9 @@ import (
 
 const ContextUserKey = "auth.user"
 
+// ContextSessionIDKey holds the AdminSession.SessionID ("sid") of the access token, if any.
+const ContextSessionIDKey = "auth.session_id"
+
 func AdminAuth(db *gorm.DB, jwtSvc *auth.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if db == nil || jwtSvc == nil {
@@ -59,7 +62,8 @@ func AdminAuth(db *gorm.DB, jwtSvc *auth.Service) gin.HandlerFunc {
 			})
 			return
 		}
-		if user.Role != "admin" || user.Status != "active" {
+		// A login lock ("locked") only blocks password guessing; existing sessions keep working.
+		if !model.IsKnownRole(user.Role) || (user.Status != "active" && user.Status != "locked") {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
 				"code":    "forbidden",
 				"message": "forbidden",
@@ -104,12 +108,94 @@ func AdminAuth(db *gorm.DB, jwtSvc *auth.Service) gin.HandlerFunc {
 			}
 		}
 
+		// Revoked via token version bump (user disabled/deleted).
+		if claims.TokenVersion != user.TokenVersion {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":    "unauthorized",
+				"message": "unauthorized",
+				"error":   "unauthorized",
+			})
+			return
+		}
+
+		// Session revoked (logout / remote logout / refresh reuse). Access tokens issued
+		// before sessions existed carry no sid and simply run out.
+		if sid := strings.TrimSpace(claims.SessionID); sid != "" {
+			var active int64
+			if err := db.WithContext(c.Request.Context()).Model(&model.AdminSession{}).
+				Where("session_id = ? AND user_id = ? AND revoked_at IS NULL", sid, user.ID).
+				Count(&active).Error; err != nil || active == 0 {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"code":    "unauthorized",
+					"message": "unauthorized",
+					"error":   "unauthorized",
+				})
+				return
+			}
+			c.Set(ContextSessionIDKey, sid)
+		}
+
 		c.Set(ContextUserKey, user)
 		EnrichLoggerWithAdmin(c, user)
 		c.Next()
 	}
 }
 
+// RequirePermission rejects requests whose authenticated user lacks perm.
+//
+// It must run after AdminAuth, which stores the user under ContextUserKey.
+func RequirePermission(perm model.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, ok := c.Get(ContextUserKey)
+		user, _ := v.(model.User)
+		if !ok || user.ID == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":    "unauthorized",
+				"message": "unauthorized",
+				"error":   "unauthorized",
+			})
+			return
+		}
+		if !model.RoleHasPermission(user.Role, perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"code":    "forbidden",
+				"message": "forbidden",
+				"error":   "forbidden",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireTwoFactor rejects users who have not enrolled in TOTP 2FA.
+//
+// It is installed when 2FA is enforced by config, after the routes a user needs
+// to enroll (/me, /me/2fa/*) so those stay reachable.
+func RequireTwoFactor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, ok := c.Get(ContextUserKey)
+		user, _ := v.(model.User)
+		if !ok || user.ID == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":    "unauthorized",
+				"message": "unauthorized",
+				"error":   "unauthorized",
+			})
+			return
+		}
+		if !user.TOTPEnabled {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"code":    "mfa_enrollment_required",
+				"message": "two-factor authentication must be enabled",
+				"error":   "two-factor authentication must be enabled",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
 func tokenFromRequest(c *gin.Context) string {
 	if c == nil {
 		return ""
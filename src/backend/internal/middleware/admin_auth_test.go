@@ -158,6 +158,52 @@ func TestAdminAuth_ErrorsAndSuccess(t *testing.T) {
 	})
 }
 
+func TestRequirePermission_ByRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		role string
+		perm model.Permission
+		want int
+	}{
+		{model.RoleOwner, model.PermSettingsWrite, http.StatusOK},
+		{model.RoleAdmin, model.PermSettingsWrite, http.StatusOK},
+		{model.RoleEditor, model.PermProductsWrite, http.StatusOK},
+		{model.RoleEditor, model.PermSettingsWrite, http.StatusForbidden},
+		{model.RoleSales, model.PermContactsWrite, http.StatusOK},
+		{model.RoleSales, model.PermProductsRead, http.StatusForbidden},
+		{model.RoleViewer, model.PermProductsRead, http.StatusOK},
+		{model.RoleViewer, model.PermProductsWrite, http.StatusForbidden},
+		{model.RoleViewer, model.PermContactsRead, http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.role+"/"+string(tc.perm), func(t *testing.T) {
+			r := gin.New()
+			r.GET("/p", func(c *gin.Context) {
+				c.Set(ContextUserKey, model.User{ID: 1, Role: tc.role, Status: "active"})
+				c.Next()
+			}, RequirePermission(tc.perm), func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/p", nil))
+			if w.Code != tc.want {
+				t.Fatalf("expected %d got %d: %s", tc.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("missing user", func(t *testing.T) {
+		r := gin.New()
+		r.GET("/p", RequirePermission(model.PermProductsRead), func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/p", nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected %d got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+}
+
 func openTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
 
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// AdminSession is one signed-in device of a backoffice user.
+//
+// SessionID is embedded into every token of the session (JWT "sid"). The session
+// owns a single refresh-token family: RefreshJTI is the jti of the only refresh
+// token that may be exchanged next. Presenting an older refresh token of the same
+// family means it leaked, so the whole session is revoked.
+type AdminSession struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	UserID     uint   `gorm:"not null;index" json:"userId"`
+	SessionID  string `gorm:"type:text;not null;uniqueIndex" json:"-"`
+	RefreshJTI string `gorm:"type:text;not null" json:"-"`
+
+	UserAgent string `gorm:"type:text;not null;default:''" json:"userAgent"`
+	IP        string `gorm:"type:text;not null;default:''" json:"ip"`
+
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt time.Time  `json:"lastUsedAt"`
+	ExpiresAt  time.Time  `gorm:"not null;index" json:"expiresAt"`
+	RevokedAt  *time.Time `gorm:"index" json:"revokedAt,omitempty"`
+	// RevokeReason: logout|revoked|refresh_reuse|password_changed|user_disabled
+	RevokeReason string `gorm:"type:text;not null;default:''" json:"revokeReason,omitempty"`
+}
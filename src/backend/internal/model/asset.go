@@ -0,0 +1,232 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Asset is one object stored in the bucket by the backoffice (an upload
+// rendition). Variants generated on demand are caches and are not registered.
+type Asset struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ObjectKey string `gorm:"type:text;uniqueIndex;not null" json:"objectKey"`
+
+	// ProductID is the owning product: the product whose styleNo the object was
+	// uploaded for, or the first product that referenced it.
+	ProductID *uint  `gorm:"index" json:"productId,omitempty"`
+	StyleNo   string `gorm:"type:text;not null;default:'';index;index:idx_assets_style_source,priority:1" json:"styleNo"`
+	Kind      string `gorm:"type:text;not null;default:''" json:"kind"` // cover|hover|gallery
+
+	ContentType string `gorm:"type:text;not null;default:''" json:"contentType"`
+	Size        int64  `gorm:"not null;default:0" json:"size"`
+	SHA256      string `gorm:"column:sha256;type:text;not null;default:'';index" json:"sha256"`
+	// SourceSHA256 is the hash of the uploaded file the rendition was made
+	// from, shared by all renditions of one upload. Uploads are deduplicated
+	// on (StyleNo, SourceSHA256); empty for backfilled rows.
+	SourceSHA256 string `gorm:"column:source_sha256;type:text;not null;default:'';index:idx_assets_style_source,priority:2" json:"sourceSha256,omitempty"`
+	Width        int    `gorm:"not null;default:0" json:"width"`
+	Height       int    `gorm:"not null;default:0" json:"height"`
+	// Blurhash and DominantColor (#rrggbb) are computed from the uploaded
+	// image and shared by all its renditions, so clients can show a
+	// placeholder while it loads. Empty for rows registered without probing.
+	Blurhash      string `gorm:"type:text;not null;default:''" json:"blurhash,omitempty"`
+	DominantColor string `gorm:"type:text;not null;default:''" json:"dominantColor,omitempty"`
+
+	// UploadedBy is the admin user id; 0 for backfilled rows or when admin auth is disabled.
+	UploadedBy uint `gorm:"not null;default:0" json:"uploadedBy"`
+
+	// ReusedAt is when a deduplicated upload last handed this object out
+	// again. The GC grace period also counts from it, since the product
+	// referencing it may not be saved yet.
+	ReusedAt *time.Time `gorm:"index" json:"reusedAt,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AssetDeletion schedules an object for deletion, e.g. the old keys of a
+// product whose styleNo was renamed. The assets GC removes it once DueAt has
+// passed and nothing restorable references it any more.
+type AssetDeletion struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ObjectKey string    `gorm:"type:text;uniqueIndex;not null" json:"objectKey"`
+	Reason    string    `gorm:"type:text;not null;default:''" json:"reason"`
+	DueAt     time.Time `gorm:"not null;index" json:"dueAt"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Product asset reference roles.
+const (
+	AssetRoleCover   = "cover"
+	AssetRoleHover   = "hover"
+	AssetRoleDetail  = "detail" // anywhere inside DetailJSON (gallery, sections, options...)
+	AssetRoleVariant = "variant"
+)
+
+// ProductAsset records that a product references an object key. Rows are
+// rewritten on every product save, and kept for soft-deleted products so a
+// restore needs no rebuild. Asset authorization joins through this table.
+type ProductAsset struct {
+	ProductID uint   `gorm:"primaryKey;autoIncrement:false" json:"productId"`
+	ObjectKey string `gorm:"type:text;primaryKey;index" json:"objectKey"`
+	Role      string `gorm:"type:text;primaryKey" json:"role"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ProductAssetRefs lists the object keys a product references, de-duplicated
+// per role and sorted. Keys are recognized both bare ("products/...") and
+// inside URLs such as /api/v1/assets/products/...?w=640.
+func ProductAssetRefs(p Product) []ProductAsset {
+	seen := map[ProductAsset]bool{}
+	add := func(role, s string) {
+		if key := AssetKeyFromString(s); key != "" {
+			seen[ProductAsset{ProductID: p.ID, ObjectKey: key, Role: role}] = true
+		}
+	}
+
+	add(AssetRoleCover, p.CoverImageKey)
+	add(AssetRoleCover, p.CoverImageURL)
+	add(AssetRoleHover, p.HoverImageKey)
+	add(AssetRoleHover, p.HoverImageURL)
+
+	if len(p.DetailJSON) > 0 {
+		var detail any
+		if err := json.Unmarshal(p.DetailJSON, &detail); err == nil {
+			walkJSONStrings(detail, func(s string) { add(AssetRoleDetail, s) })
+		}
+	}
+
+	refs := make([]ProductAsset, 0, len(seen))
+	for r := range seen {
+		refs = append(refs, r)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].ObjectKey != refs[j].ObjectKey {
+			return refs[i].ObjectKey < refs[j].ObjectKey
+		}
+		return refs[i].Role < refs[j].Role
+	})
+	return refs
+}
+
+// VariantAssetRefs lists the image keys of a product's variants.
+func VariantAssetRefs(productID uint, variants []ProductVariant) []ProductAsset {
+	seen := map[string]bool{}
+	var refs []ProductAsset
+	for _, v := range variants {
+		for _, k := range v.DecodeImageKeys() {
+			if key := AssetKeyFromString(k); key != "" && !seen[key] {
+				seen[key] = true
+				refs = append(refs, ProductAsset{ProductID: productID, ObjectKey: key, Role: AssetRoleVariant})
+			}
+		}
+	}
+	return refs
+}
+
+// AssetKeyFromString extracts a product object key from a bare key or a URL
+// that embeds one. It returns "" when s holds none.
+func AssetKeyFromString(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "products/") {
+		// URLs: /api/v1/assets/products/..., https://cdn/{bucket}/products/...
+		if !strings.HasPrefix(s, "/") && !strings.Contains(s, "://") {
+			return ""
+		}
+		i := strings.Index(s, "/products/")
+		if i < 0 {
+			return ""
+		}
+		s = s[i+1:]
+		if j := strings.IndexAny(s, "?#"); j >= 0 {
+			s = s[:j]
+		}
+	}
+	if strings.Contains(s, "..") || strings.ContainsAny(s, " \t\r\n\\\x00") ||
+		strings.HasSuffix(s, "/") || len(s) <= len("products/") {
+		return ""
+	}
+	return s
+}
+
+// RewriteAssetKeys replaces every object key p references (see
+// ProductAssetRefs) for which rename returns ok, in the cover/hover keys and
+// URLs and in every string inside DetailJSON. URLs keep their surroundings
+// (host, query). It reports whether anything changed.
+func RewriteAssetKeys(p *Product, rename func(key string) (string, bool)) (bool, error) {
+	rewrites := 0
+	rewrite := func(s string) string {
+		key := AssetKeyFromString(s)
+		if key == "" {
+			return s
+		}
+		to, ok := rename(key)
+		if !ok || to == key {
+			return s
+		}
+		rewrites++
+		return strings.Replace(s, key, to, 1)
+	}
+
+	p.CoverImageKey = rewrite(p.CoverImageKey)
+	p.CoverImageURL = rewrite(p.CoverImageURL)
+	p.HoverImageKey = rewrite(p.HoverImageKey)
+	p.HoverImageURL = rewrite(p.HoverImageURL)
+
+	if len(p.DetailJSON) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(p.DetailJSON))
+		dec.UseNumber()
+		var detail any
+		if err := dec.Decode(&detail); err != nil {
+			return rewrites > 0, err
+		}
+		n := rewrites
+		detail = mapJSONStrings(detail, rewrite)
+		if rewrites > n {
+			b, err := json.Marshal(detail)
+			if err != nil {
+				return true, err
+			}
+			p.DetailJSON = b
+		}
+	}
+	return rewrites > 0, nil
+}
+
+// mapJSONStrings returns v with every string replaced by fn(string).
+func mapJSONStrings(v any, fn func(string) string) any {
+	switch t := v.(type) {
+	case string:
+		return fn(t)
+	case []any:
+		for i, item := range t {
+			t[i] = mapJSONStrings(item, fn)
+		}
+	case map[string]any:
+		for k, item := range t {
+			t[k] = mapJSONStrings(item, fn)
+		}
+	}
+	return v
+}
+
+func walkJSONStrings(v any, fn func(string)) {
+	switch t := v.(type) {
+	case string:
+		fn(t)
+	case []any:
+		for _, item := range t {
+			walkJSONStrings(item, fn)
+		}
+	case map[string]any:
+		for _, item := range t {
+			walkJSONStrings(item, fn)
+		}
+	}
+}
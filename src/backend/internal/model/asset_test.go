@@ -0,0 +1,86 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestProductAssetRefs(t *testing.T) {
+	p := Product{
+		ID:            7,
+		CoverImageKey: "products/1001/cover/2025/09/01/a/w2048.webp",
+		CoverImageURL: "/api/v1/assets/products/1001/cover/2025/09/01/a/w2048.webp",
+		HoverImageURL: "https://cdn.example.com/bucket/products/1001/hover/2025/09/01/b.webp?x=1",
+		DetailJSON: json.RawMessage(`{
+			"gallery": [
+				{"url": "/api/v1/assets/products/1001/gallery/2025/09/01/c/w1280.webp?w=640", "objectKey": "products/1001/gallery/2025/09/01/c/w1280.webp"},
+				{"url": "https://example.com/not-ours.jpg"}
+			],
+			"sections": [{"data": {"image": "products/1001/gallery/2025/09/01/d.webp"}}],
+			"title_i18n": {"en": "products/ is not a key"}
+		}`),
+	}
+
+	got := ProductAssetRefs(p)
+	want := []ProductAsset{
+		{ProductID: 7, ObjectKey: "products/1001/cover/2025/09/01/a/w2048.webp", Role: AssetRoleCover},
+		{ProductID: 7, ObjectKey: "products/1001/gallery/2025/09/01/c/w1280.webp", Role: AssetRoleDetail},
+		{ProductID: 7, ObjectKey: "products/1001/gallery/2025/09/01/d.webp", Role: AssetRoleDetail},
+		{ProductID: 7, ObjectKey: "products/1001/hover/2025/09/01/b.webp", Role: AssetRoleHover},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d refs, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ref %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAssetKeyFromString(t *testing.T) {
+	cases := map[string]string{
+		"products/1/cover/x.webp":                        "products/1/cover/x.webp",
+		"/api/v1/admin/assets/products/1/cover/x.webp#a": "products/1/cover/x.webp",
+		"products/":                   "",
+		"products/../etc/passwd":      "",
+		"see products/1/cover/x.webp": "",
+		"":                            "",
+	}
+	for in, want := range cases {
+		if got := AssetKeyFromString(in); got != want {
+			t.Errorf("AssetKeyFromString(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRewriteAssetKeys(t *testing.T) {
+	p := Product{
+		CoverImageKey: "products/1001/cover/a/w2048.webp",
+		CoverImageURL: "https://cdn.example.com/bucket/products/1001/cover/a/w2048.webp?x=1",
+		HoverImageKey: "products/2002/hover/b.webp",
+		DetailJSON:    json.RawMessage(`{"gallery":[{"url":"/api/v1/assets/products/1001/gallery/c/w1280.webp?w=640","rank":1.50}],"title":"products/1001 is not a key"}`),
+	}
+	changed, err := RewriteAssetKeys(&p, func(key string) (string, bool) {
+		rest, ok := strings.CutPrefix(key, "products/1001/")
+		return "products/AB-1/" + rest, ok
+	})
+	if err != nil || !changed {
+		t.Fatalf("RewriteAssetKeys: changed=%v err=%v", changed, err)
+	}
+	if p.CoverImageKey != "products/AB-1/cover/a/w2048.webp" ||
+		p.CoverImageURL != "https://cdn.example.com/bucket/products/AB-1/cover/a/w2048.webp?x=1" ||
+		p.HoverImageKey != "products/2002/hover/b.webp" {
+		t.Fatalf("unexpected keys: %+v", p)
+	}
+	want := `{"gallery":[{"rank":1.50,"url":"/api/v1/assets/products/AB-1/gallery/c/w1280.webp?w=640"}],"title":"products/1001 is not a key"}`
+	if string(p.DetailJSON) != want {
+		t.Fatalf("detail = %s", p.DetailJSON)
+	}
+
+	changed, err = RewriteAssetKeys(&p, func(string) (string, bool) { return "", false })
+	if err != nil || changed {
+		t.Fatalf("expected no change, got changed=%v err=%v", changed, err)
+	}
+}
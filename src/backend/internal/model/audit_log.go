@@ -0,0 +1,120 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Audited entity types.
+const (
+	AuditEntityProduct          = "product"
+	AuditEntityUpdate           = "update"
+	AuditEntityContact          = "contact"
+	AuditEntityEvent            = "event"
+	AuditEntitySetting          = "setting"
+	AuditEntityUser             = "user"
+	AuditEntityTemplateApplyJob = "template_apply_job"
+)
+
+// AuditLog records one backoffice mutation: who did what to which entity.
+//
+// Rows are append-only. Diff holds only the top-level fields that changed:
+// {"before": {...}, "after": {...}}. Creations have an empty before side and
+// deletions an empty after side.
+type AuditLog struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// ActorID is 0 when admin auth is disabled (no user in context).
+	ActorID    uint   `gorm:"not null;default:0;index" json:"actorId"`
+	ActorEmail string `gorm:"type:text;not null;default:''" json:"actorEmail"`
+
+	Action     string `gorm:"type:text;not null;index" json:"action"` // e.g. product.publish
+	EntityType string `gorm:"type:text;not null;index:idx_audit_logs_entity" json:"entityType"`
+	// EntityID is text so non-numeric keys (settings) fit as well.
+	EntityID string `gorm:"type:text;not null;default:'';index:idx_audit_logs_entity" json:"entityId"`
+
+	Diff json.RawMessage `gorm:"type:jsonb" json:"diff"`
+
+	RequestID string `gorm:"type:text;not null;default:''" json:"requestId"`
+	IP        string `gorm:"type:text;not null;default:''" json:"ip"`
+
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+}
+
+// auditIgnoredFields are bookkeeping columns that change on every write.
+var auditIgnoredFields = map[string]bool{"updatedAt": true}
+
+// AuditDiff compares the JSON forms of before and after and returns the changed
+// top-level fields as {"before": {...}, "after": {...}}. Either side may be nil.
+func AuditDiff(before, after any) (json.RawMessage, error) {
+	b, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	out := struct {
+		Before map[string]json.RawMessage `json:"before"`
+		After  map[string]json.RawMessage `json:"after"`
+	}{Before: map[string]json.RawMessage{}, After: map[string]json.RawMessage{}}
+
+	for k, bv := range b {
+		if auditIgnoredFields[k] {
+			continue
+		}
+		av, ok := a[k]
+		if ok && jsonEqual(av, bv) {
+			continue
+		}
+		out.Before[k] = bv
+		if ok {
+			out.After[k] = av
+		}
+	}
+	for k, av := range a {
+		if auditIgnoredFields[k] {
+			continue
+		}
+		if _, ok := b[k]; !ok {
+			out.After[k] = av
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+func auditFields(v any) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// jsonEqual compares two JSON values semantically (key order and spacing ignored).
+func jsonEqual(a, b json.RawMessage) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var av, bv any
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return false
+	}
+	ca, _ := json.Marshal(av)
+	cb, _ := json.Marshal(bv)
+	return bytes.Equal(ca, cb)
+}
@@ -23,3 +23,25 @@ type ContactLead struct {
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
+
+// AuditSummary is what the audit log keeps of a deleted lead: enough to tell
+// which lead it was, without the visitor's name, contact details or message.
+func (l ContactLead) AuditSummary() map[string]any {
+	redacted := func(v string) string {
+		if v == "" {
+			return ""
+		}
+		return "[redacted]"
+	}
+	return map[string]any{
+		"id":         l.ID,
+		"status":     l.Status,
+		"sourcePage": l.SourcePage,
+		"utmSource":  l.UTMSource,
+		"name":       redacted(l.Name),
+		"phone":      redacted(l.Phone),
+		"wechat":     redacted(l.Wechat),
+		"message":    redacted(l.Message),
+		"createdAt":  l.CreatedAt,
+	}
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONChange is one difference between two JSON documents.
+//
+// Path is a JSON Pointer (RFC 6901). Op is add|remove|replace; From is absent
+// for add and To is absent for remove.
+type JSONChange struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	From any    `json:"from,omitempty"`
+	To   any    `json:"to,omitempty"`
+}
+
+// JSONDiff lists the changes that turn document a into document b.
+//
+// Objects are compared key by key (in sorted order) and arrays index by index,
+// so an insertion in the middle of an array shows up as a series of replaces
+// plus a trailing add. Empty or null input is treated as null.
+func JSONDiff(a, b json.RawMessage) ([]JSONChange, error) {
+	av, err := decodeJSONValue(a)
+	if err != nil {
+		return nil, err
+	}
+	bv, err := decodeJSONValue(b)
+	if err != nil {
+		return nil, err
+	}
+	changes := []JSONChange{}
+	diffJSONValues("", av, bv, &changes)
+	return changes, nil
+}
+
+func decodeJSONValue(raw json.RawMessage) (any, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func diffJSONValues(path string, a, b any, out *[]JSONChange) {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, seen := av[k]; !seen {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := path + "/" + escapeJSONPointer(k)
+			x, inA := av[k]
+			y, inB := bv[k]
+			switch {
+			case !inB:
+				*out = append(*out, JSONChange{Op: "remove", Path: p, From: x})
+			case !inA:
+				*out = append(*out, JSONChange{Op: "add", Path: p, To: y})
+			default:
+				diffJSONValues(p, x, y, out)
+			}
+		}
+		return
+	case []any:
+		bv, ok := b.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < len(av) || i < len(bv); i++ {
+			p := path + "/" + strconv.Itoa(i)
+			switch {
+			case i >= len(bv):
+				*out = append(*out, JSONChange{Op: "remove", Path: p, From: av[i]})
+			case i >= len(av):
+				*out = append(*out, JSONChange{Op: "add", Path: p, To: bv[i]})
+			default:
+				diffJSONValues(p, av[i], bv[i], out)
+			}
+		}
+		return
+	}
+
+	if !jsonValuesEqual(a, b) {
+		*out = append(*out, JSONChange{Op: "replace", Path: path, From: a, To: b})
+	}
+}
+
+func jsonValuesEqual(a, b any) bool {
+	ab, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(ab) == string(bb)
+}
+
+func escapeJSONPointer(s string) string {
+	s = strings.ReplaceAll(s, "~", "~0")
+	return strings.ReplaceAll(s, "/", "~1")
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONDiff(t *testing.T) {
+	a := json.RawMessage(`{"specs":[{"key":"pieces","value":"1"}],"title":"A","a/b":1,"gone":true}`)
+	b := json.RawMessage(`{"specs":[{"key":"pieces","value":"2"},{"key":"lead_time"}],"title":"A","a/b":2,"new":null}`)
+
+	got, err := JSONDiff(a, b)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	want := []JSONChange{
+		{Op: "replace", Path: "/a~1b", From: float64(1), To: float64(2)},
+		{Op: "remove", Path: "/gone", From: true},
+		{Op: "add", Path: "/new"},
+		{Op: "replace", Path: "/specs/0/value", From: "1", To: "2"},
+		{Op: "add", Path: "/specs/1", To: map[string]any{"key": "lead_time"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected diff:\n got %#v\nwant %#v", got, want)
+	}
+
+	same, err := JSONDiff(a, json.RawMessage(`{"gone":true,"a/b":1,"title":"A","specs":[{"value":"1","key":"pieces"}]}`))
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if len(same) != 0 {
+		t.Fatalf("expected no changes for reordered keys, got %#v", same)
+	}
+}
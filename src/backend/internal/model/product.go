@@ -35,6 +35,10 @@ type Product struct {
 	DetailJSON json.RawMessage `gorm:"type:jsonb" json:"detail"`
 
 	PublishedAt *time.Time `gorm:"index" json:"publishedAt,omitempty"`
+	// PublishAt/UnpublishAt schedule a future transition; the scheduler applies
+	// them once due and clears the field (see internal/scheduler).
+	PublishAt   *time.Time `gorm:"index" json:"publishAt,omitempty"`
+	UnpublishAt *time.Time `gorm:"index" json:"unpublishAt,omitempty"`
 
 	CreatedAt time.Time  `json:"createdAt"`
 	UpdatedAt time.Time  `json:"updatedAt"`
@@ -0,0 +1,500 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// DetailMigration upgrades a detail document, in place, from schema_version
+// From to From+1.
+type DetailMigration struct {
+	From int
+	Name string
+	Up   func(doc map[string]any) error
+}
+
+// detailMigrations are applied in order; each one starts at the version the
+// previous one ends at, and the last one ends at ProductDetailSchemaVersion.
+// Add new migrations at the end, together with the bump of the constant and
+// of product_detail_schema.json.
+var detailMigrations = []DetailMigration{
+	{From: 1, Name: "v1_to_v2_i18n_sections", Up: migrateDetailV1ToV2},
+}
+
+var errDetailVersion = errors.New("unsupported schema_version")
+
+// DetailMigrationResult describes what MigrateProductDetail did.
+type DetailMigrationResult struct {
+	From    int      `json:"from"`
+	To      int      `json:"to"`
+	Applied []string `json:"applied"`
+}
+
+// Changed reports whether a migration was applied.
+func (r DetailMigrationResult) Changed() bool { return len(r.Applied) > 0 }
+
+// DetailSchemaVersionOf returns the schema_version of a decoded detail
+// document. Documents written before the field existed are version 1.
+func DetailSchemaVersionOf(doc map[string]any) (int, error) {
+	raw, ok := doc["schema_version"]
+	if !ok {
+		return 1, nil
+	}
+	var f float64
+	switch v := raw.(type) {
+	case json.Number:
+		var err error
+		if f, err = v.Float64(); err != nil {
+			return 0, errDetailVersion
+		}
+	case float64:
+		f = v
+	default:
+		return 0, errDetailVersion
+	}
+	if f != float64(int(f)) || f < 1 {
+		return 0, errDetailVersion
+	}
+	return int(f), nil
+}
+
+// MigrateProductDetail upgrades raw to ProductDetailSchemaVersion by applying
+// the pending migrations in order. Up-to-date and empty documents are
+// returned as they are. Documents from a newer version, or with an invalid
+// version, are an error.
+func MigrateProductDetail(raw json.RawMessage) (json.RawMessage, DetailMigrationResult, error) {
+	res := DetailMigrationResult{From: ProductDetailSchemaVersion, To: ProductDetailSchemaVersion}
+	if len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return raw, res, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, res, err
+	}
+	doc, ok := v.(map[string]any)
+	if !ok {
+		return nil, res, errDetailNotObject
+	}
+	version, err := DetailSchemaVersionOf(doc)
+	if err != nil {
+		return nil, res, err
+	}
+	res.From, res.To = version, version
+	if version > ProductDetailSchemaVersion {
+		return nil, res, fmt.Errorf("%w %d (newer than %d)", errDetailVersion, version, ProductDetailSchemaVersion)
+	}
+	if version == ProductDetailSchemaVersion {
+		return raw, res, nil
+	}
+
+	for _, m := range detailMigrations {
+		if m.From != res.To {
+			continue
+		}
+		if err := m.Up(doc); err != nil {
+			return nil, res, fmt.Errorf("%s: %w", m.Name, err)
+		}
+		res.To = m.From + 1
+		res.Applied = append(res.Applied, m.Name)
+		doc["schema_version"] = res.To
+	}
+	if res.To != ProductDetailSchemaVersion {
+		return nil, res, fmt.Errorf("no migration from schema_version %d", res.To)
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, res, err
+	}
+	return out, res, nil
+}
+
+// UpgradeDetail is MigrateProductDetail for readers, so they only ever see
+// current documents. It is best effort: a document that cannot be migrated
+// is returned as it is.
+func UpgradeDetail(raw json.RawMessage) json.RawMessage {
+	out, res, err := MigrateProductDetail(raw)
+	if err != nil || !res.Changed() {
+		return raw
+	}
+	return out
+}
+
+// UpgradeProductDetail applies UpgradeDetail to p.DetailJSON in memory.
+func UpgradeProductDetail(p *Product) {
+	p.DetailJSON = UpgradeDetail(p.DetailJSON)
+}
+
+// Version 1 → 2
+//
+// Version 1 documents are free-form: spec rows and option groups name
+// themselves with k/label/key/name and name/title/label, values are plain
+// strings, gallery entries may be bare URLs and there is no page layout.
+// Version 2 (the admin editor format) uses a stable key plus i18n maps
+// everywhere and has sections. The rules below mirror the conversion the
+// editor applied client-side (admin/productDetail/detail.ts), except that the
+// legacy aliases are dropped once converted.
+
+var (
+	specLabelMap = map[string]struct{ key, en string }{
+		"件数":   {"pieces", "Pieces"},
+		"交付时间": {"lead_time", "Lead Time"},
+		"交期":   {"lead_time", "Lead Time"},
+	}
+	optionGroupNameMap = map[string]struct{ key, en string }{
+		"颜色": {"color", "Color"},
+		"尺码": {"size", "Size"},
+	}
+	detailLocaleRe = regexp.MustCompile(`^[a-z]{2}(-[A-Za-z]{2,4})?$`)
+)
+
+func migrateDetailV1ToV2(doc map[string]any) error {
+	if v, ok := doc["gallery"]; ok {
+		doc["gallery"] = migrateGalleryV2(v)
+	} else {
+		doc["gallery"] = []any{}
+	}
+	doc["specs"] = migrateSpecsV2(doc["specs"])
+	doc["option_groups"] = migrateOptionGroupsV2(doc["option_groups"])
+
+	for _, k := range []string{"title_i18n", "description_i18n"} {
+		if v, ok := doc[k]; ok {
+			if m := i18nOf(v); m != nil {
+				doc[k] = m
+			} else {
+				delete(doc, k)
+			}
+		}
+	}
+
+	sections := migrateSectionsV2(doc["sections"])
+	if len(sections) == 0 {
+		sections = defaultDetailSections()
+		// Seed the overview block with the legacy description.
+		for _, k := range []string{"description_i18n", "description", "desc_i18n", "desc"} {
+			text := i18nOf(doc[k])
+			if s, ok := doc[k].(string); ok && strings.TrimSpace(s) != "" {
+				text = map[string]any{"zh": s, "en": ""}
+			}
+			if text == nil {
+				continue
+			}
+			for _, s := range sections {
+				if sec := s.(map[string]any); sec["type"] == "richText" {
+					sec["data"] = map[string]any{"text_i18n": text}
+					break
+				}
+			}
+			break
+		}
+	}
+	doc["sections"] = sections
+	return nil
+}
+
+func defaultDetailSections() []any {
+	var tmpl map[string]any
+	_ = json.Unmarshal(DefaultProductDetailTemplate(), &tmpl)
+	sections, _ := tmpl["sections"].([]any)
+	return sections
+}
+
+func migrateGalleryV2(raw any) []any {
+	arr, _ := raw.([]any)
+	out := make([]any, 0, len(arr))
+	for _, it := range arr {
+		switch v := it.(type) {
+		case string:
+			if url := strings.TrimSpace(v); url != "" {
+				out = append(out, map[string]any{"url": url})
+			}
+		case map[string]any:
+			item := map[string]any{"url": textOf(v["url"])}
+			if item["url"] == "" {
+				item["url"] = textOf(v["src"])
+			}
+			if key := textOf(v["objectKey"]); key != "" {
+				item["objectKey"] = key
+			}
+			if id := textOf(v["id"]); id != "" {
+				item["id"] = id
+			}
+			alt := i18nOf(v["alt_i18n"])
+			if s := textOf(v["alt"]); alt == nil && s != "" {
+				alt = map[string]any{"zh": s, "en": s}
+			}
+			if alt != nil {
+				item["alt_i18n"] = alt
+			}
+			if item["url"] != "" || item["objectKey"] != nil {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
+
+func migrateSpecsV2(raw any) []any {
+	arr, _ := raw.([]any)
+	out := make([]any, 0, len(arr))
+	used := map[string]bool{}
+	for _, it := range arr {
+		obj, ok := it.(map[string]any)
+		if !ok {
+			continue
+		}
+		legacyLabel := firstText(obj, "k", "label", "name", "key")
+		legacyValue := firstText(obj, "v", "value", "val")
+		mapped, isMapped := specLabelMap[legacyLabel]
+
+		label := i18nOrEmpty(obj["label_i18n"])
+		value := i18nOrEmpty(obj["value_i18n"])
+		if label["zh"] == nil && legacyLabel != "" {
+			label["zh"] = legacyLabel
+		}
+		if label["en"] == nil {
+			if isMapped {
+				label["en"] = mapped.en
+			} else if legacyLabel != "" && !hasHan(legacyLabel) {
+				label["en"] = legacyLabel
+			}
+		}
+		if value["zh"] == nil && legacyValue != "" {
+			value["zh"] = legacyValue
+		}
+		if value["en"] == nil && legacyValue != "" && !hasHan(legacyValue) {
+			value["en"] = legacyValue
+		}
+
+		key := textOf(obj["key"])
+		if key == "" && isMapped {
+			key = mapped.key
+		}
+		if key == "" {
+			key = legacyLabel
+		}
+		out = append(out, map[string]any{
+			"key":        dedupeDetailKey(used, key, "spec"),
+			"label_i18n": label,
+			"value_i18n": value,
+		})
+	}
+	return out
+}
+
+func migrateOptionGroupsV2(raw any) []any {
+	arr, _ := raw.([]any)
+	out := make([]any, 0, len(arr))
+	used := map[string]bool{}
+	for _, it := range arr {
+		obj, ok := it.(map[string]any)
+		if !ok {
+			continue
+		}
+		legacyName := firstText(obj, "name", "title", "label")
+		mapped, isMapped := optionGroupNameMap[legacyName]
+
+		name := i18nOrEmpty(obj["name_i18n"])
+		if name["zh"] == nil && legacyName != "" {
+			name["zh"] = legacyName
+		}
+		if name["en"] == nil {
+			if isMapped {
+				name["en"] = mapped.en
+			} else if legacyName != "" && !hasHan(legacyName) {
+				name["en"] = legacyName
+			}
+		}
+		key := firstText(obj, "key", "id")
+		if key == "" && isMapped {
+			key = mapped.key
+		}
+		if key == "" {
+			key = legacyName
+		}
+
+		opts, _ := obj["options"].([]any)
+		options := make([]any, 0, len(opts))
+		usedOpts := map[string]bool{}
+		for _, o := range opts {
+			var optKey, legacyLabel string
+			label := map[string]any{}
+			switch v := o.(type) {
+			case string:
+				legacyLabel = strings.TrimSpace(v)
+				if legacyLabel == "" {
+					continue
+				}
+				optKey = legacyLabel
+			case map[string]any:
+				legacyLabel = firstText(v, "label", "name", "value")
+				label = i18nOrEmpty(v["label_i18n"])
+				optKey = firstText(v, "key", "id", "value")
+				if optKey == "" {
+					optKey = legacyLabel
+				}
+			default:
+				continue
+			}
+			if label["zh"] == nil && legacyLabel != "" {
+				label["zh"] = legacyLabel
+			}
+			if label["en"] == nil && legacyLabel != "" {
+				label["en"] = legacyLabel
+			}
+			options = append(options, map[string]any{
+				"key":        dedupeDetailKey(usedOpts, optKey, "opt"),
+				"label_i18n": label,
+			})
+		}
+
+		out = append(out, map[string]any{
+			"key":       dedupeDetailKey(used, key, "group"),
+			"name_i18n": name,
+			"options":   options,
+		})
+	}
+	return out
+}
+
+// migrateSectionsV2 keeps the blocks of a document that already had a layout,
+// fixing their areas the way the editor does. Unknown block types are dropped.
+func migrateSectionsV2(raw any) []any {
+	arr, _ := raw.([]any)
+	var out []any
+	hasGallery := false
+	for i, it := range arr {
+		obj, ok := it.(map[string]any)
+		if !ok {
+			continue
+		}
+		typ := textOf(obj["type"])
+		area := textOf(obj["area"])
+		switch area {
+		case "media", "sticky", "main", "aside":
+		default:
+			area = "main"
+		}
+		sec := map[string]any{"id": textOf(obj["id"]), "type": typ}
+		if sec["id"] == "" {
+			sec["id"] = fmt.Sprintf("%s-%d", strings.ToLower(typ), i+1)
+		}
+		if title := i18nOf(obj["title_i18n"]); title != nil {
+			sec["title_i18n"] = title
+		}
+		switch typ {
+		case "gallery":
+			include := true
+			if props, ok := obj["props"].(map[string]any); ok && props["includeCoverHover"] == false {
+				include = false
+			}
+			sec["area"] = "media"
+			sec["props"] = map[string]any{"includeCoverHover": include}
+			hasGallery = true
+		case "options":
+			sec["area"] = "sticky"
+		case "specs":
+			sec["area"] = "main"
+		case "service":
+			sec["area"] = "aside"
+		case "divider":
+			if area == "media" {
+				area = "main"
+			}
+			sec["area"] = area
+		case "richText":
+			if area == "aside" || area == "media" {
+				area = "main"
+			}
+			sec["area"] = area
+			text := map[string]any{"zh": "", "en": ""}
+			if data, ok := obj["data"].(map[string]any); ok {
+				if t := i18nOf(data["text_i18n"]); t != nil {
+					text = t
+				}
+			}
+			sec["data"] = map[string]any{"text_i18n": text}
+		default:
+			continue
+		}
+		out = append(out, sec)
+	}
+	if len(out) > 0 && !hasGallery {
+		out = append([]any{defaultDetailSections()[0]}, out...)
+	}
+	return out
+}
+
+// i18nOf returns the string entries of an i18n map with a valid locale key,
+// or nil when v is not an object.
+func i18nOf(v any) map[string]any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil
+	}
+	out := map[string]any{}
+	for k, val := range m {
+		if s, ok := val.(string); ok && detailLocaleRe.MatchString(k) {
+			out[k] = s
+		}
+	}
+	return out
+}
+
+func i18nOrEmpty(v any) map[string]any {
+	if m := i18nOf(v); m != nil {
+		return m
+	}
+	return map[string]any{}
+}
+
+// textOf returns v trimmed when it is a string, or a number as text.
+func textOf(v any) string {
+	switch s := v.(type) {
+	case string:
+		return strings.TrimSpace(s)
+	case json.Number:
+		return s.String()
+	}
+	return ""
+}
+
+func firstText(m map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if s := textOf(m[k]); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func hasHan(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupeDetailKey returns preferred (or prefix_N when empty), suffixed with
+// _2, _3... when already used, and marks the result used.
+func dedupeDetailKey(used map[string]bool, preferred, prefix string) string {
+	base := preferred
+	if base == "" {
+		base = fmt.Sprintf("%s_%d", prefix, len(used)+1)
+	}
+	key := base
+	for i := 2; used[key]; i++ {
+		key = fmt.Sprintf("%s_%d", base, i)
+	}
+	used[key] = true
+	return key
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDetailMigrations_ChainToCurrentVersion(t *testing.T) {
+	version := 1
+	for _, m := range detailMigrations {
+		if m.From != version || m.Name == "" || m.Up == nil {
+			t.Fatalf("migration %q starts at %d, expected %d", m.Name, m.From, version)
+		}
+		version++
+	}
+	if version != ProductDetailSchemaVersion {
+		t.Fatalf("migrations end at %d, expected %d", version, ProductDetailSchemaVersion)
+	}
+}
+
+func TestMigrateProductDetail_V1(t *testing.T) {
+	raw := json.RawMessage(`{
+		"title_i18n": {"zh": "白色幻影礼服", "en": "FLEURLIS Gown", "bad": 1},
+		"description": "真丝缎面",
+		"gallery": ["https://example.com/a.jpg", {"src": "https://example.com/b.jpg", "alt": "back"}, ""],
+		"specs": [
+			{"k": "Fabric", "v": "Silk"},
+			{"label": "件数", "value": "3"},
+			{"k": "Fabric", "v": "Lace"}
+		],
+		"option_groups": [
+			{"name": "颜色", "options": ["Ivory", {"label": "Black"}]},
+			{"title": "Train", "options": []}
+		]
+	}`)
+	out, res, err := MigrateProductDetail(raw)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if res.From != 1 || res.To != 2 || len(res.Applied) != 1 || !res.Changed() {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if errs := ValidateProductDetail(out); len(errs) != 0 {
+		t.Fatalf("migrated document does not validate: %v\n%s", errs, out)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	want := map[string]any{
+		"title_i18n": map[string]any{"zh": "白色幻影礼服", "en": "FLEURLIS Gown"},
+		"gallery": []any{
+			map[string]any{"url": "https://example.com/a.jpg"},
+			map[string]any{"url": "https://example.com/b.jpg", "alt_i18n": map[string]any{"zh": "back", "en": "back"}},
+		},
+		"specs": []any{
+			map[string]any{"key": "Fabric", "label_i18n": map[string]any{"zh": "Fabric", "en": "Fabric"}, "value_i18n": map[string]any{"zh": "Silk", "en": "Silk"}},
+			map[string]any{"key": "pieces", "label_i18n": map[string]any{"zh": "件数", "en": "Pieces"}, "value_i18n": map[string]any{"zh": "3", "en": "3"}},
+			map[string]any{"key": "Fabric_2", "label_i18n": map[string]any{"zh": "Fabric", "en": "Fabric"}, "value_i18n": map[string]any{"zh": "Lace", "en": "Lace"}},
+		},
+		"option_groups": []any{
+			map[string]any{"key": "color", "name_i18n": map[string]any{"zh": "颜色", "en": "Color"}, "options": []any{
+				map[string]any{"key": "Ivory", "label_i18n": map[string]any{"zh": "Ivory", "en": "Ivory"}},
+				map[string]any{"key": "Black", "label_i18n": map[string]any{"zh": "Black", "en": "Black"}},
+			}},
+			map[string]any{"key": "Train", "name_i18n": map[string]any{"zh": "Train", "en": "Train"}, "options": []any{}},
+		},
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(got[k], v) {
+			t.Errorf("%s: expected %#v, got %#v", k, v, got[k])
+		}
+	}
+	if got["schema_version"] != float64(2) || got["description"] != "真丝缎面" {
+		t.Errorf("unexpected top level: %s", out)
+	}
+	sections, _ := got["sections"].([]any)
+	if len(sections) != 5 {
+		t.Fatalf("expected the default sections, got %#v", got["sections"])
+	}
+	overview, _ := sections[2].(map[string]any)
+	if data, _ := overview["data"].(map[string]any); !reflect.DeepEqual(data["text_i18n"], map[string]any{"zh": "真丝缎面", "en": ""}) {
+		t.Errorf("expected the description in the overview block, got %#v", overview)
+	}
+}
+
+func TestMigrateProductDetail_KeepsSectionsAndCurrentDocuments(t *testing.T) {
+	out, _, err := MigrateProductDetail(json.RawMessage(`{"schema_version":1,"sections":[{"type":"richText","area":"aside"},{"type":"carousel"}]}`))
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	var got struct {
+		Sections []map[string]any `json:"sections"`
+	}
+	_ = json.Unmarshal(out, &got)
+	if len(got.Sections) != 2 || got.Sections[0]["type"] != "gallery" || got.Sections[1]["area"] != "main" || got.Sections[1]["id"] != "richtext-1" {
+		t.Fatalf("unexpected sections: %s", out)
+	}
+
+	current := json.RawMessage(`{"schema_version":2,"sections":[],"specs":[{"k":"kept as is"}]}`)
+	for _, raw := range []json.RawMessage{current, nil, json.RawMessage(`null`)} {
+		out, res, err := MigrateProductDetail(raw)
+		if err != nil || res.Changed() || string(out) != string(raw) {
+			t.Fatalf("%s: expected no change, got %s %+v %v", raw, out, res, err)
+		}
+	}
+
+	for _, raw := range []string{`{"schema_version":3}`, `{"schema_version":"2"}`, `{"schema_version":1.5}`, `[]`, `{`} {
+		if _, _, err := MigrateProductDetail(json.RawMessage(raw)); err == nil {
+			t.Errorf("%s: expected an error", raw)
+		}
+		if got := UpgradeDetail(json.RawMessage(raw)); string(got) != raw {
+			t.Errorf("%s: UpgradeDetail must keep the document, got %s", raw, got)
+		}
+	}
+}
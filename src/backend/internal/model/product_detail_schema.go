@@ -0,0 +1,52 @@
+package model
+
+import (
+	_ "embed"
+	"encoding/json"
+	"sync"
+
+	"evening-gown/internal/jsonschema"
+)
+
+// ProductDetailSchemaVersion is the schema_version of the detail documents
+// the admin editor writes (see DefaultProductDetailTemplate).
+const ProductDetailSchemaVersion = 2
+
+//go:embed product_detail_schema.json
+var productDetailSchemaJSON []byte
+
+var productDetailSchema = sync.OnceValue(func() *jsonschema.Schema {
+	s, err := jsonschema.Compile(productDetailSchemaJSON)
+	if err != nil {
+		panic(err) // the embedded schema is covered by tests
+	}
+	return s
+})
+
+// ProductDetailSchema returns the JSON Schema of schema_version 2 detail
+// documents (and of the detail template).
+func ProductDetailSchema() json.RawMessage {
+	return json.RawMessage(productDetailSchemaJSON)
+}
+
+// ValidateProductDetail checks a detail document (or template) against
+// ProductDetailSchema before it is stored. Older documents must be upgraded
+// with MigrateProductDetail first; one without a schema_version is invalid.
+// An empty document is valid (the template applies).
+func ValidateProductDetail(raw json.RawMessage) []jsonschema.Error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if _, err := asObject(raw); err != nil {
+		msg := "invalid JSON"
+		if err == errDetailNotObject {
+			msg = err.Error()
+		}
+		return []jsonschema.Error{{Path: "", Message: msg}}
+	}
+	errs, err := productDetailSchema().ValidateJSON(raw)
+	if err != nil {
+		return []jsonschema.Error{{Path: "", Message: "invalid JSON"}}
+	}
+	return errs
+}
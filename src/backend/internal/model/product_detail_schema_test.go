@@ -0,0 +1,86 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateProductDetail_DefaultsAreValid(t *testing.T) {
+	if errs := ValidateProductDetail(DefaultProductDetailTemplate()); len(errs) != 0 {
+		t.Fatalf("default template: %v", errs)
+	}
+
+	variants := []ProductVariant{{ID: 1, Options: json.RawMessage(`[{"group":"color","value":"ivory","label_i18n":{"zh":"象牙白"}}]`)}}
+	detail, err := ApplyVariantOptionGroups(DefaultProductDetailTemplate(), variants)
+	if err != nil {
+		t.Fatalf("apply option groups: %v", err)
+	}
+	if errs := ValidateProductDetail(detail); len(errs) != 0 {
+		t.Fatalf("generated option groups: %v", errs)
+	}
+}
+
+func TestValidateProductDetail(t *testing.T) {
+	legacy := json.RawMessage(`{"title_i18n":{"zh":"礼服"},"specs":[{"k":"Fabric","v":"Silk"}],"desc":"Silk gown"}`)
+	migrated, _, err := MigrateProductDetail(legacy)
+	if err != nil {
+		t.Fatalf("migrate legacy: %v", err)
+	}
+	for name, raw := range map[string]string{
+		"empty":                           ``,
+		"migrated legacy":                 string(migrated),
+		"legacy gallery and descriptions": `{"schema_version":2,"sections":[],"gallery":["https://example.com/a.jpg",{"url":"","objectKey":"products/A/x.webp"}],"description":"kept","desc_i18n":{"en":"kept"}}`,
+	} {
+		if errs := ValidateProductDetail(json.RawMessage(raw)); len(errs) != 0 {
+			t.Errorf("%s: expected valid, got %v", name, errs)
+		}
+	}
+	if errs := ValidateProductDetail(legacy); len(errs) == 0 {
+		t.Error("an unmigrated legacy document must not validate")
+	}
+
+	errs := ValidateProductDetail(json.RawMessage(`{
+		"schema_version": 2,
+		"titel_i18n": {"zh": "typo"},
+		"specs": {"key": "pieces"},
+		"option_groups": [{"key": "color", "options": [{"key": "ivory", "lable_i18n": {}}]}],
+		"sections": [
+			{"id": "a", "type": "gallery", "area": "media", "props": {"includeCoverHover": "yes"}},
+			{"id": "b", "type": "carousel", "area": "main", "titel_i18n": {"zh": "x"}},
+			{"type": "richText", "area": "footer", "data": {"text_i18n": {"zh": 1}}}
+		]
+	}`))
+	got := map[string]string{}
+	for _, e := range errs {
+		got[e.Path] = e.Message
+	}
+	for _, path := range []string{
+		"/titel_i18n",
+		"/specs",
+		"/option_groups/0/options/0/lable_i18n",
+		"/sections/0/props/includeCoverHover",
+		"/sections/1/type",
+		"/sections/1/titel_i18n",
+		"/sections/2/id",
+		"/sections/2/area",
+		"/sections/2/data/text_i18n/zh",
+	} {
+		if got[path] == "" {
+			t.Errorf("expected an error at %s, got %v", path, errs)
+		}
+	}
+	if len(errs) != 9 {
+		t.Errorf("expected 9 errors, got %v", errs)
+	}
+
+	for raw, path := range map[string]string{
+		`[]`:                                 "",
+		`{"schema_version":3,"sections":[]}`: "/schema_version",
+		`{"schema_version":2}`:               "/sections",
+	} {
+		errs := ValidateProductDetail(json.RawMessage(raw))
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("%s: expected one error at %q, got %v", raw, path, errs)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"encoding/json"
 	"errors"
+	"regexp"
 	"strings"
 )
 
@@ -10,6 +11,45 @@ var errDetailNotObject = errors.New("detail must be a JSON object")
 
 const SettingKeyProductDetailTemplate = "product_detail_template"
 
+// Per-category templates are stored next to the global one under
+// "product_detail_template:{category}" and take precedence over it for
+// products of that category.
+const (
+	ProductDetailTemplateKeyPrefix = SettingKeyProductDetailTemplate + ":"
+
+	ProductCategoryMaxLen = 64
+)
+
+var productCategoryRe = regexp.MustCompile(`^[a-z0-9]+(?:[_-][a-z0-9]+)*$`)
+
+// NormalizeProductCategory trims and lower-cases a category (gown, couture,
+// bridal, ...) and reports whether it can key a detail template.
+func NormalizeProductCategory(raw string) (string, bool) {
+	s := strings.ToLower(strings.TrimSpace(raw))
+	if s == "" || len(s) > ProductCategoryMaxLen || !productCategoryRe.MatchString(s) {
+		return "", false
+	}
+	return s, true
+}
+
+// ProductDetailTemplateKey returns the AppSetting key of the detail template
+// for a category. Categories that cannot key a template map to the global one.
+func ProductDetailTemplateKey(category string) string {
+	if c, ok := NormalizeProductCategory(category); ok {
+		return ProductDetailTemplateKeyPrefix + c
+	}
+	return SettingKeyProductDetailTemplate
+}
+
+// ProductDetailTemplateCategory returns the category of a per-category
+// template key, or false for any other key.
+func ProductDetailTemplateCategory(key string) (string, bool) {
+	if !strings.HasPrefix(key, ProductDetailTemplateKeyPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(key, ProductDetailTemplateKeyPrefix), true
+}
+
 // DefaultProductDetailTemplate returns the default template used to populate Product.DetailJSON.
 // It includes the requested default fields and a v2 layout skeleton:
 // - sections/blocks (drag & drop in admin)
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Product revision sources.
+const (
+	RevisionSourceInitial   = "initial" // content that existed before revisions were tracked
+	RevisionSourceCreate    = "create"
+	RevisionSourceUpdate    = "update"
+	RevisionSourceRestore   = "restore"
+	RevisionSourceRename    = "rename"    // styleNo renamed, asset keys moved
+	RevisionSourceMigration = "migration" // detail upgraded to a newer schema_version
+	RevisionSourceTemplate  = "template"  // detail template re-applied in bulk
+)
+
+// ProductRevision is an immutable snapshot of a product's editable content,
+// taken every time the product is saved. Revision numbers are per product and
+// start at 1.
+//
+// Publishing state is not part of a revision: restoring old content never
+// publishes or unpublishes a product.
+type ProductRevision struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ProductID uint `gorm:"not null;uniqueIndex:idx_product_revisions_product_rev" json:"productId"`
+	Revision  int  `gorm:"not null;uniqueIndex:idx_product_revisions_product_rev" json:"revision"`
+
+	Source string `gorm:"type:text;not null" json:"source"` // initial|create|update|restore|rename|migration|template
+	// RestoredFrom is the revision number whose content was restored (source=restore).
+	RestoredFrom *int `gorm:"" json:"restoredFrom,omitempty"`
+
+	Snapshot json.RawMessage `gorm:"type:jsonb;not null" json:"snapshot,omitempty"`
+
+	AuthorID    uint   `gorm:"not null;default:0" json:"authorId"`
+	AuthorEmail string `gorm:"type:text;not null;default:''" json:"authorEmail"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ProductSnapshot is the editable content of a product stored in a revision.
+// JSON names match the Product API fields.
+type ProductSnapshot struct {
+	Slug         string `json:"slug"`
+	StyleNo      string `json:"styleNo"`
+	Season       string `json:"season"`
+	Category     string `json:"category"`
+	Availability string `json:"availability"`
+	IsNew        bool   `json:"isNew"`
+	NewRank      int    `json:"newRank"`
+
+	CoverImageURL string `json:"coverImage"`
+	CoverImageKey string `json:"coverImageKey"`
+	HoverImageURL string `json:"hoverImage"`
+	HoverImageKey string `json:"hoverImageKey"`
+
+	PriceMode string `json:"priceMode"`
+
+	Detail json.RawMessage `json:"detail"`
+}
+
+// SnapshotOf captures the editable content of p.
+func SnapshotOf(p Product) ProductSnapshot {
+	return ProductSnapshot{
+		Slug:          p.Slug,
+		StyleNo:       p.StyleNo,
+		Season:        p.Season,
+		Category:      p.Category,
+		Availability:  p.Availability,
+		IsNew:         p.IsNew,
+		NewRank:       p.NewRank,
+		CoverImageURL: p.CoverImageURL,
+		CoverImageKey: p.CoverImageKey,
+		HoverImageURL: p.HoverImageURL,
+		HoverImageKey: p.HoverImageKey,
+		PriceMode:     p.PriceMode,
+		Detail:        p.DetailJSON,
+	}
+}
+
+// Columns returns the product column updates that bring a product back to s.
+//
+// Slug and StyleNo are left out: both are unique, and a styleNo change must go
+// through the rename flow, which moves the objects along. Image keys from
+// before a rename still resolve; the old objects are kept while a revision
+// references them.
+func (s ProductSnapshot) Columns() map[string]any {
+	return map[string]any{
+		"season":          s.Season,
+		"category":        s.Category,
+		"availability":    s.Availability,
+		"is_new":          s.IsNew,
+		"new_rank":        s.NewRank,
+		"cover_image_url": s.CoverImageURL,
+		"cover_image_key": s.CoverImageKey,
+		"hover_image_url": s.HoverImageURL,
+		"hover_image_key": s.HoverImageKey,
+		"price_mode":      s.PriceMode,
+		"detail_json":     s.Detail,
+	}
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ProductSearchVectorSQL is the Postgres tsvector expression used by the public
+// product search. It is shared by the query and the GIN expression index created
+// in bootstrap, so both must stay byte-for-byte identical for the index to be used.
+//
+// Weights:
+// - A: style number and localized titles
+// - B: localized descriptions
+// - C: spec labels/values
+const ProductSearchVectorSQL = `(setweight(to_tsvector('simple', coalesce(style_no, '')), 'A') || ` +
+	`setweight(jsonb_to_tsvector('simple', coalesce(detail_json->'title_i18n', '{}'::jsonb), '["string"]'), 'A') || ` +
+	`setweight(jsonb_to_tsvector('simple', coalesce(detail_json->'description_i18n', '{}'::jsonb), '["string"]'), 'B') || ` +
+	`setweight(jsonb_to_tsvector('simple', coalesce(detail_json->'specs', '[]'::jsonb), '["string"]'), 'C'))`
+
+// ProductSearchTextSQL is the plain-text fallback used for substring matching in Postgres.
+// The 'simple' text search config does not segment CJK text, so ILIKE keeps zh titles searchable.
+// Like the tsvector it only covers string values (never JSON keys such as "zh" or "specs"),
+// and it is lower-cased so a pg_trgm index on the same expression can serve the match.
+// The paths avoid jsonpath filters: their "?" would be taken for a bind parameter.
+const ProductSearchTextSQL = `lower(jsonb_path_query_array(detail_json, 'lax $.title_i18n.*')::text || ' ' || ` +
+	`jsonb_path_query_array(detail_json, 'lax $.description_i18n.*')::text || ' ' || ` +
+	`jsonb_path_query_array(detail_json, 'lax $.specs[*].label_i18n.*')::text || ' ' || ` +
+	`jsonb_path_query_array(detail_json, 'lax $.specs[*].value_i18n.*')::text)`
+
+// ProductSearchStyleSQL is the style number expression used for substring matching.
+const ProductSearchStyleSQL = `lower(style_no)`
+
+const productSearchMaxTerms = 8
+
+// ProductSearchTerms splits a free-text query into normalized search terms.
+//
+// Terms are lower-cased and restricted to letters, digits and '-', so they are safe
+// to embed into LIKE patterns and tsquery strings without further escaping.
+func ProductSearchTerms(q string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-')
+	})
+
+	seen := map[string]bool{}
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		f = strings.Trim(f, "-")
+		if f == "" || seen[f] {
+			continue
+		}
+		seen[f] = true
+		out = append(out, f)
+		if len(out) >= productSearchMaxTerms {
+			break
+		}
+	}
+	return out
+}
+
+// ProductSearchTSQuery builds a prefix-matching tsquery string ("a:* & b:*") for one term.
+// Hyphenated fragments such as "dr-01" are split so each part can prefix-match.
+func ProductSearchTSQuery(term string) string {
+	parts := strings.FieldsFunc(term, func(r rune) bool { return r == '-' })
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p != "" {
+			out = append(out, p+":*")
+		}
+	}
+	return strings.Join(out, " & ")
+}
+
+// ProductSearchNeedsText reports whether a term must be matched as a substring of the
+// detail text: the 'simple' config keeps a run of Han characters as one token, so a
+// word in the middle of a zh title can only be found that way.
+func ProductSearchNeedsText(term string) bool {
+	for _, r := range term {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
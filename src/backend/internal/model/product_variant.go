@@ -0,0 +1,198 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Variant availabilities (same vocabulary as Product.Availability).
+var VariantAvailabilities = []string{"in_stock", "preorder", "archived"}
+
+// ProductVariant is one orderable combination of option values of a product
+// (typically colorway × size), i.e. a SKU.
+//
+// Variants are the source of truth for a product's options once it has any:
+// every variant change regenerates option_groups in Product.DetailJSON (see
+// ApplyVariantOptionGroups) for clients that still read them.
+type ProductVariant struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ProductID uint `gorm:"not null;index;uniqueIndex:idx_product_variants_options,priority:1" json:"productId"`
+	// Code is the SKU: the product styleNo followed by the option values,
+	// e.g. SS25-DR-01-IVORY-M. It follows styleNo renames.
+	Code string `gorm:"type:text;not null;uniqueIndex" json:"code"`
+
+	// Options is a JSON array of VariantOption, in group order.
+	Options json.RawMessage `gorm:"type:jsonb" json:"options"`
+	// OptionsKey is the canonical form of Options ("color=ivory;size=m"),
+	// unique per product.
+	OptionsKey string `gorm:"type:text;not null;uniqueIndex:idx_product_variants_options,priority:2" json:"-"`
+
+	Availability string `gorm:"type:text;not null;default:in_stock" json:"availability"` // in_stock|preorder|archived
+	// MOQ is the minimum order quantity; 0 means none.
+	MOQ          int `gorm:"column:moq;not null;default:0" json:"moq"`
+	LeadTimeDays int `gorm:"not null;default:0" json:"leadTimeDays"`
+
+	// ImageKeys is a JSON array of object keys (products/{styleNo}/...).
+	ImageKeys json.RawMessage `gorm:"type:jsonb" json:"imageKeys"`
+
+	Position int `gorm:"not null;default:0" json:"position"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// VariantOption is one option value of a variant: Value within Group, both
+// lower-case slugs (e.g. group "color", value "ivory").
+type VariantOption struct {
+	Group     string            `json:"group"`
+	Value     string            `json:"value"`
+	LabelI18n map[string]string `json:"label_i18n,omitempty"`
+}
+
+var (
+	errInvalidVariantOptions = errors.New("invalid variant options")
+	variantSlugRe            = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+)
+
+// NormalizeVariantOptions trims and lower-cases groups and values (spaces
+// become '-') and validates them: at least one option, slugs only, each group
+// once.
+func NormalizeVariantOptions(opts []VariantOption) ([]VariantOption, error) {
+	if len(opts) == 0 {
+		return nil, errInvalidVariantOptions
+	}
+	slug := func(s string) string {
+		return strings.Join(strings.Fields(strings.ToLower(s)), "-")
+	}
+	seen := map[string]bool{}
+	out := make([]VariantOption, 0, len(opts))
+	for _, o := range opts {
+		o.Group, o.Value = slug(o.Group), slug(o.Value)
+		if !variantSlugRe.MatchString(o.Group) || !variantSlugRe.MatchString(o.Value) || seen[o.Group] {
+			return nil, errInvalidVariantOptions
+		}
+		seen[o.Group] = true
+		out = append(out, o)
+	}
+	return out, nil
+}
+
+// VariantOptionsKey is the canonical, order-independent form of opts.
+func VariantOptionsKey(opts []VariantOption) string {
+	parts := make([]string, 0, len(opts))
+	for _, o := range opts {
+		parts = append(parts, o.Group+"="+o.Value)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ";")
+}
+
+// VariantCode derives the SKU of a variant from the product styleNo.
+func VariantCode(styleNo string, opts []VariantOption) string {
+	parts := []string{styleNo}
+	for _, o := range opts {
+		parts = append(parts, strings.ToUpper(o.Value))
+	}
+	return strings.Join(parts, "-")
+}
+
+// DecodeOptions returns the options of v (none when malformed).
+func (v ProductVariant) DecodeOptions() []VariantOption {
+	var opts []VariantOption
+	_ = json.Unmarshal(v.Options, &opts)
+	return opts
+}
+
+// DecodeImageKeys returns the image keys of v (none when malformed).
+func (v ProductVariant) DecodeImageKeys() []string {
+	var keys []string
+	_ = json.Unmarshal(v.ImageKeys, &keys)
+	return keys
+}
+
+// SortVariants orders variants by Position, then ID.
+func SortVariants(variants []ProductVariant) {
+	sort.SliceStable(variants, func(i, j int) bool {
+		if variants[i].Position != variants[j].Position {
+			return variants[i].Position < variants[j].Position
+		}
+		return variants[i].ID < variants[j].ID
+	})
+}
+
+// defaultOptionGroupNames names the usual groups when the detail has no
+// name for them yet.
+var defaultOptionGroupNames = map[string]map[string]any{
+	"color": {"zh": "颜色", "en": "Color"},
+	"size":  {"zh": "尺码", "en": "Size"},
+}
+
+// ApplyVariantOptionGroups replaces option_groups in detail with the groups
+// and values used by variants (sorted with SortVariants first), in order of
+// first appearance. Group names (name_i18n) already in detail are kept.
+// Options of archived variants are still listed: archived only means the
+// combination is no longer produced.
+func ApplyVariantOptionGroups(detail json.RawMessage, variants []ProductVariant) (json.RawMessage, error) {
+	obj, err := asObject(detail)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]any{}
+	for _, g := range normalizeOptionGroups(obj["option_groups"]) {
+		key := pickString(g, "key", "name", "title", "label")
+		if n, ok := g["name_i18n"]; ok {
+			names[key] = n
+		}
+	}
+
+	type group struct {
+		key     string
+		options []any
+		seen    map[string]bool
+	}
+	var groups []*group
+	byKey := map[string]*group{}
+	for _, v := range variants {
+		for _, o := range v.DecodeOptions() {
+			g := byKey[o.Group]
+			if g == nil {
+				g = &group{key: o.Group, seen: map[string]bool{}}
+				byKey[o.Group] = g
+				groups = append(groups, g)
+			}
+			if g.seen[o.Value] {
+				continue
+			}
+			g.seen[o.Value] = true
+			label := map[string]any{}
+			for lang, s := range o.LabelI18n {
+				label[lang] = s
+			}
+			if len(label) == 0 {
+				label = map[string]any{"zh": o.Value, "en": o.Value}
+			}
+			g.options = append(g.options, map[string]any{"key": o.Value, "label_i18n": label})
+		}
+	}
+
+	out := make([]any, 0, len(groups))
+	for _, g := range groups {
+		name, ok := names[g.key]
+		if !ok {
+			if d, ok := defaultOptionGroupNames[g.key]; ok {
+				name = d
+			} else {
+				name = map[string]any{"zh": g.key, "en": g.key}
+			}
+		}
+		out = append(out, map[string]any{"key": g.key, "name_i18n": name, "options": g.options})
+	}
+	obj["option_groups"] = out
+	return json.Marshal(obj)
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNormalizeVariantOptions(t *testing.T) {
+	opts, err := NormalizeVariantOptions([]VariantOption{{Group: " Color ", Value: "Champagne Gold"}, {Group: "size", Value: "M"}})
+	if err != nil {
+		t.Fatalf("NormalizeVariantOptions: %v", err)
+	}
+	if opts[0].Group != "color" || opts[0].Value != "champagne-gold" || opts[1].Value != "m" {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+	if got := VariantCode("SS25-DR-01", opts); got != "SS25-DR-01-CHAMPAGNE-GOLD-M" {
+		t.Fatalf("code = %s", got)
+	}
+	if got := VariantOptionsKey([]VariantOption{opts[1], opts[0]}); got != "color=champagne-gold;size=m" {
+		t.Fatalf("options key = %s", got)
+	}
+
+	for _, bad := range [][]VariantOption{
+		nil,
+		{{Group: "color", Value: ""}},
+		{{Group: "color", Value: "red"}, {Group: "Color", Value: "blue"}},
+		{{Group: "color", Value: "red/blue"}},
+	} {
+		if _, err := NormalizeVariantOptions(bad); err == nil {
+			t.Fatalf("expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestApplyVariantOptionGroups(t *testing.T) {
+	variant := func(opts ...VariantOption) ProductVariant {
+		raw, _ := json.Marshal(opts)
+		return ProductVariant{Options: raw}
+	}
+	ivory := VariantOption{Group: "color", Value: "ivory", LabelI18n: map[string]string{"zh": "象牙白", "en": "Ivory"}}
+	variants := []ProductVariant{
+		variant(ivory, VariantOption{Group: "size", Value: "s"}),
+		variant(ivory, VariantOption{Group: "size", Value: "m"}),
+		variant(VariantOption{Group: "color", Value: "noir"}, VariantOption{Group: "size", Value: "s"}),
+	}
+	detail := json.RawMessage(`{"title_i18n":{"en":"Aurora"},"option_groups":[{"key":"color","name_i18n":{"zh":"色彩","en":"Colorway"},"options":[{"key":"stale"}]},{"key":"fabric","options":[]}]}`)
+
+	out, err := ApplyVariantOptionGroups(detail, variants)
+	if err != nil {
+		t.Fatalf("ApplyVariantOptionGroups: %v", err)
+	}
+	var got struct {
+		Title        map[string]string `json:"title_i18n"`
+		OptionGroups []struct {
+			Key      string            `json:"key"`
+			NameI18n map[string]string `json:"name_i18n"`
+			Options  []struct {
+				Key       string            `json:"key"`
+				LabelI18n map[string]string `json:"label_i18n"`
+			} `json:"options"`
+		} `json:"option_groups"`
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Title["en"] != "Aurora" || len(got.OptionGroups) != 2 {
+		t.Fatalf("unexpected detail: %s", out)
+	}
+	color, size := got.OptionGroups[0], got.OptionGroups[1]
+	if color.Key != "color" || color.NameI18n["en"] != "Colorway" || len(color.Options) != 2 ||
+		color.Options[0].Key != "ivory" || color.Options[0].LabelI18n["zh"] != "象牙白" || color.Options[1].LabelI18n["en"] != "noir" {
+		t.Fatalf("unexpected color group: %+v", color)
+	}
+	if size.Key != "size" || size.NameI18n["en"] != "Size" || len(size.Options) != 2 || size.Options[1].Key != "m" {
+		t.Fatalf("unexpected size group: %+v", size)
+	}
+
+	out, err = ApplyVariantOptionGroups(detail, nil)
+	if err != nil || string(out) != `{"option_groups":[],"title_i18n":{"en":"Aurora"}}` {
+		t.Fatalf("without variants: %s %v", out, err)
+	}
+}
@@ -0,0 +1,111 @@
+package model
+
+import "strings"
+
+// Backoffice roles.
+//
+// RoleAdmin is the legacy single-super-admin role. It is kept as an alias of
+// RoleOwner so existing rows keep full access without a data migration.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleSales  = "sales"
+	RoleViewer = "viewer"
+)
+
+// Permission is a coarse-grained backoffice capability checked per route.
+type Permission string
+
+const (
+	PermProductsRead  Permission = "products:read"
+	PermProductsWrite Permission = "products:write"
+	PermUpdatesRead   Permission = "updates:read"
+	PermUpdatesWrite  Permission = "updates:write"
+	PermContactsRead  Permission = "contacts:read"
+	PermContactsWrite Permission = "contacts:write"
+	PermEventsRead    Permission = "events:read"
+	PermEventsWrite   Permission = "events:write"
+	PermSettingsRead  Permission = "settings:read"
+	PermSettingsWrite Permission = "settings:write"
+	PermAssetsRead    Permission = "assets:read"
+	PermUploadsWrite  Permission = "uploads:write"
+	PermUsersManage   Permission = "users:manage"
+	PermAuditRead     Permission = "audit:read"
+)
+
+var allPermissions = []Permission{
+	PermProductsRead, PermProductsWrite,
+	PermUpdatesRead, PermUpdatesWrite,
+	PermContactsRead, PermContactsWrite,
+	PermEventsRead, PermEventsWrite,
+	PermSettingsRead, PermSettingsWrite,
+	PermAssetsRead, PermUploadsWrite,
+	PermUsersManage, PermAuditRead,
+}
+
+// rolePermissions maps each role to its granted permissions.
+//
+// Notes:
+//   - editor manages catalog content but not settings; it can read the detail template
+//     because the product editor needs it.
+//   - sales only sees contact leads.
+//   - viewer is read-only and does not see lead PII.
+var rolePermissions = map[string][]Permission{
+	RoleOwner: allPermissions,
+	RoleAdmin: allPermissions,
+	RoleEditor: {
+		PermProductsRead, PermProductsWrite,
+		PermUpdatesRead, PermUpdatesWrite,
+		PermEventsRead,
+		PermSettingsRead,
+		PermAssetsRead, PermUploadsWrite,
+	},
+	RoleSales: {
+		PermContactsRead, PermContactsWrite,
+	},
+	RoleViewer: {
+		PermProductsRead,
+		PermUpdatesRead,
+		PermEventsRead,
+		PermSettingsRead,
+		PermAssetsRead,
+	},
+}
+
+// IsKnownRole reports whether role is a valid backoffice role.
+func IsKnownRole(role string) bool {
+	_, ok := rolePermissions[strings.TrimSpace(role)]
+	return ok
+}
+
+// IsOwnerRole reports whether role has full access (owner or legacy admin).
+func IsOwnerRole(role string) bool {
+	role = strings.TrimSpace(role)
+	return role == RoleOwner || role == RoleAdmin
+}
+
+// RoleHasPermission reports whether role grants perm.
+func RoleHasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[strings.TrimSpace(role)] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAssignableRole reports whether role may be given to a user via the users API.
+// The legacy "admin" role is accepted on existing rows but not handed out anymore.
+func IsAssignableRole(role string) bool {
+	role = strings.TrimSpace(role)
+	return role != RoleAdmin && IsKnownRole(role)
+}
+
+// RolePermissions returns a copy of the permissions granted to role.
+func RolePermissions(role string) []Permission {
+	perms := rolePermissions[strings.TrimSpace(role)]
+	out := make([]Permission, len(perms))
+	copy(out, perms)
+	return out
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// Template apply job statuses.
+const (
+	TemplateApplyJobRunning   = "running"
+	TemplateApplyJobSucceeded = "succeeded"
+	TemplateApplyJobFailed    = "failed"
+)
+
+// TemplateApplyJob is one run of re-applying the detail templates to existing
+// products (see MergeProductDetailWithTemplate). The counters are updated
+// after every batch, so the job doubles as its progress report.
+type TemplateApplyJob struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// Category limits the job to products of one category; empty for all.
+	Category string `gorm:"type:text;not null;default:''" json:"category,omitempty"`
+	// At most one job is running: the partial unique index rejects a second.
+	Status string `gorm:"type:text;not null;index;uniqueIndex:idx_template_apply_jobs_running,where:status = 'running'" json:"status"` // running|succeeded|failed
+
+	Total     int `gorm:"not null;default:0" json:"total"`
+	Processed int `gorm:"not null;default:0" json:"processed"`
+	Changed   int `gorm:"not null;default:0" json:"changed"`
+	Failed    int `gorm:"not null;default:0" json:"failed"`
+
+	Error string `gorm:"type:text;not null;default:''" json:"error,omitempty"`
+
+	AuthorID    uint   `gorm:"not null;default:0" json:"authorId"`
+	AuthorEmail string `gorm:"type:text;not null;default:''" json:"authorEmail"`
+
+	FinishedAt *time.Time `gorm:"" json:"finishedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
@@ -18,6 +18,9 @@ type UpdatePost struct {
 	PinnedRank int `gorm:"not null;default:0" json:"pinnedRank"`
 
 	PublishedAt *time.Time `gorm:"index" json:"publishedAt,omitempty"`
+	// PublishAt/UnpublishAt schedule a future transition (see internal/scheduler).
+	PublishAt   *time.Time `gorm:"index" json:"publishAt,omitempty"`
+	UnpublishAt *time.Time `gorm:"index" json:"unpublishAt,omitempty"`
 
 	CreatedAt time.Time  `json:"createdAt"`
 	UpdatedAt time.Time  `json:"updatedAt"`
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// UploadStagingPrefix holds objects uploaded with a presigned URL until they
+// are finalized. Nothing under it is ever served; unfinalized objects expire.
+const UploadStagingPrefix = "uploads/"
+
+// UploadIntent is a presigned direct-to-storage upload. The client PUTs the
+// file to ObjectKey, then finalize turns it into product renditions
+// (products/{StyleNo}/{Kind}/...) and deletes the staged object.
+type UploadIntent struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ObjectKey string `gorm:"type:text;not null;uniqueIndex" json:"objectKey"`
+	StyleNo   string `gorm:"type:text;not null" json:"styleNo"`
+	Kind      string `gorm:"type:text;not null" json:"kind"` // cover|hover|gallery
+
+	// ExpiresAt is when the presigned URL stops accepting the upload.
+	ExpiresAt   time.Time  `gorm:"not null" json:"expiresAt"`
+	FinalizedAt *time.Time `gorm:"" json:"finalizedAt,omitempty"`
+	CreatedByID uint       `gorm:"not null;default:0" json:"createdById"`
+
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+}
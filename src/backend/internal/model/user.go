@@ -4,15 +4,15 @@ import "time"
 
 // User represents a backoffice user.
 //
-// This project uses a single super admin (no multi-tenant).
+// Access is role-based (see role.go); there is no multi-tenancy.
 type User struct {
 	ID uint `gorm:"primaryKey" json:"id"`
 
 	Email        string `gorm:"type:text;uniqueIndex;not null" json:"email"`
 	PasswordHash string `gorm:"type:text;not null" json:"-"`
 
-	Role   string `gorm:"type:text;not null;default:admin" json:"role"`   // admin
-	Status string `gorm:"type:text;not null;default:active" json:"status"` // active|disabled|locked
+	Role   string `gorm:"type:text;not null;default:admin" json:"role"`    // owner|admin|editor|sales|viewer
+	Status string `gorm:"type:text;not null;default:active" json:"status"` // invited|active|disabled|locked
 
 	FailedLoginCount  int        `gorm:"not null;default:0" json:"failedLoginCount"`
 	LockedUntil       *time.Time `gorm:"" json:"lockedUntil,omitempty"`
@@ -21,6 +21,15 @@ type User struct {
 	// RefreshTokenIssuedAt is used to invalidate older refresh tokens after rotation.
 	// Not exposed via APIs.
 	RefreshTokenIssuedAt *time.Time `gorm:"" json:"-"`
+	// TokenVersion is embedded into issued JWTs; incrementing it revokes all of them.
+	TokenVersion int `gorm:"not null;default:0" json:"-"`
+
+	// TOTP two-factor authentication. TOTPSecret holds a pending secret during
+	// enrollment and becomes active once TOTPEnabled is set by confirmation.
+	TOTPSecret  string `gorm:"type:text;not null;default:''" json:"-"`
+	TOTPEnabled bool   `gorm:"not null;default:false" json:"totpEnabled"`
+	// TOTPLastStep is the last accepted time step; codes at or before it are replays.
+	TOTPLastStep int64 `gorm:"not null;default:0" json:"-"`
 
 	CreatedAt time.Time  `json:"createdAt"`
 	UpdatedAt time.Time  `json:"updatedAt"`
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// UserRecoveryCode is a single-use 2FA fallback for a backoffice user.
+//
+// Only the SHA-256 hash is stored; the plain codes are shown once at enrollment.
+type UserRecoveryCode struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	UserID   uint       `gorm:"not null;index" json:"userId"`
+	CodeHash string     `gorm:"type:text;not null;uniqueIndex" json:"-"`
+	UsedAt   *time.Time `gorm:"" json:"usedAt,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// One-time token purposes.
+const (
+	UserTokenPurposeInvite = "invite"
+	UserTokenPurposeReset  = "reset"
+)
+
+// UserOneTimeToken lets a backoffice user set their own password (invite or reset).
+//
+// Only the SHA-256 hash of the token is stored; the plain token is returned once
+// to the owner who created it.
+type UserOneTimeToken struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	UserID    uint   `gorm:"not null;index" json:"userId"`
+	Purpose   string `gorm:"type:text;not null" json:"purpose"` // invite|reset
+	TokenHash string `gorm:"type:text;not null;uniqueIndex" json:"-"`
+
+	ExpiresAt   time.Time  `gorm:"not null" json:"expiresAt"`
+	UsedAt      *time.Time `gorm:"" json:"usedAt,omitempty"`
+	CreatedByID uint       `gorm:"not null;default:0" json:"createdById"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
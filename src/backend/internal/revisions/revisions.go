@@ -0,0 +1,88 @@
+// Package revisions numbers and stores product revisions. Every writer of a
+// product (the admin handlers, the template apply job, the detail migration)
+// saves its revision through Save, so they all share one numbering scheme
+// and one lock.
+package revisions
+
+import (
+	"encoding/json"
+
+	"evening-gown/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Options describes the revision Save stores.
+type Options struct {
+	Source       string
+	RestoredFrom *int
+	// Author is recorded on the revision; zero for system writes.
+	Author model.User
+	// SkipUntracked saves nothing for a product without revisions (Save then
+	// returns a zero revision), for writes that are not edits.
+	SkipUntracked bool
+}
+
+// Save snapshots after as the next revision of the product.
+//
+// Products saved before revisions existed have no history; in that case before
+// (when known) is stored first as an "initial" revision so the first tracked
+// edit can still be rolled back.
+//
+// The product row is locked first, so concurrent saves of one product number
+// their revisions one after the other instead of racing for the same number.
+func Save(tx *gorm.DB, before *model.Product, after model.Product, opts Options) (model.ProductRevision, error) {
+	var locked model.Product
+	if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+		Select("id").
+		Where("id = ?", after.ID).
+		Take(&locked).Error; err != nil {
+		return model.ProductRevision{}, err
+	}
+
+	var last int
+	if err := tx.Model(&model.ProductRevision{}).
+		Where("product_id = ?", after.ID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&last).Error; err != nil {
+		return model.ProductRevision{}, err
+	}
+
+	if last == 0 && opts.SkipUntracked {
+		return model.ProductRevision{}, nil
+	}
+	if last == 0 && before != nil {
+		initial, err := json.Marshal(model.SnapshotOf(*before))
+		if err != nil {
+			return model.ProductRevision{}, err
+		}
+		if err := tx.Create(&model.ProductRevision{
+			ProductID: after.ID,
+			Revision:  1,
+			Source:    model.RevisionSourceInitial,
+			Snapshot:  initial,
+		}).Error; err != nil {
+			return model.ProductRevision{}, err
+		}
+		last = 1
+	}
+
+	snapshot, err := json.Marshal(model.SnapshotOf(after))
+	if err != nil {
+		return model.ProductRevision{}, err
+	}
+	rev := model.ProductRevision{
+		ProductID:    after.ID,
+		Revision:     last + 1,
+		Source:       opts.Source,
+		RestoredFrom: opts.RestoredFrom,
+		Snapshot:     snapshot,
+		AuthorID:     opts.Author.ID,
+		AuthorEmail:  opts.Author.Email,
+	}
+	if err := tx.Create(&rev).Error; err != nil {
+		return model.ProductRevision{}, err
+	}
+	return rev, nil
+}
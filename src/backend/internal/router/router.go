@@ -15,6 +15,7 @@ import (
 	"evening-gown/internal/handler/health"
 	publicHandlers "evening-gown/internal/handler/public"
 	"evening-gown/internal/middleware"
+	"evening-gown/internal/model"
 )
 
 // Dependencies groups handlers required by the router.
@@ -25,6 +26,10 @@ type Dependencies struct {
 	// Dev toggles
 	EnableDevTokenIssuer bool
 
+	// TrustedProxies are the proxies whose forwarding headers set the client
+	// IP (see config.AppConfig.TrustedProxies); nil trusts none.
+	TrustedProxies []string
+
 	// Public website APIs (no auth)
 	Public struct {
 		Assets   *publicHandlers.AssetsHandler
@@ -36,22 +41,34 @@ type Dependencies struct {
 
 	// Admin backoffice APIs (JWT-protected)
 	Admin struct {
-		Auth     *adminHandlers.AuthHandler
-		Assets   *adminHandlers.AssetsHandler
-		Uploads  *adminHandlers.UploadsHandler
-		Products *adminHandlers.ProductsHandler
-		Updates  *adminHandlers.UpdatesHandler
-		Contacts *adminHandlers.ContactsHandler
-		Events   *adminHandlers.EventsHandler
-		Settings *adminHandlers.SettingsHandler
+		Auth      *adminHandlers.AuthHandler
+		Assets    *adminHandlers.AssetsHandler
+		Uploads   *adminHandlers.UploadsHandler
+		Products  *adminHandlers.ProductsHandler
+		Updates   *adminHandlers.UpdatesHandler
+		Contacts  *adminHandlers.ContactsHandler
+		Events    *adminHandlers.EventsHandler
+		Settings  *adminHandlers.SettingsHandler
+		Users     *adminHandlers.UsersHandler
+		TwoFactor *adminHandlers.TwoFactorHandler
+		Sessions  *adminHandlers.SessionsHandler
+		AuditLogs *adminHandlers.AuditLogsHandler
 		// Middleware applied to protected admin routes.
 		AuthMiddleware gin.HandlerFunc
+		// Optional middleware enforcing 2FA enrollment (installed after /me routes).
+		TwoFactorMiddleware gin.HandlerFunc
 	}
 }
 
 // New builds a gin.Engine with common middleware and routes.
 func New(deps Dependencies) *gin.Engine {
 	r := gin.New()
+	// Gin trusts every proxy by default, which lets any client pick its own IP
+	// with X-Forwarded-For (and a fresh login rate-limit bucket with it).
+	if err := r.SetTrustedProxies(deps.TrustedProxies); err != nil {
+		// config.Load validates the list; fail closed all the same.
+		_ = r.SetTrustedProxies(nil)
+	}
 
 	// Request ID (X-Request-Id). Useful for tracing and logs.
 	r.Use(requestid.New())
@@ -108,6 +125,9 @@ func New(deps Dependencies) *gin.Engine {
 			authGroup.POST("/token", deps.Auth.IssueToken)
 		}
 		authGroup.GET("/verify", deps.Auth.VerifyToken)
+
+		// Public keys for third-party token verification (RS256/EdDSA).
+		r.GET("/.well-known/jwks.json", deps.Auth.JWKS)
 	}
 
 	// Public website APIs (no auth)
@@ -115,9 +135,11 @@ func New(deps Dependencies) *gin.Engine {
 		api := r.Group("/api/v1")
 		if deps.Public.Assets != nil {
 			api.GET("/assets/*key", deps.Public.Assets.Get)
+			api.HEAD("/assets/*key", deps.Public.Assets.Get)
 		}
 		if deps.Public.Products != nil {
 			api.GET("/products", deps.Public.Products.List)
+			api.GET("/products/search", deps.Public.Products.Search)
 			api.GET("/products/:id", deps.Public.Products.Get)
 		}
 		if deps.Public.Updates != nil {
@@ -133,62 +155,133 @@ func New(deps Dependencies) *gin.Engine {
 	}
 
 	// Admin backoffice APIs (JWT-protected)
-	if deps.Admin.Auth != nil || deps.Admin.Products != nil || deps.Admin.Updates != nil || deps.Admin.Contacts != nil || deps.Admin.Events != nil || deps.Admin.Settings != nil {
+	if deps.Admin.Auth != nil || deps.Admin.Products != nil || deps.Admin.Updates != nil || deps.Admin.Contacts != nil || deps.Admin.Events != nil || deps.Admin.Settings != nil || deps.Admin.Users != nil {
 		admin := r.Group("/api/v1/admin")
 		if deps.Admin.Auth != nil {
 			// Login is unprotected.
 			admin.POST("/auth/login", deps.Admin.Auth.Login)
 			// Refresh is unprotected (it authenticates via refresh token).
 			admin.POST("/auth/refresh", deps.Admin.Auth.Refresh)
+			// Second login step for 2FA users (authenticates via mfa_token).
+			admin.POST("/auth/2fa/verify", deps.Admin.Auth.VerifyTwoFactor)
+		}
+		if deps.Admin.Users != nil {
+			// Invite/reset redemption is unprotected (it authenticates via one-time token).
+			admin.POST("/auth/accept-token", deps.Admin.Users.AcceptToken)
 		}
 		// Protected admin routes.
 		if deps.Admin.AuthMiddleware != nil {
 			admin.Use(deps.Admin.AuthMiddleware)
 		}
+		// can guards a route with a role permission (see model.RolePermissions).
+		// Without an auth middleware there is no user to check against.
+		can := func(perm model.Permission, h gin.HandlerFunc) []gin.HandlerFunc {
+			if deps.Admin.AuthMiddleware == nil {
+				return []gin.HandlerFunc{h}
+			}
+			return []gin.HandlerFunc{middleware.RequirePermission(perm), h}
+		}
+		if deps.Admin.Auth != nil {
+			// Any authenticated role may read its profile, change its own password,
+			// manage its own 2FA and sessions, and log out. These routes stay reachable
+			// before 2FA enrollment.
+			admin.GET("/me", deps.Admin.Auth.Me)
+			admin.PATCH("/me/password", deps.Admin.Auth.ChangePassword)
+			admin.POST("/auth/logout", deps.Admin.Auth.Logout)
+		}
+		if deps.Admin.Sessions != nil {
+			admin.GET("/sessions", deps.Admin.Sessions.List)
+			admin.DELETE("/sessions/:id", deps.Admin.Sessions.Delete)
+		}
+		if deps.Admin.TwoFactor != nil {
+			admin.GET("/me/2fa", deps.Admin.TwoFactor.Status)
+			admin.POST("/me/2fa/setup", deps.Admin.TwoFactor.Setup)
+			admin.POST("/me/2fa/confirm", deps.Admin.TwoFactor.Confirm)
+			admin.POST("/me/2fa/recovery-codes", deps.Admin.TwoFactor.RegenerateRecoveryCodes)
+			admin.POST("/me/2fa/disable", deps.Admin.TwoFactor.Disable)
+		}
+		if deps.Admin.TwoFactorMiddleware != nil {
+			admin.Use(deps.Admin.TwoFactorMiddleware)
+		}
 		if deps.Admin.Assets != nil {
-			admin.GET("/assets/*key", deps.Admin.Assets.Get)
+			admin.GET("/assets/*key", can(model.PermAssetsRead, deps.Admin.Assets.Get)...)
+			admin.HEAD("/assets/*key", can(model.PermAssetsRead, deps.Admin.Assets.Get)...)
 		}
 		if deps.Admin.Uploads != nil {
-			admin.POST("/uploads/images", deps.Admin.Uploads.UploadImage)
+			admin.POST("/uploads/images", can(model.PermUploadsWrite, deps.Admin.Uploads.UploadImage)...)
+			admin.POST("/uploads/presign", can(model.PermUploadsWrite, deps.Admin.Uploads.PresignUpload)...)
+			admin.POST("/uploads/finalize", can(model.PermUploadsWrite, deps.Admin.Uploads.FinalizeUpload)...)
 		}
 		if deps.Admin.Settings != nil {
-			admin.GET("/settings/product-detail-template", deps.Admin.Settings.GetProductDetailTemplate)
-			admin.PUT("/settings/product-detail-template", deps.Admin.Settings.PutProductDetailTemplate)
-		}
-		if deps.Admin.Auth != nil {
-			admin.GET("/me", deps.Admin.Auth.Me)
-			admin.PATCH("/me/password", deps.Admin.Auth.ChangePassword)
+			admin.GET("/settings/product-detail-template", can(model.PermSettingsRead, deps.Admin.Settings.GetProductDetailTemplate)...)
+			admin.PUT("/settings/product-detail-template", can(model.PermSettingsWrite, deps.Admin.Settings.PutProductDetailTemplate)...)
+			admin.GET("/settings/product-detail-templates", can(model.PermSettingsRead, deps.Admin.Settings.ListProductDetailTemplates)...)
+			admin.GET("/settings/product-detail-templates/:category", can(model.PermSettingsRead, deps.Admin.Settings.GetCategoryProductDetailTemplate)...)
+			admin.PUT("/settings/product-detail-templates/:category", can(model.PermSettingsWrite, deps.Admin.Settings.PutCategoryProductDetailTemplate)...)
+			admin.DELETE("/settings/product-detail-templates/:category", can(model.PermSettingsWrite, deps.Admin.Settings.DeleteCategoryProductDetailTemplate)...)
+			admin.GET("/settings/product-detail-schema", can(model.PermSettingsRead, deps.Admin.Settings.GetProductDetailSchema)...)
 		}
 		if deps.Admin.Products != nil {
-			admin.GET("/products", deps.Admin.Products.List)
-			admin.POST("/products", deps.Admin.Products.Create)
-			admin.GET("/products/:id", deps.Admin.Products.Get)
-			admin.PATCH("/products/:id", deps.Admin.Products.Update)
-			admin.POST("/products/:id/publish", deps.Admin.Products.Publish)
-			admin.POST("/products/:id/unpublish", deps.Admin.Products.Unpublish)
-			admin.DELETE("/products/:id", deps.Admin.Products.Delete)
+			admin.GET("/products", can(model.PermProductsRead, deps.Admin.Products.List)...)
+			admin.GET("/products/scheduled", can(model.PermProductsRead, deps.Admin.Products.Scheduled)...)
+			admin.POST("/products", can(model.PermProductsWrite, deps.Admin.Products.Create)...)
+			admin.POST("/products/apply-template/preview", can(model.PermProductsRead, deps.Admin.Products.PreviewApplyTemplate)...)
+			admin.POST("/products/apply-template", can(model.PermProductsWrite, deps.Admin.Products.ApplyTemplate)...)
+			admin.GET("/products/apply-template/jobs/:jobId", can(model.PermProductsRead, deps.Admin.Products.GetApplyTemplateJob)...)
+			admin.GET("/products/:id", can(model.PermProductsRead, deps.Admin.Products.Get)...)
+			admin.PATCH("/products/:id", can(model.PermProductsWrite, deps.Admin.Products.Update)...)
+			admin.POST("/products/:id/publish", can(model.PermProductsWrite, deps.Admin.Products.Publish)...)
+			admin.POST("/products/:id/unpublish", can(model.PermProductsWrite, deps.Admin.Products.Unpublish)...)
+			admin.POST("/products/:id/rename-style", can(model.PermProductsWrite, deps.Admin.Products.RenameStyle)...)
+			admin.PUT("/products/:id/schedule", can(model.PermProductsWrite, deps.Admin.Products.Schedule)...)
+			admin.DELETE("/products/:id", can(model.PermProductsWrite, deps.Admin.Products.Delete)...)
+			admin.GET("/products/:id/variants", can(model.PermProductsRead, deps.Admin.Products.ListVariants)...)
+			admin.POST("/products/:id/variants", can(model.PermProductsWrite, deps.Admin.Products.CreateVariant)...)
+			admin.PATCH("/products/:id/variants/:variantId", can(model.PermProductsWrite, deps.Admin.Products.UpdateVariant)...)
+			admin.DELETE("/products/:id/variants/:variantId", can(model.PermProductsWrite, deps.Admin.Products.DeleteVariant)...)
+			admin.GET("/products/:id/revisions", can(model.PermProductsRead, deps.Admin.Products.ListRevisions)...)
+			admin.GET("/products/:id/revisions/diff", can(model.PermProductsRead, deps.Admin.Products.DiffRevisions)...)
+			admin.GET("/products/:id/revisions/:rev", can(model.PermProductsRead, deps.Admin.Products.GetRevision)...)
+			admin.POST("/products/:id/revisions/:rev/restore", can(model.PermProductsWrite, deps.Admin.Products.RestoreRevision)...)
 		}
 		if deps.Admin.Updates != nil {
-			admin.GET("/updates", deps.Admin.Updates.List)
-			admin.POST("/updates", deps.Admin.Updates.Create)
-			admin.GET("/updates/:id", deps.Admin.Updates.Get)
-			admin.PATCH("/updates/:id", deps.Admin.Updates.Update)
-			admin.POST("/updates/:id/publish", deps.Admin.Updates.Publish)
-			admin.POST("/updates/:id/unpublish", deps.Admin.Updates.Unpublish)
-			admin.DELETE("/updates/:id", deps.Admin.Updates.Delete)
+			admin.GET("/updates", can(model.PermUpdatesRead, deps.Admin.Updates.List)...)
+			admin.GET("/updates/scheduled", can(model.PermUpdatesRead, deps.Admin.Updates.Scheduled)...)
+			admin.POST("/updates", can(model.PermUpdatesWrite, deps.Admin.Updates.Create)...)
+			admin.GET("/updates/:id", can(model.PermUpdatesRead, deps.Admin.Updates.Get)...)
+			admin.PATCH("/updates/:id", can(model.PermUpdatesWrite, deps.Admin.Updates.Update)...)
+			admin.POST("/updates/:id/publish", can(model.PermUpdatesWrite, deps.Admin.Updates.Publish)...)
+			admin.POST("/updates/:id/unpublish", can(model.PermUpdatesWrite, deps.Admin.Updates.Unpublish)...)
+			admin.PUT("/updates/:id/schedule", can(model.PermUpdatesWrite, deps.Admin.Updates.Schedule)...)
+			admin.DELETE("/updates/:id", can(model.PermUpdatesWrite, deps.Admin.Updates.Delete)...)
 		}
 		if deps.Admin.Contacts != nil {
-			admin.GET("/contacts", deps.Admin.Contacts.List)
-			admin.GET("/contacts/unread-count", deps.Admin.Contacts.UnreadCount)
-			admin.GET("/contacts/:id", deps.Admin.Contacts.Get)
-			admin.PATCH("/contacts/:id", deps.Admin.Contacts.Update)
-			admin.DELETE("/contacts/:id", deps.Admin.Contacts.Delete)
+			admin.GET("/contacts", can(model.PermContactsRead, deps.Admin.Contacts.List)...)
+			admin.GET("/contacts/unread-count", can(model.PermContactsRead, deps.Admin.Contacts.UnreadCount)...)
+			admin.GET("/contacts/:id", can(model.PermContactsRead, deps.Admin.Contacts.Get)...)
+			admin.PATCH("/contacts/:id", can(model.PermContactsWrite, deps.Admin.Contacts.Update)...)
+			admin.DELETE("/contacts/:id", can(model.PermContactsWrite, deps.Admin.Contacts.Delete)...)
 		}
 		if deps.Admin.Events != nil {
-			admin.GET("/events", deps.Admin.Events.List)
-			admin.GET("/events/metrics", deps.Admin.Events.Metrics)
-			admin.GET("/events/:id", deps.Admin.Events.Get)
-			admin.DELETE("/events/:id", deps.Admin.Events.Delete)
+			admin.GET("/events", can(model.PermEventsRead, deps.Admin.Events.List)...)
+			admin.GET("/events/metrics", can(model.PermEventsRead, deps.Admin.Events.Metrics)...)
+			admin.GET("/events/:id", can(model.PermEventsRead, deps.Admin.Events.Get)...)
+			admin.DELETE("/events/:id", can(model.PermEventsWrite, deps.Admin.Events.Delete)...)
+		}
+		if deps.Admin.Users != nil {
+			admin.GET("/users", can(model.PermUsersManage, deps.Admin.Users.List)...)
+			admin.POST("/users", can(model.PermUsersManage, deps.Admin.Users.Create)...)
+			admin.GET("/users/:id", can(model.PermUsersManage, deps.Admin.Users.Get)...)
+			admin.PATCH("/users/:id", can(model.PermUsersManage, deps.Admin.Users.Update)...)
+			admin.POST("/users/:id/disable", can(model.PermUsersManage, deps.Admin.Users.Disable)...)
+			admin.POST("/users/:id/enable", can(model.PermUsersManage, deps.Admin.Users.Enable)...)
+			admin.POST("/users/:id/unlock", can(model.PermUsersManage, deps.Admin.Users.Unlock)...)
+			admin.POST("/users/:id/2fa/reset", can(model.PermUsersManage, deps.Admin.Users.ResetTwoFactor)...)
+			admin.POST("/users/:id/reset-password", can(model.PermUsersManage, deps.Admin.Users.ResetPassword)...)
+			admin.DELETE("/users/:id", can(model.PermUsersManage, deps.Admin.Users.Delete)...)
+		}
+		if deps.Admin.AuditLogs != nil {
+			admin.GET("/audit-logs", can(model.PermAuditRead, deps.Admin.AuditLogs.List)...)
 		}
 	}
 
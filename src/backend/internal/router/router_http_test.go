@@ -7,6 +7,8 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -21,6 +23,8 @@ import (
 	"evening-gown/internal/handler/health"
 	publicHandlers "evening-gown/internal/handler/public"
 	"evening-gown/internal/middleware"
+	"evening-gown/internal/model"
+	"evening-gown/internal/security"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/sqlite"
@@ -572,6 +576,1521 @@ func TestRouter_PublicAndAdmin_APIs_EndToEnd(t *testing.T) {
 	}
 }
 
+func TestRouter_PublicProductSearch_RanksAndFacets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+	now := time.Now().UTC()
+
+	products := []model.Product{
+		{Slug: "style-ss25-dr-01", StyleNo: "SS25-DR-01", Season: "ss25", Category: "gown", Availability: "in_stock", PriceMode: "negotiable", PublishedAt: &now,
+			DetailJSON: json.RawMessage(`{"title_i18n":{"zh":"月光礼服","en":"Moonlight Gown"},"description_i18n":{"en":"Silk satin with a long train"}}`)},
+		{Slug: "style-fw25-dr-02", StyleNo: "FW25-DR-02", Season: "fw25", Category: "couture", Availability: "preorder", PriceMode: "negotiable", PublishedAt: &now,
+			DetailJSON: json.RawMessage(`{"title_i18n":{"en":"Aurora"},"specs":[{"key":"fabric","value_i18n":{"en":"Silk organza"}}]}`)},
+		{Slug: "style-ss25-dr-03", StyleNo: "SS25-DR-03", Season: "ss25", Category: "gown", Availability: "in_stock", PriceMode: "negotiable",
+			DetailJSON: json.RawMessage(`{"title_i18n":{"en":"Silk Draft"}}`)},
+	}
+	for i := range products {
+		if err := db.Create(&products[i]).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+	}
+
+	deps := Dependencies{}
+	deps.Public.Products = publicHandlers.NewProductsHandler(db, cache.NewPublicCache(nil))
+	r := New(deps)
+
+	// Missing q.
+	{
+		resp := doRequest(t, r, http.MethodGet, "/api/v1/products/search", nil, nil)
+		if resp.Code != http.StatusBadRequest {
+			t.Fatalf("expected %d, got %d: %s", http.StatusBadRequest, resp.Code, resp.Body.String())
+		}
+	}
+
+	// Spec/description text matches across published products only; drafts are excluded.
+	{
+		resp := doRequest(t, r, http.MethodGet, "/api/v1/products/search?q=silk", nil, nil)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if total := mustUintFromJSONNumber(t, got["total"]); total != 2 {
+			t.Fatalf("expected total=2, got %v", got["total"])
+		}
+		facets, _ := got["facets"].(map[string]any)
+		seasons, _ := facets["season"].([]any)
+		if len(seasons) != 2 {
+			t.Fatalf("expected 2 season buckets, got %#v", facets["season"])
+		}
+	}
+
+	// Style number fragment ranks the exact style first; facets ignore their own filter.
+	{
+		resp := doRequest(t, r, http.MethodGet, "/api/v1/products/search?q=dr-0&season=ss25", nil, nil)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		items, _ := got["items"].([]any)
+		if len(items) != 1 {
+			t.Fatalf("expected 1 item, got %#v", got["items"])
+		}
+		if first, _ := items[0].(map[string]any); first["styleNo"] != "SS25-DR-01" {
+			t.Fatalf("unexpected first item: %#v", first)
+		}
+		facets, _ := got["facets"].(map[string]any)
+		seasons, _ := facets["season"].([]any)
+		if len(seasons) != 2 {
+			t.Fatalf("expected season facet to ignore season filter, got %#v", facets["season"])
+		}
+	}
+
+	// Localized (zh) titles are searchable.
+	{
+		resp := doRequest(t, r, http.MethodGet, "/api/v1/products/search?q="+url.QueryEscape("月光"), nil, nil)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if total := mustUintFromJSONNumber(t, got["total"]); total != 1 {
+			t.Fatalf("expected total=1, got %v", got["total"])
+		}
+	}
+
+	// Only values are searched: JSON keys match nothing, spec values do.
+	for q, want := range map[string]uint{"zh": 0, "specs": 0, "value_i18n": 0, "organza": 1} {
+		resp := doRequest(t, r, http.MethodGet, "/api/v1/products/search?q="+q, nil, nil)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if total := mustUintFromJSONNumber(t, got["total"]); total != want {
+			t.Fatalf("q=%s: expected total=%d, got %v", q, want, got["total"])
+		}
+	}
+}
+
+func TestRouter_PublicProducts_ImageMeta(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+	now := time.Now().UTC()
+
+	cover := "products/SS25-DR-09/cover/2025/01/01/u1/w2048.webp"
+	gallery := "products/SS25-DR-09/gallery/2025/01/01/g1/w1280.webp"
+	p := model.Product{Slug: "style-ss25-dr-09", StyleNo: "SS25-DR-09", Season: "ss25", Category: "gown", Availability: "in_stock", PriceMode: "negotiable", PublishedAt: &now,
+		CoverImageKey: cover,
+		HoverImageURL: "https://example.com/hover.jpg",
+		DetailJSON:    json.RawMessage(`{"gallery":[{"url":"/api/v1/assets/` + gallery + `?w=640"},{"url":"https://example.com/x.jpg"},"/api/v1/assets/` + gallery + `"]}`),
+	}
+	if err := db.Create(&p).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	for _, a := range []model.Asset{
+		{ObjectKey: cover, Width: 2048, Height: 3072, Blurhash: "TiTRKJ*eeTujhzeTenenf6yXkqf+", DominantColor: "#f4efe9"},
+		{ObjectKey: gallery, Width: 1280, Height: 1920, DominantColor: "#101010"},
+	} {
+		if err := db.Create(&a).Error; err != nil {
+			t.Fatalf("create asset: %v", err)
+		}
+	}
+
+	deps := Dependencies{}
+	deps.Public.Products = publicHandlers.NewProductsHandler(db, cache.NewPublicCache(nil))
+	r := New(deps)
+
+	{
+		resp := doRequest(t, r, http.MethodGet, "/api/v1/products", nil, nil)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		items, _ := got["items"].([]any)
+		if len(items) != 1 {
+			t.Fatalf("expected 1 item, got %#v", got["items"])
+		}
+		item, _ := items[0].(map[string]any)
+		meta, _ := item["coverImageMeta"].(map[string]any)
+		if mustUintFromJSONNumber(t, meta["width"]) != 2048 || mustUintFromJSONNumber(t, meta["height"]) != 3072 ||
+			meta["blurhash"] != "TiTRKJ*eeTujhzeTenenf6yXkqf+" || meta["dominantColor"] != "#f4efe9" {
+			t.Fatalf("unexpected coverImageMeta: %#v", item["coverImageMeta"])
+		}
+		if _, ok := item["hoverImageMeta"]; ok {
+			t.Fatalf("unregistered hover image must have no meta: %#v", item)
+		}
+	}
+
+	{
+		resp := doRequest(t, r, http.MethodGet, "/api/v1/products/"+strconv.Itoa(int(p.ID)), nil, nil)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if meta, _ := got["coverImageMeta"].(map[string]any); meta["dominantColor"] != "#f4efe9" {
+			t.Fatalf("unexpected coverImageMeta: %#v", got["coverImageMeta"])
+		}
+		detail, _ := got["detail"].(map[string]any)
+		entries, _ := detail["gallery"].([]any)
+		if len(entries) != 3 {
+			t.Fatalf("unexpected gallery: %#v", detail["gallery"])
+		}
+		first, _ := entries[0].(map[string]any)
+		if mustUintFromJSONNumber(t, first["width"]) != 1280 || mustUintFromJSONNumber(t, first["height"]) != 1920 || first["dominantColor"] != "#101010" {
+			t.Fatalf("unexpected gallery entry: %#v", first)
+		}
+		if _, ok := first["blurhash"]; ok {
+			t.Fatalf("empty blurhash must be omitted: %#v", first)
+		}
+		if second, _ := entries[1].(map[string]any); second["width"] != nil {
+			t.Fatalf("external image must not be annotated: %#v", second)
+		}
+	}
+}
+
+func TestRouter_PublicProducts_Variants(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+	now := time.Now().UTC()
+
+	p := model.Product{Slug: "style-ss25-dr-10", StyleNo: "SS25-DR-10", Season: "ss25", Category: "gown", Availability: "in_stock", PriceMode: "negotiable", PublishedAt: &now}
+	if err := db.Create(&p).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	for _, v := range []model.ProductVariant{
+		{ProductID: p.ID, Code: "SS25-DR-10-IVORY-M", Options: json.RawMessage(`[{"group":"color","value":"ivory"},{"group":"size","value":"m"}]`), OptionsKey: "color=ivory;size=m", Availability: "preorder", MOQ: 5, LeadTimeDays: 30, Position: 2,
+			ImageKeys: json.RawMessage(`["products/SS25-DR-10/variant/2025/01/01/v1/w1280.webp"]`)},
+		{ProductID: p.ID, Code: "SS25-DR-10-IVORY-S", Options: json.RawMessage(`[{"group":"color","value":"ivory"},{"group":"size","value":"s"}]`), OptionsKey: "color=ivory;size=s", Availability: "in_stock", Position: 1,
+			ImageKeys: json.RawMessage(`[]`)},
+	} {
+		if err := db.Create(&v).Error; err != nil {
+			t.Fatalf("create variant: %v", err)
+		}
+	}
+
+	deps := Dependencies{}
+	deps.Public.Products = publicHandlers.NewProductsHandler(db, cache.NewPublicCache(nil))
+	r := New(deps)
+
+	resp := doRequest(t, r, http.MethodGet, "/api/v1/products/"+strconv.Itoa(int(p.ID)), nil, nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+	var got map[string]any
+	mustJSON(t, resp.Body.Bytes(), &got)
+	variants, _ := got["variants"].([]any)
+	if len(variants) != 2 {
+		t.Fatalf("unexpected variants: %#v", got["variants"])
+	}
+	first, _ := variants[0].(map[string]any)
+	second, _ := variants[1].(map[string]any)
+	if first["code"] != "SS25-DR-10-IVORY-S" || second["code"] != "SS25-DR-10-IVORY-M" {
+		t.Fatalf("variants must follow position: %#v", variants)
+	}
+	if second["availability"] != "preorder" || mustUintFromJSONNumber(t, second["moq"]) != 5 || mustUintFromJSONNumber(t, second["leadTimeDays"]) != 30 {
+		t.Fatalf("unexpected variant: %#v", second)
+	}
+	images, _ := second["images"].([]any)
+	if len(images) != 1 || images[0] != "/api/v1/assets/products/SS25-DR-10/variant/2025/01/01/v1/w1280.webp" {
+		t.Fatalf("unexpected variant images: %#v", second["images"])
+	}
+	if _, ok := first["id"]; ok {
+		t.Fatalf("internal fields must not be exposed: %#v", first)
+	}
+}
+
+func TestRouter_AdminProductDetail_SchemaValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+
+	deps := Dependencies{}
+	deps.Admin.Products = adminHandlers.NewProductsHandler(db, cache.NewPublicCache(nil))
+	deps.Admin.Settings = adminHandlers.NewSettingsHandler(db)
+	r := New(deps)
+
+	{
+		resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/settings/product-detail-schema", nil, nil)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if _, ok := got["$defs"].(map[string]any)["section"]; !ok {
+			t.Fatalf("unexpected schema: %s", resp.Body.String())
+		}
+	}
+
+	type schemaErrors struct {
+		Error  string `json:"error"`
+		Errors []struct {
+			Path    string `json:"path"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	invalid := `{"schema_version":2,"specs":{},"sections":[{"id":"x","type":"carousel","area":"main","titel_i18n":{}}]}`
+
+	{
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/products", []byte(`{"styleNo":"5001","season":"ss25","category":"gown","availability":"in_stock","detail":`+invalid+`}`), jsonHeaders())
+		if resp.Code != http.StatusBadRequest {
+			t.Fatalf("expected %d, got %d: %s", http.StatusBadRequest, resp.Code, resp.Body.String())
+		}
+		var got schemaErrors
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if got.Error != "invalid detail" || len(got.Errors) != 3 ||
+			got.Errors[0].Path != "/sections/0/titel_i18n" || got.Errors[1].Path != "/sections/0/type" || got.Errors[2].Path != "/specs" {
+			t.Fatalf("unexpected errors: %s", resp.Body.String())
+		}
+	}
+
+	var created model.Product
+	{
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/products", []byte(`{"styleNo":"5001","season":"ss25","category":"gown","availability":"in_stock"}`), jsonHeaders())
+		if resp.Code != http.StatusCreated {
+			t.Fatalf("expected %d, got %d: %s", http.StatusCreated, resp.Code, resp.Body.String())
+		}
+		mustJSON(t, resp.Body.Bytes(), &created)
+	}
+	{
+		path := "/api/v1/admin/products/" + strconv.FormatUint(uint64(created.ID), 10)
+		resp := doRequest(t, r, http.MethodPatch, path, []byte(`{"detail":`+invalid+`}`), jsonHeaders())
+		if resp.Code != http.StatusBadRequest {
+			t.Fatalf("expected %d, got %d: %s", http.StatusBadRequest, resp.Code, resp.Body.String())
+		}
+		resp = doRequest(t, r, http.MethodPatch, path, []byte(`{"detail":{"schema_version":2,"sections":[{"id":"x","type":"divider","area":"main"}]}}`), jsonHeaders())
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+	}
+
+	{
+		resp := doRequest(t, r, http.MethodPut, "/api/v1/admin/settings/product-detail-template", []byte(`{"value":`+invalid+`}`), jsonHeaders())
+		if resp.Code != http.StatusBadRequest {
+			t.Fatalf("expected %d, got %d: %s", http.StatusBadRequest, resp.Code, resp.Body.String())
+		}
+		var got schemaErrors
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if got.Error != "invalid value" || len(got.Errors) != 3 {
+			t.Fatalf("unexpected errors: %s", resp.Body.String())
+		}
+		resp = doRequest(t, r, http.MethodGet, "/api/v1/admin/settings/product-detail-template", nil, nil)
+		if strings.Contains(resp.Body.String(), "carousel") {
+			t.Fatalf("invalid template must not be stored: %s", resp.Body.String())
+		}
+	}
+}
+
+func TestRouter_ProductDetail_MigratedOnReadAndWrite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+	now := time.Now().UTC()
+
+	legacy := json.RawMessage(`{"title_i18n":{"en":"Legacy"},"specs":[{"k":"Fabric","v":"Silk"}]}`)
+	p := model.Product{Slug: "style-6001", StyleNo: "6001", Season: "ss25", Category: "gown", Availability: "in_stock", PriceMode: "negotiable", PublishedAt: &now, DetailJSON: legacy}
+	if err := db.Create(&p).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+
+	deps := Dependencies{}
+	deps.Admin.Products = adminHandlers.NewProductsHandler(db, cache.NewPublicCache(nil))
+	deps.Public.Products = publicHandlers.NewProductsHandler(db, cache.NewPublicCache(nil))
+	r := New(deps)
+	id := strconv.FormatUint(uint64(p.ID), 10)
+
+	for _, path := range []string{"/api/v1/admin/products/" + id, "/api/v1/products/" + id} {
+		resp := doRequest(t, r, http.MethodGet, path, nil, nil)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("%s: expected %d, got %d: %s", path, http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got struct {
+			Detail struct {
+				SchemaVersion int              `json:"schema_version"`
+				Specs         []map[string]any `json:"specs"`
+				Sections      []any            `json:"sections"`
+			} `json:"detail"`
+		}
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if got.Detail.SchemaVersion != 2 || len(got.Detail.Sections) == 0 || len(got.Detail.Specs) != 1 || got.Detail.Specs[0]["key"] != "Fabric" {
+			t.Fatalf("%s: expected a migrated detail, got %s", path, resp.Body.String())
+		}
+	}
+	var stored model.Product
+	db.First(&stored, p.ID)
+	if string(stored.DetailJSON) != string(legacy) {
+		t.Fatalf("reads must not write: %s", stored.DetailJSON)
+	}
+
+	// Legacy documents submitted by older clients are stored migrated.
+	resp := doRequest(t, r, http.MethodPatch, "/api/v1/admin/products/"+id, []byte(`{"detail":{"specs":[{"k":"件数","v":"2"}]}}`), jsonHeaders())
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+	db.First(&stored, p.ID)
+	var detail map[string]any
+	_ = json.Unmarshal(stored.DetailJSON, &detail)
+	specs, _ := detail["specs"].([]any)
+	first, _ := specs[0].(map[string]any)
+	if detail["schema_version"] != float64(2) || first["key"] != "pieces" || first["k"] != nil {
+		t.Fatalf("unexpected stored detail: %s", stored.DetailJSON)
+	}
+	if errs := model.ValidateProductDetail(stored.DetailJSON); len(errs) != 0 {
+		t.Fatalf("stored detail does not validate: %v", errs)
+	}
+}
+
+func TestRouter_AdminProductDetailTemplates_PerCategory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+
+	deps := Dependencies{}
+	deps.Admin.Products = adminHandlers.NewProductsHandler(db, cache.NewPublicCache(nil))
+	deps.Admin.Settings = adminHandlers.NewSettingsHandler(db)
+	r := New(deps)
+
+	const base = "/api/v1/admin/settings/product-detail-templates/"
+	template := func(spec string) []byte {
+		return []byte(`{"value":{"schema_version":2,"specs":[{"key":"` + spec + `","label_i18n":{"en":"` + spec + `"},"value_i18n":{"en":""}}],` +
+			`"sections":[{"id":"gallery","type":"gallery","area":"media"},{"id":"` + spec + `","type":"specs","area":"main"}]}}`)
+	}
+	type templateResponse struct {
+		Key       string          `json:"key"`
+		Category  string          `json:"category"`
+		Inherited bool            `json:"inherited"`
+		Value     json.RawMessage `json:"value"`
+	}
+	getTemplate := func(category string) templateResponse {
+		t.Helper()
+		resp := doRequest(t, r, http.MethodGet, base+category, nil, nil)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got templateResponse
+		mustJSON(t, resp.Body.Bytes(), &got)
+		return got
+	}
+	specKeys := func(detail json.RawMessage) []string {
+		var d struct {
+			Specs []struct {
+				Key string `json:"key"`
+			} `json:"specs"`
+		}
+		_ = json.Unmarshal(detail, &d)
+		var keys []string
+		for _, s := range d.Specs {
+			keys = append(keys, s.Key)
+		}
+		return keys
+	}
+
+	if resp := doRequest(t, r, http.MethodPut, "/api/v1/admin/settings/product-detail-template", template("fabric"), jsonHeaders()); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+	if got := getTemplate("bridal"); !got.Inherited || got.Key != "product_detail_template:bridal" || !reflect.DeepEqual(specKeys(got.Value), []string{"fabric"}) {
+		t.Fatalf("expected the global template, got %+v", got)
+	}
+
+	if resp := doRequest(t, r, http.MethodPut, base+"Bridal", template("veil"), jsonHeaders()); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+	if got := getTemplate("bridal"); got.Inherited || got.Category != "bridal" || !reflect.DeepEqual(specKeys(got.Value), []string{"veil"}) {
+		t.Fatalf("expected the bridal template, got %+v", got)
+	}
+	for _, tc := range []struct {
+		method, path string
+		body         []byte
+	}{
+		{http.MethodGet, base + "bad%20category", nil},
+		{http.MethodPut, base + "couture", []byte(`{"value":{"schema_version":2}}`)},
+	} {
+		if resp := doRequest(t, r, tc.method, tc.path, tc.body, jsonHeaders()); resp.Code != http.StatusBadRequest {
+			t.Fatalf("%s %s: expected %d, got %d: %s", tc.method, tc.path, http.StatusBadRequest, resp.Code, resp.Body.String())
+		}
+	}
+	{
+		resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/settings/product-detail-templates", nil, nil)
+		var got struct {
+			Items []struct {
+				Category string `json:"category"`
+			} `json:"items"`
+		}
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if len(got.Items) != 1 || got.Items[0].Category != "bridal" {
+			t.Fatalf("unexpected templates: %s", resp.Body.String())
+		}
+	}
+
+	create := func(styleNo, category string) model.Product {
+		t.Helper()
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/products", []byte(`{"styleNo":"`+styleNo+`","season":"ss25","category":"`+category+`","availability":"in_stock"}`), jsonHeaders())
+		if resp.Code != http.StatusCreated {
+			t.Fatalf("expected %d, got %d: %s", http.StatusCreated, resp.Code, resp.Body.String())
+		}
+		var p model.Product
+		mustJSON(t, resp.Body.Bytes(), &p)
+		return p
+	}
+	if p := create("7001", "bridal"); !reflect.DeepEqual(specKeys(p.DetailJSON), []string{"veil"}) {
+		t.Fatalf("expected the bridal template, got %s", p.DetailJSON)
+	}
+	gown := create("7002", "gown")
+	if !reflect.DeepEqual(specKeys(gown.DetailJSON), []string{"fabric"}) {
+		t.Fatalf("expected the global template, got %s", gown.DetailJSON)
+	}
+
+	// Changing the category merges the new category's template in.
+	path := "/api/v1/admin/products/" + strconv.FormatUint(uint64(gown.ID), 10)
+	resp := doRequest(t, r, http.MethodPatch, path, []byte(`{"category":"bridal"}`), jsonHeaders())
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+	var moved model.Product
+	mustJSON(t, resp.Body.Bytes(), &moved)
+	if !reflect.DeepEqual(specKeys(moved.DetailJSON), []string{"fabric", "veil"}) {
+		t.Fatalf("expected the bridal template merged in, got %s", moved.DetailJSON)
+	}
+
+	if resp := doRequest(t, r, http.MethodDelete, base+"bridal", nil, nil); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d: %s", http.StatusNoContent, resp.Code, resp.Body.String())
+	}
+	if got := getTemplate("bridal"); !got.Inherited {
+		t.Fatalf("expected the global template after delete, got %+v", got)
+	}
+	if resp := doRequest(t, r, http.MethodDelete, base+"bridal", nil, nil); resp.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d: %s", http.StatusNotFound, resp.Code, resp.Body.String())
+	}
+}
+
+func TestRouter_AdminProducts_ApplyTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+
+	deps := Dependencies{}
+	deps.Admin.Products = adminHandlers.NewProductsHandler(db, cache.NewPublicCache(nil))
+	deps.Admin.Settings = adminHandlers.NewSettingsHandler(db)
+	r := New(deps)
+
+	var products []model.Product
+	for _, styleNo := range []string{"8001", "8002"} {
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/products", []byte(`{"styleNo":"`+styleNo+`","season":"ss25","category":"gown","availability":"in_stock"}`), jsonHeaders())
+		if resp.Code != http.StatusCreated {
+			t.Fatalf("expected %d, got %d: %s", http.StatusCreated, resp.Code, resp.Body.String())
+		}
+		var p model.Product
+		mustJSON(t, resp.Body.Bytes(), &p)
+		products = append(products, p)
+	}
+
+	// Merchandising adds a spec row to the template afterwards.
+	tpl := `{"value":{"schema_version":2,"specs":[{"key":"fabric","label_i18n":{"en":"Fabric"},"value_i18n":{"en":""}}],"sections":[]}}`
+	if resp := doRequest(t, r, http.MethodPut, "/api/v1/admin/settings/product-detail-template", []byte(tpl), jsonHeaders()); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+
+	if resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/products/apply-template/preview", []byte(`{"category":"not a category"}`), jsonHeaders()); resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d, got %d: %s", http.StatusBadRequest, resp.Code, resp.Body.String())
+	}
+	{
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/products/apply-template/preview", nil, nil)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got struct {
+			Changed int `json:"changed"`
+			Items   []struct {
+				ProductID uint               `json:"productId"`
+				Changes   []model.JSONChange `json:"changes"`
+			} `json:"items"`
+		}
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if got.Changed != 2 || len(got.Items) != 2 || got.Items[0].ProductID != products[0].ID ||
+			len(got.Items[0].Changes) != 1 || got.Items[0].Changes[0].Path != "/specs/2" {
+			t.Fatalf("unexpected preview: %s", resp.Body.String())
+		}
+	}
+
+	resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/products/apply-template", []byte(`{"category":"gown"}`), jsonHeaders())
+	if resp.Code != http.StatusAccepted {
+		t.Fatalf("expected %d, got %d: %s", http.StatusAccepted, resp.Code, resp.Body.String())
+	}
+	var job model.TemplateApplyJob
+	mustJSON(t, resp.Body.Bytes(), &job)
+	if job.Status != model.TemplateApplyJobRunning || job.Total != 2 || job.Category != "gown" {
+		t.Fatalf("unexpected job: %s", resp.Body.String())
+	}
+
+	path := "/api/v1/admin/products/apply-template/jobs/" + strconv.FormatUint(uint64(job.ID), 10)
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status == model.TemplateApplyJobRunning {
+		if time.Now().After(deadline) {
+			t.Fatalf("job did not finish: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+		resp := doRequest(t, r, http.MethodGet, path, nil, nil)
+		if resp.Code != http.StatusOK {
+			// SQLite locks the table while a batch transaction is open.
+			continue
+		}
+		mustJSON(t, resp.Body.Bytes(), &job)
+	}
+	if job.Status != model.TemplateApplyJobSucceeded || job.Processed != 2 || job.Changed != 2 || job.Failed != 0 || job.FinishedAt == nil {
+		t.Fatalf("unexpected finished job: %+v", job)
+	}
+
+	var stored model.Product
+	db.First(&stored, products[1].ID)
+	if !strings.Contains(string(stored.DetailJSON), `"fabric"`) {
+		t.Fatalf("expected the template applied: %s", stored.DetailJSON)
+	}
+	var rev model.ProductRevision
+	db.Where("product_id = ?", stored.ID).Order("revision desc").First(&rev)
+	if rev.Source != model.RevisionSourceTemplate {
+		t.Fatalf("expected a template revision, got %+v", rev)
+	}
+
+	// The database allows one running job, whoever starts it.
+	running := model.TemplateApplyJob{Status: model.TemplateApplyJobRunning}
+	if err := db.Create(&running).Error; err != nil {
+		t.Fatalf("create running job: %v", err)
+	}
+	if err := db.Create(&model.TemplateApplyJob{Status: model.TemplateApplyJobRunning}).Error; err == nil {
+		t.Fatal("expected a second running job to be rejected")
+	}
+	resp = doRequest(t, r, http.MethodPost, "/api/v1/admin/products/apply-template", nil, nil)
+	if resp.Code != http.StatusConflict || !strings.Contains(resp.Body.String(), `"id":`+strconv.FormatUint(uint64(running.ID), 10)) {
+		t.Fatalf("expected %d with the running job, got %d: %s", http.StatusConflict, resp.Code, resp.Body.String())
+	}
+
+	if resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/products/apply-template/jobs/999", nil, nil); resp.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d: %s", http.StatusNotFound, resp.Code, resp.Body.String())
+	}
+}
+
+func TestRouter_AdminUsers_InviteAcceptAndDisable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+
+	jwtCfg := config.JWTConfig{Secret: "test-secret", Issuer: "evening-gown", ExpiresIn: time.Hour}
+	jwtSvc, err := jwtauth.New(jwtCfg)
+	if err != nil {
+		t.Fatalf("create jwt service: %v", err)
+	}
+
+	ownerEmail := "owner@example.com"
+	ownerPassword := "passw0rd123"
+	if err := bootstrap.EnsureSingleAdmin(db, ownerEmail, ownerPassword); err != nil {
+		t.Fatalf("ensure admin: %v", err)
+	}
+
+	deps := Dependencies{}
+	deps.Admin.Auth = adminHandlers.NewAuthHandler(db, jwtSvc)
+	deps.Admin.Products = adminHandlers.NewProductsHandler(db, cache.NewPublicCache(nil))
+	deps.Admin.Users = adminHandlers.NewUsersHandler(db, time.Hour)
+	deps.Admin.AuthMiddleware = middleware.AdminAuth(db, jwtSvc)
+	r := New(deps)
+
+	login := func(email, password string) *httptest.ResponseRecorder {
+		return doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/login", []byte(`{"email":"`+email+`","password":"`+password+`"}`), jsonHeaders())
+	}
+
+	var ownerToken string
+	{
+		resp := login(ownerEmail, ownerPassword)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		ownerToken, _ = got["token"].(string)
+	}
+
+	// Invite an editor.
+	var editorID uint
+	var inviteToken string
+	{
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/users", []byte(`{"email":"Editor@Example.com","role":"editor"}`), withAuth(jsonHeaders(), ownerToken))
+		if resp.Code != http.StatusCreated {
+			t.Fatalf("expected %d, got %d: %s", http.StatusCreated, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		user, _ := got["user"].(map[string]any)
+		invite, _ := got["invite"].(map[string]any)
+		editorID = mustUintFromJSONNumber(t, user["id"])
+		inviteToken, _ = invite["token"].(string)
+		if user["status"] != "invited" || strings.TrimSpace(inviteToken) == "" {
+			t.Fatalf("unexpected invite response: %s", resp.Body.String())
+		}
+	}
+
+	// Duplicate email and the legacy admin role are rejected.
+	{
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/users", []byte(`{"email":"editor@example.com","role":"viewer"}`), withAuth(jsonHeaders(), ownerToken))
+		if resp.Code != http.StatusConflict {
+			t.Fatalf("expected %d, got %d: %s", http.StatusConflict, resp.Code, resp.Body.String())
+		}
+		resp = doRequest(t, r, http.MethodPost, "/api/v1/admin/users", []byte(`{"email":"x@example.com","role":"admin"}`), withAuth(jsonHeaders(), ownerToken))
+		if resp.Code != http.StatusBadRequest {
+			t.Fatalf("expected %d, got %d: %s", http.StatusBadRequest, resp.Code, resp.Body.String())
+		}
+	}
+
+	// Invited users cannot log in until they accept.
+	editorPassword := "edit0rPass99"
+	if resp := login("editor@example.com", editorPassword); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d: %s", http.StatusUnauthorized, resp.Code, resp.Body.String())
+	}
+	{
+		body := []byte(`{"token":"` + inviteToken + `","password":"` + editorPassword + `"}`)
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/accept-token", body, jsonHeaders())
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		// Tokens are single-use.
+		resp = doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/accept-token", body, jsonHeaders())
+		if resp.Code != http.StatusBadRequest {
+			t.Fatalf("expected %d, got %d: %s", http.StatusBadRequest, resp.Code, resp.Body.String())
+		}
+	}
+
+	var editorToken string
+	{
+		resp := login("editor@example.com", editorPassword)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		editorToken, _ = got["token"].(string)
+	}
+
+	// Editors cannot manage users but can use catalog routes.
+	if resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/users", nil, withAuth(nil, editorToken)); resp.Code != http.StatusForbidden {
+		t.Fatalf("expected %d, got %d: %s", http.StatusForbidden, resp.Code, resp.Body.String())
+	}
+	if resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/products", nil, withAuth(nil, editorToken)); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+
+	// Disabling revokes the editor's existing token.
+	editorPath := "/api/v1/admin/users/" + strconv.FormatUint(uint64(editorID), 10)
+	if resp := doRequest(t, r, http.MethodPost, editorPath+"/disable", nil, withAuth(nil, ownerToken)); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+	if resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/products", nil, withAuth(nil, editorToken)); resp.Code != http.StatusForbidden && resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected disabled user to be rejected, got %d: %s", resp.Code, resp.Body.String())
+	}
+
+	// Re-inviting a deleted user's email restores the account as a new invite.
+	if resp := doRequest(t, r, http.MethodDelete, editorPath, nil, withAuth(nil, ownerToken)); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d: %s", http.StatusNoContent, resp.Code, resp.Body.String())
+	}
+	{
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/users", []byte(`{"email":"editor@example.com","role":"viewer"}`), withAuth(jsonHeaders(), ownerToken))
+		if resp.Code != http.StatusCreated {
+			t.Fatalf("expected %d, got %d: %s", http.StatusCreated, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		user, _ := got["user"].(map[string]any)
+		if mustUintFromJSONNumber(t, user["id"]) != editorID || user["status"] != "invited" || user["role"] != "viewer" || user["deletedAt"] != nil {
+			t.Fatalf("unexpected re-invite response: %s", resp.Body.String())
+		}
+	}
+	if resp := login("editor@example.com", editorPassword); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d: %s", http.StatusUnauthorized, resp.Code, resp.Body.String())
+	}
+
+	// The last active owner cannot be demoted or delete themselves.
+	var ownerID uint
+	if err := db.Model(&model.User{}).Select("id").Where("email = ?", ownerEmail).Scan(&ownerID).Error; err != nil {
+		t.Fatalf("load owner id: %v", err)
+	}
+	ownerPath := "/api/v1/admin/users/" + strconv.FormatUint(uint64(ownerID), 10)
+	if resp := doRequest(t, r, http.MethodPatch, ownerPath, []byte(`{"role":"viewer"}`), withAuth(jsonHeaders(), ownerToken)); resp.Code != http.StatusConflict {
+		t.Fatalf("expected %d, got %d: %s", http.StatusConflict, resp.Code, resp.Body.String())
+	}
+	if resp := doRequest(t, r, http.MethodDelete, ownerPath, nil, withAuth(nil, ownerToken)); resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d, got %d: %s", http.StatusBadRequest, resp.Code, resp.Body.String())
+	}
+}
+
+func TestRouter_AdminLogin_LockoutAndUnlock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+
+	jwtCfg := config.JWTConfig{Secret: "test-secret", Issuer: "evening-gown", ExpiresIn: time.Hour}
+	jwtSvc, err := jwtauth.New(jwtCfg)
+	if err != nil {
+		t.Fatalf("create jwt service: %v", err)
+	}
+
+	ownerEmail := "owner@example.com"
+	ownerPassword := "passw0rd123"
+	if err := bootstrap.EnsureSingleAdmin(db, ownerEmail, ownerPassword); err != nil {
+		t.Fatalf("ensure admin: %v", err)
+	}
+	editorPassword := "edit0rPass99"
+	hash, err := security.HashPassword(editorPassword)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	editor := model.User{Email: "editor@example.com", PasswordHash: hash, Role: model.RoleEditor, Status: "active"}
+	if err := db.Create(&editor).Error; err != nil {
+		t.Fatalf("create editor: %v", err)
+	}
+
+	deps := Dependencies{}
+	deps.Admin.Auth = adminHandlers.NewAuthHandlerWithLoginGuard(db, jwtSvc, security.LockoutPolicy{MaxFailures: 2, BaseLock: time.Hour, MaxLock: 4 * time.Hour}, nil)
+	deps.Admin.Users = adminHandlers.NewUsersHandler(db, time.Hour)
+	deps.Admin.AuthMiddleware = middleware.AdminAuth(db, jwtSvc)
+	r := New(deps)
+
+	login := func(email, password string) *httptest.ResponseRecorder {
+		return doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/login", []byte(`{"email":"`+email+`","password":"`+password+`"}`), jsonHeaders())
+	}
+
+	for i := 0; i < 2; i++ {
+		if resp := login(editor.Email, "wrong-password"); resp.Code != http.StatusUnauthorized {
+			t.Fatalf("expected %d, got %d: %s", http.StatusUnauthorized, resp.Code, resp.Body.String())
+		}
+	}
+
+	// Locked: the right password is refused with the same error as a wrong one.
+	{
+		resp := login(editor.Email, editorPassword)
+		if resp.Code != http.StatusUnauthorized {
+			t.Fatalf("expected %d, got %d: %s", http.StatusUnauthorized, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if got["code"] != "invalid_credentials" {
+			t.Fatalf("expected invalid_credentials, got %#v", got)
+		}
+	}
+	{
+		var u model.User
+		if err := db.First(&u, editor.ID).Error; err != nil {
+			t.Fatalf("load editor: %v", err)
+		}
+		if u.Status != "locked" || u.LockedUntil == nil || u.FailedLoginCount != 2 {
+			t.Fatalf("expected locked editor, got status=%s lockedUntil=%v failures=%d", u.Status, u.LockedUntil, u.FailedLoginCount)
+		}
+	}
+
+	var ownerToken string
+	{
+		resp := login(ownerEmail, ownerPassword)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		ownerToken, _ = got["token"].(string)
+	}
+
+	unlockPath := "/api/v1/admin/users/" + strconv.FormatUint(uint64(editor.ID), 10) + "/unlock"
+	if resp := doRequest(t, r, http.MethodPost, unlockPath, nil, withAuth(nil, ownerToken)); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+	if resp := login(editor.Email, editorPassword); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+}
+
+// The login limiter is keyed by c.ClientIP(), which the admin session records
+// as well: spoofed X-Forwarded-For values must resolve to one client (one
+// limiter bucket) unless the peer is a trusted proxy.
+func TestRouter_ClientIP_IgnoresUntrustedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+
+	jwtCfg := config.JWTConfig{Secret: "test-secret", Issuer: "evening-gown", ExpiresIn: time.Hour}
+	jwtSvc, err := jwtauth.New(jwtCfg)
+	if err != nil {
+		t.Fatalf("create jwt service: %v", err)
+	}
+
+	ownerEmail := "owner@example.com"
+	ownerPassword := "passw0rd123"
+	if err := bootstrap.EnsureSingleAdmin(db, ownerEmail, ownerPassword); err != nil {
+		t.Fatalf("ensure admin: %v", err)
+	}
+
+	loginIPs := func(trusted []string) []string {
+		t.Helper()
+		deps := Dependencies{TrustedProxies: trusted}
+		deps.Admin.Auth = adminHandlers.NewAuthHandler(db, jwtSvc)
+		r := New(deps)
+		db.Where("1 = 1").Delete(&model.AdminSession{})
+		for _, forwarded := range []string{"203.0.113.1", "203.0.113.2"} {
+			headers := jsonHeaders()
+			headers["X-Forwarded-For"] = forwarded
+			resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/login", []byte(`{"email":"`+ownerEmail+`","password":"`+ownerPassword+`"}`), headers)
+			if resp.Code != http.StatusOK {
+				t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+			}
+		}
+		var ips []string
+		db.Model(&model.AdminSession{}).Order("id asc").Pluck("ip", &ips)
+		return ips
+	}
+
+	// httptest requests come from 192.0.2.1.
+	if ips := loginIPs(nil); len(ips) != 2 || ips[0] != "192.0.2.1" || ips[1] != "192.0.2.1" {
+		t.Fatalf("expected the peer address for both logins, got %v", ips)
+	}
+	if ips := loginIPs([]string{"192.0.2.0/24"}); len(ips) != 2 || ips[0] != "203.0.113.1" || ips[1] != "203.0.113.2" {
+		t.Fatalf("expected the forwarded addresses behind a trusted proxy, got %v", ips)
+	}
+}
+
+func TestRouter_AdminTwoFactor_EnrollAndLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+
+	jwtCfg := config.JWTConfig{Secret: "test-secret", Issuer: "evening-gown", ExpiresIn: time.Hour}
+	jwtSvc, err := jwtauth.New(jwtCfg)
+	if err != nil {
+		t.Fatalf("create jwt service: %v", err)
+	}
+
+	ownerEmail := "owner@example.com"
+	ownerPassword := "passw0rd123"
+	if err := bootstrap.EnsureSingleAdmin(db, ownerEmail, ownerPassword); err != nil {
+		t.Fatalf("ensure admin: %v", err)
+	}
+
+	deps := Dependencies{}
+	deps.Admin.Auth = adminHandlers.NewAuthHandler(db, jwtSvc)
+	deps.Admin.Products = adminHandlers.NewProductsHandler(db, cache.NewPublicCache(nil))
+	deps.Admin.TwoFactor = adminHandlers.NewTwoFactorHandler(db, "evening-gown")
+	deps.Admin.AuthMiddleware = middleware.AdminAuth(db, jwtSvc)
+	deps.Admin.TwoFactorMiddleware = middleware.RequireTwoFactor()
+	r := New(deps)
+
+	loginBody := []byte(`{"email":"` + ownerEmail + `","password":"` + ownerPassword + `"}`)
+	var token string
+	{
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/login", loginBody, jsonHeaders())
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		token, _ = got["token"].(string)
+	}
+
+	// Enforcement: business routes are blocked until enrollment, /me stays reachable.
+	if resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/products", nil, withAuth(nil, token)); resp.Code != http.StatusForbidden {
+		t.Fatalf("expected %d, got %d: %s", http.StatusForbidden, resp.Code, resp.Body.String())
+	}
+	if resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/me", nil, withAuth(nil, token)); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+
+	var secret string
+	{
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/me/2fa/setup", nil, withAuth(nil, token))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		secret, _ = got["secret"].(string)
+		if uri, _ := got["otpauth_url"].(string); !strings.HasPrefix(uri, "otpauth://totp/") || secret == "" {
+			t.Fatalf("unexpected setup response: %s", resp.Body.String())
+		}
+	}
+
+	var recoveryCodes []any
+	{
+		code, err := security.TOTPCode(secret, time.Now())
+		if err != nil {
+			t.Fatalf("totp code: %v", err)
+		}
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/me/2fa/confirm", []byte(`{"code":"`+code+`"}`), withAuth(jsonHeaders(), token))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		recoveryCodes, _ = got["recoveryCodes"].([]any)
+		if len(recoveryCodes) == 0 {
+			t.Fatalf("expected recovery codes: %s", resp.Body.String())
+		}
+	}
+	if resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/products", nil, withAuth(nil, token)); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+
+	mfaLogin := func() string {
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/login", loginBody, jsonHeaders())
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if got["mfa_required"] != true || got["token"] != nil {
+			t.Fatalf("expected mfa challenge only, got %s", resp.Body.String())
+		}
+		mfaToken, _ := got["mfa_token"].(string)
+		// The challenge token is not an access token.
+		if resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/me", nil, withAuth(nil, mfaToken)); resp.Code != http.StatusUnauthorized {
+			t.Fatalf("expected %d, got %d: %s", http.StatusUnauthorized, resp.Code, resp.Body.String())
+		}
+		return mfaToken
+	}
+
+	// The code used for confirmation cannot be replayed; the next step's code works.
+	{
+		mfaToken := mfaLogin()
+		used, _ := security.TOTPCode(secret, time.Now())
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/2fa/verify", []byte(`{"mfa_token":"`+mfaToken+`","code":"`+used+`"}`), jsonHeaders())
+		if resp.Code != http.StatusUnauthorized {
+			t.Fatalf("expected replay to be rejected, got %d: %s", resp.Code, resp.Body.String())
+		}
+		next, _ := security.TOTPCode(secret, time.Now().Add(30*time.Second))
+		resp = doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/2fa/verify", []byte(`{"mfa_token":"`+mfaToken+`","code":"`+next+`"}`), jsonHeaders())
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+	}
+
+	// Recovery codes are single-use.
+	{
+		rc, _ := recoveryCodes[0].(string)
+		body := []byte(`{"mfa_token":"` + mfaLogin() + `","recovery_code":"` + rc + `"}`)
+		if resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/2fa/verify", body, jsonHeaders()); resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		body = []byte(`{"mfa_token":"` + mfaLogin() + `","recovery_code":"` + rc + `"}`)
+		if resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/2fa/verify", body, jsonHeaders()); resp.Code != http.StatusUnauthorized {
+			t.Fatalf("expected %d, got %d: %s", http.StatusUnauthorized, resp.Code, resp.Body.String())
+		}
+	}
+}
+
+func TestRouter_AdminSessions_RefreshFamiliesAndLogout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+
+	jwtCfg := config.JWTConfig{Secret: "test-secret", Issuer: "evening-gown", ExpiresIn: time.Hour}
+	jwtSvc, err := jwtauth.New(jwtCfg)
+	if err != nil {
+		t.Fatalf("create jwt service: %v", err)
+	}
+
+	ownerEmail := "owner@example.com"
+	ownerPassword := "passw0rd123"
+	if err := bootstrap.EnsureSingleAdmin(db, ownerEmail, ownerPassword); err != nil {
+		t.Fatalf("ensure admin: %v", err)
+	}
+
+	deps := Dependencies{}
+	deps.Admin.Auth = adminHandlers.NewAuthHandler(db, jwtSvc)
+	deps.Admin.Sessions = adminHandlers.NewSessionsHandler(db)
+	deps.Admin.Users = adminHandlers.NewUsersHandler(db, time.Hour)
+	deps.Admin.AuthMiddleware = middleware.AdminAuth(db, jwtSvc)
+	r := New(deps)
+
+	type tokens struct{ access, refresh string }
+	decodeTokens := func(resp *httptest.ResponseRecorder) tokens {
+		t.Helper()
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		access, _ := got["token"].(string)
+		refresh, _ := got["refresh_token"].(string)
+		return tokens{access: access, refresh: refresh}
+	}
+	login := func() tokens {
+		return decodeTokens(doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/login", []byte(`{"email":"`+ownerEmail+`","password":"`+ownerPassword+`"}`), jsonHeaders()))
+	}
+	refresh := func(rt string) *httptest.ResponseRecorder {
+		return doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/refresh", []byte(`{"refresh_token":"`+rt+`"}`), jsonHeaders())
+	}
+
+	laptop := login()
+	phone := login()
+
+	// Logging in on a second device keeps the first device's refresh token valid.
+	laptop2 := decodeTokens(refresh(laptop.refresh))
+
+	// Replaying the rotated-out refresh token revokes the laptop's whole family.
+	if resp := refresh(laptop.refresh); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d: %s", http.StatusUnauthorized, resp.Code, resp.Body.String())
+	}
+	if resp := refresh(laptop2.refresh); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected revoked family, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/me", nil, withAuth(nil, laptop2.access)); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d: %s", http.StatusUnauthorized, resp.Code, resp.Body.String())
+	}
+
+	// The phone session is unaffected and is the only one listed.
+	phone = decodeTokens(refresh(phone.refresh))
+	var phoneSessionID uint
+	{
+		resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/sessions", nil, withAuth(nil, phone.access))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		items, _ := got["items"].([]any)
+		if len(items) != 1 {
+			t.Fatalf("expected 1 session, got %s", resp.Body.String())
+		}
+		item, _ := items[0].(map[string]any)
+		if item["current"] != true {
+			t.Fatalf("expected current session flag, got %#v", item)
+		}
+		phoneSessionID = mustUintFromJSONNumber(t, item["id"])
+	}
+
+	// Remote logout of another session.
+	tablet := login()
+	{
+		resp := doRequest(t, r, http.MethodDelete, "/api/v1/admin/sessions/"+strconv.FormatUint(uint64(phoneSessionID), 10), nil, withAuth(nil, tablet.access))
+		if resp.Code != http.StatusNoContent {
+			t.Fatalf("expected %d, got %d: %s", http.StatusNoContent, resp.Code, resp.Body.String())
+		}
+		if resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/me", nil, withAuth(nil, phone.access)); resp.Code != http.StatusUnauthorized {
+			t.Fatalf("expected %d, got %d: %s", http.StatusUnauthorized, resp.Code, resp.Body.String())
+		}
+	}
+
+	// Logout revokes the current session, including its refresh token.
+	if resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/logout", nil, withAuth(nil, tablet.access)); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d: %s", http.StatusNoContent, resp.Code, resp.Body.String())
+	}
+	if resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/me", nil, withAuth(nil, tablet.access)); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d: %s", http.StatusUnauthorized, resp.Code, resp.Body.String())
+	}
+	if resp := refresh(tablet.refresh); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d: %s", http.StatusUnauthorized, resp.Code, resp.Body.String())
+	}
+
+	// Redeeming a password reset revokes every session of the user.
+	desk := login()
+	var owner model.User
+	if err := db.Where("email = ?", ownerEmail).First(&owner).Error; err != nil {
+		t.Fatalf("load owner: %v", err)
+	}
+	var resetToken string
+	{
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/users/"+strconv.FormatUint(uint64(owner.ID), 10)+"/reset-password", nil, withAuth(nil, desk.access))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		resetToken, _ = got["token"].(string)
+	}
+	if resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/accept-token", []byte(`{"token":"`+resetToken+`","password":"newpassw0rd456"}`), jsonHeaders()); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+	if resp := refresh(desk.refresh); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the refresh token revoked by the reset, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var active int64
+	db.Model(&model.AdminSession{}).Where("user_id = ? AND revoked_at IS NULL", owner.ID).Count(&active)
+	if active != 0 {
+		t.Fatalf("expected no active sessions after the reset, got %d", active)
+	}
+}
+
+func TestRouter_AdminAuditLogs_RecordsMutations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+
+	jwtCfg := config.JWTConfig{Secret: "test-secret", Issuer: "evening-gown", ExpiresIn: time.Hour}
+	jwtSvc, err := jwtauth.New(jwtCfg)
+	if err != nil {
+		t.Fatalf("create jwt service: %v", err)
+	}
+
+	ownerEmail := "owner@example.com"
+	ownerPassword := "passw0rd123"
+	if err := bootstrap.EnsureSingleAdmin(db, ownerEmail, ownerPassword); err != nil {
+		t.Fatalf("ensure admin: %v", err)
+	}
+
+	deps := Dependencies{}
+	deps.Admin.Auth = adminHandlers.NewAuthHandler(db, jwtSvc)
+	deps.Admin.Products = adminHandlers.NewProductsHandler(db, cache.NewPublicCache(nil))
+	deps.Admin.Contacts = adminHandlers.NewContactsHandler(db)
+	deps.Admin.Users = adminHandlers.NewUsersHandler(db, time.Hour)
+	deps.Admin.AuditLogs = adminHandlers.NewAuditLogsHandler(db)
+	deps.Admin.AuthMiddleware = middleware.AdminAuth(db, jwtSvc)
+	r := New(deps)
+
+	login := func(email, password string) string {
+		t.Helper()
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/login", []byte(`{"email":"`+email+`","password":"`+password+`"}`), jsonHeaders())
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		token, _ := got["token"].(string)
+		return token
+	}
+	ownerToken := login(ownerEmail, ownerPassword)
+
+	var productID uint
+	{
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/products", []byte(`{"styleNo":"2001","season":"ss25","category":"gown","availability":"in_stock"}`), withAuth(jsonHeaders(), ownerToken))
+		if resp.Code != http.StatusCreated {
+			t.Fatalf("expected %d, got %d: %s", http.StatusCreated, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		productID = mustUintFromJSONNumber(t, got["id"])
+	}
+	productPath := "/api/v1/admin/products/" + strconv.FormatUint(uint64(productID), 10)
+	for _, step := range []struct{ method, path string }{
+		{http.MethodPost, productPath + "/publish"},
+		{http.MethodPost, productPath + "/unpublish"},
+		{http.MethodDelete, productPath},
+	} {
+		resp := doRequest(t, r, step.method, step.path, nil, withAuth(nil, ownerToken))
+		if resp.Code != http.StatusOK && resp.Code != http.StatusNoContent {
+			t.Fatalf("%s %s: unexpected %d: %s", step.method, step.path, resp.Code, resp.Body.String())
+		}
+	}
+
+	lead := model.ContactLead{Name: "Lead", Phone: "123", Status: "new"}
+	if err := db.Create(&lead).Error; err != nil {
+		t.Fatalf("create lead: %v", err)
+	}
+	if resp := doRequest(t, r, http.MethodDelete, "/api/v1/admin/contacts/"+strconv.FormatUint(uint64(lead.ID), 10), nil, withAuth(nil, ownerToken)); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d: %s", http.StatusNoContent, resp.Code, resp.Body.String())
+	}
+
+	list := func(query string) (items []map[string]any, next string) {
+		t.Helper()
+		resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/audit-logs"+query, nil, withAuth(nil, ownerToken))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got struct {
+			Items      []map[string]any `json:"items"`
+			NextCursor *string          `json:"next_cursor"`
+		}
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if got.NextCursor != nil {
+			next = *got.NextCursor
+		}
+		return got.Items, next
+	}
+
+	// The deleted lead is still traceable to its actor.
+	{
+		items, _ := list("?entity_type=contact")
+		if len(items) != 1 || items[0]["action"] != "contact.delete" || items[0]["actorEmail"] != ownerEmail {
+			t.Fatalf("unexpected contact audit: %#v", items)
+		}
+		if items[0]["requestId"] == "" || items[0]["entityId"] != strconv.FormatUint(uint64(lead.ID), 10) {
+			t.Fatalf("expected request id and entity id, got %#v", items[0])
+		}
+		// Only a redacted summary of the lead is kept.
+		diff, _ := items[0]["diff"].(map[string]any)
+		before, _ := diff["before"].(map[string]any)
+		if before["status"] != "new" || before["name"] != "[redacted]" || before["phone"] != "[redacted]" {
+			t.Fatalf("expected redacted lead in diff, got %#v", diff)
+		}
+		if raw, _ := json.Marshal(items[0]); strings.Contains(string(raw), `"Lead"`) || strings.Contains(string(raw), `"123"`) {
+			t.Fatalf("lead details leaked into the audit log: %s", raw)
+		}
+	}
+
+	// Cursor pagination walks product actions newest first.
+	var actions []string
+	query := "?entity_type=product&limit=2"
+	for page := 0; page < 5; page++ {
+		items, next := list(query)
+		for _, it := range items {
+			a, _ := it["action"].(string)
+			actions = append(actions, a)
+		}
+		if next == "" {
+			break
+		}
+		query = "?entity_type=product&limit=2&cursor=" + next
+	}
+	want := []string{"product.delete", "product.unpublish", "product.publish", "product.create"}
+	if strings.Join(actions, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, actions)
+	}
+
+	// The publish diff only carries the changed field.
+	{
+		items, _ := list("?action=product.publish")
+		if len(items) != 1 {
+			t.Fatalf("expected 1 publish record, got %d", len(items))
+		}
+		diff, _ := items[0]["diff"].(map[string]any)
+		before, _ := diff["before"].(map[string]any)
+		after, _ := diff["after"].(map[string]any)
+		if _, ok := after["publishedAt"]; !ok || len(after) != 1 || len(before) != 0 {
+			t.Fatalf("unexpected publish diff: %#v", diff)
+		}
+	}
+
+	// Audit logs are owner-only.
+	{
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/users", []byte(`{"email":"ed@example.com","role":"editor"}`), withAuth(jsonHeaders(), ownerToken))
+		if resp.Code != http.StatusCreated {
+			t.Fatalf("expected %d, got %d: %s", http.StatusCreated, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		invite, _ := got["invite"].(map[string]any)
+		token, _ := invite["token"].(string)
+		if resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/accept-token", []byte(`{"token":"`+token+`","password":"editorpass123"}`), jsonHeaders()); resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		editorToken := login("ed@example.com", "editorpass123")
+		if resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/audit-logs", nil, withAuth(nil, editorToken)); resp.Code != http.StatusForbidden {
+			t.Fatalf("expected %d, got %d: %s", http.StatusForbidden, resp.Code, resp.Body.String())
+		}
+	}
+}
+
+func TestRouter_AdminProductRevisions_DiffAndRestore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+
+	jwtCfg := config.JWTConfig{Secret: "test-secret", Issuer: "evening-gown", ExpiresIn: time.Hour}
+	jwtSvc, err := jwtauth.New(jwtCfg)
+	if err != nil {
+		t.Fatalf("create jwt service: %v", err)
+	}
+
+	ownerEmail := "owner@example.com"
+	ownerPassword := "passw0rd123"
+	if err := bootstrap.EnsureSingleAdmin(db, ownerEmail, ownerPassword); err != nil {
+		t.Fatalf("ensure admin: %v", err)
+	}
+
+	deps := Dependencies{}
+	deps.Admin.Auth = adminHandlers.NewAuthHandler(db, jwtSvc)
+	deps.Admin.Products = adminHandlers.NewProductsHandler(db, cache.NewPublicCache(nil))
+	deps.Admin.AuthMiddleware = middleware.AdminAuth(db, jwtSvc)
+	r := New(deps)
+
+	var token string
+	{
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/auth/login", []byte(`{"email":"`+ownerEmail+`","password":"`+ownerPassword+`"}`), jsonHeaders())
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		token, _ = got["token"].(string)
+	}
+
+	var productPath string
+	{
+		resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/products", []byte(`{"styleNo":"3001","season":"ss25","category":"gown","availability":"in_stock","detail":{"title_i18n":{"en":"Aurora"}}}`), withAuth(jsonHeaders(), token))
+		if resp.Code != http.StatusCreated {
+			t.Fatalf("expected %d, got %d: %s", http.StatusCreated, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		productPath = "/api/v1/admin/products/" + strconv.FormatUint(uint64(mustUintFromJSONNumber(t, got["id"])), 10)
+	}
+
+	// A bad edit on the published page.
+	if resp := doRequest(t, r, http.MethodPost, productPath+"/publish", nil, withAuth(nil, token)); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+	if resp := doRequest(t, r, http.MethodPatch, productPath, []byte(`{"season":"fw25","detail":{"title_i18n":{"en":"Oops"}}}`), withAuth(jsonHeaders(), token)); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+
+	{
+		resp := doRequest(t, r, http.MethodGet, productPath+"/revisions", nil, withAuth(nil, token))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		items, _ := got["items"].([]any)
+		if len(items) != 2 {
+			t.Fatalf("expected 2 revisions, got %s", resp.Body.String())
+		}
+		latest, _ := items[0].(map[string]any)
+		if latest["source"] != "update" || latest["authorEmail"] != ownerEmail {
+			t.Fatalf("unexpected latest revision: %#v", latest)
+		}
+		if _, ok := latest["snapshot"]; ok {
+			t.Fatalf("expected list without snapshots")
+		}
+	}
+
+	{
+		resp := doRequest(t, r, http.MethodGet, productPath+"/revisions/diff?from=1&to=2", nil, withAuth(nil, token))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got struct {
+			Fields []struct {
+				Field string `json:"field"`
+				From  string `json:"from"`
+				To    string `json:"to"`
+			} `json:"fields"`
+			Detail []model.JSONChange `json:"detail"`
+		}
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if len(got.Fields) != 1 || got.Fields[0].Field != "season" || got.Fields[0].From != "ss25" || got.Fields[0].To != "fw25" {
+			t.Fatalf("unexpected field diff: %s", resp.Body.String())
+		}
+		found := false
+		for _, ch := range got.Detail {
+			if ch.Path == "/title_i18n/en" && ch.Op == "replace" && ch.From == "Aurora" && ch.To == "Oops" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("expected detail title change, got %s", resp.Body.String())
+		}
+	}
+
+	// Restore keeps the product published and becomes revision 3.
+	{
+		resp := doRequest(t, r, http.MethodPost, productPath+"/revisions/1/restore", nil, withAuth(nil, token))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		detail, _ := got["detail"].(map[string]any)
+		title, _ := detail["title_i18n"].(map[string]any)
+		if got["season"] != "ss25" || title["en"] != "Aurora" || got["publishedAt"] == nil {
+			t.Fatalf("unexpected restored product: %s", resp.Body.String())
+		}
+
+		resp = doRequest(t, r, http.MethodGet, productPath+"/revisions/3", nil, withAuth(nil, token))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var rev map[string]any
+		mustJSON(t, resp.Body.Bytes(), &rev)
+		if rev["source"] != "restore" || mustUintFromJSONNumber(t, rev["restoredFrom"]) != 1 {
+			t.Fatalf("unexpected restore revision: %s", resp.Body.String())
+		}
+	}
+
+	if resp := doRequest(t, r, http.MethodPost, productPath+"/revisions/9/restore", nil, withAuth(nil, token)); resp.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d: %s", http.StatusNotFound, resp.Code, resp.Body.String())
+	}
+}
+
+func TestRouter_AdminSchedule_SetAndListUpcoming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := openTestDB(t)
+
+	deps := Dependencies{}
+	deps.Admin.Products = adminHandlers.NewProductsHandler(db, cache.NewPublicCache(nil))
+	deps.Admin.Updates = adminHandlers.NewUpdatesHandler(db, cache.NewPublicCache(nil))
+	r := New(deps)
+
+	p := model.Product{Slug: "style-4001", StyleNo: "4001", Season: "fw25", Category: "gown", Availability: "preorder"}
+	if err := db.Create(&p).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	post := model.UpdatePost{Type: "company", Status: "draft", Title: "FW25"}
+	if err := db.Create(&post).Error; err != nil {
+		t.Fatalf("create update: %v", err)
+	}
+	productPath := "/api/v1/admin/products/" + strconv.FormatUint(uint64(p.ID), 10)
+	updatePath := "/api/v1/admin/updates/" + strconv.FormatUint(uint64(post.ID), 10)
+
+	// Midnight in the buyer's timezone (UTC+8) is stored as UTC.
+	if resp := doRequest(t, r, http.MethodPut, productPath+"/schedule", []byte(`{"publishAt":"2030-09-01T00:00:00+08:00","unpublishAt":"2030-12-01T00:00:00+08:00"}`), jsonHeaders()); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	} else {
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if got["publishAt"] != "2030-08-31T16:00:00Z" {
+			t.Fatalf("unexpected publishAt: %v", got["publishAt"])
+		}
+	}
+	if resp := doRequest(t, r, http.MethodPut, productPath+"/schedule", []byte(`{"publishAt":"2030-09-01T00:00:00Z","unpublishAt":"2030-08-01T00:00:00Z"}`), jsonHeaders()); resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d, got %d: %s", http.StatusBadRequest, resp.Code, resp.Body.String())
+	}
+	if resp := doRequest(t, r, http.MethodPut, productPath+"/schedule", []byte(`{"publishAt":"2020-09-01T00:00:00Z"}`), jsonHeaders()); resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected past publishAt to be rejected, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if resp := doRequest(t, r, http.MethodPut, updatePath+"/schedule", []byte(`{"publishAt":"2030-10-01T00:00:00Z","unpublishAt":null}`), jsonHeaders()); resp.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+
+	{
+		resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/products/scheduled", nil, nil)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+		}
+		var got struct {
+			Items []struct {
+				Action string `json:"action"`
+				At     string `json:"at"`
+				ID     uint   `json:"id"`
+			} `json:"items"`
+		}
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if len(got.Items) != 2 || got.Items[0].Action != "publish" || got.Items[1].Action != "unpublish" || got.Items[0].ID != p.ID {
+			t.Fatalf("unexpected scheduled products: %s", resp.Body.String())
+		}
+
+		resp = doRequest(t, r, http.MethodGet, "/api/v1/admin/products/scheduled?until=2030-10-01T00:00:00Z", nil, nil)
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if len(got.Items) != 1 {
+			t.Fatalf("expected until filter to drop the unpublish, got %s", resp.Body.String())
+		}
+	}
+	{
+		resp := doRequest(t, r, http.MethodGet, "/api/v1/admin/updates/scheduled", nil, nil)
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if items, _ := got["items"].([]any); len(items) != 1 {
+			t.Fatalf("unexpected scheduled updates: %s", resp.Body.String())
+		}
+	}
+
+	// A manual publish supersedes the pending scheduled publish.
+	{
+		resp := doRequest(t, r, http.MethodPost, productPath+"/publish", nil, nil)
+		var got map[string]any
+		mustJSON(t, resp.Body.Bytes(), &got)
+		if _, ok := got["publishAt"]; ok || got["unpublishAt"] == nil {
+			t.Fatalf("expected publishAt cleared and unpublishAt kept, got %s", resp.Body.String())
+		}
+	}
+}
+
 func openTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
 
@@ -0,0 +1,314 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"evening-gown/internal/cache"
+	"evening-gown/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// LockKey guards a scheduler run across instances.
+const LockKey = "eg:scheduler:publish:lock"
+
+// batchSize bounds the rows handled per entity and transition in one run;
+// anything left over is picked up by the next tick.
+const batchSize = 100
+
+// runTimeout bounds one run. The lock is held for the interval plus this, so
+// it always outlives the run holding it and runs never overlap.
+const runTimeout = 2 * time.Minute
+
+// Scheduler applies due publish_at/unpublish_at transitions of products and
+// company updates.
+//
+// Each due row is updated with a conditional UPDATE that re-checks the schedule,
+// so an admin editing the schedule concurrently never gets overwritten. Applied
+// transitions clear the schedule field, bump the public cache version and are
+// written to the audit log without an actor.
+type Scheduler struct {
+	db       *gorm.DB
+	cache    *cache.PublicCache
+	locker   *cache.Locker
+	interval time.Duration
+	logger   *slog.Logger
+
+	// now is overridable in tests.
+	now func() time.Time
+}
+
+func New(db *gorm.DB, publicCache *cache.PublicCache, locker *cache.Locker, interval time.Duration, logger *slog.Logger) *Scheduler {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Scheduler{
+		db:       db,
+		cache:    publicCache,
+		locker:   locker,
+		interval: interval,
+		logger:   logger,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Result counts the transitions applied by one run.
+type Result struct {
+	ProductsPublished   int
+	ProductsUnpublished int
+	UpdatesPublished    int
+	UpdatesUnpublished  int
+}
+
+func (r Result) productsChanged() bool { return r.ProductsPublished+r.ProductsUnpublished > 0 }
+func (r Result) updatesChanged() bool  { return r.UpdatesPublished+r.UpdatesUnpublished > 0 }
+
+// Run ticks until ctx is canceled.
+func (s *Scheduler) Run(ctx context.Context) {
+	if s == nil || s.db == nil {
+		return
+	}
+	s.logger.Info("scheduler started", "interval", s.interval.String())
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	// The lease outlives the longest possible run; a crashed holder blocks the
+	// schedule for at most a few ticks.
+	release, ok, err := s.locker.TryLock(ctx, LockKey, s.interval+runTimeout)
+	if err != nil {
+		s.logger.Warn("scheduler lock failed", "err", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, runTimeout)
+	defer cancel()
+	res, err := s.RunOnce(ctx)
+	if err != nil {
+		s.logger.Error("scheduler run failed", "err", err)
+	}
+	if res.productsChanged() || res.updatesChanged() {
+		s.logger.Info("scheduler applied transitions",
+			"products_published", res.ProductsPublished,
+			"products_unpublished", res.ProductsUnpublished,
+			"updates_published", res.UpdatesPublished,
+			"updates_unpublished", res.UpdatesUnpublished,
+		)
+	}
+}
+
+// RunOnce applies every transition due now. It does not take the lock.
+//
+// Publishes run before unpublishes, so a window that fully elapsed while the
+// scheduler was down ends unpublished. A publish due on an item that is already
+// published only clears the schedule; its published_at is kept.
+func (s *Scheduler) RunOnce(ctx context.Context) (Result, error) {
+	var res Result
+	if s == nil || s.db == nil {
+		return res, nil
+	}
+	now := s.now()
+
+	var err error
+	if res.ProductsPublished, err = s.publishProducts(ctx, now); err != nil {
+		return res, err
+	}
+	if res.ProductsUnpublished, err = s.unpublishProducts(ctx, now); err != nil {
+		return res, err
+	}
+	if res.productsChanged() && s.cache != nil {
+		_, _ = s.cache.BumpProductsVersion(ctx)
+	}
+
+	if res.UpdatesPublished, err = s.publishUpdates(ctx, now); err != nil {
+		return res, err
+	}
+	if res.UpdatesUnpublished, err = s.unpublishUpdates(ctx, now); err != nil {
+		return res, err
+	}
+	if res.updatesChanged() && s.cache != nil {
+		_, _ = s.cache.BumpUpdatesVersion(ctx)
+	}
+
+	return res, nil
+}
+
+func (s *Scheduler) publishProducts(ctx context.Context, now time.Time) (int, error) {
+	var due []model.Product
+	if err := s.db.WithContext(ctx).
+		Where("deleted_at IS NULL AND publish_at IS NOT NULL AND publish_at <= ?", now).
+		Order("publish_at asc, id asc").
+		Limit(batchSize).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, before := range due {
+		if before.PublishedAt != nil {
+			if err := s.clearPublishAt(ctx, &model.Product{}, before.ID, now); err != nil {
+				return n, err
+			}
+			continue
+		}
+		after := before
+		after.PublishedAt = before.PublishAt
+		after.PublishAt = nil
+		res := s.db.WithContext(ctx).Model(&model.Product{}).
+			Where("id = ? AND deleted_at IS NULL AND published_at IS NULL AND publish_at IS NOT NULL AND publish_at <= ?", before.ID, now).
+			Updates(map[string]any{"published_at": after.PublishedAt, "publish_at": nil})
+		if res.Error != nil {
+			return n, res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		n++
+		s.audit(ctx, "product.scheduled_publish", model.AuditEntityProduct, before.ID, before, after)
+	}
+	return n, nil
+}
+
+func (s *Scheduler) unpublishProducts(ctx context.Context, now time.Time) (int, error) {
+	var due []model.Product
+	if err := s.db.WithContext(ctx).
+		Where("deleted_at IS NULL AND unpublish_at IS NOT NULL AND unpublish_at <= ?", now).
+		Order("unpublish_at asc, id asc").
+		Limit(batchSize).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, before := range due {
+		after := before
+		after.PublishedAt = nil
+		after.UnpublishAt = nil
+		res := s.db.WithContext(ctx).Model(&model.Product{}).
+			Where("id = ? AND deleted_at IS NULL AND unpublish_at IS NOT NULL AND unpublish_at <= ?", before.ID, now).
+			Updates(map[string]any{"published_at": nil, "unpublish_at": nil})
+		if res.Error != nil {
+			return n, res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		n++
+		s.audit(ctx, "product.scheduled_unpublish", model.AuditEntityProduct, before.ID, before, after)
+	}
+	return n, nil
+}
+
+func (s *Scheduler) publishUpdates(ctx context.Context, now time.Time) (int, error) {
+	var due []model.UpdatePost
+	if err := s.db.WithContext(ctx).
+		Where("deleted_at IS NULL AND publish_at IS NOT NULL AND publish_at <= ?", now).
+		Order("publish_at asc, id asc").
+		Limit(batchSize).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, before := range due {
+		if before.Status == "published" && before.PublishedAt != nil {
+			if err := s.clearPublishAt(ctx, &model.UpdatePost{}, before.ID, now); err != nil {
+				return n, err
+			}
+			continue
+		}
+		after := before
+		after.Status = "published"
+		after.PublishedAt = before.PublishAt
+		after.PublishAt = nil
+		res := s.db.WithContext(ctx).Model(&model.UpdatePost{}).
+			Where("id = ? AND deleted_at IS NULL AND NOT (status = ? AND published_at IS NOT NULL) AND publish_at IS NOT NULL AND publish_at <= ?", before.ID, "published", now).
+			Updates(map[string]any{"status": "published", "published_at": after.PublishedAt, "publish_at": nil})
+		if res.Error != nil {
+			return n, res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		n++
+		s.audit(ctx, "update.scheduled_publish", model.AuditEntityUpdate, before.ID, before, after)
+	}
+	return n, nil
+}
+
+func (s *Scheduler) unpublishUpdates(ctx context.Context, now time.Time) (int, error) {
+	var due []model.UpdatePost
+	if err := s.db.WithContext(ctx).
+		Where("deleted_at IS NULL AND unpublish_at IS NOT NULL AND unpublish_at <= ?", now).
+		Order("unpublish_at asc, id asc").
+		Limit(batchSize).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, before := range due {
+		after := before
+		after.Status = "draft"
+		after.PublishedAt = nil
+		after.UnpublishAt = nil
+		res := s.db.WithContext(ctx).Model(&model.UpdatePost{}).
+			Where("id = ? AND deleted_at IS NULL AND unpublish_at IS NOT NULL AND unpublish_at <= ?", before.ID, now).
+			Updates(map[string]any{"status": "draft", "published_at": nil, "unpublish_at": nil})
+		if res.Error != nil {
+			return n, res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		n++
+		s.audit(ctx, "update.scheduled_unpublish", model.AuditEntityUpdate, before.ID, before, after)
+	}
+	return n, nil
+}
+
+// clearPublishAt drops a due publish_at of an item that is already published,
+// so it stops showing up as scheduled without its published_at changing.
+func (s *Scheduler) clearPublishAt(ctx context.Context, m any, id uint, now time.Time) error {
+	return s.db.WithContext(ctx).Model(m).
+		Where("id = ? AND published_at IS NOT NULL AND publish_at IS NOT NULL AND publish_at <= ?", id, now).
+		Update("publish_at", nil).Error
+}
+
+// audit records a scheduler transition (ActorID 0). Failures are only logged.
+func (s *Scheduler) audit(ctx context.Context, action, entityType string, entityID uint, before, after any) {
+	diff, err := model.AuditDiff(before, after)
+	if err != nil {
+		s.logger.Warn("scheduler audit diff failed", "action", action, "err", err)
+		return
+	}
+	entry := model.AuditLog{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   strconv.FormatUint(uint64(entityID), 10),
+		Diff:       diff,
+	}
+	if err := s.db.WithContext(ctx).Create(&entry).Error; err != nil {
+		s.logger.Warn("scheduler audit write failed", "action", action, "err", err)
+	}
+}
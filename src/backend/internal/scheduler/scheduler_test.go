@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"evening-gown/internal/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err == nil {
+		t.Cleanup(func() { _ = sqlDB.Close() })
+	}
+	if err := db.AutoMigrate(&model.Product{}, &model.UpdatePost{}, &model.AuditLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestScheduler_RunOnce_AppliesDueTransitions(t *testing.T) {
+	db := openTestDB(t)
+
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+	publishedAt := now.Add(-24 * time.Hour)
+
+	dueDrop := model.Product{Slug: "a", StyleNo: "A1", Season: "fw25", Category: "gown", Availability: "in_stock", PublishAt: &past}
+	laterDrop := model.Product{Slug: "b", StyleNo: "B1", Season: "fw25", Category: "gown", Availability: "in_stock", PublishAt: &future}
+	ending := model.Product{Slug: "c", StyleNo: "C1", Season: "ss25", Category: "gown", Availability: "in_stock", PublishedAt: &publishedAt, UnpublishAt: &past}
+	live := model.Product{Slug: "d", StyleNo: "D1", Season: "ss25", Category: "gown", Availability: "in_stock", PublishedAt: &publishedAt, PublishAt: &past}
+	for _, p := range []*model.Product{&dueDrop, &laterDrop, &ending, &live} {
+		if err := db.Create(p).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+	}
+	post := model.UpdatePost{Type: "company", Status: "draft", Title: "FW25 launch", PublishAt: &past}
+	if err := db.Create(&post).Error; err != nil {
+		t.Fatalf("create update: %v", err)
+	}
+
+	s := New(db, nil, nil, time.Minute, nil)
+	s.now = func() time.Time { return now }
+
+	res, err := s.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if res != (Result{ProductsPublished: 1, ProductsUnpublished: 1, UpdatesPublished: 1}) {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	load := func(id uint) model.Product {
+		t.Helper()
+		var p model.Product
+		if err := db.First(&p, id).Error; err != nil {
+			t.Fatalf("load product: %v", err)
+		}
+		return p
+	}
+	got := load(dueDrop.ID)
+	if got.PublishedAt == nil || !got.PublishedAt.Equal(past) || got.PublishAt != nil {
+		t.Fatalf("expected scheduled publish applied, got %+v", got)
+	}
+	got = load(laterDrop.ID)
+	if got.PublishedAt != nil || got.PublishAt == nil {
+		t.Fatalf("expected future schedule untouched, got %+v", got)
+	}
+	got = load(ending.ID)
+	if got.PublishedAt != nil || got.UnpublishAt != nil {
+		t.Fatalf("expected scheduled unpublish applied, got %+v", got)
+	}
+	// Already published: the schedule is dropped, published_at is kept.
+	got = load(live.ID)
+	if got.PublishedAt == nil || !got.PublishedAt.Equal(publishedAt) || got.PublishAt != nil {
+		t.Fatalf("expected published product untouched, got %+v", got)
+	}
+	var gotPost model.UpdatePost
+	db.First(&gotPost, post.ID)
+	if gotPost.Status != "published" || gotPost.PublishedAt == nil || gotPost.PublishAt != nil {
+		t.Fatalf("expected update published, got %+v", gotPost)
+	}
+
+	var audits int64
+	db.Model(&model.AuditLog{}).Where("actor_id = 0 AND action LIKE ?", "%.scheduled_%").Count(&audits)
+	if audits != 3 {
+		t.Fatalf("expected 3 audit records, got %d", audits)
+	}
+
+	// A second run is a no-op.
+	res, err = s.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if res != (Result{}) {
+		t.Fatalf("expected no changes, got %+v", res)
+	}
+}
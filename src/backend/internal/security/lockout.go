@@ -0,0 +1,48 @@
+package security
+
+import "time"
+
+// LockoutPolicy controls account lockout after repeated failed logins.
+//
+// Once FailedLoginCount reaches MaxFailures the account is locked for BaseLock;
+// every further failure doubles the lock, capped at MaxLock (the default cap,
+// or BaseLock if longer, when MaxLock is not positive).
+type LockoutPolicy struct {
+	MaxFailures int
+	BaseLock    time.Duration
+	MaxLock     time.Duration
+}
+
+// DefaultLockoutPolicy returns the policy used when nothing is configured.
+func DefaultLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{MaxFailures: 5, BaseLock: time.Minute, MaxLock: time.Hour}
+}
+
+// Enabled reports whether lockout is active.
+func (p LockoutPolicy) Enabled() bool {
+	return p.MaxFailures > 0 && p.BaseLock > 0
+}
+
+// LockDuration returns how long to lock an account after failures consecutive
+// failed logins. Zero means the account stays unlocked.
+func (p LockoutPolicy) LockDuration(failures int) time.Duration {
+	if !p.Enabled() || failures < p.MaxFailures {
+		return 0
+	}
+	maxLock := p.MaxLock
+	if maxLock <= 0 {
+		maxLock = max(DefaultLockoutPolicy().MaxLock, p.BaseLock)
+	}
+	if p.BaseLock >= maxLock {
+		return maxLock
+	}
+	// Stop doubling at the cap; d never overflows since it stays below it.
+	d := p.BaseLock
+	for i := p.MaxFailures; i < failures; i++ {
+		d *= 2
+		if d >= maxLock {
+			return maxLock
+		}
+	}
+	return d
+}
@@ -0,0 +1,40 @@
+package security
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicy_LockDuration(t *testing.T) {
+	p := LockoutPolicy{MaxFailures: 3, BaseLock: time.Minute, MaxLock: 10 * time.Minute}
+
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{5, 4 * time.Minute},
+		{6, 8 * time.Minute},
+		{7, 10 * time.Minute},
+		{100, 10 * time.Minute},
+	}
+	for _, tc := range cases {
+		if got := p.LockDuration(tc.failures); got != tc.want {
+			t.Fatalf("failures=%d: expected %s got %s", tc.failures, tc.want, got)
+		}
+	}
+
+	// Without a cap the lock stops growing at the default one instead of
+	// overflowing.
+	uncapped := LockoutPolicy{MaxFailures: 3, BaseLock: time.Minute}
+	if got := uncapped.LockDuration(200); got != DefaultLockoutPolicy().MaxLock {
+		t.Fatalf("expected default cap, got %s", got)
+	}
+
+	if got := (LockoutPolicy{}).LockDuration(100); got != 0 {
+		t.Fatalf("expected disabled policy to never lock, got %s", got)
+	}
+}
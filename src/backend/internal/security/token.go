@@ -0,0 +1,27 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// NewOneTimeToken returns a random URL-safe token and its SHA-256 hash.
+//
+// Only the hash should be persisted; the plain token is handed to the user once.
+func NewOneTimeToken() (plain string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	plain = base64.RawURLEncoding.EncodeToString(b)
+	return plain, HashToken(plain), nil
+}
+
+// HashToken returns the hex SHA-256 of a one-time token.
+func HashToken(plain string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(plain)))
+	return hex.EncodeToString(sum[:])
+}
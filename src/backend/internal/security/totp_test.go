@@ -0,0 +1,57 @@
+package security
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B (SHA1), truncated to 6 digits.
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tc := range cases {
+		got, err := TOTPCode(secret, time.Unix(tc.unix, 0))
+		if err != nil {
+			t.Fatalf("totp code: %v", err)
+		}
+		if got != tc.want {
+			t.Fatalf("t=%d: expected %s got %s", tc.unix, tc.want, got)
+		}
+	}
+}
+
+func TestVerifyTOTP_SkewAndRecoveryCodes(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatalf("new secret: %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	prev, _ := TOTPCode(secret, now.Add(-30*time.Second))
+	if _, ok := VerifyTOTP(secret, prev, now); !ok {
+		t.Fatalf("expected previous step to be accepted")
+	}
+	old, _ := TOTPCode(secret, now.Add(-2*time.Minute))
+	if _, ok := VerifyTOTP(secret, old, now); ok {
+		t.Fatalf("expected stale code to be rejected")
+	}
+
+	codes, err := NewRecoveryCodes(3)
+	if err != nil {
+		t.Fatalf("recovery codes: %v", err)
+	}
+	if len(codes) != 3 || HashRecoveryCode(strings.ToUpper(codes[0])) != HashRecoveryCode(strings.ReplaceAll(codes[0], "-", "")) {
+		t.Fatalf("expected normalized recovery code hashes, got %v", codes)
+	}
+}
@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// LocalStore is the Store backed by a directory; key "a/b.webp" is the file
+// {dir}/a/b.webp. All access goes through an os.Root, so keys (or symlinks
+// planted in the directory) cannot reach files outside it.
+//
+// Content types are derived from the key extension (sniffed when unknown);
+// the contentType passed to Put is not persisted.
+type LocalStore struct {
+	dir  string
+	root *os.Root
+}
+
+var _ Store = (*LocalStore)(nil)
+
+// NewLocalStore opens dir, creating it if needed.
+func NewLocalStore(dir string) (*LocalStore, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, fmt.Errorf("local storage dir is not set (STORAGE_LOCAL_DIR)")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create local storage dir: %w", err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("open local storage dir: %w", err)
+	}
+	return &LocalStore{dir: dir, root: root}, nil
+}
+
+// Close releases the directory handle.
+func (s *LocalStore) Close() error { return s.root.Close() }
+
+func (s *LocalStore) Backend() string { return BackendLocal }
+
+func (s *LocalStore) Ping(context.Context) error {
+	_, err := s.root.Stat(".")
+	return err
+}
+
+// localKey validates key for use as a path under the root: non-empty
+// slash-separated segments, none empty or starting with "." (which also
+// rules out "." / ".." and hides temporary files), no backslashes or NULs.
+func localKey(key string) (string, error) {
+	key = normalizeKey(key)
+	if key == "" || strings.ContainsAny(key, "\\\x00") {
+		return "", ErrInvalidKey
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || strings.HasPrefix(seg, ".") {
+			return "", ErrInvalidKey
+		}
+	}
+	return key, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64, _ string) error {
+	key, err := localKey(key)
+	if err != nil {
+		return err
+	}
+	if size <= 0 {
+		return fmt.Errorf("invalid object size")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	dir, name := path.Split(key)
+	if dir != "" {
+		if err := s.root.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("put object: %w", err)
+		}
+	}
+
+	// Write to a hidden temporary file next to the target and rename it into
+	// place, so readers never observe a partial object.
+	var suffix [8]byte
+	_, _ = rand.Read(suffix[:])
+	tmp := dir + "." + name + "." + hex.EncodeToString(suffix[:]) + ".tmp"
+	f, err := s.root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+	n, err := io.Copy(f, io.LimitReader(r, size))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n != size {
+		err = fmt.Errorf("short write: %d of %d bytes", n, size)
+	}
+	if err == nil {
+		err = s.root.Rename(tmp, key)
+	}
+	if err != nil {
+		_ = s.root.Remove(tmp)
+		return fmt.Errorf("put object: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStore) Stat(_ context.Context, key string) (ObjectMeta, error) {
+	key, err := localKey(key)
+	if err != nil {
+		return ObjectMeta{}, fmt.Errorf("%w: %v", ErrObjectNotFound, err)
+	}
+	fi, err := s.root.Stat(key)
+	if err != nil || !fi.Mode().IsRegular() {
+		return ObjectMeta{}, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	return s.meta(key, fi), nil
+}
+
+func (s *LocalStore) meta(key string, fi fs.FileInfo) ObjectMeta {
+	return ObjectMeta{
+		Size:         fi.Size(),
+		ContentType:  s.contentType(key),
+		ETag:         fmt.Sprintf("%x-%x", fi.ModTime().UnixNano(), fi.Size()),
+		LastModified: fi.ModTime(),
+	}
+}
+
+func (s *LocalStore) contentType(key string) string {
+	if ct := mime.TypeByExtension(path.Ext(key)); ct != "" {
+		return ct
+	}
+	f, err := s.root.Open(key)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	var head [512]byte
+	n, _ := io.ReadFull(f, head[:])
+	return http.DetectContentType(head[:n])
+}
+
+func (s *LocalStore) Open(_ context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	key, err := localKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrObjectNotFound, err)
+	}
+	f, err := s.root.Open(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, length), f}, nil
+}
+
+func (s *LocalStore) Copy(ctx context.Context, src, dst string) error {
+	meta, err := s.Stat(ctx, src)
+	if err != nil {
+		return err
+	}
+	body, err := s.Open(ctx, src, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return s.Put(ctx, dst, body, meta.Size, "")
+}
+
+func (s *LocalStore) Remove(_ context.Context, key string) error {
+	key, err := localKey(key)
+	if err != nil {
+		return err
+	}
+	if err := s.root.Remove(key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	prefix = normalizeKey(prefix)
+	// Walk the deepest directory that contains every match.
+	start := "."
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		start = prefix[:i]
+		if _, err := localKey(start); err != nil {
+			return nil
+		}
+	}
+
+	err := fs.WalkDir(s.root.FS(), start, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && p != "." {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			// Prune directories that cannot contain a match.
+			if p != start && !strings.HasPrefix(p+"/", prefix) && !strings.HasPrefix(prefix, p+"/") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !strings.HasPrefix(p, prefix) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil // removed concurrently
+		}
+		return fn(ObjectInfo{Key: p, ObjectMeta: s.meta(p, fi)})
+	})
+	return err
+}
+
+func (s *LocalStore) PresignPut(context.Context, string, time.Duration) (string, error) {
+	return "", ErrPresignUnsupported
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestLocalStore(t *testing.T) (*LocalStore, string) {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := NewLocalStore(dir)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s, dir
+}
+
+func TestLocalStore_PutStatOpenRemove(t *testing.T) {
+	s, dir := newTestLocalStore(t)
+	ctx := context.Background()
+	key := "products/A1/cover/2025/09/01/u/w640.webp"
+
+	if err := s.Put(ctx, "/"+key, strings.NewReader("0123456789"), 10, "image/webp"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	meta, err := s.Stat(ctx, key)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if meta.Size != 10 || meta.ContentType != "image/webp" || meta.ETag == "" || meta.LastModified.IsZero() {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+
+	for _, tc := range []struct {
+		offset, length int64
+		want           string
+	}{{0, -1, "0123456789"}, {2, 3, "234"}, {7, -1, "789"}} {
+		rc, err := s.Open(ctx, key, tc.offset, tc.length)
+		if err != nil {
+			t.Fatalf("Open(%d,%d): %v", tc.offset, tc.length, err)
+		}
+		got, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(got) != tc.want {
+			t.Fatalf("Open(%d,%d) = %q, want %q", tc.offset, tc.length, got, tc.want)
+		}
+	}
+
+	// A short body must not leave a (partial) object or temp file behind.
+	if err := s.Put(ctx, "products/A1/short.webp", strings.NewReader("abc"), 10, ""); err == nil {
+		t.Fatalf("expected short write to fail")
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "products", "A1"))
+	for _, e := range entries {
+		if e.Name() != "cover" {
+			t.Fatalf("unexpected leftover %s", e.Name())
+		}
+	}
+
+	if err := s.Remove(ctx, key); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := s.Remove(ctx, key); err != nil {
+		t.Fatalf("Remove of a missing key should succeed: %v", err)
+	}
+	if _, err := s.Stat(ctx, key); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("expected ErrObjectNotFound, got %v", err)
+	}
+	if _, err := s.Open(ctx, key, 0, -1); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("expected ErrObjectNotFound, got %v", err)
+	}
+}
+
+func TestLocalStore_Copy(t *testing.T) {
+	s, _ := newTestLocalStore(t)
+	ctx := context.Background()
+	if err := s.Put(ctx, "products/A1/cover/u/w640.webp", strings.NewReader("abc"), 3, ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s.Copy(ctx, "products/A1/cover/u/w640.webp", "products/B2/cover/u/w640.webp"); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	rc, err := s.Open(ctx, "products/B2/cover/u/w640.webp", 0, -1)
+	if err != nil {
+		t.Fatalf("Open copy: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != "abc" {
+		t.Fatalf("copy = %q", got)
+	}
+	if _, err := s.Stat(ctx, "products/A1/cover/u/w640.webp"); err != nil {
+		t.Fatalf("source removed: %v", err)
+	}
+	if err := s.Copy(ctx, "products/A1/missing.webp", "products/B2/missing.webp"); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("expected ErrObjectNotFound, got %v", err)
+	}
+}
+
+func TestLocalStore_RejectsEscapingKeys(t *testing.T) {
+	s, dir := newTestLocalStore(t)
+	ctx := context.Background()
+
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Dir(outside), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"", "../secret.txt", "products/../../x", "products/.hidden", `products\x`, "products//x", "products/x\x00"} {
+		if err := s.Put(ctx, key, strings.NewReader("x"), 1, ""); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("Put(%q): expected ErrInvalidKey, got %v", key, err)
+		}
+		if _, err := s.Stat(ctx, key); !errors.Is(err, ErrObjectNotFound) {
+			t.Fatalf("Stat(%q): expected ErrObjectNotFound, got %v", key, err)
+		}
+	}
+
+	// Symlinks inside the directory cannot be used to leave it.
+	if _, err := s.Open(ctx, "link/secret.txt", 0, -1); err == nil {
+		t.Fatalf("expected symlink escape to fail")
+	}
+	if err := s.Put(ctx, "link/new.txt", strings.NewReader("x"), 1, ""); err == nil {
+		t.Fatalf("expected write through symlink to fail")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(outside), "new.txt")); err == nil {
+		t.Fatalf("file written outside the store")
+	}
+}
+
+func TestLocalStore_List(t *testing.T) {
+	s, _ := newTestLocalStore(t)
+	ctx := context.Background()
+	for _, key := range []string{
+		"products/A1/cover/a.webp",
+		"products/A1/gallery/b.webp",
+		"products/B2/cover/c.webp",
+		"variants/products/A1/cover/a.webp/w320.jpeg",
+	} {
+		if err := s.Put(ctx, key, strings.NewReader("x"), 1, ""); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	list := func(prefix string) string {
+		var keys []string
+		if err := s.List(ctx, prefix, func(o ObjectInfo) error {
+			keys = append(keys, o.Key)
+			return nil
+		}); err != nil {
+			t.Fatalf("List(%q): %v", prefix, err)
+		}
+		return strings.Join(keys, ",")
+	}
+	if got := list("products/"); got != "products/A1/cover/a.webp,products/A1/gallery/b.webp,products/B2/cover/c.webp" {
+		t.Fatalf("List(products/) = %s", got)
+	}
+	if got := list("products/A1/co"); got != "products/A1/cover/a.webp" {
+		t.Fatalf("List(products/A1/co) = %s", got)
+	}
+	if got := list("missing/"); got != "" {
+		t.Fatalf("List(missing/) = %s", got)
+	}
+	if got := list(""); strings.Count(got, ",") != 3 {
+		t.Fatalf("List(\"\") = %s", got)
+	}
+}
+
+func TestServeObject_LocalStore(t *testing.T) {
+	s, _ := newTestLocalStore(t)
+	if err := s.Put(context.Background(), "products/a.webp", strings.NewReader("0123456789"), 10, ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/assets/products/a.webp", nil)
+	req.Header.Set("Range", "bytes=3-5")
+	w := httptest.NewRecorder()
+	if err := ServeObject(w, req, s, "products/a.webp", nil); err != nil {
+		t.Fatalf("ServeObject: %v", err)
+	}
+	if w.Code != http.StatusPartialContent || w.Body.String() != "345" || w.Header().Get("Content-Type") != "image/webp" {
+		t.Fatalf("unexpected response: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	err := ServeObject(w, httptest.NewRequest(http.MethodGet, "/x", nil), s, "products/missing.webp", nil)
+	if !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("expected ErrObjectNotFound, got %v", err)
+	}
+}
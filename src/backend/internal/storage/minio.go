@@ -12,6 +12,7 @@ import (
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
 )
 
 // NewClient creates a MinIO (S3 compatible) client and verifies connectivity.
@@ -70,8 +71,80 @@ func EnsureBucket(ctx context.Context, client *minio.Client, cfg config.MinioCon
 	return nil
 }
 
-func PutObject(ctx context.Context, client *minio.Client, cfg config.MinioConfig, objectKey string, r io.Reader, size int64, contentType string) error {
-	objectKey = strings.TrimSpace(strings.TrimPrefix(objectKey, "/"))
+// NewPresignClient creates the client presigned URLs are signed with.
+// Browsers never talk to MINIO_ENDPOINT, so URLs are signed for the host of
+// MINIO_PUBLIC_BASE_URL, a proxy that must forward the Host header unchanged
+// (and strip its path prefix, if any). Signing is offline: the client never
+// connects. Without a public base URL it returns (nil, "", nil) and presigned
+// uploads are unsupported.
+func NewPresignClient(cfg config.MinioConfig) (client *minio.Client, pathPrefix string, err error) {
+	base := strings.TrimSuffix(strings.TrimSpace(cfg.PublicBaseURL), "/")
+	if base == "" {
+		return nil, "", nil
+	}
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, "", fmt.Errorf("parse public base url: %w", err)
+	}
+	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, "", fmt.Errorf("invalid public base url: %q", base)
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1" // MinIO's default; a fixed region avoids a location lookup
+	}
+	client, err = minio.New(u.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure:       u.Scheme == "https",
+		Region:       region,
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("create minio presign client: %w", err)
+	}
+	return client, strings.TrimSuffix(u.Path, "/"), nil
+}
+
+// MinioStore is the Store backed by one MinIO (S3 compatible) bucket.
+type MinioStore struct {
+	client *minio.Client
+	cfg    config.MinioConfig
+
+	// presign signs upload URLs for the public base URL (nil: unsupported);
+	// presignPath is that URL's path prefix, added after signing.
+	presign     *minio.Client
+	presignPath string
+}
+
+var (
+	_ Store   = (*MinioStore)(nil)
+	_ Expirer = (*MinioStore)(nil)
+)
+
+func NewMinioStore(client *minio.Client, cfg config.MinioConfig) (*MinioStore, error) {
+	presign, presignPath, err := NewPresignClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &MinioStore{client: client, cfg: cfg, presign: presign, presignPath: presignPath}, nil
+}
+
+// Client exposes the underlying MinIO client.
+func (s *MinioStore) Client() *minio.Client { return s.client }
+
+func (s *MinioStore) Backend() string { return BackendMinio }
+
+func (s *MinioStore) Ping(ctx context.Context) error {
+	_, err := s.client.ListBuckets(ctx)
+	return err
+}
+
+func (s *MinioStore) Put(ctx context.Context, objectKey string, r io.Reader, size int64, contentType string) error {
+	objectKey = normalizeKey(objectKey)
 	if objectKey == "" {
 		return fmt.Errorf("objectKey is empty")
 	}
@@ -79,11 +152,11 @@ func PutObject(ctx context.Context, client *minio.Client, cfg config.MinioConfig
 		return fmt.Errorf("invalid object size")
 	}
 
-	if err := EnsureBucket(ctx, client, cfg); err != nil {
+	if err := EnsureBucket(ctx, s.client, s.cfg); err != nil {
 		return err
 	}
 
-	_, err := client.PutObject(ctx, cfg.Bucket, objectKey, r, size, minio.PutObjectOptions{
+	_, err := s.client.PutObject(ctx, s.cfg.Bucket, objectKey, r, size, minio.PutObjectOptions{
 		ContentType: contentType,
 	})
 	if err != nil {
@@ -92,6 +165,187 @@ func PutObject(ctx context.Context, client *minio.Client, cfg config.MinioConfig
 	return nil
 }
 
+func (s *MinioStore) Stat(ctx context.Context, objectKey string) (ObjectMeta, error) {
+	stat, err := s.client.StatObject(ctx, s.cfg.Bucket, normalizeKey(objectKey), minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectMeta{}, minioError(err)
+	}
+	return ObjectMeta{
+		Size:         stat.Size,
+		ContentType:  stat.ContentType,
+		ETag:         stat.ETag,
+		LastModified: stat.LastModified,
+	}, nil
+}
+
+func (s *MinioStore) Open(ctx context.Context, objectKey string, offset, length int64) (io.ReadCloser, error) {
+	opts := minio.GetObjectOptions{}
+	if offset != 0 || length >= 0 {
+		end := int64(0) // open-ended: bytes={offset}-
+		if length >= 0 {
+			if length == 0 {
+				return io.NopCloser(strings.NewReader("")), nil
+			}
+			end = offset + length - 1
+		}
+		if err := opts.SetRange(offset, end); err != nil {
+			return nil, err
+		}
+	}
+	obj, err := s.client.GetObject(ctx, s.cfg.Bucket, normalizeKey(objectKey), opts)
+	if err != nil {
+		return nil, minioError(err)
+	}
+	return minioObject{obj}, nil
+}
+
+func (s *MinioStore) Copy(ctx context.Context, src, dst string) error {
+	_, err := s.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: s.cfg.Bucket, Object: normalizeKey(dst)},
+		minio.CopySrcOptions{Bucket: s.cfg.Bucket, Object: normalizeKey(src)},
+	)
+	return minioError(err)
+}
+
+func (s *MinioStore) Remove(ctx context.Context, objectKey string) error {
+	return s.client.RemoveObject(ctx, s.cfg.Bucket, normalizeKey(objectKey), minio.RemoveObjectOptions{})
+}
+
+func (s *MinioStore) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // stops the listing goroutine when fn fails early
+	for obj := range s.client.ListObjects(ctx, s.cfg.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		if err := fn(ObjectInfo{Key: obj.Key, ObjectMeta: ObjectMeta{
+			Size:         obj.Size,
+			ContentType:  obj.ContentType,
+			ETag:         obj.ETag,
+			LastModified: obj.LastModified,
+		}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PresignPut signs a PUT URL on MINIO_PUBLIC_BASE_URL (see NewPresignClient);
+// without one it returns ErrPresignUnsupported.
+func (s *MinioStore) PresignPut(ctx context.Context, objectKey string, expires time.Duration) (string, error) {
+	objectKey = normalizeKey(objectKey)
+	if objectKey == "" {
+		return "", fmt.Errorf("objectKey is empty")
+	}
+	if s.presign == nil {
+		return "", ErrPresignUnsupported
+	}
+	if err := EnsureBucket(ctx, s.client, s.cfg); err != nil {
+		return "", err
+	}
+	return s.signPut(ctx, objectKey, expires)
+}
+
+func (s *MinioStore) signPut(ctx context.Context, objectKey string, expires time.Duration) (string, error) {
+	u, err := s.presign.PresignedPutObject(ctx, s.cfg.Bucket, objectKey, expires)
+	if err != nil {
+		return "", fmt.Errorf("presign put: %w", err)
+	}
+	// The proxy strips its prefix, so the signed path stays what MinIO sees.
+	if s.presignPath != "" {
+		u.Path = s.presignPath + u.Path
+		if u.RawPath != "" {
+			u.RawPath = s.presignPath + u.RawPath
+		}
+	}
+	return u.String(), nil
+}
+
+// ExpirePrefix installs (or updates) a bucket lifecycle rule deleting objects
+// under prefix after the given age, rounded up to whole days. Other rules in
+// the bucket configuration are kept, and nothing is written when an
+// equivalent rule is already there, so restarts leave the bucket alone.
+func (s *MinioStore) ExpirePrefix(ctx context.Context, prefix string, after time.Duration) error {
+	if err := EnsureBucket(ctx, s.client, s.cfg); err != nil {
+		return err
+	}
+	days := int((after + 24*time.Hour - 1) / (24 * time.Hour))
+	if days < 1 {
+		days = 1
+	}
+
+	cfg, err := s.client.GetBucketLifecycle(ctx, s.cfg.Bucket)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code != "NoSuchLifecycleConfiguration" {
+			return fmt.Errorf("get bucket lifecycle: %w", err)
+		}
+		cfg = lifecycle.NewConfiguration()
+	}
+
+	id := "expire-" + strings.TrimSuffix(prefix, "/")
+	rule := lifecycle.Rule{
+		ID:         id,
+		Status:     "Enabled",
+		RuleFilter: lifecycle.Filter{Prefix: prefix},
+		Expiration: lifecycle.Expiration{Days: lifecycle.ExpirationDays(days)},
+	}
+	replaced := false
+	for i, r := range cfg.Rules {
+		if expiresPrefix(r, prefix, days) {
+			// Already in place (ours, or an equivalent rule set up by hand):
+			// leave the bucket configuration alone.
+			return nil
+		}
+		if r.ID == id {
+			cfg.Rules[i] = rule
+			replaced = true
+		}
+	}
+	if !replaced {
+		cfg.Rules = append(cfg.Rules, rule)
+	}
+	if err := s.client.SetBucketLifecycle(ctx, s.cfg.Bucket, cfg); err != nil {
+		return fmt.Errorf("set bucket lifecycle: %w", err)
+	}
+	return nil
+}
+
+// expiresPrefix reports whether r is an enabled rule expiring exactly the
+// objects under prefix after days. The prefix may come back as the legacy
+// rule-level element or inside And, depending on how the rule was written.
+func expiresPrefix(r lifecycle.Rule, prefix string, days int) bool {
+	if r.Status != "Enabled" || int(r.Expiration.Days) != days {
+		return false
+	}
+	f := r.RuleFilter
+	if !f.Tag.IsEmpty() || f.ObjectSizeLessThan != 0 || f.ObjectSizeGreaterThan != 0 {
+		return false
+	}
+	if !f.And.IsEmpty() {
+		return f.And.Prefix == prefix && len(f.And.Tags) == 0 && f.And.ObjectSizeLessThan == 0 && f.And.ObjectSizeGreaterThan == 0
+	}
+	return f.Prefix == prefix || (f.Prefix == "" && r.Prefix == prefix)
+}
+
+// minioObject maps errors surfacing on the (lazy) first read of a GetObject.
+type minioObject struct{ *minio.Object }
+
+func (o minioObject) Read(p []byte) (int, error) {
+	n, err := o.Object.Read(p)
+	if err != nil && err != io.EOF {
+		err = minioError(err)
+	}
+	return n, err
+}
+
+func minioError(err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NoSuchBucket":
+		return fmt.Errorf("%w: %v", ErrObjectNotFound, err)
+	}
+	return err
+}
+
 func PublicObjectURL(cfg config.MinioConfig, objectKey string) (string, error) {
 	objectKey = strings.TrimSpace(strings.TrimPrefix(objectKey, "/"))
 	if objectKey == "" {
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"evening-gown/internal/config"
+
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+)
+
+func TestMinioStore_PresignsForPublicBaseURL(t *testing.T) {
+	cfg := config.MinioConfig{
+		Endpoint:      "minio:9000",
+		AccessKey:     "access",
+		SecretKey:     "secret",
+		Bucket:        "gowns",
+		PublicBaseURL: "https://cdn.example.com/storage/",
+	}
+	s, err := NewMinioStore(nil, cfg)
+	if err != nil {
+		t.Fatalf("NewMinioStore: %v", err)
+	}
+	raw, err := s.signPut(context.Background(), "uploads/2025/09/01/abc", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("signPut: %v", err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse %q: %v", raw, err)
+	}
+	if u.Scheme != "https" || u.Host != "cdn.example.com" || u.Path != "/storage/gowns/uploads/2025/09/01/abc" {
+		t.Fatalf("expected a URL on the public base, got %s", raw)
+	}
+	if u.Query().Get("X-Amz-Signature") == "" || !strings.Contains(u.Query().Get("X-Amz-Credential"), "/us-east-1/") {
+		t.Fatalf("expected a SigV4 presigned URL, got %s", raw)
+	}
+
+	// Browsers never reach MINIO_ENDPOINT: without a public base, no presigning.
+	cfg.PublicBaseURL = ""
+	s, err = NewMinioStore(nil, cfg)
+	if err != nil {
+		t.Fatalf("NewMinioStore: %v", err)
+	}
+	if _, err := s.PresignPut(context.Background(), "uploads/x", time.Minute); !errors.Is(err, ErrPresignUnsupported) {
+		t.Fatalf("expected ErrPresignUnsupported, got %v", err)
+	}
+
+	cfg.PublicBaseURL = "ftp://cdn.example.com"
+	if _, err := NewMinioStore(nil, cfg); err == nil {
+		t.Fatalf("expected an invalid public base url to fail")
+	}
+}
+
+func TestExpiresPrefix(t *testing.T) {
+	rule := func(f lifecycle.Filter, days int) lifecycle.Rule {
+		return lifecycle.Rule{ID: "r", Status: "Enabled", RuleFilter: f, Expiration: lifecycle.Expiration{Days: lifecycle.ExpirationDays(days)}}
+	}
+	legacy := rule(lifecycle.Filter{}, 1)
+	legacy.Prefix = "uploads/"
+	disabled := rule(lifecycle.Filter{Prefix: "uploads/"}, 1)
+	disabled.Status = "Disabled"
+
+	cases := []struct {
+		name string
+		rule lifecycle.Rule
+		want bool
+	}{
+		{"filter prefix", rule(lifecycle.Filter{Prefix: "uploads/"}, 1), true},
+		{"and prefix", rule(lifecycle.Filter{And: lifecycle.And{Prefix: "uploads/"}}, 1), true},
+		{"legacy prefix", legacy, true},
+		{"other days", rule(lifecycle.Filter{Prefix: "uploads/"}, 2), false},
+		{"other prefix", rule(lifecycle.Filter{Prefix: "products/"}, 1), false},
+		{"tagged", rule(lifecycle.Filter{Prefix: "uploads/", Tag: lifecycle.Tag{Key: "k", Value: "v"}}, 1), false},
+		{"disabled", disabled, false},
+	}
+	for _, tc := range cases {
+		if got := expiresPrefix(tc.rule, "uploads/", 1); got != tc.want {
+			t.Errorf("%s: expiresPrefix = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
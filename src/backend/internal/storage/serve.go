@@ -0,0 +1,332 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrObjectNotFound is returned by ServeObject when nothing was written and the
+// caller should respond 404 itself.
+var ErrObjectNotFound = errors.New("object not found")
+
+// maxRanges caps the parts of a multi-range request. Clients asking for more
+// get the whole object instead, which is always a valid answer.
+const maxRanges = 32
+
+// ObjectMeta is what ServeContent needs to know about an object.
+type ObjectMeta struct {
+	Size         int64
+	ContentType  string
+	ETag         string // without quotes, as reported by the store
+	LastModified time.Time
+}
+
+// OpenRangeFunc opens length bytes of an object starting at offset.
+type OpenRangeFunc func(ctx context.Context, offset, length int64) (io.ReadCloser, error)
+
+// ServeObject streams objectKey to w, honoring conditional requests
+// (If-None-Match, If-Modified-Since) and byte ranges (single and multipart
+// Range, If-Range). Each range is read from the store with a ranged Open.
+//
+// header is copied onto every response, 304 included (e.g. Cache-Control).
+func ServeObject(w http.ResponseWriter, r *http.Request, store Store, objectKey string, header map[string]string) error {
+	meta, err := store.Stat(r.Context(), objectKey)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrObjectNotFound, err)
+	}
+	open := func(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+		return store.Open(ctx, objectKey, offset, length)
+	}
+	return ServeContent(w, r, meta, header, open)
+}
+
+// ServeContent implements ServeObject for any object source.
+func ServeContent(w http.ResponseWriter, r *http.Request, meta ObjectMeta, header map[string]string, open OpenRangeFunc) error {
+	h := w.Header()
+	for k, v := range header {
+		h.Set(k, v)
+	}
+	etag := ""
+	if e := strings.Trim(strings.TrimSpace(meta.ETag), `"`); e != "" {
+		etag = `"` + e + `"`
+		h.Set("ETag", etag)
+	}
+	modified := meta.LastModified.UTC().Truncate(time.Second)
+	if !meta.LastModified.IsZero() {
+		h.Set("Last-Modified", modified.Format(http.TimeFormat))
+	}
+	h.Set("Accept-Ranges", "bytes")
+
+	if notModified(r, etag, modified) {
+		// RFC 9110 §15.4.5: no representation headers on a 304.
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	contentType := strings.TrimSpace(meta.ContentType)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	rangeHeader := r.Header.Get("Range")
+	if rangeHeader != "" && !ifRangeMatches(r, etag, modified) {
+		rangeHeader = ""
+	}
+	ranges, err := parseRange(rangeHeader, meta.Size)
+	if err != nil {
+		h.Set("Content-Range", fmt.Sprintf("bytes */%d", meta.Size))
+		h.Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		_, _ = io.WriteString(w, `{"error":"range not satisfiable"}`)
+		return nil
+	}
+	if len(ranges) > maxRanges || sumRanges(ranges) > meta.Size {
+		ranges = nil
+	}
+
+	ctx := r.Context()
+	head := r.Method == http.MethodHead
+
+	switch len(ranges) {
+	case 0:
+		body, err := open(ctx, 0, meta.Size)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrObjectNotFound, err)
+		}
+		defer body.Close()
+		h.Set("Content-Type", contentType)
+		h.Set("Content-Length", strconv.FormatInt(meta.Size, 10))
+		w.WriteHeader(http.StatusOK)
+		if head {
+			return nil
+		}
+		_, err = io.CopyN(w, body, meta.Size)
+		return err
+
+	case 1:
+		ra := ranges[0]
+		body, err := open(ctx, ra.start, ra.length)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrObjectNotFound, err)
+		}
+		defer body.Close()
+		h.Set("Content-Type", contentType)
+		h.Set("Content-Range", ra.contentRange(meta.Size))
+		h.Set("Content-Length", strconv.FormatInt(ra.length, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		if head {
+			return nil
+		}
+		_, err = io.CopyN(w, body, ra.length)
+		return err
+	}
+
+	mw := multipart.NewWriter(w)
+	h.Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+	h.Set("Content-Length", strconv.FormatInt(multipartSize(ranges, contentType, meta.Size, mw.Boundary()), 10))
+	w.WriteHeader(http.StatusPartialContent)
+	if head {
+		return nil
+	}
+	for _, ra := range ranges {
+		part, err := mw.CreatePart(ra.mimeHeader(contentType, meta.Size))
+		if err != nil {
+			return err
+		}
+		body, err := open(ctx, ra.start, ra.length)
+		if err != nil {
+			return err
+		}
+		_, err = io.CopyN(part, body, ra.length)
+		body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// notModified evaluates If-None-Match, falling back to If-Modified-Since only
+// when no If-None-Match was sent (RFC 9110 §13.2.2).
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && etagListMatches(inm, etag, true)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || modified.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !modified.After(t)
+}
+
+// ifRangeMatches reports whether a Range request may be honored. A missing
+// If-Range always matches; otherwise it must strongly match the ETag or
+// exactly match Last-Modified.
+func ifRangeMatches(r *http.Request, etag string, modified time.Time) bool {
+	ir := strings.TrimSpace(r.Header.Get("If-Range"))
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, `"`) || strings.HasPrefix(ir, "W/") {
+		return etag != "" && etagListMatches(ir, etag, false)
+	}
+	t, err := http.ParseTime(ir)
+	return err == nil && !modified.IsZero() && t.Equal(modified)
+}
+
+// etagListMatches compares etag against a comma-separated If-None-Match /
+// If-Range value. Weak comparison ignores the W/ prefix.
+func etagListMatches(list, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = candidate[2:]
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+type byteRange struct {
+	start, length int64
+}
+
+func (ra byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", ra.start, ra.start+ra.length-1, size)
+}
+
+func (ra byteRange) mimeHeader(contentType string, size int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Range": {ra.contentRange(size)},
+		"Content-Type":  {contentType},
+	}
+}
+
+var errUnsatisfiableRange = errors.New("invalid range")
+
+// parseRange parses a "bytes=" Range header (RFC 9110 §14.1.2). An empty
+// header yields no ranges; ranges starting past the end are dropped and an
+// error is returned only when none is satisfiable or the header is malformed.
+func parseRange(s string, size int64) ([]byteRange, error) {
+	if s == "" {
+		return nil, nil
+	}
+	const prefix = "bytes="
+	if !strings.HasPrefix(s, prefix) {
+		return nil, errUnsatisfiableRange
+	}
+
+	var ranges []byteRange
+	noOverlap := false
+	for _, spec := range strings.Split(s[len(prefix):], ",") {
+		spec = textproto.TrimString(spec)
+		if spec == "" {
+			continue
+		}
+		startStr, endStr, ok := strings.Cut(spec, "-")
+		if !ok {
+			return nil, errUnsatisfiableRange
+		}
+		startStr, endStr = textproto.TrimString(startStr), textproto.TrimString(endStr)
+
+		var ra byteRange
+		if startStr == "" {
+			// Suffix range: the last n bytes.
+			if endStr == "" || endStr[0] == '-' {
+				return nil, errUnsatisfiableRange
+			}
+			n, err := strconv.ParseInt(endStr, 10, 64)
+			if err != nil || n < 0 {
+				return nil, errUnsatisfiableRange
+			}
+			if n == 0 {
+				noOverlap = true
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			ra = byteRange{start: size - n, length: n}
+		} else {
+			start, err := strconv.ParseInt(startStr, 10, 64)
+			if err != nil || start < 0 {
+				return nil, errUnsatisfiableRange
+			}
+			if start >= size {
+				noOverlap = true
+				continue
+			}
+			ra.start = start
+			if endStr == "" {
+				ra.length = size - start
+			} else {
+				end, err := strconv.ParseInt(endStr, 10, 64)
+				if err != nil || start > end {
+					return nil, errUnsatisfiableRange
+				}
+				if end >= size {
+					end = size - 1
+				}
+				ra.length = end - start + 1
+			}
+		}
+		ranges = append(ranges, ra)
+	}
+	if noOverlap && len(ranges) == 0 {
+		return nil, errUnsatisfiableRange
+	}
+	return ranges, nil
+}
+
+func sumRanges(ranges []byteRange) int64 {
+	var n int64
+	for _, ra := range ranges {
+		n += ra.length
+	}
+	return n
+}
+
+// multipartSize is the exact body length of a multipart/byteranges response.
+func multipartSize(ranges []byteRange, contentType string, size int64, boundary string) int64 {
+	var cw countingWriter
+	mw := multipart.NewWriter(&cw)
+	_ = mw.SetBoundary(boundary)
+	for _, ra := range ranges {
+		_, _ = mw.CreatePart(ra.mimeHeader(contentType, size))
+		cw += countingWriter(ra.length)
+	}
+	_ = mw.Close()
+	return int64(cw)
+}
+
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
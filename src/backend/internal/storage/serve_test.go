@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type openCall struct{ offset, length int64 }
+
+func serveTestContent(t *testing.T, content []byte, reqHeader map[string]string) (*httptest.ResponseRecorder, []openCall) {
+	t.Helper()
+	meta := ObjectMeta{
+		Size:         int64(len(content)),
+		ContentType:  "image/webp",
+		ETag:         "abc123",
+		LastModified: time.Date(2025, 9, 1, 8, 0, 0, 500, time.UTC),
+	}
+	var calls []openCall
+	open := func(_ context.Context, offset, length int64) (io.ReadCloser, error) {
+		calls = append(calls, openCall{offset, length})
+		return io.NopCloser(bytes.NewReader(content[offset : offset+length])), nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/assets/x", nil)
+	for k, v := range reqHeader {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	if err := ServeContent(w, req, meta, map[string]string{"Cache-Control": "public, max-age=31536000, immutable"}, open); err != nil {
+		t.Fatalf("ServeContent: %v", err)
+	}
+	return w, calls
+}
+
+func TestServeContent_FullAndValidators(t *testing.T) {
+	w, calls := serveTestContent(t, []byte("0123456789"), nil)
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Fatalf("expected 200 full body, got %d %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("ETag"); got != `"abc123"` {
+		t.Fatalf("ETag = %q", got)
+	}
+	if got := w.Header().Get("Last-Modified"); got != "Mon, 01 Sep 2025 08:00:00 GMT" {
+		t.Fatalf("Last-Modified = %q", got)
+	}
+	if w.Header().Get("Accept-Ranges") != "bytes" || w.Header().Get("Cache-Control") == "" {
+		t.Fatalf("missing Accept-Ranges/Cache-Control: %v", w.Header())
+	}
+	if len(calls) != 1 || calls[0] != (openCall{0, 10}) {
+		t.Fatalf("unexpected opens: %v", calls)
+	}
+}
+
+func TestServeContent_NotModified(t *testing.T) {
+	cases := []map[string]string{
+		{"If-None-Match": `"abc123"`},
+		{"If-None-Match": `W/"other", W/"abc123"`},
+		{"If-None-Match": "*"},
+		{"If-Modified-Since": "Mon, 01 Sep 2025 08:00:00 GMT"},
+		{"If-Modified-Since": "Tue, 02 Sep 2025 08:00:00 GMT"},
+	}
+	for _, h := range cases {
+		w, calls := serveTestContent(t, []byte("0123456789"), h)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 || len(calls) != 0 {
+			t.Fatalf("%v: expected empty 304 without fetching, got %d (%d opens)", h, w.Code, len(calls))
+		}
+		if w.Header().Get("ETag") == "" || w.Header().Get("Cache-Control") == "" {
+			t.Fatalf("%v: 304 must keep ETag and Cache-Control", h)
+		}
+	}
+
+	// If-None-Match wins over If-Modified-Since.
+	w, _ := serveTestContent(t, []byte("0123456789"), map[string]string{
+		"If-None-Match":     `"stale"`,
+		"If-Modified-Since": "Tue, 02 Sep 2025 08:00:00 GMT",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for mismatched If-None-Match, got %d", w.Code)
+	}
+	w, _ = serveTestContent(t, []byte("0123456789"), map[string]string{"If-Modified-Since": "Sun, 31 Aug 2025 08:00:00 GMT"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for older If-Modified-Since, got %d", w.Code)
+	}
+}
+
+func TestServeContent_SingleRange(t *testing.T) {
+	cases := []struct {
+		rng, body, contentRange string
+		call                    openCall
+	}{
+		{"bytes=2-5", "2345", "bytes 2-5/10", openCall{2, 4}},
+		{"bytes=7-", "789", "bytes 7-9/10", openCall{7, 3}},
+		{"bytes=-3", "789", "bytes 7-9/10", openCall{7, 3}},
+		{"bytes=8-100", "89", "bytes 8-9/10", openCall{8, 2}},
+	}
+	for _, tc := range cases {
+		w, calls := serveTestContent(t, []byte("0123456789"), map[string]string{"Range": tc.rng})
+		if w.Code != http.StatusPartialContent || w.Body.String() != tc.body {
+			t.Fatalf("%s: got %d %q", tc.rng, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Range"); got != tc.contentRange {
+			t.Fatalf("%s: Content-Range = %q", tc.rng, got)
+		}
+		if len(calls) != 1 || calls[0] != tc.call {
+			t.Fatalf("%s: expected one ranged open %v, got %v", tc.rng, tc.call, calls)
+		}
+	}
+
+	w, _ := serveTestContent(t, []byte("0123456789"), map[string]string{"Range": "bytes=20-30"})
+	if w.Code != http.StatusRequestedRangeNotSatisfiable || w.Header().Get("Content-Range") != "bytes */10" {
+		t.Fatalf("expected 416, got %d %v", w.Code, w.Header())
+	}
+
+	// A stale If-Range falls back to the full representation.
+	w, _ = serveTestContent(t, []byte("0123456789"), map[string]string{"Range": "bytes=2-5", "If-Range": `"stale"`})
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Fatalf("expected 200 for stale If-Range, got %d", w.Code)
+	}
+	w, _ = serveTestContent(t, []byte("0123456789"), map[string]string{"Range": "bytes=2-5", "If-Range": `"abc123"`})
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("expected 206 for matching If-Range, got %d", w.Code)
+	}
+}
+
+func TestServeContent_MultiRange(t *testing.T) {
+	w, calls := serveTestContent(t, []byte("0123456789"), map[string]string{"Range": "bytes=0-1, 6-8"})
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d", w.Code)
+	}
+	if len(calls) != 2 || calls[0] != (openCall{0, 2}) || calls[1] != (openCall{6, 3}) {
+		t.Fatalf("expected two ranged opens, got %v", calls)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("Content-Type = %q", w.Header().Get("Content-Type"))
+	}
+	if got, want := w.Header().Get("Content-Length"), w.Body.Len(); got != strconv.Itoa(want) {
+		t.Fatalf("Content-Length = %s, body is %d bytes", got, want)
+	}
+
+	mr := multipart.NewReader(w.Body, params["boundary"])
+	want := []struct{ body, contentRange string }{{"01", "bytes 0-1/10"}, {"678", "bytes 6-8/10"}}
+	for i, exp := range want {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+		body, _ := io.ReadAll(part)
+		if string(body) != exp.body || part.Header.Get("Content-Range") != exp.contentRange || part.Header.Get("Content-Type") != "image/webp" {
+			t.Fatalf("part %d: %q %v", i, body, part.Header)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Fatalf("expected exactly two parts, got %v", err)
+	}
+}
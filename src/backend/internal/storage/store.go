@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"evening-gown/internal/config"
+)
+
+// Storage backends (STORAGE_BACKEND).
+const (
+	BackendMinio = "minio"
+	BackendLocal = "local"
+)
+
+var (
+	// ErrInvalidKey is returned for object keys a store refuses to address.
+	ErrInvalidKey = errors.New("invalid object key")
+	// ErrPresignUnsupported is returned by stores that cannot hand out
+	// presigned URLs (clients must upload through the application).
+	ErrPresignUnsupported = errors.New("presigned urls not supported by this storage backend")
+)
+
+// Store is the object storage the application keeps uploads in: one MinIO
+// bucket or one local directory. Keys are slash-separated ("products/...").
+type Store interface {
+	// Backend names the implementation (BackendMinio, BackendLocal).
+	Backend() string
+	// Ping checks that the store is reachable.
+	Ping(ctx context.Context) error
+
+	// Put stores size bytes from r under key, replacing any existing object.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Stat returns the object metadata, or an error wrapping ErrObjectNotFound.
+	Stat(ctx context.Context, key string) (ObjectMeta, error)
+	// Open reads length bytes starting at offset; a negative length reads to
+	// the end. Missing objects yield an error wrapping ErrObjectNotFound
+	// (possibly only on the first Read).
+	Open(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Copy duplicates the object src as dst, replacing any existing dst.
+	// A missing src yields an error wrapping ErrObjectNotFound.
+	Copy(ctx context.Context, src, dst string) error
+	// Remove deletes key. Removing a missing object is not an error.
+	Remove(ctx context.Context, key string) error
+	// List calls fn for every object whose key starts with prefix, stopping
+	// at the first error fn returns.
+	List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+
+	// PresignPut returns a URL that accepts a single HTTP PUT of key until
+	// expires elapses, or ErrPresignUnsupported.
+	PresignPut(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// Expirer is implemented by stores that can expire objects under a prefix by
+// themselves (e.g. a bucket lifecycle rule), without the application sweeping.
+type Expirer interface {
+	ExpirePrefix(ctx context.Context, prefix string, after time.Duration) error
+}
+
+// ObjectInfo is one listed object.
+type ObjectInfo struct {
+	Key string
+	ObjectMeta
+}
+
+// New opens the store selected by cfg.Backend. For the MinIO backend it
+// returns (nil, nil) when MINIO_ENDPOINT is empty, meaning storage is disabled.
+func New(ctx context.Context, cfg config.MinioConfig) (Store, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Backend)) {
+	case "", BackendMinio:
+		client, err := NewClient(ctx, cfg)
+		if err != nil || client == nil {
+			return nil, err
+		}
+		if strings.TrimSpace(cfg.Bucket) == "" {
+			return nil, fmt.Errorf("minio bucket is not set (MINIO_BUCKET)")
+		}
+		return NewMinioStore(client, cfg)
+	case BackendLocal:
+		return NewLocalStore(cfg.LocalDir)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (STORAGE_BACKEND: minio|local)", cfg.Backend)
+	}
+}
+
+// normalizeKey trims whitespace and leading slashes, as the MinIO helpers
+// always have.
+func normalizeKey(key string) string {
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(key), "/"))
+}
@@ -0,0 +1,240 @@
+// Package templateapply re-applies the product detail templates to existing
+// products.
+//
+// Templates only reach a product when it is created or its detail is saved
+// (see model.MergeProductDetailWithTemplate), so a spec row or option group
+// added to a template later is missing from every product nobody edited since.
+// Preview lists the products that would gain something and Apply writes the
+// merged details back, batch by batch.
+package templateapply
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"evening-gown/internal/assets"
+	"evening-gown/internal/model"
+	"evening-gown/internal/revisions"
+
+	"gorm.io/gorm"
+)
+
+const defaultBatchSize = 50
+
+// Options selects the products to apply the templates to. Soft-deleted
+// products are never touched.
+type Options struct {
+	// Category limits the run to products of one category (see
+	// model.NormalizeProductCategory); empty for all products.
+	Category string
+	// BatchSize is the number of products written per transaction (0: 50).
+	BatchSize int
+	// Author is recorded on the revisions Apply saves.
+	Author model.User
+}
+
+// ProductChange describes one product whose detail changes (or would), or
+// could not be merged.
+type ProductChange struct {
+	ProductID uint   `json:"productId"`
+	StyleNo   string `json:"styleNo"`
+	Category  string `json:"category"`
+	// Template is the AppSetting key of the template applied; empty for the
+	// built-in default.
+	Template string             `json:"template,omitempty"`
+	Changes  []model.JSONChange `json:"changes,omitempty"`
+	// Revision is the revision Apply saved; 0 on a preview.
+	Revision int    `json:"revision,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Progress counts the products handled so far.
+type Progress struct {
+	Total     int `json:"total"`
+	Processed int `json:"processed"`
+	Changed   int `json:"changed"`
+	Failed    int `json:"failed"`
+}
+
+// Count returns the number of products opts selects.
+func Count(ctx context.Context, db *gorm.DB, opts Options) (int, error) {
+	var n int64
+	err := scope(db.WithContext(ctx), opts).Count(&n).Error
+	return int(n), err
+}
+
+// Preview reports every product selected by opts whose detail the templates
+// would change, and how, without writing anything.
+func Preview(ctx context.Context, db *gorm.DB, opts Options) ([]ProductChange, Progress, error) {
+	items := []ProductChange{}
+	progress, err := run(ctx, db, opts, false, func(pc ProductChange) { items = append(items, pc) }, nil)
+	return items, progress, err
+}
+
+// Apply writes the merged details back. Each batch of products is written in
+// one transaction, with a revision per changed product; a product whose
+// detail cannot be merged is counted as failed and skipped. progress, when
+// not nil, is called after every committed batch; an error from it stops the
+// run. Batches committed before an error stay applied.
+func Apply(ctx context.Context, db *gorm.DB, opts Options, progress func(Progress) error) (Progress, error) {
+	return run(ctx, db, opts, true, nil, progress)
+}
+
+func run(ctx context.Context, db *gorm.DB, opts Options, write bool, report func(ProductChange), progress func(Progress) error) (Progress, error) {
+	var res Progress
+	total, err := Count(ctx, db, opts)
+	if err != nil {
+		return res, err
+	}
+	res.Total = total
+	templates, err := LoadTemplates(ctx, db)
+	if err != nil {
+		return res, err
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	var batch []model.Product
+	err = scope(db.WithContext(ctx), opts).Select("id").Order("id asc").
+		FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			step := res
+			if err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				for _, row := range batch {
+					step.Processed++
+					pc, changed, err := applyProduct(tx, templates, row.ID, write, opts.Author)
+					if err != nil {
+						return err
+					}
+					switch {
+					case pc.Error != "":
+						step.Failed++
+					case changed:
+						step.Changed++
+					default:
+						continue
+					}
+					if report != nil {
+						report(pc)
+					}
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			res = step
+			if progress != nil {
+				return progress(res)
+			}
+			return nil
+		}).Error
+	return res, err
+}
+
+func scope(db *gorm.DB, opts Options) *gorm.DB {
+	q := db.Model(&model.Product{}).Where("deleted_at IS NULL")
+	if opts.Category != "" {
+		q = q.Where("LOWER(TRIM(category)) = ?", opts.Category)
+	}
+	return q
+}
+
+// applyProduct merges the template into one product, reloaded inside the
+// batch transaction so a concurrent save is not overwritten. A detail that
+// cannot be merged is reported in ProductChange.Error; the error returned is
+// a database error, which aborts the transaction.
+func applyProduct(tx *gorm.DB, templates Templates, id uint, write bool, author model.User) (ProductChange, bool, error) {
+	var products []model.Product
+	if err := tx.Where("deleted_at IS NULL").Where("id = ?", id).Limit(1).Find(&products).Error; err != nil {
+		return ProductChange{}, false, err
+	}
+	if len(products) == 0 {
+		// Deleted since the batch was listed.
+		return ProductChange{}, false, nil
+	}
+	p := products[0]
+	var variants []model.ProductVariant
+	if err := tx.Where("product_id = ?", p.ID).Find(&variants).Error; err != nil {
+		return ProductChange{}, false, err
+	}
+	key, tpl := templates.For(p.Category)
+	pc := ProductChange{ProductID: p.ID, StyleNo: p.StyleNo, Category: p.Category, Template: key}
+
+	current := model.UpgradeDetail(p.DetailJSON)
+	merged, err := model.MergeProductDetailWithTemplate(tpl, current)
+	if err == nil && len(variants) > 0 {
+		// Products with variants get their option groups from them, as on
+		// every other save.
+		model.SortVariants(variants)
+		merged, err = model.ApplyVariantOptionGroups(merged, variants)
+	}
+	if err == nil {
+		pc.Changes, err = model.JSONDiff(current, merged)
+	}
+	if err != nil {
+		pc.Error = err.Error()
+		return pc, false, nil
+	}
+	if len(pc.Changes) == 0 || !write {
+		return pc, len(pc.Changes) > 0, nil
+	}
+
+	before := p
+	if err := tx.Model(&model.Product{}).Where("id = ?", p.ID).Update("detail_json", merged).Error; err != nil {
+		return ProductChange{}, false, err
+	}
+	if err := tx.First(&p, p.ID).Error; err != nil {
+		return ProductChange{}, false, err
+	}
+	if err := assets.SyncProduct(tx, p); err != nil {
+		return ProductChange{}, false, err
+	}
+	rev, err := revisions.Save(tx, &before, p, revisions.Options{Source: model.RevisionSourceTemplate, Author: author})
+	if err != nil {
+		return ProductChange{}, false, err
+	}
+	pc.Revision = rev.Revision
+	return pc, true, nil
+}
+
+// Templates holds the stored detail templates, upgraded to the current
+// schema_version, by AppSetting key.
+type Templates map[string]json.RawMessage
+
+// LoadTemplates loads the global and the per-category templates.
+func LoadTemplates(ctx context.Context, db *gorm.DB) (Templates, error) {
+	var settings []model.AppSetting
+	if err := db.WithContext(ctx).
+		Where("key = ? OR key LIKE ?", model.SettingKeyProductDetailTemplate, model.ProductDetailTemplateKeyPrefix+"%").
+		Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	t := Templates{}
+	for _, s := range settings {
+		if len(s.ValueJSON) == 0 {
+			continue
+		}
+		// "_" is a LIKE wildcard.
+		if s.Key == model.SettingKeyProductDetailTemplate || strings.HasPrefix(s.Key, model.ProductDetailTemplateKeyPrefix) {
+			t[s.Key] = model.UpgradeDetail(s.ValueJSON)
+		}
+	}
+	return t, nil
+}
+
+// For returns the template products of a category get, and its key: the
+// category's own template, else the global one, else the built-in default
+// (with an empty key).
+func (t Templates) For(category string) (string, json.RawMessage) {
+	for _, key := range []string{model.ProductDetailTemplateKey(category), model.SettingKeyProductDetailTemplate} {
+		if tpl, ok := t[key]; ok {
+			return key, tpl
+		}
+	}
+	return "", model.DefaultProductDetailTemplate()
+}
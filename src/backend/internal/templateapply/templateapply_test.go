@@ -0,0 +1,129 @@
+package templateapply
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"evening-gown/internal/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err == nil {
+		t.Cleanup(func() { _ = sqlDB.Close() })
+	}
+	if err := db.AutoMigrate(&model.Product{}, &model.ProductRevision{}, &model.ProductVariant{}, &model.ProductAsset{}, &model.Asset{}, &model.AppSetting{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestApply(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	deletedAt := time.Now().UTC()
+
+	template := func(specs ...string) json.RawMessage {
+		out := []any{}
+		for _, k := range specs {
+			out = append(out, map[string]any{"key": k, "label_i18n": map[string]any{"en": k}, "value_i18n": map[string]any{"en": ""}})
+		}
+		b, _ := json.Marshal(map[string]any{"schema_version": 2, "specs": out, "sections": []any{}})
+		return b
+	}
+	for _, s := range []model.AppSetting{
+		{Key: model.SettingKeyProductDetailTemplate, ValueJSON: template("pieces", "fabric")},
+		{Key: model.ProductDetailTemplateKey("bridal"), ValueJSON: template("veil")},
+	} {
+		if err := db.Create(&s).Error; err != nil {
+			t.Fatalf("create setting: %v", err)
+		}
+	}
+
+	products := []model.Product{
+		{Slug: "a", StyleNo: "A1", Category: "gown", DetailJSON: template("pieces")},
+		{Slug: "b", StyleNo: "B1", Category: "gown", DetailJSON: template("pieces", "fabric")},
+		{Slug: "c", StyleNo: "C1", Category: "Bridal", DetailJSON: json.RawMessage(`{"schema_version":2,"specs":[],"sections":[],"gallery":[{"url":"","objectKey":"products/C1/gallery/x.webp"}]}`)},
+		{Slug: "d", StyleNo: "D1", Category: "gown", DetailJSON: json.RawMessage(`[]`)},
+		{Slug: "e", StyleNo: "E1", Category: "gown", DetailJSON: template(), DeletedAt: &deletedAt},
+	}
+	for i := range products {
+		products[i].Season, products[i].Availability = "ss25", "in_stock"
+		if err := db.Create(&products[i]).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+	}
+	a, c := products[0], products[2]
+
+	items, res, err := Preview(ctx, db, Options{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if res != (Progress{Total: 4, Processed: 4, Changed: 2, Failed: 1}) || len(items) != 3 {
+		t.Fatalf("unexpected preview: %+v %+v", res, items)
+	}
+	if items[0].ProductID != a.ID || items[0].Template != model.SettingKeyProductDetailTemplate ||
+		len(items[0].Changes) != 1 || items[0].Changes[0].Op != "add" || items[0].Changes[0].Path != "/specs/1" {
+		t.Fatalf("unexpected change: %+v", items[0])
+	}
+	if items[1].ProductID != c.ID || items[1].Template != "product_detail_template:bridal" || items[2].Error == "" {
+		t.Fatalf("unexpected changes: %+v", items[1:])
+	}
+	var stored model.Product
+	db.First(&stored, a.ID)
+	if string(stored.DetailJSON) != string(a.DetailJSON) {
+		t.Fatalf("preview wrote the detail: %s", stored.DetailJSON)
+	}
+
+	if _, res, _ := Preview(ctx, db, Options{Category: "bridal"}); res.Total != 1 || res.Changed != 1 {
+		t.Fatalf("unexpected filtered preview: %+v", res)
+	}
+
+	var steps []Progress
+	res, err = Apply(ctx, db, Options{BatchSize: 2, Author: model.User{ID: 7, Email: "editor@example.com"}}, func(p Progress) error {
+		steps = append(steps, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if res.Changed != 2 || res.Failed != 1 || len(steps) != 2 || steps[0].Processed != 2 || steps[1] != res {
+		t.Fatalf("unexpected apply: %+v %+v", res, steps)
+	}
+	db.First(&stored, a.ID)
+	var detail struct {
+		Specs []struct {
+			Key string `json:"key"`
+		} `json:"specs"`
+	}
+	_ = json.Unmarshal(stored.DetailJSON, &detail)
+	if len(detail.Specs) != 2 || detail.Specs[1].Key != "fabric" {
+		t.Fatalf("unexpected stored detail: %s", stored.DetailJSON)
+	}
+	var revs []model.ProductRevision
+	db.Where("product_id = ?", a.ID).Order("revision asc").Find(&revs)
+	if len(revs) != 2 || revs[0].Source != model.RevisionSourceInitial || revs[1].Source != model.RevisionSourceTemplate || revs[1].AuthorID != 7 {
+		t.Fatalf("unexpected revisions: %+v", revs)
+	}
+
+	var refs []model.ProductAsset
+	db.Where("product_id = ?", c.ID).Find(&refs)
+	if len(refs) != 1 || refs[0].ObjectKey != "products/C1/gallery/x.webp" {
+		t.Fatalf("asset references were not synced: %+v", refs)
+	}
+
+	// Idempotent.
+	if _, res, _ := Preview(ctx, db, Options{}); res.Changed != 0 || res.Failed != 1 {
+		t.Fatalf("unexpected second preview: %+v", res)
+	}
+}